@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"ctp/pkg/db"
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+)
+
+// auditCmd lists the deletions, restorations and purges of users
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Lists the records of the audit log",
+	Long: `Lists the deletions, restorations and purges of users as JSON, the most recent first.
+The records of a purge contain the number of documents purged from each collection. The records are kept after the users are purged.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		db, err := db.New(ctx, config.fbkey)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		records, err := db.GetAuditRecords(ctx)
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(records)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringVarP(&config.fbkey, "fbkey", "f", "./fbkey.json", "Path to the firebase key file")
+}
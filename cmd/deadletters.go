@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"ctp/pkg/db"
+	"ctp/pkg/models"
+	"encoding/json"
+
+	"github.com/spf13/cobra"
+)
+
+// deadLetter is a job in the dead-letter list, along with the user it belongs to
+type deadLetter struct {
+	UserID string `json:"userId"`
+	*models.Job
+}
+
+// deadLettersCmd lists the jobs which have failed for reasons other than the user's request
+var deadLettersCmd = &cobra.Command{
+	Use:   "deadletters",
+	Short: "Lists the jobs in the dead-letter list",
+	Long: `Lists the jobs which have failed for reasons other than the user's request as JSON, the most recently failed first.
+These are the jobs which failed after every retry, e.g. as an external API was down, and the jobs which failed unexpectedly.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+
+		db, err := db.New(ctx, config.fbkey)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		jobs, err := db.GetDeadLetters(ctx)
+		if err != nil {
+			return err
+		}
+
+		letters := make([]deadLetter, 0, len(jobs))
+		for i := range jobs {
+			letters = append(letters, deadLetter{UserID: jobs[i].UserID, Job: &jobs[i]})
+		}
+
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(letters)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deadLettersCmd)
+
+	deadLettersCmd.Flags().StringVarP(&config.fbkey, "fbkey", "f", "./fbkey.json", "Path to the firebase key file")
+}
@@ -20,10 +20,13 @@ import (
 	"context"
 	"ctp/pkg/auth"
 	"ctp/pkg/blizzard"
+	"ctp/pkg/catalog"
 	"ctp/pkg/db"
 	"ctp/pkg/jagex"
+	"ctp/pkg/metadata"
 	"ctp/pkg/models"
 	"ctp/pkg/riot"
+	"ctp/pkg/tracing"
 	"ctp/pkg/user"
 	"ctp/pkg/valve"
 	"fmt"
@@ -46,6 +49,14 @@ var config struct {
 	clientTimeout   int
 	port            int
 	fbkey           string
+	otlpEndpoint    string
+	achievements    bool
+	overwatchAPI    string
+	xpRates         string
+	catalog         string
+	metadata        bool
+	workers         int
+	deletionGrace   int
 }
 
 // rootCmd represents the base command
@@ -84,21 +95,64 @@ var rootCmd = &cobra.Command{
 			domain = "localhost"
 		}
 
-		// Initializing each of the provider packages
+		// Linking Battle.net accounts is optional, and only enabled if the OAuth client is given
+		battleNet := blizzard.BattleNetConfig{
+			ClientID:     os.Getenv("BLIZZARD_CLIENT_ID"),
+			ClientSecret: os.Getenv("BLIZZARD_CLIENT_SECRET"),
+			RedirectURL:  fmt.Sprintf("http://%s:%d/api/v2/battlenet/callback", domain, config.port),
+			StateSecret:  hmacSecret,
+		}
+
+		// The Runescape playtime is estimated with the rate model built in, unless another is given
+		rates := jagex.DefaultRateModel()
+		if config.xpRates != "" {
+			var err error
+			rates, err = jagex.LoadRateModel(config.xpRates)
+			if err != nil {
+				logrus.WithError(err).Fatalf("Unable to load XP rates:%s", err)
+			}
+		}
+
+		// Duplicate games are identified with the game catalog built in, unless another is given
+		gameCatalog := catalog.DefaultCatalog()
+		if config.catalog != "" {
+			var err error
+			gameCatalog, err = catalog.LoadCatalog(config.catalog)
+			if err != nil {
+				logrus.WithError(err).Fatalf("Unable to load game catalog:%s", err)
+			}
+		}
+
+		// Initializing each of the provider packages.
+		// The getter sends requests bound to the context of the request, such that they are cancelled along with it.
+		getter := models.NewGetter(client)
 		riot := riot.New(client, riotAPIKey)
-		valve := valve.New(client, valveAPIKey)
-		blizzard := blizzard.New(client)
-		jagex := jagex.New(client)
+		valve := valve.New(getter, valveAPIKey, config.achievements)
+		blizzard := blizzard.New(getter, client, config.overwatchAPI, battleNet)
+		jagex := jagex.New(getter, rates)
+
+		// ctxC is the base context for every request, which is cancelled if the server doesn't shut down gracefully in time
+		ctx := context.Background()
+		ctxC, cancelC := context.WithCancel(ctx)
+		defer cancelC()
 
 		// Getting a database instance
-		db, err := db.New(config.fbkey)
+		db, err := db.New(ctxC, config.fbkey)
 		if err != nil {
 			logrus.WithError(err).Fatalf("Unable to get new Database:%s", err)
 		}
 
-		ctx := context.Background()
-		ctxC, cancelC := context.WithCancel(ctx)
-		defer cancelC()
+		// Tracing is only enabled if a collector endpoint is given, either as a flag or the standard OpenTelemetry variable
+		otlpEndpoint := config.otlpEndpoint
+		if otlpEndpoint == "" {
+			otlpEndpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		}
+
+		stopTracing := func(context.Context) error { return nil }
+		if otlpEndpoint != "" {
+			logrus.Infof("Exporting traces to %s", otlpEndpoint)
+			stopTracing = tracing.Setup(client, otlpEndpoint, "ctp")
+		}
 
 		// getting a new authenticator, which is passed to the usermanager and server
 		auth, err := auth.New(ctxC, db, config.port, domain, clientID, clientSecret, hmacSecret)
@@ -115,8 +169,21 @@ var rootCmd = &cobra.Command{
 			models.TokenGenerator
 		}{valve, riot, blizzard, jagex, auth}
 
-		um := user.New(db, organizer)
-		srv := server.New(config.port, um, auth)
+		// The games are enriched with metadata from the Steam store, cached in the database
+		var enricher *metadata.Enricher
+		if config.metadata {
+			enricher = metadata.New(metadata.NewSteamStore(getter), db, gameCatalog)
+		}
+
+		um := user.New(db, organizer, gameCatalog, enricher)
+		um.SetDeletionGrace(time.Duration(config.deletionGrace) * 24 * time.Hour)
+		srv := server.New(ctxC, config.port, um, auth)
+
+		// The workers run the jobs queued in the database until the server has shut down.
+		// Jobs stopped while running are run again, by this or another instance, when their lease expires.
+		ctxJ, stopJobs := context.WithCancel(ctx)
+		defer stopJobs()
+		um.StartJobs(ctxJ, config.workers)
 
 		// Making an channel to listen for errors (later blocking until either error or signal is received)
 		errChan := make(chan error)
@@ -147,7 +214,16 @@ var rootCmd = &cobra.Command{
 
 		// Attempting to shut down the server
 		if err := srv.Shutdown(ctxT); err != nil {
-			logrus.WithError(err).Fatalf("Unable to gracefully shutdown server")
+			// cancelling in-flight requests, such that they stop contacting the database and external APIs
+			cancelC()
+			logrus.WithError(err).Errorf("Unable to gracefully shutdown server, cancelled in-flight requests")
+		}
+
+		stopJobs()
+
+		// Sending any remaining spans to the collector
+		if err := stopTracing(ctxT); err != nil {
+			logrus.WithError(err).Warn("Unable to export remaining spans")
 		}
 
 		logrus.Infoln("Finished shutting down")
@@ -175,6 +251,22 @@ func init() {
 	rootCmd.Flags().BoolVarP(&config.verbose, "verbose", "v", false, "Verbose logging")
 	rootCmd.Flags().BoolVarP(&config.jsonFormatter, "jsonFormatter", "j", false, "JSON logging format")
 	rootCmd.Flags().StringVarP(&config.fbkey, "fbkey", "f", "./fbkey.json", "Path to the firebase key file")
+	rootCmd.Flags().StringVarP(&config.otlpEndpoint, "otlpEndpoint", "o", "",
+		"Sets the OpenTelemetry collector endpoint (OTLP/HTTP) traces are exported to. Tracing is disabled if not set")
+	rootCmd.Flags().BoolVarP(&config.achievements, "achievements", "a", false,
+		"Gets the achievement progress for each Steam game when updating games (two extra requests per game)")
+	rootCmd.Flags().StringVarP(&config.overwatchAPI, "overwatchAPI", "w", blizzard.DefaultBackend,
+		"Sets the base URL of the backend (serving the OverFast API) the Overwatch 2 statistics are collected from")
+	rootCmd.Flags().StringVarP(&config.xpRates, "xpRates", "x", "",
+		"Path to a YAML (or JSON) rate model used to estimate the Runescape playtime, instead of the one built in")
+	rootCmd.Flags().StringVarP(&config.catalog, "catalog", "g", "",
+		"Path to a YAML (or JSON) game catalog used to merge duplicate games, instead of the one built in")
+	rootCmd.Flags().BoolVarP(&config.metadata, "metadata", "m", true,
+		"Enriches the games with their genres, developer, cover and release year from the Steam store when updating games")
+	rootCmd.Flags().IntVarP(&config.workers, "workers", "n", 4,
+		"Sets the number of workers running the jobs of the users, such as updating their games")
+	rootCmd.Flags().IntVarP(&config.deletionGrace, "deletionGrace", "d", 30,
+		"Sets the grace period (in days) a deleted user can restore their account in, before the user and their data are purged")
 }
 
 // setupLog initializes logrus logger
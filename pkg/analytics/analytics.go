@@ -0,0 +1,454 @@
+// Package analytics derives statistics from the user's games and the history of their playtime,
+// which is recorded as a snapshot of the playtime for each day the games are updated.
+package analytics
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ctp/pkg/models"
+	"ctp/pkg/tracing"
+)
+
+// dateFormat is the format of the dates of the snapshots
+const dateFormat = "2006-01-02"
+
+// historyDays is how far back the history is used for the stats
+const historyDays = 365
+
+// improvementDays is the period the most improved game is found for
+const improvementDays = 30
+
+// topGames is the number of games listed in TopGames
+const topGames = 5
+
+// publicMaxAge is how long the playtime of the public users is used for the percentiles and ranks before it is
+// queried again
+const publicMaxAge = time.Hour
+
+// gainsTimeout bounds the queries for the playtime gained by the public users. They are not cancelled with the request
+// they are made for, as the playtime is cached for every request.
+const gainsTimeout = time.Minute
+
+// Analyzer records the history of the users' playtime and derives the stats from it
+type Analyzer struct {
+	db models.Database
+
+	mu        sync.Mutex
+	public    []int // the total playtime (hours) of the public users, sorted
+	fetchedAt time.Time
+	gains     map[int]*yearGains // the playtime gained by the public users, by year
+	forgotten int                // the number of times the cached playtime has been dropped
+
+	gainsMu sync.Mutex // held while querying the playtime gained by the public users, such that it is queried once
+}
+
+// yearGains is the playtime (minutes) gained in a year by each public user, by their ID
+type yearGains struct {
+	minutes   map[string]int
+	fetchedAt time.Time
+}
+
+// New returns an analyzer storing the history in the database
+func New(db models.Database) *Analyzer {
+	return &Analyzer{db: db}
+}
+
+// Record stores the snapshot of the user's playtime for today, replacing the snapshot of an earlier update today,
+// along with the playtime gained this year up to the snapshot
+func (a *Analyzer) Record(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "analytics.Record")
+	defer span.End()
+
+	now := time.Now()
+	err := a.db.SetSnapshot(ctx, user.ID, NewSnapshot(user.Games, now))
+	if err != nil {
+		return err
+	}
+
+	_, err = a.storeGains(ctx, user.ID, now.UTC().Year())
+
+	return err
+}
+
+// storeGains computes the playtime gained by the user in the year from their history, and stores it
+func (a *Analyzer) storeGains(ctx context.Context, id string, year int) (int, error) {
+	snapshots, err := a.db.GetSnapshots(ctx, id, strconv.Itoa(year-1)+"-01-01")
+	if err != nil {
+		return 0, err
+	}
+
+	minutes := yearMinutes(snapshots, year)
+
+	return minutes, a.db.SetYearPlaytime(ctx, id, &models.YearPlaytime{Year: year, Minutes: minutes})
+}
+
+// NewSnapshot returns the snapshot of the playtime of the games at the time
+func NewSnapshot(games []models.Game, t time.Time) *models.PlaytimeSnapshot {
+	snapshot := &models.PlaytimeSnapshot{Date: t.UTC().Format(dateFormat), Games: []models.GamePlaytime{}}
+	for i := range games {
+		minutes := games[i].PlayMinutes()
+		if minutes == 0 {
+			continue
+		}
+
+		snapshot.Minutes += minutes
+		snapshot.Games = append(snapshot.Games, models.GamePlaytime{Name: games[i].Name, Minutes: minutes,
+			SelfReported: games[i].PlaytimeSelfReported()})
+	}
+
+	return snapshot
+}
+
+// Stats returns the stats of the user's games, using the history of the last year
+func (a *Analyzer) Stats(ctx context.Context, user *models.User) (*models.Stats, error) {
+	ctx, span := tracing.Start(ctx, "analytics.Stats")
+	defer span.End()
+
+	now := time.Now()
+
+	snapshots, err := a.db.GetSnapshots(ctx, user.ID, now.AddDate(0, 0, -historyDays).UTC().Format(dateFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	// the current games are the latest snapshot, which is not stored if the games have not been updated today
+	current := NewSnapshot(user.Games, now)
+	if len(snapshots) > 0 && snapshots[len(snapshots)-1].Date == current.Date {
+		snapshots = snapshots[:len(snapshots)-1]
+	}
+	history := append(snapshots, *current)
+
+	stats := &models.Stats{
+		TotalMinutes: current.Minutes,
+		Games:        len(user.Games),
+		TopGames:     top(user.Games, current.Minutes),
+		Providers:    byProvider(user.Games),
+		Genres:       models.PlaytimeByGenre(user.Games),
+		HistoryDays:  days(history[0].Date, current.Date),
+	}
+
+	stats.AverageWeeklyHours = averageWeeklyHours(history, user.Games)
+	stats.LongestStreak = longestStreak(history)
+	stats.MostImproved = mostImproved(history, now)
+
+	stats.Percentile, err = a.percentile(ctx, user.TotalGameTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// top returns the most played games, with their share of the total playtime
+func top(games []models.Game, total int) []models.GameShare {
+	shares := []models.GameShare{}
+	for i := range games {
+		if minutes := games[i].PlayMinutes(); minutes > 0 {
+			shares = append(shares, models.GameShare{Name: games[i].Name, Minutes: minutes, Share: percent(minutes, total)})
+		}
+	}
+
+	sort.SliceStable(shares, func(i, j int) bool {
+		return shares[i].Minutes > shares[j].Minutes
+	})
+
+	if len(shares) > topGames {
+		shares = shares[:topGames]
+	}
+
+	return shares
+}
+
+// byProvider returns the playtime of the games from each provider, sorted by the playtime (and then the provider)
+func byProvider(games []models.Game) []models.ProviderPlaytime {
+	providers := make(map[string]*models.ProviderPlaytime)
+	for i := range games {
+		name := provider(&games[i])
+
+		p, ok := providers[name]
+		if !ok {
+			p = &models.ProviderPlaytime{Provider: name}
+			providers[name] = p
+		}
+
+		p.Minutes += games[i].PlayMinutes()
+		p.Games++
+	}
+
+	result := make([]models.ProviderPlaytime, 0, len(providers))
+	for _, p := range providers {
+		result = append(result, *p)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Minutes != result[j].Minutes {
+			return result[i].Minutes > result[j].Minutes
+		}
+
+		return result[i].Provider < result[j].Provider
+	})
+
+	return result
+}
+
+// provider returns the provider of the game: the launcher it is imported from or "manual", "steam" for Steam games,
+// or the provider of the account the game is fetched from (lol, overwatch, battlenet or runescape).
+// Merged games are from the provider of the entry shown.
+func provider(game *models.Game) string {
+	switch {
+	case game.Source != "":
+		return game.Source
+	case game.AppID != 0:
+		return "steam"
+	case game.Name == "LeagueOfLegends":
+		return "lol"
+	case game.Name == "Overwatch 2":
+		return "overwatch"
+	case game.Name == models.WorldOfWarcraft, game.Name == models.DiabloIII, game.Name == models.StarCraftII:
+		return "battlenet"
+	case strings.Contains(strings.ToLower(game.Name), "runescape"):
+		return "runescape"
+	}
+
+	return "other"
+}
+
+// averageWeeklyHours returns the average hours played per week over the history. If the history covers less than a
+// week, the average of the last two weeks is used instead, as reported by the providers with recent playtime (Steam).
+func averageWeeklyHours(history []models.PlaytimeSnapshot, games []models.Game) float64 {
+	first, last := history[0], history[len(history)-1]
+
+	span := days(first.Date, last.Date)
+	if span < 7 {
+		var recent int
+		for i := range games {
+			recent += games[i].RecentMinutes
+		}
+
+		return round(float64(recent) / 2 / 60)
+	}
+
+	// the playtime decreases if games are removed, e.g. an imported library
+	played := last.Minutes - first.Minutes
+	if played < 0 {
+		played = 0
+	}
+
+	return round(float64(played) / float64(span) * 7 / 60)
+}
+
+// longestStreak returns the most consecutive days played in the history. A day is played if the playtime increased
+// since the snapshot of the day before, such that days without a snapshot (not updated) end the streak.
+func longestStreak(history []models.PlaytimeSnapshot) int {
+	var longest, streak int
+	for i := 1; i < len(history); i++ {
+		if days(history[i-1].Date, history[i].Date) == 1 && history[i].Minutes > history[i-1].Minutes {
+			streak++
+		} else {
+			streak = 0
+		}
+
+		if streak > longest {
+			longest = streak
+		}
+	}
+
+	return longest
+}
+
+// mostImproved returns the game with the most playtime gained the last 30 days, compared to the last snapshot from
+// before (or the first snapshot if the history is shorter). Games added since are left out, as their playtime may be
+// from before, e.g. the games of an imported library. Returns nil if no game has been played.
+func mostImproved(history []models.PlaytimeSnapshot, now time.Time) *models.GameImprovement {
+	if len(history) < 2 {
+		return nil
+	}
+
+	since := now.AddDate(0, 0, -improvementDays).UTC().Format(dateFormat)
+
+	base := history[0]
+	for _, snapshot := range history[:len(history)-1] {
+		if snapshot.Date <= since {
+			base = snapshot
+		}
+	}
+
+	before := make(map[string]int)
+	for _, game := range base.Games {
+		before[game.Name] = game.Minutes
+	}
+
+	var improved *models.GameImprovement
+	for _, game := range history[len(history)-1].Games {
+		minutes, ok := before[game.Name]
+		if !ok || game.Minutes <= minutes {
+			continue
+		}
+
+		if improved == nil || game.Minutes-minutes > improved.Minutes {
+			improved = &models.GameImprovement{Name: game.Name, Minutes: game.Minutes - minutes}
+		}
+	}
+
+	return improved
+}
+
+// percentile returns the percentage of the public users with less playtime than the hours,
+// or nil if there are no public users
+func (a *Analyzer) percentile(ctx context.Context, hours int) (*float64, error) {
+	public, err := a.publicPlaytimes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(public) == 0 {
+		return nil, nil
+	}
+
+	p := percent(sort.SearchInts(public, hours), len(public))
+
+	return &p, nil
+}
+
+// Forget drops the cached playtime of the public users, such that the user is left out of (or included in) the
+// percentiles and ranks right away, e.g. once the user has been deleted. Returns the number of years the playtime
+// gained by the user was cached for.
+func (a *Analyzer) Forget(id string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cached := 0
+	for _, gains := range a.gains {
+		if _, ok := gains.minutes[id]; ok {
+			cached++
+		}
+	}
+
+	a.public, a.fetchedAt, a.gains = nil, time.Time{}, nil
+	a.forgotten++
+
+	return cached
+}
+
+// publicPlaytimes returns the total playtime (hours) of the public users, sorted. The playtime is queried at most
+// once an hour, as every public user is queried.
+func (a *Analyzer) publicPlaytimes(ctx context.Context) ([]int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if time.Since(a.fetchedAt) > publicMaxAge {
+		public, err := a.db.GetPublicPlaytimes(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Ints(public)
+		a.public, a.fetchedAt = public, time.Now()
+	}
+
+	return a.public, nil
+}
+
+// publicGains returns the playtime (minutes) gained in the year by each public user, by their ID. The playtime is
+// queried at most once an hour for each year, without holding the lock of the analyzer meanwhile, and with a context
+// of its own, as it is cached for every request.
+func (a *Analyzer) publicGains(ctx context.Context, year int) (map[string]int, error) {
+	a.gainsMu.Lock()
+	defer a.gainsMu.Unlock()
+
+	a.mu.Lock()
+	gains, forgotten := a.gains[year], a.forgotten
+	a.mu.Unlock()
+
+	if gains != nil && time.Since(gains.fetchedAt) <= publicMaxAge {
+		return gains.minutes, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gainsTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "analytics.publicGains")
+	defer span.End()
+
+	minutes, err := a.queryGains(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// the playtime is not cached if it was dropped while it was queried, as it may contain a user who was forgotten
+	if a.forgotten == forgotten {
+		if a.gains == nil {
+			a.gains = make(map[int]*yearGains)
+		}
+		a.gains[year] = &yearGains{minutes: minutes, fetchedAt: time.Now()}
+	}
+
+	return minutes, nil
+}
+
+// queryGains queries the playtime gained in the year by each public user, by their ID. The playtime is stored with the
+// history of the users, and is only computed from the history of the public users it is not stored for yet (e.g. the
+// years before the users were last updated), which is then stored such that it is only computed once.
+func (a *Analyzer) queryGains(ctx context.Context, year int) (map[string]int, error) {
+	ids, err := a.db.GetPublicUserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := a.db.GetYearPlaytimes(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+
+	minutes := make(map[string]int, len(ids))
+	for _, id := range ids {
+		gained, ok := stored[id]
+		if !ok {
+			gained, err = a.storeGains(ctx, id, year)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		minutes[id] = gained
+	}
+
+	return minutes, nil
+}
+
+// days returns the number of days between the dates
+func days(from, to string) int {
+	f, err := time.Parse(dateFormat, from)
+	if err != nil {
+		return 0
+	}
+
+	t, err := time.Parse(dateFormat, to)
+	if err != nil {
+		return 0
+	}
+
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// percent returns the part of the total in percent, rounded to one decimal
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return round(float64(part) / float64(total) * 100)
+}
+
+// round rounds to one decimal
+func round(f float64) float64 {
+	return math.Round(f*10) / 10
+}
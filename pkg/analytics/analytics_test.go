@@ -0,0 +1,244 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockDB only implements the methods of the database used by the analyzer, the others panic
+type mockDB struct {
+	models.Database
+	snapshots []models.PlaytimeSnapshot
+	public    []int
+	publicIDs []string
+	others    map[string][]models.PlaytimeSnapshot // the snapshots of other users, by their ID
+	gains     map[int]map[string]int               // the stored playtime gained by the users, by year and ID
+	queries   int                                  // the number of times the public playtime is queried
+	histories int                                  // the number of times the snapshots of other users are queried
+	err       error
+}
+
+func (m *mockDB) GetSnapshots(ctx context.Context, id, since string) ([]models.PlaytimeSnapshot, error) {
+	all := m.snapshots
+	if others, ok := m.others[id]; ok {
+		all = others
+		m.histories++
+	}
+
+	var snapshots []models.PlaytimeSnapshot
+	for _, snapshot := range all {
+		if snapshot.Date >= since {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	return snapshots, m.err
+}
+
+func (m *mockDB) SetSnapshot(ctx context.Context, id string, snapshot *models.PlaytimeSnapshot) error {
+	m.snapshots = append(m.snapshots, *snapshot)
+	return m.err
+}
+
+func (m *mockDB) SetYearPlaytime(ctx context.Context, id string, playtime *models.YearPlaytime) error {
+	if m.gains == nil {
+		m.gains = make(map[int]map[string]int)
+	}
+	if m.gains[playtime.Year] == nil {
+		m.gains[playtime.Year] = make(map[string]int)
+	}
+	m.gains[playtime.Year][id] = playtime.Minutes
+
+	return m.err
+}
+
+func (m *mockDB) GetYearPlaytimes(ctx context.Context, year int) (map[string]int, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	minutes := make(map[string]int)
+	for id, gained := range m.gains[year] {
+		minutes[id] = gained
+	}
+
+	return minutes, m.err
+}
+
+func (m *mockDB) GetPublicPlaytimes(ctx context.Context) ([]int, error) {
+	m.queries++
+	return m.public, m.err
+}
+
+func (m *mockDB) GetPublicUserIDs(ctx context.Context) ([]string, error) {
+	m.queries++
+	return m.publicIDs, m.err
+}
+
+// day returns the date of the day relative to today
+func day(days int) string {
+	return time.Now().AddDate(0, 0, days).UTC().Format(dateFormat)
+}
+
+// snapshot returns a snapshot of the day relative to today, with the minutes of Portal and Dota 2
+func snapshot(days, portal, dota int) models.PlaytimeSnapshot {
+	return models.PlaytimeSnapshot{Date: day(days), Minutes: portal + dota, Games: []models.GamePlaytime{
+		{Name: "Portal", Minutes: portal},
+		{Name: "Dota 2", Minutes: dota},
+	}}
+}
+
+func TestStats(t *testing.T) {
+	games := []models.Game{
+		{Name: "Dota 2", AppID: 570, Minutes: 6000, RecentMinutes: 840,
+			Metadata: &models.GameMetadata{Genres: []string{"Action", "Strategy"}}},
+		{Name: "Portal", AppID: 400, Minutes: 1200},
+		{Name: "LeagueOfLegends", Time: 30},
+		{Name: "Celeste", Source: "gog", Minutes: 600},
+		{Name: "Halo", Source: "manual", Minutes: 200, SelfReported: true},
+		{Name: "Unplayed", AppID: 1},
+	}
+	improved := &models.GameImprovement{Name: "Dota 2", Minutes: 1000}
+	percentile := 50.0
+
+	var cases = []struct {
+		name               string
+		snapshots          []models.PlaytimeSnapshot
+		public             []int
+		err                error
+		expectedWeekly     float64
+		expectedStreak     int
+		expectedImproved   *models.GameImprovement
+		expectedPercentile *float64
+		expectedDays       int
+		expectedErr        error
+	}{
+		{"Test no history", nil, []int{10, 100, 200, 300}, nil, 7, 0, nil, &percentile, 0, nil},
+		{"Test history", []models.PlaytimeSnapshot{
+			snapshot(-60, 1000, 4000),
+			snapshot(-40, 1100, 5000),
+			snapshot(-3, 1200, 5800),
+			snapshot(-2, 1200, 5900),
+			snapshot(-1, 1200, 5950),
+		}, []int{10, 100, 200, 300}, nil, 9.3, 3, improved, &percentile, 60, nil},
+		{"Test short history", []models.PlaytimeSnapshot{snapshot(-2, 1200, 5900)}, nil, nil, 7, 0,
+			&models.GameImprovement{Name: "Dota 2", Minutes: 100}, nil, 2, nil},
+		{"Test old history", []models.PlaytimeSnapshot{snapshot(-400, 0, 0)}, nil, nil, 7, 0, nil, nil, 0, nil},
+		{"Test database error", nil, nil, errors.New("unavailable"), 0, 0, nil, nil, 0, errors.New("unavailable")},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDB{snapshots: tc.snapshots, public: tc.public, err: tc.err}
+			user := &models.User{ID: "12345", TotalGameTime: 150, Games: games}
+
+			stats, err := New(db).Stats(context.Background(), user)
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, 9800, stats.TotalMinutes)
+			assert.Equal(t, 6, stats.Games)
+			assert.Equal(t, []models.GameShare{
+				{Name: "Dota 2", Minutes: 6000, Share: 61.2},
+				{Name: "LeagueOfLegends", Minutes: 1800, Share: 18.4},
+				{Name: "Portal", Minutes: 1200, Share: 12.2},
+				{Name: "Celeste", Minutes: 600, Share: 6.1},
+				{Name: "Halo", Minutes: 200, Share: 2},
+			}, stats.TopGames)
+			assert.Equal(t, []models.ProviderPlaytime{
+				{Provider: "steam", Minutes: 7200, Games: 3},
+				{Provider: "lol", Minutes: 1800, Games: 1},
+				{Provider: "gog", Minutes: 600, Games: 1},
+				{Provider: "manual", Minutes: 200, Games: 1},
+			}, stats.Providers)
+			assert.Equal(t, []models.GenrePlaytime{
+				{Genre: "Action", Minutes: 6000, Games: 1},
+				{Genre: "Strategy", Minutes: 6000, Games: 1},
+			}, stats.Genres)
+			assert.Equal(t, tc.expectedWeekly, stats.AverageWeeklyHours)
+			assert.Equal(t, tc.expectedStreak, stats.LongestStreak)
+			assert.Equal(t, tc.expectedImproved, stats.MostImproved)
+			assert.Equal(t, tc.expectedPercentile, stats.Percentile)
+			assert.Equal(t, tc.expectedDays, stats.HistoryDays)
+		})
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	db := &mockDB{public: []int{0, 10, 10, 20, 30}}
+	a := New(db)
+
+	var cases = []struct {
+		hours    int
+		expected float64
+	}{
+		{0, 0},
+		{10, 20},
+		{15, 60},
+		{100, 100},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		p, err := a.percentile(context.Background(), tc.hours)
+		require.Nil(t, err)
+		assert.Equal(t, tc.expected, *p)
+	}
+
+	// the playtime of the public users is only queried again after an hour
+	assert.Equal(t, 1, db.queries)
+}
+
+func TestForget(t *testing.T) {
+	db := &mockDB{public: []int{0, 10}}
+	a := New(db)
+
+	_, err := a.percentile(context.Background(), 10)
+	require.Nil(t, err)
+	a.gains = map[int]*yearGains{
+		2023: {minutes: map[string]int{"12345": 60, "67890": 120}, fetchedAt: time.Now()},
+		2024: {minutes: map[string]int{"67890": 60}, fetchedAt: time.Now()},
+	}
+
+	assert.Equal(t, 1, a.Forget("12345"))
+	assert.Nil(t, a.gains)
+
+	// the playtime of the public users is queried again right away
+	_, err = a.percentile(context.Background(), 10)
+	require.Nil(t, err)
+	assert.Equal(t, 2, db.queries)
+}
+
+func TestRecord(t *testing.T) {
+	year := time.Now().UTC().Year()
+	lastYear := dated(strconv.Itoa(year-1)+"-12-31", models.GamePlaytime{Name: "Dota 2", Minutes: 5000})
+	db := &mockDB{snapshots: []models.PlaytimeSnapshot{lastYear}}
+	user := &models.User{ID: "12345", Games: []models.Game{
+		{Name: "Dota 2", AppID: 570, Minutes: 6000},
+		{Name: "Overwatch 2", Time: 2},
+		{Name: "Unplayed", AppID: 1},
+	}}
+
+	err := New(db).Record(context.Background(), user)
+	require.Nil(t, err)
+
+	assert.Equal(t, []models.PlaytimeSnapshot{lastYear, {Date: day(0), Minutes: 6120, Games: []models.GamePlaytime{
+		{Name: "Dota 2", Minutes: 6000},
+		{Name: "Overwatch 2", Minutes: 120},
+	}}}, db.snapshots)
+
+	// the playtime gained this year is stored with the snapshot, counting the whole playtime of the new game
+	assert.Equal(t, map[int]map[string]int{year: {"12345": 1120}}, db.gains)
+}
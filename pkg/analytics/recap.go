@@ -0,0 +1,220 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"ctp/pkg/models"
+	"ctp/pkg/tracing"
+)
+
+// Recap returns the summary of the user's playtime in the year. The playtime gained in the year is the difference
+// between the last snapshot of the year (or the current games for the current year) and the last snapshot from before
+// the year, or the first snapshot of the year if the history starts during the year.
+// Returns models.ErrNotFound if there is no history for the year.
+func (a *Analyzer) Recap(ctx context.Context, user *models.User, year int) (*models.Recap, error) {
+	ctx, span := tracing.Start(ctx, "analytics.Recap")
+	defer span.End()
+
+	now := time.Now()
+	if year > now.UTC().Year() {
+		return nil, models.NewReqErrStr("future year", "invalid year: "+strconv.Itoa(year)+" has not started")
+	}
+
+	// the snapshots of the year before are only needed for the last one, which the playtime is gained from
+	snapshots, err := a.db.GetSnapshots(ctx, user.ID, strconv.Itoa(year-1)+"-01-01")
+	if err != nil {
+		return nil, err
+	}
+
+	start := strconv.Itoa(year) + "-01-01"
+	end := strconv.Itoa(year) + "-12-31"
+	base, history := inYear(snapshots, year)
+
+	if current := NewSnapshot(user.Games, now); current.Date <= end {
+		if len(history) > 0 && history[len(history)-1].Date == current.Date {
+			history = history[:len(history)-1]
+		}
+		history = append(history, *current)
+	}
+
+	if len(history) == 0 {
+		return nil, fmt.Errorf("no history in %d: %w", year, models.ErrNotFound)
+	}
+
+	if base == nil {
+		base, history = &history[0], history[1:]
+	}
+
+	recap := &models.Recap{Year: year, Name: user.Displayed(), From: base.Date, To: base.Date, Games: []models.GamePlaytime{},
+		NewGames: []string{}}
+	if len(history) > 0 {
+		recap.To = history[len(history)-1].Date
+	}
+
+	if base.Date < start {
+		recap.From = start
+	}
+
+	recap.Games, recap.NewGames = gained(base, history)
+	for _, game := range recap.Games {
+		recap.Minutes += game.Minutes
+	}
+
+	recap.BiggestWeek = biggestWeek(base, history)
+
+	recap.Rank, err = a.rank(ctx, user, year, recap.Minutes)
+	if err != nil {
+		return nil, err
+	}
+
+	return recap, nil
+}
+
+// inYear returns the last snapshot from before the year (nil if there is none), and the snapshots of the year
+func inYear(snapshots []models.PlaytimeSnapshot, year int) (*models.PlaytimeSnapshot, []models.PlaytimeSnapshot) {
+	start := strconv.Itoa(year) + "-01-01"
+	end := strconv.Itoa(year) + "-12-31"
+
+	var base *models.PlaytimeSnapshot
+	var history []models.PlaytimeSnapshot
+	for i := range snapshots {
+		switch {
+		case snapshots[i].Date < start:
+			base = &snapshots[i]
+		case snapshots[i].Date <= end:
+			history = append(history, snapshots[i])
+		}
+	}
+
+	return base, history
+}
+
+// yearMinutes returns the playtime gained in the year according to the snapshots, as counted by Recap
+func yearMinutes(snapshots []models.PlaytimeSnapshot, year int) int {
+	base, history := inYear(snapshots, year)
+	if base == nil {
+		if len(history) == 0 {
+			return 0
+		}
+		base, history = &history[0], history[1:]
+	}
+
+	games, _ := gained(base, history)
+
+	var minutes int
+	for _, game := range games {
+		minutes += game.Minutes
+	}
+
+	return minutes
+}
+
+// gained returns the playtime gained in each game from the base to the last snapshot of the history, and the games
+// added since the base. The whole playtime of the games added is counted, as they are first played in the period.
+// A game is self-reported as flagged in the last snapshot.
+func gained(base *models.PlaytimeSnapshot, history []models.PlaytimeSnapshot) ([]models.GamePlaytime, []string) {
+	games, added := []models.GamePlaytime{}, []string{}
+	if len(history) == 0 {
+		return games, added
+	}
+
+	before := make(map[string]int)
+	for _, game := range base.Games {
+		before[game.Name] = game.Minutes
+	}
+
+	var newGames []models.GamePlaytime
+	for _, game := range history[len(history)-1].Games {
+		minutes, ok := before[game.Name]
+		if game.Minutes <= minutes {
+			continue
+		}
+
+		games = append(games, models.GamePlaytime{Name: game.Name, Minutes: game.Minutes - minutes, SelfReported: game.SelfReported})
+		if !ok {
+			newGames = append(newGames, game)
+		}
+	}
+
+	sort.SliceStable(games, func(i, j int) bool {
+		return games[i].Minutes > games[j].Minutes
+	})
+
+	sort.SliceStable(newGames, func(i, j int) bool {
+		return newGames[i].Minutes > newGames[j].Minutes
+	})
+
+	for _, game := range newGames {
+		added = append(added, game.Name)
+	}
+
+	return games, added
+}
+
+// biggestWeek returns the week with the most playtime gained, where the playtime gained between two snapshots is
+// counted in the week of the later one. Returns nil if no playtime has been gained.
+func biggestWeek(base *models.PlaytimeSnapshot, history []models.PlaytimeSnapshot) *models.RecapWeek {
+	weeks := make(map[string]int)
+
+	previous := base
+	for i := range history {
+		if played := history[i].Minutes - previous.Minutes; played > 0 {
+			weeks[monday(history[i].Date)] += played
+		}
+		previous = &history[i]
+	}
+
+	var biggest *models.RecapWeek
+	for start, minutes := range weeks {
+		if biggest == nil || minutes > biggest.Minutes || (minutes == biggest.Minutes && start < biggest.Start) {
+			biggest = &models.RecapWeek{Start: start, Minutes: minutes}
+		}
+	}
+
+	return biggest
+}
+
+// monday returns the monday starting the week of the date
+func monday(date string) string {
+	t, err := time.Parse(dateFormat, date)
+	if err != nil {
+		return date
+	}
+
+	// time.Sunday is 0, and Sunday is the last day of the week
+	offset := (int(t.Weekday()) + 6) % 7
+
+	return t.AddDate(0, 0, -offset).Format(dateFormat)
+}
+
+// rank returns the position of the user among the public users by the playtime gained in the year, given the minutes
+// the user has gained, or nil if there are no other users to rank among
+func (a *Analyzer) rank(ctx context.Context, user *models.User, year, minutes int) (*models.Rank, error) {
+	gains, err := a.publicGains(ctx, year)
+	if err != nil {
+		return nil, err
+	}
+
+	// the user is ranked by the minutes of their recap, which include the current games, rather than by their history
+	users, more := 1, 0
+	for id, gain := range gains {
+		if id == user.ID {
+			continue
+		}
+
+		users++
+		if gain > minutes {
+			more++
+		}
+	}
+
+	if users < 2 {
+		return nil, nil
+	}
+
+	return &models.Rank{Rank: more + 1, Users: users}, nil
+}
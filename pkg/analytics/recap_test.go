@@ -0,0 +1,221 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dated returns a snapshot of the date with the games
+func dated(date string, games ...models.GamePlaytime) models.PlaytimeSnapshot {
+	snapshot := models.PlaytimeSnapshot{Date: date, Games: games}
+	for _, game := range games {
+		snapshot.Minutes += game.Minutes
+	}
+
+	return snapshot
+}
+
+// public returns the IDs of the public users, including the user with ID 12345, and the snapshots of the others.
+// In 2024, user 1 gained 3000 minutes and user 2 gained 100 minutes.
+func public() ([]string, map[string][]models.PlaytimeSnapshot) {
+	return []string{"1", "2", "12345"}, map[string][]models.PlaytimeSnapshot{
+		"1": {
+			dated("2023-06-01", models.GamePlaytime{Name: "Portal", Minutes: 1000}),
+			dated("2024-06-01", models.GamePlaytime{Name: "Portal", Minutes: 4000}),
+		},
+		"2": {
+			dated("2024-02-01", models.GamePlaytime{Name: "Celeste", Minutes: 100}),
+			dated("2024-05-01", models.GamePlaytime{Name: "Celeste", Minutes: 200}),
+		},
+	}
+}
+
+func TestRecap(t *testing.T) {
+	history := []models.PlaytimeSnapshot{
+		dated("2024-03-06", models.GamePlaytime{Name: "Portal", Minutes: 500},
+			models.GamePlaytime{Name: "Dota 2", Minutes: 2600}),
+		dated("2024-03-08", models.GamePlaytime{Name: "Portal", Minutes: 600},
+			models.GamePlaytime{Name: "Dota 2", Minutes: 2600}, models.GamePlaytime{Name: "Celeste", Minutes: 300}),
+		dated("2024-11-20", models.GamePlaytime{Name: "Portal", Minutes: 600},
+			models.GamePlaytime{Name: "Dota 2", Minutes: 4000},
+			models.GamePlaytime{Name: "Celeste", Minutes: 300, SelfReported: true}),
+		dated("2025-01-05", models.GamePlaytime{Name: "Portal", Minutes: 5000}),
+	}
+	before := []models.PlaytimeSnapshot{
+		dated("2022-12-01", models.GamePlaytime{Name: "Portal", Minutes: 100}),
+		dated("2023-12-20", models.GamePlaytime{Name: "Portal", Minutes: 500},
+			models.GamePlaytime{Name: "Dota 2", Minutes: 2000}),
+	}
+	year := time.Now().UTC().Year()
+	today := time.Now().UTC().Format(dateFormat)
+
+	var cases = []struct {
+		name        string
+		snapshots   []models.PlaytimeSnapshot
+		year        int
+		expected    *models.Recap
+		expectedErr error
+	}{
+		{"Test history before the year", append(before, history...), 2024, &models.Recap{
+			Year:    2024,
+			Name:    "Onijuan",
+			Minutes: 2400,
+			Games: []models.GamePlaytime{
+				{Name: "Dota 2", Minutes: 2000},
+				{Name: "Celeste", Minutes: 300, SelfReported: true},
+				{Name: "Portal", Minutes: 100},
+			},
+			NewGames:    []string{"Celeste"},
+			BiggestWeek: &models.RecapWeek{Start: "2024-11-18", Minutes: 1400},
+			Rank:        &models.Rank{Rank: 2, Users: 3},
+			From:        "2024-01-01",
+			To:          "2024-11-20",
+		}, nil},
+		{"Test history starting in the year", history, 2024, &models.Recap{
+			Year:    2024,
+			Name:    "Onijuan",
+			Minutes: 1800,
+			Games: []models.GamePlaytime{
+				{Name: "Dota 2", Minutes: 1400},
+				{Name: "Celeste", Minutes: 300, SelfReported: true},
+				{Name: "Portal", Minutes: 100},
+			},
+			NewGames:    []string{"Celeste"},
+			BiggestWeek: &models.RecapWeek{Start: "2024-11-18", Minutes: 1400},
+			Rank:        &models.Rank{Rank: 2, Users: 3},
+			From:        "2024-03-06",
+			To:          "2024-11-20",
+		}, nil},
+		{"Test current year without history", nil, year, &models.Recap{
+			Year:     year,
+			Name:     "Onijuan",
+			Games:    []models.GamePlaytime{},
+			NewGames: []string{},
+			Rank:     &models.Rank{Rank: 1, Users: 3},
+			From:     today,
+			To:       today,
+		}, nil},
+		{"Test no history in the year", before, 2024, nil, models.ErrNotFound},
+		{"Test future year", history, year + 1, nil, &models.RequestError{}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDB{snapshots: tc.snapshots}
+			db.publicIDs, db.others = public()
+			user := &models.User{ID: "12345", Name: "Onijuan", TotalGameTime: 150, Games: []models.Game{
+				{Name: "Portal", AppID: 400, Minutes: 6000},
+			}}
+
+			recap, err := New(db).Recap(context.Background(), user, tc.year)
+			if tc.expectedErr != nil {
+				var reqErr *models.RequestError
+				if errors.As(tc.expectedErr, &reqErr) {
+					assert.True(t, errors.As(err, &reqErr), err)
+				} else {
+					assert.True(t, errors.Is(err, tc.expectedErr), err)
+				}
+
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, tc.expected, recap)
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	db := &mockDB{}
+	db.publicIDs, db.others = public()
+	a := New(db)
+	user := &models.User{ID: "12345", TotalGameTime: 10000}
+
+	var cases = []struct {
+		name     string
+		year     int
+		minutes  int
+		expected *models.Rank
+	}{
+		{"Test middle", 2024, 2400, &models.Rank{Rank: 2, Users: 3}},
+		{"Test first", 2024, 5000, &models.Rank{Rank: 1, Users: 3}},
+		{"Test last", 2024, 50, &models.Rank{Rank: 3, Users: 3}},
+		{"Test tied", 2024, 100, &models.Rank{Rank: 2, Users: 3}},
+		{"Test nothing gained by others", 2023, 0, &models.Rank{Rank: 1, Users: 3}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rank, err := a.rank(context.Background(), user, tc.year, tc.minutes)
+			require.Nil(t, err)
+			assert.Equal(t, tc.expected, rank)
+		})
+	}
+
+	// the playtime gained by the public users is only computed again after an hour, for each year, and is stored
+	assert.Equal(t, 2, db.queries)
+	assert.Equal(t, map[int]map[string]int{
+		2023: {"1": 0, "2": 0, "12345": 0},
+		2024: {"1": 3000, "2": 100, "12345": 0},
+	}, db.gains)
+
+	// a user without other public users to rank among has no rank
+	a = New(&mockDB{publicIDs: []string{"12345"}})
+	rank, err := a.rank(context.Background(), user, 2024, 100)
+	require.Nil(t, err)
+	assert.Nil(t, rank)
+}
+
+func TestPublicGains(t *testing.T) {
+	db := &mockDB{publicIDs: []string{"1", "2"}, gains: map[int]map[string]int{2024: {"1": 50, "3": 70}}}
+	_, db.others = public()
+	a := New(db)
+
+	// the stored playtime is used, and only the playtime of the public users without it is computed from their history
+	gains, err := a.publicGains(context.Background(), 2024)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]int{"1": 50, "2": 100}, gains)
+	assert.Equal(t, 1, db.histories)
+	assert.Equal(t, 100, db.gains[2024]["2"])
+
+	// the playtime is computed with a context of its own, as it is cached for every request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.Forget("1")
+	gains, err = a.publicGains(ctx, 2024)
+	require.Nil(t, err)
+	assert.Equal(t, map[string]int{"1": 50, "2": 100}, gains)
+	assert.Equal(t, 1, db.histories)
+
+	db.err = errors.New("unavailable")
+	a.Forget("1")
+	_, err = a.publicGains(context.Background(), 2024)
+	assert.Equal(t, db.err, err)
+}
+
+func TestMonday(t *testing.T) {
+	var cases = []struct {
+		date     string
+		expected string
+	}{
+		{"2024-11-18", "2024-11-18"},
+		{"2024-11-20", "2024-11-18"},
+		{"2024-11-24", "2024-11-18"},
+		{"2024-01-03", "2024-01-01"},
+		{"2025-01-01", "2024-12-30"},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, monday(tc.date))
+	}
+}
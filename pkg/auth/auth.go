@@ -11,13 +11,11 @@ import (
 
 	"github.com/coreos/go-oidc"
 	"github.com/gorilla/mux"
-	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 )
 
 // Authenticator contains everything used by an authenticator
 type Authenticator struct {
-	ctx        context.Context
 	config     oauth2.Config
 	verifier   *oidc.IDTokenVerifier
 	hmacSecret []byte
@@ -27,12 +25,13 @@ type Authenticator struct {
 const stateCookie = "oauthstate"
 
 // New initializes and returns an Authenticator.
+// The context is only used to discover the OpenID Connect provider.
 // The authenticator fulfills the TokenGenerator and AuthMiddleware interfaces
 // Authenticating the user through OpenIDConnect with Google as provider
 // https://developers.google.com/identity/protocols/OpenIDConnect
 func New(ctx context.Context, uv models.UserValidator, port int,
 	domain, clientID, clientSecret, hmacSecret string) (*Authenticator, error) {
-	authenticator := &Authenticator{ctx: ctx, uv: uv}
+	authenticator := &Authenticator{uv: uv}
 
 	provider, err := oidc.NewProvider(ctx, "https://accounts.google.com")
 	if err != nil {
@@ -63,8 +62,8 @@ func New(ctx context.Context, uv models.UserValidator, port int,
 func (a *Authenticator) AuthRedirect(w http.ResponseWriter, r *http.Request) {
 	state, err := generateStateOauthCookie(w)
 	if err != nil {
-		logrus.WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("Could not generate state for authentication")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		models.Log(r.Context()).WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("Could not generate state for authentication")
+		models.WriteProblem(w, r, models.NewProblem(http.StatusInternalServerError, models.CodeInternalError, ""))
 
 		return
 	}
@@ -89,7 +88,7 @@ func (a *Authenticator) HandleOAuth2Callback(w http.ResponseWriter, r *http.Requ
 	// }
 
 	// exchanging the authorization code for an oauth2token
-	oauth2Token, err := a.config.Exchange(a.ctx, r.URL.Query().Get("code"))
+	oauth2Token, err := a.config.Exchange(r.Context(), r.URL.Query().Get("code"))
 	if err != nil {
 		return "", err
 	}
@@ -102,7 +101,7 @@ func (a *Authenticator) HandleOAuth2Callback(w http.ResponseWriter, r *http.Requ
 	}
 
 	// verifying the id token
-	idToken, err := a.verifier.Verify(a.ctx, rawIDToken)
+	idToken, err := a.verifier.Verify(r.Context(), rawIDToken)
 	if err != nil {
 		return "", err
 	}
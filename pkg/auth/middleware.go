@@ -4,41 +4,46 @@ import (
 	"context"
 	"ctp/pkg/models"
 	"net/http"
-
-	"github.com/sirupsen/logrus"
 )
 
 // Auth is a middleware that validates received token and passes the id to handlers by request context.
 // If the token was invalid, or some error occurred, the request is rejected and no handler is called.
 func (a *Authenticator) Auth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log := models.Log(r.Context())
+
 		token := r.Header.Get("Authorization")
 		if token == "" {
-			logrus.Warn("no token provided")
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			log.Warn("no token provided")
+			models.WriteProblem(w, r, models.NewProblem(http.StatusForbidden, models.CodeForbidden, "missing authorization token"))
 			return
 		}
 		id, err := a.validateToken(token)
 		if err != nil {
-			logrus.WithError(err).Warn("invalid authorization")
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			log.WithError(err).Warn("invalid authorization")
+			models.WriteProblem(w, r, models.NewProblem(http.StatusForbidden, models.CodeForbidden, "invalid authorization token"))
 			return
 		}
 
 		// Checking whether or not the user exists in the database.
 		// A user can have a valid token, but not exist in the database if they have deleted their account.
-		validUser, err := a.uv.IsUser(id)
+		validUser, err := a.uv.IsUser(r.Context(), id)
 		if err != nil {
-			logrus.WithError(err).Warn("error getting user from database")
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			log.WithError(err).Warn("error getting user from database")
+			models.WriteProblem(w, r, models.NewProblem(http.StatusInternalServerError, models.CodeInternalError, ""))
 			return
 		}
 		if !validUser {
-			logrus.Warn("non-existing user with valid token tried to login")
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			log.Warn("non-existing user with valid token tried to login")
+			models.WriteProblem(w, r, models.NewProblem(http.StatusForbidden, models.CodeForbidden, "user does not exist"))
 			return
 		}
 
+		// adding the user id to the request info, such that it is included in the access log
+		if info := models.GetRequestInfo(r.Context()); info != nil {
+			info.UserID = id
+		}
+
 		k := models.CtxKey("id")
 		ctx := context.WithValue(r.Context(), k, id)
 
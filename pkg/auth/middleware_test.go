@@ -34,7 +34,9 @@ type mockUserValidator struct {
 	err  error
 }
 
-func (m *mockUserValidator) IsUser(id string) (bool, error) { return m.resp, m.err }
+func (m *mockUserValidator) IsUser(ctx context.Context, id string) (bool, error) {
+	return m.resp, m.err
+}
 
 func TestAuthMiddleware(t *testing.T) {
 	var cases = []struct {
@@ -0,0 +1,416 @@
+package blizzard
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"ctp/pkg/models"
+	"ctp/pkg/tracing"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const battleNetAuthorize = "https://oauth.battle.net/authorize"
+const battleNetToken = "https://oauth.battle.net/token"
+const battleNetUserInfo = "https://oauth.battle.net/userinfo"
+const battleNetAPI = "https://%s.api.blizzard.com%s"
+
+// battleNetScopes are the scopes needed to read the profiles of each of the titles
+const battleNetScopes = "openid wow.profile d3.profile sc2.profile"
+
+// stateKeyLabel is used to derive the key signing the OAuth state from the state secret
+const stateKeyLabel = "ctp battle.net oauth state"
+
+// sc2MatchMinutes is the average length of a StarCraft II match, used to estimate the playtime from the number of games
+const sc2MatchMinutes = 15
+
+// battleNetRegions are the regions of the Battle.net APIs
+var battleNetRegions = []string{"us", "eu", "kr", "tw"}
+
+// BattleNetConfig contains the OAuth client used to link Battle.net accounts. Linking is disabled if the client ID is empty.
+type BattleNetConfig struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	StateSecret  string // the key signing the OAuth state, which contains the ID of the user linking the account, is derived from it
+}
+
+// tokenResp is used for decoding the response when exchanging the authorization code
+type tokenResp struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"` // seconds
+}
+
+// userInfoResp is used for decoding the Battle.net account
+type userInfoResp struct {
+	ID        int64  `json:"id"`
+	BattleTag string `json:"battletag"`
+}
+
+// wowProfile is used for decoding the World of Warcraft characters of the account
+type wowProfile struct {
+	WowAccounts []struct {
+		Characters []struct {
+			Name  string `json:"name"`
+			Level int    `json:"level"`
+			Realm struct {
+				Name string `json:"name"`
+			} `json:"realm"`
+			PlayableClass struct {
+				Name string `json:"name"`
+			} `json:"playable_class"`
+		} `json:"characters"`
+	} `json:"wow_accounts"`
+}
+
+// d3Profile is used for decoding the Diablo III heroes of the account
+type d3Profile struct {
+	Heroes []struct {
+		Name  string `json:"name"`
+		Class string `json:"class"`
+		Level int    `json:"level"`
+	} `json:"heroes"`
+}
+
+// sc2Player is used for decoding the StarCraft II profiles of the account
+type sc2Player struct {
+	ProfileID string `json:"profileId"`
+	RegionID  int    `json:"regionId"`
+	RealmID   int    `json:"realmId"`
+}
+
+// sc2Profile is used for decoding a StarCraft II profile
+type sc2Profile struct {
+	Summary struct {
+		DisplayName     string `json:"displayName"`
+		TotalSwarmLevel int    `json:"totalSwarmLevel"`
+	} `json:"summary"`
+	Career struct {
+		TotalCareerGames int `json:"totalCareerGames"`
+	} `json:"career"`
+}
+
+// BattleNetAuthURL returns the URL the user should be redirected to, to authorize linking their Battle.net account.
+// The state contains the ID of the user, the region and a random nonce, signed such that it can not be tampered with.
+// The nonce is returned as well, to be kept by the browser of the user, as the callback is only accepted along with it.
+func (b *Blizzard) BattleNetAuthURL(userID, region string) (string, string, error) {
+	if b.bnet.ClientID == "" {
+		return "", "", models.NewReqErr(fmt.Errorf("battle.net linking: %w", models.ErrNotFound),
+			"Battle.net linking is not enabled")
+	}
+
+	if region == "" {
+		region = "us"
+	}
+
+	if !models.Contains(battleNetRegions, region) {
+		return "", "", models.NewReqErrStr("invalid Battle.net region", "invalid region for Battle.net account")
+	}
+
+	nonce, err := newNonce()
+	if err != nil {
+		return "", "", err
+	}
+
+	params := url.Values{}
+	params.Set("client_id", b.bnet.ClientID)
+	params.Set("redirect_uri", b.bnet.RedirectURL)
+	params.Set("response_type", "code")
+	params.Set("scope", battleNetScopes)
+	params.Set("state", b.signState(userID, region, nonce, time.Now()))
+
+	return battleNetAuthorize + "?" + params.Encode(), nonce, nil
+}
+
+// HandleBattleNetCallback verifies the state against the nonce kept by the browser, exchanges the authorization code for
+// an access token and returns the ID of the user who started linking the account, along with the Battle.net account
+func (b *Blizzard) HandleBattleNetCallback(ctx context.Context, code, state, nonce string) (string,
+	*models.BattleNetAccount, error) {
+	ctx, span := tracing.StartKind(ctx, "blizzard.HandleBattleNetCallback", tracing.KindClient)
+	defer span.End()
+
+	userID, region, err := b.verifyState(state, nonce, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+
+	if code == "" {
+		return "", nil, models.NewReqErrStr("no authorization code", "the Battle.net account was not authorized")
+	}
+
+	token, err := b.exchange(ctx, code)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var info userInfoResp
+	_, err = b.apiGet(ctx, battleNetUserInfo, token.AccessToken, &info)
+	if err != nil {
+		return "", nil, err
+	}
+
+	account := &models.BattleNetAccount{
+		ID:        info.ID,
+		BattleTag: info.BattleTag,
+		Region:    region,
+		Token:     token.AccessToken,
+		ExpiresAt: time.Now().Unix() + token.ExpiresIn,
+	}
+	account.SetStatus(models.BattleNetLinked)
+
+	return userID, account, nil
+}
+
+// GetBattleNetPlaytime gets the games the user has played on Battle.net, with their characters.
+// The APIs do not provide the playtime of World of Warcraft and Diablo III, while the playtime of StarCraft II is estimated
+// from the number of games played. Titles the user has not played are left out.
+// Returns models.ErrTokenExpired if the account has to be linked again.
+func (b *Blizzard) GetBattleNetPlaytime(ctx context.Context, account *models.BattleNetAccount) ([]models.Game, error) {
+	ctx, span := tracing.StartKind(ctx, "blizzard.GetBattleNetPlaytime", tracing.KindClient)
+	defer span.End()
+
+	if time.Now().Unix() >= account.ExpiresAt {
+		return nil, fmt.Errorf("battle.net account %d: %w", account.ID, models.ErrTokenExpired)
+	}
+
+	games := []models.Game{}
+	for _, get := range []func(context.Context, *models.BattleNetAccount) (*models.Game, error){b.getWoW, b.getD3, b.getSC2} {
+		game, err := get(ctx, account)
+		if err != nil {
+			return nil, err
+		}
+
+		if game != nil {
+			games = append(games, *game)
+		}
+	}
+
+	return games, nil
+}
+
+// getWoW returns the World of Warcraft characters of the account, or nil if the user has none
+func (b *Blizzard) getWoW(ctx context.Context, account *models.BattleNetAccount) (*models.Game, error) {
+	var profile wowProfile
+	path := fmt.Sprintf("/profile/user/wow?namespace=profile-%s&locale=en_US", account.Region)
+
+	found, err := b.apiGet(ctx, fmt.Sprintf(battleNetAPI, account.Region, path), account.Token, &profile)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	game := &models.Game{Name: models.WorldOfWarcraft}
+	for _, wowAccount := range profile.WowAccounts {
+		for _, c := range wowAccount.Characters {
+			game.Characters = append(game.Characters, models.Character{
+				Name: c.Name, Realm: c.Realm.Name, Class: c.PlayableClass.Name, Level: c.Level})
+		}
+	}
+
+	if len(game.Characters) == 0 {
+		return nil, nil
+	}
+
+	return game, nil
+}
+
+// getD3 returns the Diablo III heroes of the account, or nil if the user has none
+func (b *Blizzard) getD3(ctx context.Context, account *models.BattleNetAccount) (*models.Game, error) {
+	var profile d3Profile
+	path := fmt.Sprintf("/d3/profile/%s/?locale=en_US", playerID(account.BattleTag))
+
+	found, err := b.apiGet(ctx, fmt.Sprintf(battleNetAPI, account.Region, path), account.Token, &profile)
+	if err != nil || !found || len(profile.Heroes) == 0 {
+		return nil, err
+	}
+
+	game := &models.Game{Name: models.DiabloIII}
+	for _, h := range profile.Heroes {
+		game.Characters = append(game.Characters, models.Character{Name: h.Name, Class: h.Class, Level: h.Level})
+	}
+
+	return game, nil
+}
+
+// getSC2 returns the StarCraft II profiles of the account, with the playtime estimated from the number of games played.
+// Returns nil if the user has no profiles.
+func (b *Blizzard) getSC2(ctx context.Context, account *models.BattleNetAccount) (*models.Game, error) {
+	var players []sc2Player
+	path := "/sc2/player/" + strconv.FormatInt(account.ID, 10)
+
+	found, err := b.apiGet(ctx, fmt.Sprintf(battleNetAPI, account.Region, path), account.Token, &players)
+	if err != nil || !found || len(players) == 0 {
+		return nil, err
+	}
+
+	game := &models.Game{Name: models.StarCraftII}
+	for _, p := range players {
+		var profile sc2Profile
+		path := fmt.Sprintf("/sc2/profile/%d/%d/%s?locale=en_US", p.RegionID, p.RealmID, url.PathEscape(p.ProfileID))
+
+		found, err := b.apiGet(ctx, fmt.Sprintf(battleNetAPI, account.Region, path), account.Token, &profile)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+
+		minutes := profile.Career.TotalCareerGames * sc2MatchMinutes
+		game.Minutes += minutes
+		game.Characters = append(game.Characters, models.Character{
+			Name: profile.Summary.DisplayName, Level: profile.Summary.TotalSwarmLevel, Minutes: minutes})
+	}
+
+	game.Time = game.Minutes / 60
+
+	return game, nil
+}
+
+// apiGet gets the resource with the access token, and decodes it into v.
+// Returns false if the resource does not exist, and models.ErrTokenExpired if the token is no longer valid.
+func (b *Blizzard) apiGet(ctx context.Context, u, token string, v interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := b.Do(req)
+	if err != nil {
+		return false, models.NewAPIErr(err, "Blizzard")
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return false, nil
+	case http.StatusUnauthorized:
+		return false, fmt.Errorf("unauthorized request to Battle.net: %w", models.ErrTokenExpired)
+	}
+
+	err = models.CheckStatusCode(resp.StatusCode, "Blizzard", "invalid Battle.net account")
+	if err != nil {
+		return false, err
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(v)
+	if err != nil {
+		return false, models.NewAPIErr(err, "Blizzard")
+	}
+
+	return true, nil
+}
+
+// exchange exchanges the authorization code for an access token
+func (b *Blizzard) exchange(ctx context.Context, code string) (*tokenResp, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", b.bnet.RedirectURL)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, battleNetToken, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(b.bnet.ClientID, b.bnet.ClientSecret)
+
+	resp, err := b.Do(req)
+	if err != nil {
+		return nil, models.NewAPIErr(err, "Blizzard")
+	}
+	defer resp.Body.Close()
+
+	// an invalid (e.g. already used) authorization code is rejected with 400 Bad Request
+	err = models.CheckStatusCode(resp.StatusCode, "Blizzard", "invalid Battle.net authorization code")
+	if err != nil {
+		return nil, err
+	}
+
+	var token tokenResp
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return nil, models.NewAPIErr(err, "Blizzard")
+	}
+
+	if token.AccessToken == "" {
+		return nil, models.NewAPIErr(errors.New("no access token in response"), "Blizzard")
+	}
+
+	return &token, nil
+}
+
+// newNonce returns a random nonce, binding the state to the browser which started linking the account
+func newNonce() (string, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(nonce), nil
+}
+
+// signState returns the state containing the user ID, region, nonce and expiry, followed by its signature
+func (b *Blizzard) signState(userID, region, nonce string, now time.Time) string {
+	payload := fmt.Sprintf("%s|%s|%s|%d", userID, region, nonce, now.Add(models.BattleNetStateTTL).Unix())
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+
+	return encoded + "." + b.sign(encoded)
+}
+
+// verifyState verifies the signature and expiry of the state, and that it contains the given nonce.
+// Returns the user ID and region the state contains.
+func (b *Blizzard) verifyState(state, nonce string, now time.Time) (string, string, error) {
+	parts := strings.Split(state, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(b.sign(parts[0])), []byte(parts[1])) {
+		return "", "", models.ErrInvalidAuthState
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", "", models.ErrInvalidAuthState
+	}
+
+	fields := strings.Split(string(payload), "|")
+	if len(fields) != 4 {
+		return "", "", models.ErrInvalidAuthState
+	}
+
+	// the state is only accepted from the browser it was issued to, which keeps the nonce
+	if nonce == "" || !hmac.Equal([]byte(fields[2]), []byte(nonce)) {
+		return "", "", models.ErrInvalidAuthState
+	}
+
+	expiry, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil || now.Unix() > expiry {
+		return "", "", models.ErrInvalidAuthState
+	}
+
+	return fields[0], fields[1], nil
+}
+
+// sign returns the HMAC of the data
+func (b *Blizzard) sign(data string) string {
+	mac := hmac.New(sha256.New, b.stateKey)
+	mac.Write([]byte(data))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// stateKey derives the key signing the OAuth state from the secret, such that a secret used elsewhere (e.g. to sign the
+// tokens of the users) is never used as is
+func stateKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(stateKeyLabel))
+
+	return mac.Sum(nil)
+}
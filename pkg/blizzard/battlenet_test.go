@@ -0,0 +1,276 @@
+package blizzard
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"ctp/pkg/models"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockBattleNet is a mock http.Client that implements the "Client" interface, responding with the body for the URL.
+// URLs without a response are responded with 404 Not Found.
+type mockBattleNet struct {
+	responses map[string]string
+	status    int // the status code of every response, if set
+	requests  []*http.Request
+}
+
+func (m *mockBattleNet) Do(req *http.Request) (*http.Response, error) {
+	m.requests = append(m.requests, req)
+
+	body, ok := m.responses[req.URL.String()]
+	status := http.StatusOK
+	switch {
+	case m.status != 0:
+		status = m.status
+	case !ok:
+		status = http.StatusNotFound
+	}
+
+	return &http.Response{StatusCode: status, Header: make(http.Header), Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+}
+
+var testBattleNet = BattleNetConfig{
+	ClientID:     "client",
+	ClientSecret: "secret",
+	RedirectURL:  "http://localhost:80/api/v2/battlenet/callback",
+	StateSecret:  "hmac secret",
+}
+
+// battleNetResponses are the responses of the Battle.net APIs for the account 1234 in eu
+var battleNetResponses = map[string]string{
+	battleNetToken:    `{"access_token": "token", "token_type": "bearer", "expires_in": 86399}`,
+	battleNetUserInfo: `{"sub": "1234", "id": 1234, "battletag": "Onijuan#2670"}`,
+	"https://eu.api.blizzard.com/profile/user/wow?namespace=profile-eu&locale=en_US": `{"wow_accounts": [{"characters": [
+		{"name": "Onijuan", "level": 70, "realm": {"name": "Draenor"}, "playable_class": {"name": "Paladin"}},
+		{"name": "Onyo", "level": 12, "realm": {"name": "Silvermoon"}, "playable_class": {"name": "Mage"}}]}]}`,
+	"https://eu.api.blizzard.com/d3/profile/Onijuan-2670/?locale=en_US": `{"heroes": [
+		{"name": "Nephalem", "class": "crusader", "level": 70}]}`,
+	"https://eu.api.blizzard.com/sc2/player/1234": `[{"name": "Onijuan", "profileId": "5678", "regionId": 2, "realmId": 1}]`,
+	"https://eu.api.blizzard.com/sc2/profile/2/1/5678?locale=en_US": `{"summary": {"displayName": "Onijuan", "totalSwarmLevel": 42},
+		"career": {"totalCareerGames": 250}}`,
+}
+
+func TestBlizzard_State(t *testing.T) {
+	b := New(nil, nil, "", testBattleNet)
+	now := time.Now()
+	state := b.signState("12345", "eu", "nonce", now)
+
+	// the payload and signature of another state, used to tamper with the state
+	other := strings.Split(b.signState("54321", "eu", "nonce", now), ".")
+	parts := strings.Split(state, ".")
+
+	// the state signed with the secret itself, rather than with the key derived from it
+	mac := hmac.New(sha256.New, []byte(testBattleNet.StateSecret))
+	mac.Write([]byte(parts[0]))
+	underived := parts[0] + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	var cases = []struct {
+		name    string
+		state   string
+		nonce   string
+		secret  string
+		now     time.Time
+		invalid bool
+	}{
+		{"Test ok", state, "nonce", testBattleNet.StateSecret, now, false},
+		{"Test ok before expiry", state, "nonce", testBattleNet.StateSecret, now.Add(models.BattleNetStateTTL), false},
+		{"Test expired", state, "nonce", testBattleNet.StateSecret, now.Add(models.BattleNetStateTTL + time.Second), true},
+		{"Test other secret", state, "nonce", "other secret", now, true},
+		{"Test signed with secret", underived, "nonce", testBattleNet.StateSecret, now, true},
+		{"Test other nonce", state, "other nonce", testBattleNet.StateSecret, now, true},
+		{"Test no nonce", state, "", testBattleNet.StateSecret, now, true},
+		{"Test tampered payload", other[0] + "." + parts[1], "nonce", testBattleNet.StateSecret, now, true},
+		{"Test no signature", parts[0], "nonce", testBattleNet.StateSecret, now, true},
+		{"Test empty", "", "nonce", testBattleNet.StateSecret, now, true},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := testBattleNet
+			config.StateSecret = tc.secret
+
+			userID, region, err := New(nil, nil, "", config).verifyState(tc.state, tc.nonce, tc.now)
+			if tc.invalid {
+				assert.Equal(t, models.ErrInvalidAuthState, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, "12345", userID)
+			assert.Equal(t, "eu", region)
+		})
+	}
+}
+
+func TestBlizzard_BattleNetAuthURL(t *testing.T) {
+	var cases = []struct {
+		name   string
+		config BattleNetConfig
+		region string
+		err    string
+	}{
+		{"Test ok", testBattleNet, "eu", ""},
+		{"Test default region", testBattleNet, "", ""},
+		{"Test invalid region", testBattleNet, "asia", "invalid Battle.net region"},
+		{"Test disabled", BattleNetConfig{}, "eu", "not found"},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := New(nil, nil, "", tc.config)
+
+			authURL, nonce, err := b.BattleNetAuthURL("12345", tc.region)
+			if tc.err != "" {
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tc.err)
+				}
+				return
+			}
+
+			require.Nil(t, err)
+			u, err := url.Parse(authURL)
+			require.Nil(t, err)
+			assert.Equal(t, battleNetAuthorize, u.Scheme+"://"+u.Host+u.Path)
+			assert.Equal(t, testBattleNet.ClientID, u.Query().Get("client_id"))
+			assert.Equal(t, testBattleNet.RedirectURL, u.Query().Get("redirect_uri"))
+
+			assert.NotEmpty(t, nonce)
+			userID, region, err := b.verifyState(u.Query().Get("state"), nonce, time.Now())
+			assert.Nil(t, err)
+			assert.Equal(t, "12345", userID)
+			if tc.region == "" {
+				assert.Equal(t, "us", region)
+			} else {
+				assert.Equal(t, tc.region, region)
+			}
+		})
+	}
+}
+
+func TestBlizzard_HandleBattleNetCallback(t *testing.T) {
+	var cases = []struct {
+		name   string
+		code   string
+		state  func(b *Blizzard) string
+		status int // the status code of every response, if set
+		err    string
+	}{
+		{"Test ok", "code", func(b *Blizzard) string { return b.signState("12345", "eu", "nonce", time.Now()) }, 0, ""},
+		{"Test invalid state", "code", func(b *Blizzard) string { return "test.test" }, 0, "invalid authorization state"},
+		{"Test no code", "", func(b *Blizzard) string { return b.signState("12345", "eu", "nonce", time.Now()) }, 0,
+			"no authorization code"},
+		{"Test invalid code", "code", func(b *Blizzard) string { return b.signState("12345", "eu", "nonce", time.Now()) },
+			http.StatusBadRequest, "invalid Battle.net authorization code"},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockBattleNet{responses: battleNetResponses, status: tc.status}
+			b := New(nil, client, "", testBattleNet)
+
+			userID, account, err := b.HandleBattleNetCallback(context.Background(), tc.code, tc.state(b), "nonce")
+			if tc.err != "" {
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tc.err)
+				}
+				assert.Nil(t, account)
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, "12345", userID)
+			assert.Equal(t, int64(1234), account.ID)
+			assert.Equal(t, "Onijuan#2670", account.BattleTag)
+			assert.Equal(t, "eu", account.Region)
+			assert.Equal(t, "token", account.Token)
+			assert.Equal(t, models.BattleNetLinked, account.Status)
+			assert.InDelta(t, time.Now().Unix()+86399, account.ExpiresAt, 5)
+
+			// the code is exchanged with the client credentials, and the account is requested with the token
+			require.Len(t, client.requests, 2)
+			clientID, secret, ok := client.requests[0].BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, testBattleNet.ClientID, clientID)
+			assert.Equal(t, testBattleNet.ClientSecret, secret)
+			assert.Equal(t, "Bearer token", client.requests[1].Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestBlizzard_GetBattleNetPlaytime(t *testing.T) {
+	account := &models.BattleNetAccount{ID: 1234, BattleTag: "Onijuan#2670", Region: "eu", Token: "token",
+		ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+	client := &mockBattleNet{responses: battleNetResponses}
+	games, err := New(nil, client, "", testBattleNet).GetBattleNetPlaytime(context.Background(), account)
+	require.Nil(t, err)
+
+	// the playtime of StarCraft II is estimated from the 250 games played
+	expected := []models.Game{
+		{Name: models.WorldOfWarcraft, Characters: []models.Character{
+			{Name: "Onijuan", Realm: "Draenor", Class: "Paladin", Level: 70},
+			{Name: "Onyo", Realm: "Silvermoon", Class: "Mage", Level: 12}}},
+		{Name: models.DiabloIII, Characters: []models.Character{{Name: "Nephalem", Class: "crusader", Level: 70}}},
+		{Name: models.StarCraftII, Time: 62, Minutes: 3750, Characters: []models.Character{
+			{Name: "Onijuan", Level: 42, Minutes: 3750}}},
+	}
+	assert.Equal(t, expected, games)
+
+	for _, req := range client.requests {
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+	}
+}
+
+func TestBlizzard_GetBattleNetPlaytimeErrors(t *testing.T) {
+	var cases = []struct {
+		name      string
+		expiresAt time.Time
+		responses map[string]string
+		status    int
+		expected  []models.Game
+		err       error // the error wrapped by the returned error
+		apiErr    bool  // whether the returned error is an external API error
+	}{
+		{"Test no titles played", time.Now().Add(time.Hour), map[string]string{}, 0, []models.Game{}, nil, false},
+		{"Test expired", time.Now().Add(-time.Hour), battleNetResponses, 0, nil, models.ErrTokenExpired, false},
+		{"Test revoked", time.Now().Add(time.Hour), battleNetResponses, http.StatusUnauthorized, nil, models.ErrTokenExpired,
+			false},
+		{"Test server error", time.Now().Add(time.Hour), battleNetResponses, http.StatusInternalServerError, nil, nil, true},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			account := &models.BattleNetAccount{ID: 1234, BattleTag: "Onijuan#2670", Region: "eu", Token: "token",
+				ExpiresAt: tc.expiresAt.Unix()}
+			client := &mockBattleNet{responses: tc.responses, status: tc.status}
+
+			games, err := New(nil, client, "", testBattleNet).GetBattleNetPlaytime(context.Background(), account)
+			assert.Equal(t, tc.expected, games)
+
+			switch {
+			case tc.apiErr:
+				assert.IsType(t, &models.ExternalAPIError{}, err)
+			case tc.err != nil:
+				assert.True(t, errors.Is(err, tc.err))
+			default:
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
@@ -1,211 +1,234 @@
 package blizzard
 
 import (
+	"context"
 	"ctp/pkg/models"
+	"ctp/pkg/tracing"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
-	"time"
-
-	"github.com/sirupsen/logrus"
 )
 
+// DefaultBackend is the public instance of OverFast API, which collects the statistics from the Overwatch 2 career profiles.
+// Any backend serving the same API (e.g. a self-hosted instance) can be used instead.
+const DefaultBackend = "https://overfast-api.tekrop.fr"
+
+const playerSummary = "%s/players/%s/summary"
+const playerStats = "%s/players/%s/stats/summary?gamemode=%s"
+
+// gameModes are the game modes the playtime is collected for
+var gameModes = []string{"quickplay", "competitive"}
+
+// battleTagRegexp matches battle tags with either "#" (as shown in game) or "-" (as used in URLs) before the number
+var battleTagRegexp = regexp.MustCompile(`^(\p{L}[\p{L}\p{N}]{2,11})[#-](\d{3,8})$`)
+
 // Blizzard is a struct which contains everything necessary to handle a request related to blizzard
 type Blizzard struct {
 	models.Getter
+	models.Client                 // used for the Battle.net APIs, which require the access token of the user
+	backend       string          // the base URL of the stats backend
+	bnet          BattleNetConfig // the OAuth client used to link Battle.net accounts
+	stateKey      []byte          // the key signing the OAuth state, derived from the state secret
 }
 
-// blizzardResp struct retrieves only time played in Overwatch
-type blizzardResp struct {
-	CompetitiveStats struct {
-		CareerStats careerStats `json:"careerStats"`
-	} `json:"competitiveStats"`
-	QuickPlayStats struct {
-		CareerStats careerStats `json:"careerStats"`
-	} `json:"quickPlayStats"`
+// summaryResp is used for decoding the summary of a player
+type summaryResp struct {
+	Username string `json:"username"`
+	Privacy  string `json:"privacy"`
 }
 
-// careerStats includes time played for all heroes by game
-type careerStats struct {
-	AllHeroes struct {
-		Game struct {
-			TimePlayed string `json:"timePlayed"`
-		} `json:"game"`
-	} `json:"allHeroes"`
+// statsResp is used for decoding the statistics of a player in a game mode. The time played is in seconds.
+type statsResp struct {
+	General struct {
+		TimePlayed int `json:"time_played"`
+	} `json:"general"`
+	Heroes map[string]struct {
+		TimePlayed int `json:"time_played"`
+	} `json:"heroes"`
 }
 
-func New(getter models.Getter) *Blizzard {
-	return &Blizzard{getter}
+// New returns a new blizzard instance getting the Overwatch statistics from the given backend, or DefaultBackend if empty.
+// The client is used for linking Battle.net accounts, and getting the data of the other Blizzard titles.
+func New(getter models.Getter, client models.Client, backend string, bnet BattleNetConfig) *Blizzard {
+	if backend == "" {
+		backend = DefaultBackend
+	}
+
+	return &Blizzard{Getter: getter, Client: client, backend: strings.TrimSuffix(backend, "/"), bnet: bnet,
+		stateKey: stateKey(bnet.StateSecret)}
 }
 
-// errInvalidTimePlayed is used to indicate to try the request again
-var errInvalidTimePlayed = errors.New("invalid time played in response")
+// NormalizeBattleTag returns the battle tag as shown in game ("Name#1234"), accepting "Name-1234" as well
+func NormalizeBattleTag(battleTag string) (string, error) {
+	m := battleTagRegexp.FindStringSubmatch(strings.TrimSpace(battleTag))
+	if m == nil {
+		return "", models.NewReqErrStr("invalid battle tag", "invalid Blizzard battle tag, expected e.g. \"Name#1234\"")
+	}
 
-// ValidateBattleUser func validates a users input to *Game Overwatch
-func (b *Blizzard) ValidateBattleUser(payload *models.Overwatch) error {
-	logrus.Debug("ValidateBattleUser()")
+	return m[1] + "#" + m[2], nil
+}
 
-	if payload == nil {
-		return errors.New("no payload to ValidateBattleUser")
+// normalizePlatform returns the Overwatch 2 platform, where every console shares the same profile.
+// The platform is optional, as the profile contains the statistics for both platforms.
+func normalizePlatform(platform string) (string, error) {
+	switch strings.ToLower(platform) {
+	case "":
+		return "", nil
+	case "pc":
+		return "pc", nil
+	case "console", "switch", "xbox", "ps4", "ps5", "psn", "xbl":
+		return "console", nil
 	}
 
-	var regions = []string{"us", "eu", "asia"}
+	return "", models.NewReqErrStr("invalid Overwatch platform", "invalid platform for Overwatch account")
+}
+
+// playerID returns the battle tag as used by the backend
+func playerID(battleTag string) string {
+	return url.PathEscape(strings.Replace(battleTag, "#", "-", 1))
+}
+
+// ValidateBattleUser func validates a users input to *Game Overwatch, normalizing the battle tag and platform
+func (b *Blizzard) ValidateBattleUser(ctx context.Context, payload *models.Overwatch) error {
+	ctx, span := tracing.StartKind(ctx, "blizzard.ValidateBattleUser", tracing.KindClient)
+	defer span.End()
 
-	var platforms = []string{"pc", "switch", "xbox", "ps4"}
+	models.Log(ctx).Debug("ValidateBattleUser()")
+
+	if payload == nil {
+		return errors.New("no payload to ValidateBattleUser")
+	}
 
-	if !models.Contains(regions, payload.Region) {
+	// the region is no longer used, but is still validated for accounts linked before Overwatch 2
+	if payload.Region != "" && !models.Contains([]string{"us", "eu", "asia"}, payload.Region) {
 		return models.NewReqErrStr("invalid Overwatch region", "invalid region for Overwatch account")
 	}
 
-	if !models.Contains(platforms, payload.Platform) {
-		return models.NewReqErrStr("invalid Overwatch platform", "invalid platform for Overwatch account")
+	platform, err := normalizePlatform(payload.Platform)
+	if err != nil {
+		return err
 	}
 
-	// check that provided battle tag is correct
-	url := fmt.Sprintf("https://ow-api.com/v1/stats/%s/%s/%s/heroes/complete",
-		payload.Platform, payload.Region, payload.BattleTag)
-	resp, err := b.Get(url)
+	battleTag, err := NormalizeBattleTag(payload.BattleTag)
+	if err != nil {
+		return err
+	}
 
+	// check that provided battle tag is correct
+	resp, err := b.Get(ctx, fmt.Sprintf(playerSummary, b.backend, playerID(battleTag)))
 	if err != nil {
 		return models.NewAPIErr(err, "Blizzard")
 	}
 	defer resp.Body.Close()
 
 	// Checks status header
-	if err := models.AccValStatusCode(resp.StatusCode, "Blizzard", "invalid Blizzard battle tag, platform or region"); err != nil {
+	if err := models.AccValStatusCode(resp.StatusCode, "Blizzard", "invalid Blizzard battle tag"); err != nil {
 		return err
 	}
 
+	var summary summaryResp
+	err = json.NewDecoder(resp.Body).Decode(&summary)
+	if err != nil {
+		return models.NewAPIErr(err, "Blizzard")
+	}
+
+	if summary.Privacy == "private" {
+		return models.NewReqErrStr("private Overwatch profile", `the Overwatch profile is private, set "Career Profile Visibility" `+
+			`to "Public" in the social options in Overwatch 2`)
+	}
+
+	payload.BattleTag = battleTag
+	payload.Platform = platform
+
 	return nil
 }
 
-// GetBlizzardPlaytime gets playtime for PUBLIC Overwatch profiles
-func (b *Blizzard) GetBlizzardPlaytime(payload *models.Overwatch) (*models.Game, error) {
-	logrus.Debugf("GetBlizzardPlaytime")
+// GetBlizzardPlaytime gets playtime for PUBLIC Overwatch 2 profiles, with the playtime for each hero in each game mode
+func (b *Blizzard) GetBlizzardPlaytime(ctx context.Context, payload *models.Overwatch) (*models.Game, error) {
+	ctx, span := tracing.StartKind(ctx, "blizzard.GetBlizzardPlaytime", tracing.KindClient)
+	defer span.End()
 
-	url := fmt.Sprintf("https://ow-api.com/v1/stats/%s/%s/%s/heroes/complete",
-		payload.Platform, payload.Region, payload.BattleTag)
+	models.Log(ctx).Debugf("GetBlizzardPlaytime")
 
-	// Tries to get a response from unreliable api
-	for tries := 0; tries < 10; tries++ {
-		gameStats, err := b.queryAPI(url)
-		if err != nil {
-			if !errors.Is(err, errInvalidTimePlayed) {
-				return nil, models.NewAPIErr(err, "Blizzard")
-			}
+	battleTag, err := NormalizeBattleTag(payload.BattleTag)
+	if err != nil {
+		return nil, err
+	}
 
-			continue // try again....
+	platform, err := normalizePlatform(payload.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &models.Game{Name: "Overwatch 2"}
+	var seconds int
+
+	for _, mode := range gameModes {
+		stats, err := b.queryAPI(ctx, battleTag, platform, mode)
+		if err != nil {
+			return nil, err
 		}
 
-		// got valid time values, thus returning Game object
-		return gameStats, nil
+		seconds += stats.General.TimePlayed
+		game.Modes = append(game.Modes, models.ModePlaytime{Mode: mode, Minutes: stats.General.TimePlayed / 60})
+		game.Heroes = append(game.Heroes, heroPlaytime(mode, stats)...)
 	}
 
-	// returns error if no request returned valid response
-	return nil, models.NewAPIErr(errors.New("no acceptable response from OW-api"), "Blizzard")
+	game.Time = seconds / 3600
+	game.Minutes = seconds / 60
+
+	return game, nil
 }
 
-// queryAPI func returns response from the OverwatchAPI
-func (b *Blizzard) queryAPI(url string) (*models.Game, error) {
-	var gameTime blizzardResp
+// queryAPI func returns the statistics of the player in the game mode from the backend
+func (b *Blizzard) queryAPI(ctx context.Context, battleTag, platform, mode string) (*statsResp, error) {
+	u := fmt.Sprintf(playerStats, b.backend, playerID(battleTag), mode)
+	if platform != "" {
+		u += "&platform=" + platform
+	}
 
-	// Gets statistics from the battle tag provided
-	resp, err := b.Get(url)
+	resp, err := b.Get(ctx, u)
 	if err != nil {
 		return nil, models.NewAPIErr(err, "Blizzard")
 	}
 	defer resp.Body.Close()
 
 	// Checks status code
-	err = models.CheckStatusCode(resp.StatusCode, "Blizzard", "invalid Blizzard battle tag or region")
+	err = models.CheckStatusCode(resp.StatusCode, "Blizzard", "invalid Blizzard battle tag")
 	if err != nil {
 		return nil, err
 	}
 
-	// Decodes the response to get playtime
-	err = json.NewDecoder(resp.Body).Decode(&gameTime)
+	var stats statsResp
+	err = json.NewDecoder(resp.Body).Decode(&stats)
 	if err != nil {
 		return nil, models.NewAPIErr(err, "Blizzard")
 	}
 
-	if gameTime.QuickPlayStats.CareerStats.AllHeroes.Game.TimePlayed == "" ||
-		gameTime.CompetitiveStats.CareerStats.AllHeroes.Game.TimePlayed == "" {
-		return nil, errInvalidTimePlayed
-	}
-
-	// Converts the returned strings to int64 (time.Duration)
-	quickTime, err := nanoTime(gameTime.QuickPlayStats.CareerStats.AllHeroes.Game.TimePlayed)
-	if err != nil {
-		return nil, err
-	}
-
-	compTime, err := nanoTime(gameTime.CompetitiveStats.CareerStats.AllHeroes.Game.TimePlayed)
-
-	if err != nil {
-		return nil, err
-	}
-
-	// returns *Game struct
-	return &models.Game{
-		Name: "Overwatch",
-		Time: int((quickTime + compTime).Hours())}, nil
+	return &stats, nil
 }
 
-// nanoTime gets a formatted time string and returns it in nanoseconds
-func nanoTime(strTime string) (time.Duration, error) {
-	absTime := time.Duration(0)
-	parts := strings.Split(strTime, ":")
+// heroPlaytime returns the playtime of each hero played at least a minute in the game mode, sorted by the minutes played
+func heroPlaytime(mode string, stats *statsResp) []models.HeroPlaytime {
+	var heroes []models.HeroPlaytime
 
-	// expected time format is "ss" or "mm:ss" or "hr:mm:ss" aka max 3 parts
-	switch len(parts) {
-	case 1:
-		sec, err := strconv.Atoi(parts[0])
-		if err != nil {
-			return 0, err
+	for hero, heroStats := range stats.Heroes {
+		if minutes := heroStats.TimePlayed / 60; minutes > 0 {
+			heroes = append(heroes, models.HeroPlaytime{Hero: hero, Mode: mode, Minutes: minutes})
 		}
+	}
 
-		absTime += time.Duration(sec) * time.Second
-	case 2:
-		min, err := strconv.Atoi(parts[0])
-		if err != nil {
-			return 0, err
-		}
-
-		absTime += time.Duration(min) * time.Minute
-		sec, err := strconv.Atoi(parts[1])
-
-		if err != nil {
-			return 0, err
-		}
-
-		absTime += time.Duration(sec) * time.Second
-	case 3:
-		hour, err := strconv.Atoi(parts[0])
-		if err != nil {
-			return 0, err
-		}
-
-		absTime += time.Duration(hour) * time.Hour
-		min, err := strconv.Atoi(parts[1])
-
-		if err != nil {
-			return 0, err
-		}
-
-		absTime += time.Duration(min) * time.Minute
-		sec, err := strconv.Atoi(parts[2])
-
-		if err != nil {
-			return 0, err
+	sort.Slice(heroes, func(i, j int) bool {
+		if heroes[i].Minutes == heroes[j].Minutes {
+			return heroes[i].Hero < heroes[j].Hero
 		}
 
-		absTime += time.Duration(sec) * time.Second
-	default:
-		// returns error if parts of string exceeds 3 (hr:min:sec)
-		return 0, fmt.Errorf("OW API changed the way time is encoded, got |%d| parts", len(parts))
-	}
+		return heroes[i].Minutes > heroes[j].Minutes
+	})
 
-	return absTime, nil
+	return heroes
 }
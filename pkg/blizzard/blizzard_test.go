@@ -1,148 +1,182 @@
 package blizzard
 
 import (
-	"bytes"
+	"context"
 	"ctp/pkg/models"
-	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// mockBlizzard is a mock http.Get that implements the "Getter" interface,
+// responding with the responses recorded from the backend in testdata
 type mockBlizzard struct {
-	setup respSetup
+	private bool  // whether the summary of the player is private
+	err     error // returned by every request, if set
+	urls    []string
 }
 
-// a mock http.Get that implements the "Getter" interface
-// which allows for customized http responses
-func (m *mockBlizzard) Get(url string) (*http.Response, error) {
-	// if a wrong url is sent, return default error
-	if !strings.Contains(url, "ow-api.com/v1/stats/") {
-		return nil, m.setup.err
+func (m *mockBlizzard) Get(ctx context.Context, url string) (*http.Response, error) {
+	m.urls = append(m.urls, url)
+	if m.err != nil {
+		return nil, m.err
 	}
 
-	// set http response header
-	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
-	if !strings.Contains(url, "Onijuan-2670") {
-		// ensure that a valid user is made, mock invalid user if not
-		resp.StatusCode = http.StatusNotFound
+	fixture, status := "player_not_found.json", http.StatusNotFound
+	if strings.HasPrefix(url, DefaultBackend+"/players/Onijuan-2670/") {
+		status = http.StatusOK
+		switch {
+		case strings.HasSuffix(url, "/summary") && m.private:
+			fixture = "summary_private.json"
+		case strings.HasSuffix(url, "/summary"):
+			fixture = "summary.json"
+		case strings.Contains(url, "gamemode=quickplay"):
+			fixture = "stats_quickplay.json"
+		case strings.Contains(url, "gamemode=competitive"):
+			fixture = "stats_competitive.json"
+		}
 	}
 
-	// add preconfigured response body to THIS response
-	body, err := json.Marshal(m.setup.resp)
+	f, err := os.Open(filepath.Join("testdata", fixture))
 	if err != nil {
 		return nil, err
 	}
-	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
 
-	// successful request
-	return resp, nil
+	return &http.Response{StatusCode: status, Header: make(http.Header), Body: ioutil.NopCloser(f)}, nil
 }
 
-// struct for setting response body in get request
-type respSetup struct {
-	resp blizzardResp
-	err  error
+func TestNormalizeBattleTag(t *testing.T) {
+	var cases = []struct {
+		name        string
+		battleTag   string
+		expected    string
+		expectedErr bool
+	}{
+		{"Test hash", "Onijuan#2670", "Onijuan#2670", false},
+		{"Test dash", "Onijuan-2670", "Onijuan#2670", false},
+		{"Test whitespace", " Onijuan#2670 ", "Onijuan#2670", false},
+		{"Test unicode", "Ørjan#21345", "Ørjan#21345", false},
+		{"Test missing number", "Onijuan", "", true},
+		{"Test too short", "On#2670", "", true},
+		{"Test starts with digit", "1Onijuan#2670", "", true},
+		{"Test invalid separator", "Onijuan_2670", "", true},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			battleTag, err := NormalizeBattleTag(tc.battleTag)
+			if tc.expectedErr {
+				assert.IsType(t, &models.RequestError{}, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, battleTag)
+		})
+	}
 }
 
 func TestBlizzard_ValidateBattleUser(t *testing.T) {
 	var test = []struct {
-		name    string
-		payload *models.Overwatch
-		err     string
+		name     string
+		payload  *models.Overwatch
+		private  bool
+		expected *models.Overwatch
+		err      string
 	}{
-		{name: "Test OK", payload: &models.Overwatch{BattleTag: "Onijuan-2670", Platform: "pc", Region: "eu"}, err: ""},
-		{name: "Test invalid BattleTag", payload: &models.Overwatch{BattleTag: "Onyoooo-2670", Platform: "pc", Region: "eu"},
-			err: "invalid Blizzard battle tag, platform or region"},
+		{name: "Test OK", payload: &models.Overwatch{BattleTag: "Onijuan#2670", Platform: "pc"},
+			expected: &models.Overwatch{BattleTag: "Onijuan#2670", Platform: "pc"}},
+		{name: "Test OK legacy", payload: &models.Overwatch{BattleTag: "Onijuan-2670", Platform: "ps4", Region: "eu"},
+			expected: &models.Overwatch{BattleTag: "Onijuan#2670", Platform: "console", Region: "eu"}},
+		{name: "Test OK no platform", payload: &models.Overwatch{BattleTag: "Onijuan-2670"},
+			expected: &models.Overwatch{BattleTag: "Onijuan#2670"}},
+		{name: "Test invalid BattleTag", payload: &models.Overwatch{BattleTag: "Onyoooo-2670", Platform: "pc"},
+			err: "invalid Blizzard battle tag"},
+		{name: "Test malformed BattleTag", payload: &models.Overwatch{BattleTag: "Onijuan", Platform: "pc"},
+			err: "invalid battle tag"},
+		{name: "Test private", payload: &models.Overwatch{BattleTag: "Onijuan#2670", Platform: "pc"}, private: true,
+			err: "private Overwatch profile"},
 		{name: "Test invalid region", payload: &models.Overwatch{BattleTag: "Onijuan-2670", Platform: "pc", Region: "pc"},
 			err: "invalid Overwatch region"},
 		{name: "Test invalid platform", payload: &models.Overwatch{BattleTag: "Onijuan-2670", Platform: "eu", Region: "eu"},
 			err: "invalid Overwatch platform"},
 		{name: "Test no payload", payload: nil, err: "no payload to ValidateBattleUser"},
-		// {name:"", payload:&models.Overwatch{BattleTag: "", Platform: "", Region: ""}, err: ""},
 	}
 
-	// creating a mockBlizzard item to use the custom "Get" func
-	getter := &mockBlizzard{}
-	ow := New(getter)
-
 	// run a test for each of the test items (array above)
 	for _, tc := range test {
 		t.Run(tc.name, func(t *testing.T) {
-			// runs the actual function
-			err := ow.ValidateBattleUser(tc.payload)
+			getter := &mockBlizzard{private: tc.private}
+			ow := New(getter, nil, "", BattleNetConfig{})
 
-			// if the error we got does not correspond with the expected error, fail test
-			if err != nil {
-				if !strings.Contains(err.Error(), tc.err) {
-					t.Errorf("Not correct error: |%+v| -- expected |%s|", err, tc.err)
-				}
-			} else {
-				if len(tc.err) > 0 {
-					t.Errorf("Expected error: |%s|", tc.err)
+			// runs the actual function
+			err := ow.ValidateBattleUser(context.Background(), tc.payload)
+			if tc.err != "" {
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tc.err)
 				}
+				return
 			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, tc.payload)
 		})
 	}
 }
 
 func TestBlizzard_GetBlizzardPlaytime(t *testing.T) {
+	getter := &mockBlizzard{}
+	ow := New(getter, nil, DefaultBackend+"/", BattleNetConfig{})
+
+	game, err := ow.GetBlizzardPlaytime(context.Background(), &models.Overwatch{BattleTag: "Onijuan#2670", Platform: "pc"})
+	require.Nil(t, err)
+
+	// the playtime of the recorded responses (189420 and 43200 seconds), where heroes played less than a minute are left out
+	expected := &models.Game{Name: "Overwatch 2", Time: 64, Minutes: 3877,
+		Modes: []models.ModePlaytime{{Mode: "quickplay", Minutes: 3157}, {Mode: "competitive", Minutes: 720}},
+		Heroes: []models.HeroPlaytime{
+			{Hero: "soldier-76", Mode: "quickplay", Minutes: 1260}, {Hero: "ana", Mode: "quickplay", Minutes: 954},
+			{Hero: "cassidy", Mode: "quickplay", Minutes: 613}, {Hero: "reinhardt", Mode: "quickplay", Minutes: 330},
+			{Hero: "soldier-76", Mode: "competitive", Minutes: 420}, {Hero: "cassidy", Mode: "competitive", Minutes: 300},
+		},
+	}
+	assert.Equal(t, expected, game)
+
+	assert.Equal(t, []string{
+		DefaultBackend + "/players/Onijuan-2670/stats/summary?gamemode=quickplay&platform=pc",
+		DefaultBackend + "/players/Onijuan-2670/stats/summary?gamemode=competitive&platform=pc",
+	}, getter.urls)
+}
+
+func TestBlizzard_GetBlizzardPlaytimeErrors(t *testing.T) {
 	var testcase = []struct {
 		name          string
 		payload       *models.Overwatch
-		cTime         string
-		qTime         string
-		fullTime      int
-		expectedError error
+		getErr        error
+		expectedError string
 	}{
-		{"Test OK", &models.Overwatch{BattleTag: "Onijuan-2670", Platform: "pc", Region: "eu"}, "0:0:0", "0:0:0", 0, nil},
-		{"Test unacceptable response", &models.Overwatch{BattleTag: "Onijuan-2670", Platform: "pc", Region: "eu"}, "", "", 0,
-			errors.New("no acceptable response from OW-api")},
-		{"Test 404", &models.Overwatch{BattleTag: "Onijuan2670", Platform: "pc", Region: "eu"}, "", "", 0,
-			errors.New("invalid Blizzard battle tag or region")},
-		{"Test time [1]", &models.Overwatch{BattleTag: "Onijuan-2670", Platform: "pc", Region: "eu"}, "0", "0", 0, nil},
-		{"Test time [2]", &models.Overwatch{BattleTag: "Onijuan-2670", Platform: "pc", Region: "eu"}, "1:0", "59:0", 1, nil},
-		{"Test time [4]", &models.Overwatch{BattleTag: "Onijuan-2670", Platform: "pc", Region: "eu"}, "4:34:3:2", "0", 0,
-			errors.New("OW API changed the way time is encoded")},
-		// {"Test ",&models.Overwatch{BattleTag: "Onijuan-2670", Platform:  "pc", Region:    "eu"}, "","",0,errors.New("")},
+		{"Test not found", &models.Overwatch{BattleTag: "Onijuan#2671", Platform: "pc"}, nil, "invalid Blizzard battle tag"},
+		{"Test malformed battle tag", &models.Overwatch{BattleTag: "Onijuan", Platform: "pc"}, nil, "invalid battle tag"},
+		{"Test getter error", &models.Overwatch{BattleTag: "Onijuan#2670", Platform: "pc"}, errors.New("test error"), "test error"},
 	}
 
-	// creating a mockBlizzard instance to use the custom "Get" func
-	getter := &mockBlizzard{}
-	ow := New(getter)
-
 	// Run one test for each of the test cases in array above
 	for _, tc := range testcase {
 		t.Run(tc.name, func(t *testing.T) {
-			// set up expected response body with information from test-case
-			setup := &respSetup{}
-			setup.resp.CompetitiveStats.CareerStats.AllHeroes.Game.TimePlayed = tc.cTime
-			setup.resp.QuickPlayStats.CareerStats.AllHeroes.Game.TimePlayed = tc.qTime
-			getter.setup = *setup
-
-			// runs the function
-			gem, err := ow.GetBlizzardPlaytime(tc.payload)
-
-			// if the error we got does not correspond with the expected error, fail test
-			if err == nil {
-				if tc.expectedError != nil {
-					t.Errorf("Got unexpected error: |%v| != |%v|", err, tc.expectedError)
-				}
-				// if the resulting time total does not match the test case -> error out
-				if gem.Time != tc.fullTime {
-					t.Errorf("Unexpected total time played: |%s, %s| -> |%d|", tc.cTime, tc.qTime, tc.fullTime)
-				}
-				return
-			}
-			if err != tc.expectedError {
-				if strings.Contains(err.Error(), tc.expectedError.Error()) {
-					// if the errors contain the expected text -> allow to pass
-					return
-				}
-				t.Errorf("Got unexpected error: |%v| != |%v|", err, tc.expectedError)
-				return
+			ow := New(&mockBlizzard{err: tc.getErr}, nil, "", BattleNetConfig{})
+
+			game, err := ow.GetBlizzardPlaytime(context.Background(), tc.payload)
+			assert.Nil(t, game)
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), tc.expectedError)
 			}
 		})
 	}
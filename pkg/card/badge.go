@@ -0,0 +1,96 @@
+package card
+
+import (
+	"sort"
+	"strconv"
+
+	"ctp/pkg/models"
+)
+
+// BadgeSize is the size of a badge, and the number of games listed on it
+type BadgeSize struct {
+	Width  int
+	Height int
+	Games  int
+}
+
+// BadgeSizes are the sizes of the badges by name. The medium size is the default.
+var BadgeSizes = map[string]BadgeSize{
+	"small":  {Width: 360, Height: 46},
+	"medium": {Width: 480, Height: 152, Games: 3},
+	"large":  {Width: 600, Height: 216, Games: 5},
+}
+
+// the layout of the badges: the padding around the badge, and the height of each game listed below the name
+const (
+	badgePadding = 16
+	badgeRow     = 32
+	badgeScale   = 2
+)
+
+// selfReportedMark marks the playtime recorded manually by the user on badges
+const selfReportedMark = "*"
+
+// Badge lays out the user's total playtime and most played games as a badge, e.g. for forum signatures and READMEs.
+// The name and total playtime are on the first line, followed by a line and a bar for each of the most played games.
+// The badge shows the user as given, which should be the user as shown publicly (see models.User.Publicly).
+// Playtime recorded manually is marked by selfReportedMark, on the games and on the total playtime including it.
+func Badge(user *models.User, theme Theme, size BadgeSize) *Card {
+	c := New(size.Width, size.Height, theme.Background)
+	inner := size.Width - 2*badgePadding
+
+	total := strconv.Itoa(user.TotalGameTime) + "h played"
+	for i := range user.Games {
+		if user.Games[i].PlaytimeSelfReported() {
+			total += selfReportedMark
+			break
+		}
+	}
+	totalWidth := TextWidth(total, badgeScale)
+	c.Text(badgePadding+inner-totalWidth, badgePadding, badgeScale, theme.Accent, total)
+	c.Text(badgePadding, badgePadding, badgeScale, theme.Foreground, Truncate(user.Displayed(), fits(inner-totalWidth)))
+
+	games := mostPlayed(user.Games, size.Games)
+	for i := range games {
+		y := badgePadding + (i+1)*badgeRow
+
+		played := hours(games[i].PlayMinutes())
+		if games[i].PlaytimeSelfReported() {
+			played += selfReportedMark
+		}
+		playedWidth := TextWidth(played, badgeScale)
+		c.Text(badgePadding, y, badgeScale, theme.Foreground, Truncate(games[i].Name, fits(inner-playedWidth)))
+		c.Text(badgePadding+inner-playedWidth, y, badgeScale, theme.Muted, played)
+
+		// the bars are relative to the most played game
+		c.Rect(badgePadding, y+20, inner, 4, theme.Track)
+		c.Rect(badgePadding, y+20, max(1, inner*games[i].PlayMinutes()/games[0].PlayMinutes()), 4, theme.Accent)
+	}
+
+	return c
+}
+
+// mostPlayed returns up to n of the games with the most playtime, most played first, leaving out unplayed games
+func mostPlayed(games []models.Game, n int) []models.Game {
+	var played []models.Game
+	for i := range games {
+		if games[i].PlayMinutes() > 0 {
+			played = append(played, games[i])
+		}
+	}
+
+	sort.SliceStable(played, func(i, j int) bool {
+		return played[i].PlayMinutes() > played[j].PlayMinutes()
+	})
+
+	if len(played) > n {
+		played = played[:n]
+	}
+
+	return played
+}
+
+// fits returns the number of characters fitting in the width at the badge scale, leaving a space before the text beside it
+func fits(width int) int {
+	return width/(badgeScale*(glyphWidth+1)) - 1
+}
@@ -0,0 +1,172 @@
+// Package card renders shareable images, such as the yearly recap, as SVG and PNG.
+// A card is laid out once from rectangles and lines of text, and rendered the same way in both formats:
+// the PNG uses the bitmap font in font.go, and the SVG a monospace font of the same size.
+package card
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// Card is an image laid out from rectangles and text, drawn in the order they are added
+type Card struct {
+	Width      int
+	Height     int
+	Background string // hex color, e.g. "#1b2838"
+	elements   []element
+}
+
+// Theme is the colors of a card, as hex colors
+type Theme struct {
+	Background string
+	Foreground string
+	Muted      string // secondary text
+	Accent     string // highlighted text and bars
+	Track      string // the background of bars
+}
+
+// Themes are the themes of the cards by name. The dark theme is the default.
+var Themes = map[string]Theme{
+	"dark":  {Background: "#1b2838", Foreground: "#ffffff", Muted: "#8f98a0", Accent: "#66c0f4", Track: "#2a475e"},
+	"light": {Background: "#ffffff", Foreground: "#24292f", Muted: "#57606a", Accent: "#0969da", Track: "#d0d7de"},
+}
+
+// element is a rectangle, or a line of text if text is set
+type element struct {
+	x, y          int // the top left corner
+	width, height int // the size of a rectangle
+	scale         int // the size of each pixel of the font, for text
+	color         string
+	text          string
+}
+
+// New returns an empty card with the size and background color
+func New(width, height int, background string) *Card {
+	return &Card{Width: width, Height: height, Background: background}
+}
+
+// Rect adds a rectangle with the top left corner at x, y
+func (c *Card) Rect(x, y, width, height int, color string) {
+	c.elements = append(c.elements, element{x: x, y: y, width: width, height: height, color: color})
+}
+
+// Text adds a line of text with the top left corner at x, y. Each pixel of the font is scale pixels wide, such that
+// each character is 6*scale pixels wide (including the space between characters) and 7*scale pixels high.
+func (c *Card) Text(x, y, scale int, color, text string) {
+	c.elements = append(c.elements, element{x: x, y: y, scale: scale, color: color, text: text})
+}
+
+// TextWidth returns the width of the text in pixels at the scale, not including the space after the last character
+func TextWidth(text string, scale int) int {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+
+	return (n*(glyphWidth+1) - 1) * scale
+}
+
+// Truncate shortens the text to at most max characters, ending it with "..." if it is shortened
+func Truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+
+	if max <= 3 {
+		return string(runes[:max])
+	}
+
+	return string(runes[:max-3]) + "..."
+}
+
+// SVG renders the card as an SVG image
+func (c *Card) SVG() []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		c.Width, c.Height, c.Width, c.Height)
+	buf.WriteString("\n")
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="%s"/>`, c.Width, c.Height, c.Background)
+	buf.WriteString("\n")
+
+	for _, e := range c.elements {
+		if e.text == "" {
+			fmt.Fprintf(&buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`, e.x, e.y, e.width, e.height, e.color)
+			buf.WriteString("\n")
+
+			continue
+		}
+
+		// the capitals of a monospace font are about 0.7 em high and 0.6 em wide, as the glyphs of the bitmap font
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-family="monospace" font-size="%d" fill="%s" textLength="%d">`,
+			e.x, e.y+glyphHeight*e.scale, 10*e.scale, e.color, TextWidth(e.text, e.scale))
+		_ = xml.EscapeText(&buf, []byte(e.text)) // writing to a bytes.Buffer never fails
+		buf.WriteString("</text>\n")
+	}
+
+	buf.WriteString("</svg>\n")
+
+	return buf.Bytes()
+}
+
+// PNG renders the card as a PNG image. Text is drawn in upper case, with "?" for characters the font does not have.
+func (c *Card) PNG() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))
+	fill(img, img.Bounds(), c.Background)
+
+	for _, e := range c.elements {
+		if e.text == "" {
+			fill(img, image.Rect(e.x, e.y, e.x+e.width, e.y+e.height), e.color)
+			continue
+		}
+
+		x := e.x
+		for _, r := range e.text {
+			g := glyph(r)
+			for row := 0; row < glyphHeight; row++ {
+				for col := 0; col < glyphWidth; col++ {
+					if g[row]&(1<<uint(glyphWidth-1-col)) == 0 {
+						continue
+					}
+
+					px, py := x+col*e.scale, e.y+row*e.scale
+					fill(img, image.Rect(px, py, px+e.scale, py+e.scale), e.color)
+				}
+			}
+
+			x += (glyphWidth + 1) * e.scale
+		}
+	}
+
+	var buf bytes.Buffer
+
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// fill fills the rectangle of the image with the hex color
+func fill(img draw.Image, rect image.Rectangle, hex string) {
+	draw.Draw(img, rect, &image.Uniform{C: parseColor(hex)}, image.Point{}, draw.Src)
+}
+
+// parseColor parses a hex color (#rrggbb), returning black if it is invalid
+func parseColor(hex string) color.RGBA {
+	v, err := strconv.ParseUint(strings.TrimPrefix(hex, "#"), 16, 32)
+	if err != nil || len(hex) != 7 {
+		return color.RGBA{A: 0xff}
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
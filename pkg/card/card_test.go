@@ -0,0 +1,185 @@
+package card
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	var cases = []struct {
+		text     string
+		max      int
+		expected string
+	}{
+		{"Portal", 10, "Portal"},
+		{"Portal", 6, "Portal"},
+		{"The Witcher 3: Wild Hunt", 10, "The Wit..."},
+		{"Portal", 2, "Po"},
+		{"ポータル 2", 5, "ポー..."},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, Truncate(tc.text, tc.max))
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	assert.Equal(t, 0, TextWidth("", 3))
+	assert.Equal(t, 5, TextWidth("a", 1))
+	assert.Equal(t, 69, TextWidth("2024", 3))
+}
+
+func TestSVG(t *testing.T) {
+	c := New(100, 50, "#000000")
+	c.Rect(10, 10, 20, 5, "#66c0f4")
+	c.Text(10, 20, 2, "#ffffff", "Tom & Jerry <3")
+
+	svg := string(c.SVG())
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" width="100" height="50"`), svg)
+	assert.Contains(t, svg, `<rect x="10" y="10" width="20" height="5" fill="#66c0f4"/>`)
+	assert.Contains(t, svg, `font-size="20" fill="#ffffff" textLength="166">Tom &amp; Jerry &lt;3</text>`)
+}
+
+func TestPNG(t *testing.T) {
+	c := New(100, 50, "#1b2838")
+	c.Rect(0, 0, 10, 10, "#66c0f4")
+	c.Text(20, 20, 1, "#ffffff", "I")
+
+	body, err := c.PNG()
+	require.Nil(t, err)
+
+	img, err := png.Decode(bytes.NewReader(body))
+	require.Nil(t, err)
+	assert.Equal(t, 100, img.Bounds().Dx())
+	assert.Equal(t, 50, img.Bounds().Dy())
+
+	var cases = []struct {
+		x, y     int
+		expected string
+	}{
+		{5, 5, "#66c0f4"},   // the rectangle
+		{50, 40, "#1b2838"}, // the background
+		{21, 20, "#ffffff"}, // the top of the "I"
+		{20, 21, "#1b2838"}, // beside the stem of the "I"
+		{22, 21, "#ffffff"}, // the stem of the "I"
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		assert.Equal(t, parseColor(tc.expected), img.At(tc.x, tc.y), "%d, %d", tc.x, tc.y)
+	}
+}
+
+func TestRecap(t *testing.T) {
+	recap := &models.Recap{
+		Year:    2024,
+		Name:    "Onijuan",
+		Minutes: 2400,
+		Games: []models.GamePlaytime{
+			{Name: "Dota 2", Minutes: 2000},
+			{Name: "Celeste", Minutes: 300},
+			{Name: "Zelda", Minutes: 50, SelfReported: true},
+			{Name: "Portal", Minutes: 45},
+		},
+		NewGames:    []string{"Celeste"},
+		BiggestWeek: &models.RecapWeek{Start: "2024-11-18", Minutes: 1400},
+		Rank:        &models.Rank{Rank: 2, Users: 4},
+		From:        "2024-01-01",
+		To:          "2024-11-20",
+	}
+
+	svg := string(Recap(recap).SVG())
+	for _, text := range []string{"Onijuan&#39;s 2024 in games", "40h played*", "2024-01-01 to 2024-11-20", "Dota 2", "33h",
+		"50m*", "45m", "New games: 1 | Biggest week: 23h | Rank: #2 of 4"} {
+		assert.Contains(t, svg, ">"+text+"</text>")
+	}
+}
+
+func TestBadge(t *testing.T) {
+	user := &models.User{Name: "onijuan", TotalGameTime: 905, Games: []models.Game{
+		{Name: "Portal 2", AppID: 620, Minutes: 3000},
+		{Name: "Unplayed", AppID: 1},
+		{Name: "Dota 2", AppID: 570, Minutes: 30000},
+		{Name: "LeagueOfLegends", Time: 20},
+		{Name: "Celeste", Source: "gog", Minutes: 45},
+	}}
+
+	var cases = []struct {
+		name       string
+		size       string
+		theme      string
+		expected   []string
+		unexpected []string
+	}{
+		{"Test small", "small", "dark", []string{"onijuan", "905h played"}, []string{"Dota 2"}},
+		{"Test medium", "medium", "light", []string{"onijuan", "905h played", "Dota 2", "500h", "LeagueOfLegends", "20h",
+			"Portal 2", "50h"}, []string{"Celeste", "Unplayed"}},
+		{"Test large", "large", "dark", []string{"Dota 2", "LeagueOfLegends", "Portal 2", "Celeste", "45m"},
+			[]string{"Unplayed"}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			size := BadgeSizes[tc.size]
+			c := Badge(user, Themes[tc.theme], size)
+			assert.Equal(t, size.Width, c.Width)
+			assert.Equal(t, size.Height, c.Height)
+			assert.Equal(t, Themes[tc.theme].Background, c.Background)
+
+			svg := string(c.SVG())
+			for _, text := range tc.expected {
+				assert.Contains(t, svg, ">"+text+"</text>")
+			}
+			for _, text := range tc.unexpected {
+				assert.NotContains(t, svg, ">"+text+"</text>")
+			}
+		})
+	}
+}
+
+func TestBadgeSelfReported(t *testing.T) {
+	user := &models.User{Name: "onijuan", TotalGameTime: 660, Games: []models.Game{
+		{Name: "Dota 2", AppID: 570, Time: 500, Minutes: 30000},
+		{Name: "Zelda", Time: 100, Minutes: 6000, Source: models.ManualSource, Platform: "switch", SelfReported: true},
+		{Name: "Portal 2", AppID: 620, Time: 60, Minutes: 3600, GameID: "portal-2", Merged: []models.MergedGame{
+			{Name: "Portal 2", Source: "steam", Minutes: 3000},
+			{Name: "Portal 2", Source: models.ManualSource, Minutes: 3600},
+		}},
+	}}
+
+	var cases = []struct {
+		name       string
+		hidden     []string
+		expected   []string
+		unexpected []string
+	}{
+		{"Test marked", nil, []string{"660h played*", "500h", "100h*", "60h*"}, nil},
+		{"Test hidden self-reported", []string{models.HideSelfReported}, []string{"550h played", "500h", "50h"},
+			[]string{"Zelda", "*</text>"}},
+		{"Test hidden games", []string{models.HideGames}, []string{"660h played"}, []string{"Dota 2", "Zelda", "*</text>"}},
+		{"Test hidden total", []string{models.HideTotal}, []string{"0h played*", "Dota 2"}, []string{"660h played*"}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user.Hidden = tc.hidden
+			svg := string(Badge(user.Publicly(), Themes["dark"], BadgeSizes["medium"]).SVG())
+			for _, text := range tc.expected {
+				assert.Contains(t, svg, ">"+text+"</text>")
+			}
+			for _, text := range tc.unexpected {
+				assert.NotContains(t, svg, text)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package card
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"ctp/pkg/models"
+)
+
+// recapGames is the number of games shown on the recap card
+const recapGames = 5
+
+// Recap lays out the yearly recap as a card of 1200x630 pixels (the size of link previews), with the hours played,
+// the most played games of the year and the other highlights. Self-reported playtime is marked as on badges.
+func Recap(recap *models.Recap) *Card {
+	theme := Themes["dark"]
+	c := New(1200, 630, theme.Background)
+
+	title := fmt.Sprintf("%d in games", recap.Year)
+	if recap.Name != "" {
+		title = recap.Name + "'s " + title
+	}
+	c.Text(60, 50, 6, theme.Foreground, Truncate(title, 30))
+
+	played := hours(recap.Minutes) + " played"
+	for _, game := range recap.Games {
+		if game.SelfReported {
+			played += selfReportedMark
+			break
+		}
+	}
+	c.Text(60, 130, 8, theme.Accent, Truncate(played, 22))
+	c.Text(60, 200, 3, theme.Muted, fmt.Sprintf("%s to %s", recap.From, recap.To))
+
+	// the most played games, with bars relative to the most played game
+	for i, game := range recap.Games {
+		if i == recapGames {
+			break
+		}
+
+		y := 260 + i*56
+		c.Text(60, y+4, 3, theme.Foreground, Truncate(game.Name, 26))
+		c.Rect(560, y, 480, 28, theme.Track)
+		c.Rect(560, y, max(1, 480*game.Minutes/recap.Games[0].Minutes), 28, theme.Accent)
+		gained := hours(game.Minutes)
+		if game.SelfReported {
+			gained += selfReportedMark
+		}
+		c.Text(1060, y+4, 3, theme.Foreground, gained)
+	}
+
+	highlights := []string{"New games: " + strconv.Itoa(len(recap.NewGames))}
+	if recap.BiggestWeek != nil {
+		highlights = append(highlights, "Biggest week: "+hours(recap.BiggestWeek.Minutes))
+	}
+	if recap.Rank != nil {
+		highlights = append(highlights, fmt.Sprintf("Rank: #%d of %d", recap.Rank.Rank, recap.Rank.Users))
+	}
+	c.Text(60, 560, 3, theme.Muted, Truncate(strings.Join(highlights, " | "), 60))
+
+	return c
+}
+
+// hours formats the minutes as hours, or as minutes if less than an hour has been played
+func hours(minutes int) string {
+	if minutes < 60 {
+		return strconv.Itoa(minutes) + "m"
+	}
+
+	return strconv.Itoa(minutes/60) + "h"
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,174 @@
+// Package catalog identifies the same game across providers, launcher imports and manual games,
+// such that the duplicate entries of a game can be merged into one.
+package catalog
+
+//go:generate go run ../../tools/embedgen -in catalog.yaml -out catalog_gen.go -pkg catalog -name defaultCatalog -doc "is the default game catalog, the content of catalog.yaml"
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"ctp/pkg/models"
+
+	"gopkg.in/yaml.v2"
+)
+
+// catalogVersion is the version of the catalog format supported
+const catalogVersion = 1
+
+// maxResults is the highest number of games returned by Search
+const maxResults = 25
+
+// validID matches the ids of the games in the catalog
+var validID = regexp.MustCompile("^[a-z0-9]+(-[a-z0-9]+)*$")
+
+// Catalog contains the known games, indexed by their id, external ids and normalized names.
+// It is loaded from a versioned YAML (or JSON) file, see catalog.yaml for the default catalog.
+type Catalog struct {
+	games    []*models.CatalogGame
+	byID     map[string]*models.CatalogGame
+	byKey    map[string]*models.CatalogGame // by the normalized name and aliases
+	external map[string]*models.CatalogGame // by provider and external id, e.g. "steam:730"
+}
+
+// catalogFile is the format of the catalog file
+type catalogFile struct {
+	Version int `yaml:"version"`
+	Games   []struct {
+		ID          string              `yaml:"id"`
+		Name        string              `yaml:"name"`
+		Aliases     []string            `yaml:"aliases"`
+		ExternalIDs map[string][]string `yaml:"externalIds"`
+	} `yaml:"games"`
+}
+
+// DefaultCatalog returns the catalog the application is built with
+func DefaultCatalog() *Catalog {
+	c, err := ParseCatalog([]byte(defaultCatalog))
+	if err != nil {
+		panic(fmt.Sprintf("invalid default catalog: %s", err)) // tested by TestDefaultCatalog
+	}
+
+	return c
+}
+
+// LoadCatalog loads the catalog from the YAML (or JSON) file
+func LoadCatalog(path string) (*Catalog, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseCatalog(data)
+}
+
+// ParseCatalog parses and validates the YAML (or JSON, which is valid YAML) catalog.
+// The ids, normalized names and aliases, and external ids have to be unique across the catalog.
+func ParseCatalog(data []byte) (*Catalog, error) {
+	var file catalogFile
+
+	err := yaml.UnmarshalStrict(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Version != catalogVersion {
+		return nil, fmt.Errorf("unsupported catalog version %d, expected %d", file.Version, catalogVersion)
+	}
+
+	c := &Catalog{
+		byID:     make(map[string]*models.CatalogGame),
+		byKey:    make(map[string]*models.CatalogGame),
+		external: make(map[string]*models.CatalogGame),
+	}
+
+	for _, g := range file.Games {
+		game := &models.CatalogGame{ID: g.ID, Name: g.Name, Aliases: g.Aliases, ExternalIDs: g.ExternalIDs}
+
+		err = c.add(game)
+		if err != nil {
+			return nil, fmt.Errorf("invalid game %q: %w", g.ID, err)
+		}
+	}
+
+	return c, nil
+}
+
+// add validates and indexes the game
+func (c *Catalog) add(game *models.CatalogGame) error {
+	if !validID.MatchString(game.ID) {
+		return errors.New("invalid id")
+	}
+
+	if _, ok := c.byID[game.ID]; ok {
+		return errors.New("duplicate id")
+	}
+
+	if game.Name == "" {
+		return errors.New("no name")
+	}
+
+	for _, name := range append([]string{game.Name}, game.Aliases...) {
+		key := Key(name)
+		if key == "" {
+			return fmt.Errorf("invalid name %q", name)
+		}
+
+		// the name and an alias may normalize to the same key, e.g. an alias only differing by an edition suffix
+		if other, ok := c.byKey[key]; ok && other != game {
+			return fmt.Errorf("name %q is also a name of %q", name, other.ID)
+		}
+
+		c.byKey[key] = game
+	}
+
+	for provider, ids := range game.ExternalIDs {
+		for _, id := range ids {
+			if other, ok := c.external[provider+":"+id]; ok {
+				return fmt.Errorf("%s id %s is also an id of %q", provider, id, other.ID)
+			}
+
+			c.external[provider+":"+id] = game
+		}
+	}
+
+	c.byID[game.ID] = game
+	c.games = append(c.games, game)
+
+	return nil
+}
+
+// Get returns the game with the id, or nil if the catalog has no such game
+func (c *Catalog) Get(id string) *models.CatalogGame {
+	return c.byID[id]
+}
+
+// Search returns the games with a name or alias containing the name (after normalization), sorted by name.
+// At most 25 games are returned.
+func (c *Catalog) Search(name string) []models.CatalogGame {
+	key := Key(name)
+
+	var result []models.CatalogGame
+	for _, game := range c.games {
+		for _, n := range append([]string{game.Name}, game.Aliases...) {
+			if strings.Contains(Key(n), key) {
+				result = append(result, *game)
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+	})
+
+	if len(result) > maxResults {
+		result = result[:maxResults]
+	}
+
+	return result
+}
@@ -0,0 +1,103 @@
+package catalog
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	// the generated file has to be regenerated with "go generate" whenever catalog.yaml is changed
+	data, err := ioutil.ReadFile("catalog.yaml")
+	if assert.Nil(t, err) {
+		assert.Equal(t, string(data), defaultCatalog)
+	}
+
+	c := DefaultCatalog()
+
+	// the names of the games of the providers without app ids have to be in the catalog
+	for name, id := range map[string]string{
+		"LeagueOfLegends":      "league-of-legends",
+		"Overwatch 2":          "overwatch",
+		"Old School Runescape": "old-school-runescape",
+		"RuneScape 3":          "runescape",
+		models.WorldOfWarcraft: "world-of-warcraft",
+		models.DiabloIII:       "diablo-3",
+		models.StarCraftII:     "starcraft-2",
+	} {
+		if match := c.Match(&models.Game{Name: name}); assert.NotNil(t, match, name) {
+			assert.Equal(t, id, match.ID)
+		}
+	}
+}
+
+func TestParseCatalog(t *testing.T) {
+	var cases = []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"Test ok", `{"version": 1, "games": [{"id": "portal-2", "name": "Portal 2", "externalIds": {"steam": ["620"]}},
+			{"id": "portal", "name": "Portal", "aliases": ["Portal Still Alive"]}]}`, ""},
+		{"Test wrong version", `{"version": 2, "games": []}`, "unsupported catalog version 2"},
+		{"Test unknown field", `{"version": 1, "games": [{"id": "portal", "name": "Portal", "steam": 400}]}`,
+			"field steam not found"},
+		{"Test invalid id", `{"version": 1, "games": [{"id": "Portal 2", "name": "Portal 2"}]}`, "invalid id"},
+		{"Test duplicate id", `{"version": 1, "games": [{"id": "portal", "name": "Portal"}, {"id": "portal", "name": "Portal 2"}]}`,
+			"duplicate id"},
+		{"Test no name", `{"version": 1, "games": [{"id": "portal"}]}`, "no name"},
+		{"Test invalid alias", `{"version": 1, "games": [{"id": "portal", "name": "Portal", "aliases": ["!"]}]}`,
+			`invalid name "!"`},
+		{"Test duplicate name", `{"version": 1, "games": [{"id": "portal", "name": "Portal"},
+			{"id": "portal-goty", "name": "Portal: Game of the Year Edition"}]}`, `is also a name of "portal"`},
+		{"Test duplicate external id", `{"version": 1, "games": [{"id": "portal", "name": "Portal", "externalIds": {"steam": ["400"]}},
+			{"id": "portal-2", "name": "Portal 2", "externalIds": {"steam": ["400"]}}]}`, `steam id 400 is also an id of "portal"`},
+		{"Test invalid", "{", "yaml"},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := ParseCatalog([]byte(tc.data))
+			if tc.err != "" {
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tc.err)
+				}
+				assert.Nil(t, c)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.NotNil(t, c)
+		})
+	}
+}
+
+func TestCatalog_Search(t *testing.T) {
+	var cases = []struct {
+		name     string
+		search   string
+		expected []string
+	}{
+		{"Test name", "portal", []string{"portal", "portal-2"}},
+		{"Test alias", "CS:GO", []string{"counter-strike-2"}},
+		{"Test part of a name", "Stardew", []string{"stardew-valley"}},
+		{"Test no results", "Not a game", nil},
+	}
+
+	c := DefaultCatalog()
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ids []string
+			for _, game := range c.Search(tc.search) {
+				ids = append(ids, game.ID)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
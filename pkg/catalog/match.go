@@ -0,0 +1,204 @@
+package catalog
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"ctp/pkg/models"
+)
+
+// editions are the suffixes ignored when comparing names, as the same game is often listed with or without them
+var editions = [][]string{
+	{"game", "of", "the", "year", "edition"},
+	{"game", "of", "the", "year"},
+	{"goty", "edition"},
+	{"goty"},
+	{"definitive", "edition"},
+	{"complete", "edition"},
+	{"deluxe", "edition"},
+	{"special", "edition"},
+	{"enhanced", "edition"},
+	{"ultimate", "edition"},
+	{"standard", "edition"},
+	{"anniversary", "edition"},
+	{"directors", "cut"},
+	{"remastered"},
+}
+
+// Key returns the normalized name, which is the same for names only differing by case, punctuation, spacing,
+// trademark symbols or an edition suffix, e.g. "The Witcher® 3: Wild Hunt - Game of the Year Edition" and
+// "the witcher 3 wild hunt" are both "thewitcher3wildhunt".
+func Key(name string) string {
+	name = strings.ToLower(name)
+	name = strings.NewReplacer("'", "", "’", "", "&", " and ").Replace(name)
+
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	// the edition suffixes are removed, unless the name is only an edition
+	for stripped := true; stripped; {
+		stripped = false
+		for _, edition := range editions {
+			if len(words) > len(edition) && hasSuffix(words, edition) {
+				words = words[:len(words)-len(edition)]
+				stripped = true
+			}
+		}
+	}
+
+	return strings.Join(words, "")
+}
+
+// hasSuffix checks whether the words end with the suffix
+func hasSuffix(words, suffix []string) bool {
+	words = words[len(words)-len(suffix):]
+	for i := range suffix {
+		if words[i] != suffix[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// source returns the source of a game: the launcher it is imported from, "manual", or "steam" for Steam games
+func source(game *models.Game) string {
+	if game.Source == "" && game.AppID != 0 {
+		return "steam"
+	}
+
+	return game.Source
+}
+
+// Match returns the catalog game of the game, matched by the external id of its provider (the Steam app id),
+// or by its normalized name. Returns nil if the game is not in the catalog.
+func (c *Catalog) Match(game *models.Game) *models.CatalogGame {
+	if game.AppID != 0 {
+		if match, ok := c.external["steam:"+strconv.Itoa(game.AppID)]; ok {
+			return match
+		}
+	}
+
+	return c.byKey[Key(game.Name)]
+}
+
+// Merge merges the duplicate entries of the same game, e.g. listed both by Steam and in a launcher import.
+// Games are duplicates if they match the same catalog game, or have the same normalized name if they are not in the
+// catalog. The user's matches override how the games with the same normalized name are matched, either to another
+// catalog game or to none, such that they are never merged.
+//
+// The merged game is the entry from a provider with the most information (preferring games which are not
+// self-reported, and then Steam games), with the highest playtime and last played time of the entries, as launchers
+// such as GOG Galaxy and Playnite report the playtime of the games they also import from the other launchers.
+// Every entry of a merged game is listed in its Merged. The order of the games is kept.
+func (c *Catalog) Merge(games []models.Game, matches []models.GameMatch) []models.Game {
+	overrides := make(map[string]string)
+	for _, match := range matches {
+		overrides[match.Key] = match.GameID
+	}
+
+	var keys []string
+	groups := make(map[string][]models.Game)
+
+	for i, game := range games {
+		name := Key(game.Name)
+
+		game.GameID = ""
+		if match := c.Match(&game); match != nil {
+			game.GameID = match.ID
+		}
+
+		override, overridden := overrides[name]
+		if overridden {
+			game.GameID = override
+		}
+
+		var key string
+		switch {
+		case overridden && override == "":
+			// games the user has chosen not to match are never merged
+			key = "game:" + strconv.Itoa(i)
+		case game.GameID != "":
+			key = "id:" + game.GameID
+		case name == "":
+			key = "name:" + game.Name
+		default:
+			key = "key:" + name
+		}
+
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], game)
+	}
+
+	var merged []models.Game
+	for _, key := range keys {
+		merged = append(merged, mergeGames(groups[key]))
+	}
+
+	return merged
+}
+
+// mergeGames merges the duplicate entries of a game into one, which is the game itself if there is only one
+func mergeGames(entries []models.Game) models.Game {
+	if len(entries) == 1 {
+		game := entries[0]
+		game.Merged = nil
+
+		return game
+	}
+
+	best := 0
+	for i := range entries {
+		if better(&entries[i], &entries[best]) {
+			best = i
+		}
+	}
+
+	game := entries[best]
+	game.Merged = nil
+
+	var minutes int
+	for i := range entries {
+		entry := &entries[i]
+
+		minutes = max(minutes, entry.PlayMinutes())
+		if entry.LastPlayed > game.LastPlayed {
+			game.LastPlayed = entry.LastPlayed
+		}
+		game.RecentMinutes = max(game.RecentMinutes, entry.RecentMinutes)
+		game.SelfReported = game.SelfReported && entry.SelfReported
+
+		game.Merged = append(game.Merged, models.MergedGame{Name: entry.Name, Source: source(entry), Minutes: entry.PlayMinutes()})
+	}
+
+	game.Minutes = minutes
+	game.Time = minutes / 60
+
+	return game
+}
+
+// better checks whether the entry has more information than the other entry, preferring games which are not
+// self-reported, then Steam games, and then the highest playtime
+func better(entry, other *models.Game) bool {
+	if entry.SelfReported != other.SelfReported {
+		return !entry.SelfReported
+	}
+
+	if (entry.AppID != 0) != (other.AppID != 0) {
+		return entry.AppID != 0
+	}
+
+	return entry.PlayMinutes() > other.PlayMinutes()
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
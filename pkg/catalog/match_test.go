@@ -0,0 +1,125 @@
+package catalog
+
+import (
+	"testing"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	var cases = []struct {
+		name     string
+		expected string
+	}{
+		{"The Witcher® 3: Wild Hunt - Game of the Year Edition", "thewitcher3wildhunt"},
+		{"the witcher 3 wild hunt", "thewitcher3wildhunt"},
+		{"Baldur's Gate 3", "baldursgate3"},
+		{"Ratchet & Clank", "ratchetandclank"},
+		{"Skyrim Special Edition", "skyrim"},
+		{"Portal GOTY Remastered", "portal"},
+		{"GOTY", "goty"},
+		{"Pokémon Légendes", "pokémonlégendes"},
+		{"!?", ""},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Key(tc.name))
+		})
+	}
+}
+
+func TestCatalog_Match(t *testing.T) {
+	var cases = []struct {
+		name     string
+		game     models.Game
+		expected string // the id of the game matched, if any
+	}{
+		{"Test app id", models.Game{Name: "Counter-Strike: Global Offensive", AppID: 730}, "counter-strike-2"},
+		{"Test app id of another edition", models.Game{Name: "The Elder Scrolls V: Skyrim Special Edition", AppID: 489830},
+			"skyrim"},
+		{"Test name", models.Game{Name: "THE WITCHER 3: WILD HUNT", Source: "gog"}, "the-witcher-3"},
+		{"Test alias", models.Game{Name: "GTA 5", Source: "manual"}, "grand-theft-auto-5"},
+		{"Test unknown app id", models.Game{Name: "Stardew Valley", AppID: 1}, "stardew-valley"},
+		{"Test not in the catalog", models.Game{Name: "Not a game"}, ""},
+	}
+
+	c := DefaultCatalog()
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			match := c.Match(&tc.game)
+			if tc.expected == "" {
+				assert.Nil(t, match)
+				return
+			}
+
+			if assert.NotNil(t, match) {
+				assert.Equal(t, tc.expected, match.ID)
+			}
+		})
+	}
+}
+
+func TestCatalog_Merge(t *testing.T) {
+	steam := models.Game{Name: "The Witcher 3: Wild Hunt", AppID: 292030, Time: 10, Minutes: 600, LastPlayed: 100,
+		Icon: "icon"}
+	gog := models.Game{Name: "The Witcher 3: Wild Hunt - Game of the Year Edition", Time: 12, Minutes: 720,
+		LastPlayed: 200, Source: "gog"}
+	manual := models.Game{Name: "Witcher 3", Time: 1, Minutes: 60, Source: "manual", Platform: "playstation",
+		SelfReported: true}
+	indie := models.Game{Name: "Some Indie Game", Time: 2, Minutes: 120, Source: "csv"}
+	indieManual := models.Game{Name: "some indie game!", Time: 3, Minutes: 180, Source: "manual", SelfReported: true}
+
+	var cases = []struct {
+		name     string
+		games    []models.Game
+		matches  []models.GameMatch
+		expected []models.Game
+	}{
+		{"Test no duplicates", []models.Game{steam, indie}, nil, []models.Game{
+			{Name: "The Witcher 3: Wild Hunt", AppID: 292030, Time: 10, Minutes: 600, LastPlayed: 100, Icon: "icon",
+				GameID: "the-witcher-3"},
+			indie}},
+		{"Test catalog duplicates", []models.Game{manual, steam, gog}, nil, []models.Game{
+			{Name: "The Witcher 3: Wild Hunt", AppID: 292030, Time: 12, Minutes: 720, LastPlayed: 200, Icon: "icon",
+				GameID: "the-witcher-3", Merged: []models.MergedGame{
+					{Name: "Witcher 3", Source: "manual", Minutes: 60},
+					{Name: "The Witcher 3: Wild Hunt", Source: "steam", Minutes: 600},
+					{Name: "The Witcher 3: Wild Hunt - Game of the Year Edition", Source: "gog", Minutes: 720}}}}},
+		{"Test duplicates not in the catalog", []models.Game{indieManual, indie}, nil, []models.Game{
+			{Name: "Some Indie Game", Time: 3, Minutes: 180, Source: "csv", Merged: []models.MergedGame{
+				{Name: "some indie game!", Source: "manual", Minutes: 180},
+				{Name: "Some Indie Game", Source: "csv", Minutes: 120}}}}},
+		{"Test self-reported duplicates", []models.Game{indieManual, indieManual}, nil, []models.Game{
+			{Name: "some indie game!", Time: 3, Minutes: 180, Source: "manual", SelfReported: true,
+				Merged: []models.MergedGame{
+					{Name: "some indie game!", Source: "manual", Minutes: 180},
+					{Name: "some indie game!", Source: "manual", Minutes: 180}}}}},
+		{"Test match kept separate", []models.Game{steam, manual}, []models.GameMatch{{Key: "witcher3", GameID: ""}},
+			[]models.Game{
+				{Name: "The Witcher 3: Wild Hunt", AppID: 292030, Time: 10, Minutes: 600, LastPlayed: 100, Icon: "icon",
+					GameID: "the-witcher-3"},
+				manual}},
+		{"Test match to another game", []models.Game{indie, steam}, []models.GameMatch{
+			{Key: "someindiegame", GameID: "the-witcher-3"}},
+			[]models.Game{{Name: "The Witcher 3: Wild Hunt", AppID: 292030, Time: 10, Minutes: 600, LastPlayed: 100,
+				Icon: "icon", GameID: "the-witcher-3", Merged: []models.MergedGame{
+					{Name: "Some Indie Game", Source: "csv", Minutes: 120},
+					{Name: "The Witcher 3: Wild Hunt", Source: "steam", Minutes: 600}}}}},
+		{"Test no games", nil, nil, nil},
+	}
+
+	c := DefaultCatalog()
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, c.Merge(tc.games, tc.matches))
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Package client is a Go client for the API. The methods for each operation are generated from
+// the OpenAPI document (api/openapi.json) by tools/apigen, and are found in client_gen.go.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"ctp/pkg/models"
+)
+
+// Client is used to make requests to the API. Token is the JWT returned by AuthCallback,
+// and is required for every operation which needs authentication.
+type Client struct {
+	BaseURL    string
+	HTTPClient models.Client
+	Token      string
+}
+
+// New returns a new client for the API served at baseURL, e.g. "https://example.com"
+func New(baseURL string, client models.Client) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTPClient: client}
+}
+
+// APIError is returned when the API responds with an error. It contains the problem returned by the API.
+type APIError struct {
+	models.Problem
+}
+
+func (e *APIError) Error() string {
+	if e.Detail != "" {
+		return fmt.Sprintf("%d %s (%s): %s", e.Status, e.Title, e.Code, e.Detail)
+	}
+
+	return fmt.Sprintf("%d %s (%s)", e.Status, e.Title, e.Code)
+}
+
+// do sends the request and decodes the response into result (if not nil). Returns the header of the response.
+// Bodies with the content type "text/plain" are sent as they are, other bodies are JSON encoded.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header,
+	contentType string, body, result interface{}) (http.Header, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if contentType == "text/plain" {
+		reqBody = strings.NewReader(fmt.Sprint(body))
+	} else if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if reqBody != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	if c.Token != "" {
+		req.Header.Set("Authorization", c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		apiErr := &APIError{}
+		if json.Unmarshal(respBody, &apiErr.Problem) != nil || apiErr.Status == 0 {
+			// the response was not a problem, e.g. from a proxy in front of the API
+			apiErr.Problem = *models.NewProblem(resp.StatusCode, "", strings.TrimSpace(string(respBody)))
+		}
+
+		return resp.Header, apiErr
+	}
+
+	if result == nil {
+		return resp.Header, nil
+	}
+
+	return resp.Header, json.Unmarshal(respBody, result)
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	req    *http.Request
+	body   string
+	status int
+	resp   string
+	err    error
+}
+
+func (m *mockClient) Do(req *http.Request) (*http.Response, error) {
+	m.req = req
+	m.body = ""
+
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		m.body = string(b)
+	}
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	w := httptest.NewRecorder()
+	w.WriteHeader(m.status)
+	_, err := w.WriteString(m.resp)
+
+	return w.Result(), err
+}
+
+func TestClient(t *testing.T) {
+	var cases = []struct {
+		name         string
+		call         func(c *Client) error
+		status       int
+		resp         string
+		err          error
+		expectedURL  string
+		expectedBody string
+		expectedErr  bool
+	}{
+		{"Test GetUser", func(c *Client) error {
+			user, err := c.GetUser(context.Background())
+			if err == nil {
+				assert.Equal(t, "test", user.Name)
+			}
+			return err
+		}, http.StatusOK, `{"name":"test"}`, nil, "http://test/api/v1/user", "", false},
+		{"Test GetPublicUser escapes username", func(c *Client) error {
+			_, err := c.GetPublicUser(context.Background(), "test user")
+			return err
+		}, http.StatusOK, `{"name":"test user"}`, nil, "http://test/api/v1/user/test%20user", "", false},
+		{"Test AuthCallback query", func(c *Client) error {
+			token, err := c.AuthCallback(context.Background(), "abc", "")
+			if err == nil {
+				assert.Equal(t, "token", token.Token)
+			}
+			return err
+		}, http.StatusOK, `{"token":"token"}`, nil, "http://test/api/v1/authcallback?code=abc", "", false},
+		{"Test UpdateKey plain body", func(c *Client) error {
+			_, err := c.UpdateKey(context.Background(), "key")
+			return err
+		}, http.StatusOK, `{"status":"success"}`, nil, "http://test/api/v1/riotapikey", "key", false},
+		{"Test DeleteUser JSON body", func(c *Client) error {
+			_, err := c.DeleteUser(context.Background(), []string{"lol"})
+			return err
+		}, http.StatusOK, `{"status":"success"}`, nil, "http://test/api/v1/user", `["lol"]`, false},
+		{"Test problem response", func(c *Client) error {
+			_, err := c.GetUser(context.Background())
+			var apiErr *APIError
+			if assert.True(t, errors.As(err, &apiErr)) {
+				assert.Equal(t, models.CodeNotFound, apiErr.Code)
+			}
+			return err
+		}, http.StatusNotFound, `{"type":"about:blank","title":"Not Found","status":404,"code":"not_found"}`, nil,
+			"http://test/api/v1/user", "", true},
+		{"Test non-problem error response", func(c *Client) error {
+			_, err := c.GetUser(context.Background())
+			var apiErr *APIError
+			if assert.True(t, errors.As(err, &apiErr)) {
+				assert.Equal(t, http.StatusBadGateway, apiErr.Status)
+			}
+			return err
+		}, http.StatusBadGateway, "bad gateway", nil, "http://test/api/v1/user", "", true},
+		{"Test invalid response", func(c *Client) error {
+			_, err := c.GetUser(context.Background())
+			return err
+		}, http.StatusOK, "{", nil, "http://test/api/v1/user", "", true},
+		{"Test client error", func(c *Client) error {
+			_, err := c.UpdateGames(context.Background(), false)
+			return err
+		}, 0, "", errors.New("test"), "http://test/api/v1/updategames", "", true},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockClient{status: tc.status, resp: tc.resp, err: tc.err}
+			c := New("http://test/", mock)
+			c.Token = "token"
+
+			err := tc.call(c)
+			if tc.expectedErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+
+			require.NotNil(t, mock.req)
+			assert.Equal(t, tc.expectedURL, mock.req.URL.String())
+			assert.Equal(t, tc.expectedBody, strings.TrimSpace(mock.body))
+			assert.Equal(t, "token", mock.req.Header.Get("Authorization"))
+		})
+	}
+}
@@ -1,16 +1,19 @@
 package db
 
 import (
+	"context"
 	"ctp/pkg/models"
+	"ctp/pkg/tracing"
 	"errors"
+	"strconv"
 	"strings"
+	"time"
 
 	"sort"
 
 	"cloud.google.com/go/firestore"
 	"github.com/fatih/structs"
 	"github.com/mitchellh/mapstructure"
-	"golang.org/x/net/context"
 
 	firebase "firebase.google.com/go" // Same as python's import dependency as alias.
 
@@ -19,28 +22,66 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-// Database contains a firestore client and a context
+// Database contains a firestore client.
+// Every method takes the context of the request, such that cancelled requests stop querying firestore.
 type Database struct {
 	*firestore.Client
-	ctx context.Context
 }
 
 const userCol = "users"
 
-var deletableFields = [...]string{"name", "games", "lol", "valve", "overwatch", "runescape", "games"}
+// importCol is the subcollection of each user containing the libraries imported from launchers
+const importCol = "imports"
 
-// New returns a new databse containing context and a firestore client
-func New(key string) (*Database, error) {
-	db := &Database{ctx: context.Background()}
+// providerCol is the subcollection of each user containing the games last fetched from Steam and Battle.net, by provider
+const providerCol = "providergames"
+
+// manualCol is the subcollection of each user containing the games the user records the playtime of manually
+const manualCol = "manualgames"
+
+// matchCol is the subcollection of each user containing the user's overrides of how games are matched to the catalog
+const matchCol = "matches"
+
+// historyCol is the subcollection of each user containing a snapshot of the playtime of the user's games for each day
+const historyCol = "history"
+
+// gainsCol is the subcollection of each user containing the playtime gained by the user in each year, by the year,
+// which is kept with the history it is computed from
+const gainsCol = "gains"
+
+// metadataCol contains the game metadata fetched from the metadata source, by the provider and external id of the game
+const metadataCol = "metadata"
+
+// jobCol contains the queued, running and finished jobs of every user
+const jobCol = "jobs"
+
+// deadLetterCol contains a copy of the jobs which failed for reasons other than the user's request, e.g. after every retry
+const deadLetterCol = "deadletters"
+
+// usernameCol is the username registry, containing a document for each name taken (or held) by a user, by the name
+const usernameCol = "usernames"
+
+// auditCol is the audit log, containing a record of every deletion, restoration and purge of a user
+const auditCol = "audit"
+
+// errNameInUse is returned when a user tries to take a name which another user has, or which is held for them
+var errNameInUse = models.NewReqErrStr("name already in use", "the name is already in use")
+
+var deletableFields = [...]string{"name", "displayName", "hidden", "games", "lol", "valve", "overwatch", "runescape", "battlenet", "games"}
+
+// New returns a new databse containing a firestore client.
+// The context is only used to initialize the client.
+func New(ctx context.Context, key string) (*Database, error) {
+	db := &Database{}
 
 	// We use a service account. The key location defaults to "./fbkey.json", but can be configured by the "-f" flag
 	opt := option.WithCredentialsFile(key)
-	app, err := firebase.NewApp(db.ctx, nil, opt)
+	app, err := firebase.NewApp(ctx, nil, opt)
 	if err != nil {
 		return nil, err
 	}
 
-	db.Client, err = app.Firestore(db.ctx)
+	db.Client, err = app.Firestore(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -49,8 +90,13 @@ func New(key string) (*Database, error) {
 }
 
 // CreateUser creates a user
-func (db *Database) CreateUser(user *models.User) error {
-	_, err := db.Collection(userCol).Doc(user.ID).Create(db.ctx, user)
+func (db *Database) CreateUser(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "db.CreateUser")
+	defer span.End()
+
+	user.Skeleton = models.Skeleton(user.DisplayName)
+
+	_, err := db.Collection(userCol).Doc(user.ID).Create(ctx, user)
 	if err != nil && status.Code(err) != codes.AlreadyExists {
 		return err
 	}
@@ -59,8 +105,11 @@ func (db *Database) CreateUser(user *models.User) error {
 }
 
 // GetUserByID gets a user from the database
-func (db *Database) GetUserByID(id string) (*models.User, error) {
-	doc, err := db.Collection(userCol).Doc(id).Get(db.ctx)
+func (db *Database) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "db.GetUserByID")
+	defer span.End()
+
+	doc, err := db.Collection(userCol).Doc(id).Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return nil, models.ErrNotFound
@@ -81,14 +130,45 @@ func (db *Database) GetUserByID(id string) (*models.User, error) {
 	return &user, nil
 }
 
-// GetUserByName gets a user by name
-func (db *Database) GetUserByName(name string) (*models.User, error) {
-	docs, err := db.Collection(userCol).Where("name", "==", name).Where("public", "==", true).Documents(db.ctx).GetAll()
-	if err != nil {
-		if status.Code(err) != codes.NotFound {
+// GetUserByName gets a public user by name, which is looked up in the username registry.
+// A previous name of the user gets the user as long as the name is held for them, in which case the user's name
+// is different from the given name. Names taken before the registry existed are looked up on the users.
+// Users pending deletion are not found.
+func (db *Database) GetUserByName(ctx context.Context, name string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "db.GetUserByName")
+	defer span.End()
+
+	doc, err := db.Collection(usernameCol).Doc(name).Get(ctx)
+	switch {
+	case err == nil:
+		var entry models.Username
+
+		err = mapstructure.Decode(doc.Data(), &entry)
+		if err != nil {
+			return nil, err
+		}
+
+		if !entry.Current && entry.HeldUntil <= time.Now().Unix() {
 			return nil, models.ErrNotFound
 		}
 
+		user, err := db.GetUserByID(ctx, entry.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !user.Public || user.Name == "" || user.PendingDeletion() {
+			return nil, models.ErrNotFound
+		}
+
+		return user, nil
+	case status.Code(err) != codes.NotFound:
+		return nil, err
+	}
+
+	// a query without results is not an error, only the empty result means that the name is not taken
+	docs, err := db.Collection(userCol).Where("name", "==", name).Where("public", "==", true).Documents(ctx).GetAll()
+	if err != nil {
 		return nil, err
 	}
 
@@ -109,14 +189,23 @@ func (db *Database) GetUserByName(name string) (*models.User, error) {
 		return nil, err
 	}
 
+	if user.PendingDeletion() {
+		return nil, models.ErrNotFound
+	}
+
 	return &user, nil
 }
 
 // UpdateUser updates the relevant fields of the user
 // checks for empty values
-func (db *Database) UpdateUser(user *models.User) error {
+func (db *Database) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "db.UpdateUser")
+	defer span.End()
+
 	user.Name = "" // username and games are updated by dedicated functions
 	user.Games = nil
+	user.NameChangedAt, user.PreviousNames = 0, nil
+	user.Skeleton = models.Skeleton(user.DisplayName) // only stored along with a display name
 
 	s := structs.New(user)
 	m := make(map[string]interface{})
@@ -126,63 +215,766 @@ func (db *Database) UpdateUser(user *models.User) error {
 			m[f.Tag("firestore")] = f.Value()
 		}
 	}
+	m["version"] = firestore.Increment(1)
 
-	_, err := db.Collection(userCol).Doc(user.ID).Set(db.ctx, m, firestore.MergeAll)
+	_, err := db.Collection(userCol).Doc(user.ID).Set(ctx, m, firestore.MergeAll)
 
 	return err
 }
 
-// UpdateGames updates the games and total game time for the given user
-func (db *Database) UpdateGames(user *models.User) error {
+// ReplaceUser replaces every field of the stored user with the given user, as long as the stored version is the given version.
+// Returns models.ErrPreconditionFailed if the user has been modified since, and a request error if the name is already in use
+// or the user changed their name less than models.RenameCooldown ago.
+// A new name is registered as the user's, holding the previous name for them (see SetUsername).
+// The version is incremented and set on the given user, as are the name history and the time the name was changed.
+func (db *Database) ReplaceUser(ctx context.Context, user *models.User, version int64) error {
+	ctx, span := tracing.Start(ctx, "db.ReplaceUser")
+	defer span.End()
+
+	ref := db.Collection(userCol).Doc(user.ID)
+
+	return db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		stored, err := getUserInTx(tx, ref)
+		if err != nil {
+			return err
+		}
+
+		if stored.Version != version {
+			return models.ErrPreconditionFailed
+		}
+
+		// the name history is only changed along with the name, and the deletion is only changed by SetDeleted
+		user.NameChangedAt, user.PreviousNames = stored.NameChangedAt, stored.PreviousNames
+		user.DeletedAt, user.PurgeAt = stored.DeletedAt, stored.PurgeAt
+
+		if user.Name != stored.Name {
+			err = models.CheckRenameCooldown(stored, user.Name, time.Now())
+			if err != nil {
+				return err
+			}
+
+			now := time.Now().Unix()
+
+			// every read in a transaction has to happen before the writes
+			err = db.checkName(tx, user.ID, user.Name, now)
+			if err != nil {
+				return err
+			}
+
+			err = db.rename(tx, user, stored.Name, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		user.Version = version + 1
+		user.Skeleton = models.Skeleton(user.DisplayName)
+
+		return tx.Set(ref, user)
+	})
+}
+
+// UpdateGames updates the games, total game time, steam privacy state and Battle.net authorization state for the given user
+func (db *Database) UpdateGames(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "db.UpdateGames")
+	defer span.End()
+
 	// sorting the games, such that they are sorted when the user retrieves them
 	sort.Slice(user.Games, func(i, j int) bool {
-		return user.Games[i].Time > user.Games[j].Time
+		return user.Games[i].PlayMinutes() > user.Games[j].PlayMinutes()
 	})
 
-	// calculating total game time
+	// calculating total game time, which is set on the user as well
 	var totalGameTime int
 	for _, game := range user.Games {
 		totalGameTime += game.Time
 	}
+	user.TotalGameTime = totalGameTime
 
-	_, err := db.Collection(userCol).Doc(user.ID).Update(db.ctx, []firestore.Update{
+	updates := []firestore.Update{
 		{Path: "games", Value: user.Games},
 		{Path: "totalGameTime", Value: totalGameTime},
-	})
+		{Path: "version", Value: firestore.Increment(1)},
+	}
+
+	// the privacy state of the steam profile is updated with the games
+	if user.Valve != nil {
+		updates = append(updates, firestore.Update{Path: "valve.status", Value: user.Valve.Status},
+			firestore.Update{Path: "valve.hint", Value: user.Valve.Hint})
+	}
+
+	// as is the state of the Battle.net authorization
+	if user.BattleNet != nil {
+		updates = append(updates, firestore.Update{Path: "battlenet.status", Value: user.BattleNet.Status},
+			firestore.Update{Path: "battlenet.hint", Value: user.BattleNet.Hint})
+	}
+
+	_, err := db.Collection(userCol).Doc(user.ID).Update(ctx, updates)
+
+	return err
+}
+
+// GetImports gets the libraries the user has imported from launchers, which are stored in a subcollection of the user
+// (one document for each format) to keep the user document small
+func (db *Database) GetImports(ctx context.Context, id string) ([]models.ImportedLibrary, error) {
+	ctx, span := tracing.Start(ctx, "db.GetImports")
+	defer span.End()
+
+	docs, err := db.Collection(userCol).Doc(id).Collection(importCol).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []models.ImportedLibrary
+	for _, doc := range docs {
+		var library models.ImportedLibrary
+
+		err = mapstructure.Decode(doc.Data(), &library)
+		if err != nil {
+			return nil, err
+		}
+
+		imports = append(imports, library)
+	}
+
+	return imports, nil
+}
+
+// SetImport stores the library imported by the user, replacing the library imported earlier in the same format
+func (db *Database) SetImport(ctx context.Context, id string, library *models.ImportedLibrary) error {
+	ctx, span := tracing.Start(ctx, "db.SetImport")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Collection(importCol).Doc(library.Format).Set(ctx, library)
+
+	return err
+}
+
+// DeleteImport deletes the library imported by the user in the given format. Returns models.ErrNotFound if there is none.
+func (db *Database) DeleteImport(ctx context.Context, id, format string) error {
+	ctx, span := tracing.Start(ctx, "db.DeleteImport")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Collection(importCol).Doc(format).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return models.ErrNotFound
+	}
+
+	return err
+}
+
+// GetProviderGames gets the games last fetched from the provider for the user.
+// Returns models.ErrNotFound if none have been stored.
+func (db *Database) GetProviderGames(ctx context.Context, id, provider string) (*models.ProviderGames, error) {
+	ctx, span := tracing.Start(ctx, "db.GetProviderGames")
+	defer span.End()
+
+	doc, err := db.Collection(userCol).Doc(id).Collection(providerCol).Doc(provider).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var games models.ProviderGames
+
+	err = mapstructure.Decode(doc.Data(), &games)
+	if err != nil {
+		return nil, err
+	}
+
+	return &games, nil
+}
+
+// SetProviderGames stores the games fetched from the provider for the user, replacing the games fetched before
+func (db *Database) SetProviderGames(ctx context.Context, id string, games *models.ProviderGames) error {
+	ctx, span := tracing.Start(ctx, "db.SetProviderGames")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Collection(providerCol).Doc(games.Provider).Set(ctx, games)
+
+	return err
+}
+
+// GetManualGames gets the games the user records the playtime of manually, which are stored in a subcollection of the user
+func (db *Database) GetManualGames(ctx context.Context, id string) ([]models.ManualGame, error) {
+	ctx, span := tracing.Start(ctx, "db.GetManualGames")
+	defer span.End()
+
+	docs, err := db.Collection(userCol).Doc(id).Collection(manualCol).OrderBy("name", firestore.Asc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var games []models.ManualGame
+	for _, doc := range docs {
+		var game models.ManualGame
+
+		err = mapstructure.Decode(doc.Data(), &game)
+		if err != nil {
+			return nil, err
+		}
+
+		games = append(games, game)
+	}
+
+	return games, nil
+}
+
+// GetManualGame gets a game the user records the playtime of manually. Returns models.ErrNotFound if there is none.
+func (db *Database) GetManualGame(ctx context.Context, id, gameID string) (*models.ManualGame, error) {
+	ctx, span := tracing.Start(ctx, "db.GetManualGame")
+	defer span.End()
+
+	doc, err := db.Collection(userCol).Doc(id).Collection(manualCol).Doc(gameID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var game models.ManualGame
+
+	err = mapstructure.Decode(doc.Data(), &game)
+	if err != nil {
+		return nil, err
+	}
+
+	return &game, nil
+}
+
+// SetManualGame stores a game the user records the playtime of manually, replacing the game with the same id.
+// Games without an id are given a new one.
+func (db *Database) SetManualGame(ctx context.Context, id string, game *models.ManualGame) error {
+	ctx, span := tracing.Start(ctx, "db.SetManualGame")
+	defer span.End()
+
+	col := db.Collection(userCol).Doc(id).Collection(manualCol)
+
+	ref := col.NewDoc()
+	if game.ID != "" {
+		ref = col.Doc(game.ID)
+	}
+	game.ID = ref.ID
+
+	_, err := ref.Set(ctx, game)
+
+	return err
+}
+
+// DeleteManualGame deletes a game the user records the playtime of manually. Returns models.ErrNotFound if there is none.
+func (db *Database) DeleteManualGame(ctx context.Context, id, gameID string) error {
+	ctx, span := tracing.Start(ctx, "db.DeleteManualGame")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Collection(manualCol).Doc(gameID).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return models.ErrNotFound
+	}
+
+	return err
+}
+
+// GetMatches gets the user's overrides of how games are matched to the catalog, which are stored in a subcollection
+// of the user
+func (db *Database) GetMatches(ctx context.Context, id string) ([]models.GameMatch, error) {
+	ctx, span := tracing.Start(ctx, "db.GetMatches")
+	defer span.End()
+
+	docs, err := db.Collection(userCol).Doc(id).Collection(matchCol).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []models.GameMatch
+	for _, doc := range docs {
+		var match models.GameMatch
+
+		err = mapstructure.Decode(doc.Data(), &match)
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
+
+// SetMatch stores the user's override of how a game is matched to the catalog, replacing the match with the same key
+func (db *Database) SetMatch(ctx context.Context, id string, match *models.GameMatch) error {
+	ctx, span := tracing.Start(ctx, "db.SetMatch")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Collection(matchCol).Doc(match.Key).Set(ctx, match)
+
+	return err
+}
+
+// DeleteMatch deletes the user's override of how a game is matched to the catalog.
+// Returns models.ErrNotFound if there is none.
+func (db *Database) DeleteMatch(ctx context.Context, id, key string) error {
+	ctx, span := tracing.Start(ctx, "db.DeleteMatch")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Collection(matchCol).Doc(key).Delete(ctx, firestore.Exists)
+	if status.Code(err) == codes.NotFound {
+		return models.ErrNotFound
+	}
+
+	return err
+}
+
+// GetSnapshots gets the snapshots of the playtime of the user's games since the date (formatted as 2006-01-02),
+// sorted by date
+func (db *Database) GetSnapshots(ctx context.Context, id, since string) ([]models.PlaytimeSnapshot, error) {
+	ctx, span := tracing.Start(ctx, "db.GetSnapshots")
+	defer span.End()
+
+	query := db.Collection(userCol).Doc(id).Collection(historyCol).Where("date", ">=", since).OrderBy("date", firestore.Asc)
+
+	docs, err := query.Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []models.PlaytimeSnapshot
+	for _, doc := range docs {
+		var snapshot models.PlaytimeSnapshot
+
+		err = mapstructure.Decode(doc.Data(), &snapshot)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// SetSnapshot stores the snapshot of the playtime of the user's games, replacing the snapshot of the same day
+func (db *Database) SetSnapshot(ctx context.Context, id string, snapshot *models.PlaytimeSnapshot) error {
+	ctx, span := tracing.Start(ctx, "db.SetSnapshot")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Collection(historyCol).Doc(snapshot.Date).Set(ctx, snapshot)
+
+	return err
+}
+
+// SetYearPlaytime stores the playtime gained by the user in a year, replacing the playtime stored for the year
+func (db *Database) SetYearPlaytime(ctx context.Context, id string, playtime *models.YearPlaytime) error {
+	ctx, span := tracing.Start(ctx, "db.SetYearPlaytime")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Collection(gainsCol).Doc(strconv.Itoa(playtime.Year)).Set(ctx, playtime)
+
+	return err
+}
+
+// GetYearPlaytimes gets the playtime gained in the year by every user it is stored for, by their ID, with a single
+// query across the users (which needs the single-field index on "year" enabled for the "gains" collection group)
+func (db *Database) GetYearPlaytimes(ctx context.Context, year int) (map[string]int, error) {
+	ctx, span := tracing.Start(ctx, "db.GetYearPlaytimes")
+	defer span.End()
+
+	docs, err := db.CollectionGroup(gainsCol).Where("year", "==", year).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	minutes := make(map[string]int, len(docs))
+	for _, doc := range docs {
+		var playtime models.YearPlaytime
+
+		err = mapstructure.Decode(doc.Data(), &playtime)
+		if err != nil {
+			return nil, err
+		}
+
+		// the document is in the subcollection of the user
+		minutes[doc.Ref.Parent.Parent.ID] = playtime.Minutes
+	}
+
+	return minutes, nil
+}
+
+// GetPublicPlaytimes gets the total playtime (in hours) of every public user, except the users pending deletion
+func (db *Database) GetPublicPlaytimes(ctx context.Context) ([]int, error) {
+	ctx, span := tracing.Start(ctx, "db.GetPublicPlaytimes")
+	defer span.End()
+
+	// users which have never been deleted have no purgeAt, so they can't be filtered by the query
+	docs, err := db.Collection(userCol).Where("public", "==", true).Select("totalGameTime", "purgeAt").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	playtimes := make([]int, 0, len(docs))
+	for _, doc := range docs {
+		var user models.User
+
+		err = mapstructure.Decode(doc.Data(), &user)
+		if err != nil {
+			return nil, err
+		}
+
+		if user.PendingDeletion() {
+			continue
+		}
+
+		playtimes = append(playtimes, user.TotalGameTime)
+	}
+
+	return playtimes, nil
+}
+
+// GetPublicUserIDs gets the IDs of every public user, except the users pending deletion
+func (db *Database) GetPublicUserIDs(ctx context.Context) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "db.GetPublicUserIDs")
+	defer span.End()
+
+	// users which have never been deleted have no purgeAt, so they can't be filtered by the query
+	docs, err := db.Collection(userCol).Where("public", "==", true).Select("purgeAt").Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		var user models.User
+
+		err = mapstructure.Decode(doc.Data(), &user)
+		if err != nil {
+			return nil, err
+		}
+
+		if user.PendingDeletion() {
+			continue
+		}
+
+		ids = append(ids, doc.Ref.ID)
+	}
+
+	return ids, nil
+}
+
+// GetLookalikes gets the IDs of the users with a name which looks like a name with the skeleton (see models.Skeleton):
+// a handle, a previous handle which is still held for the user, or a display name.
+// Names stored before their skeletons were are not found.
+func (db *Database) GetLookalikes(ctx context.Context, skeleton string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "db.GetLookalikes")
+	defer span.End()
+
+	var ids []string
+	if skeleton == "" {
+		return ids, nil
+	}
+
+	docs, err := db.Collection(userCol).Where("skeleton", "==", skeleton).Select().Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, doc := range docs {
+		ids = append(ids, doc.Ref.ID)
+	}
+
+	docs, err = db.Collection(usernameCol).Where("skeleton", "==", skeleton).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	for _, doc := range docs {
+		var entry models.Username
+
+		err = mapstructure.Decode(doc.Data(), &entry)
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.Current || entry.HeldUntil > now {
+			ids = append(ids, entry.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+// GetMetadata gets the cached metadata of a game by its key (the provider and external id of the game).
+// Returns models.ErrNotFound if the metadata is not cached.
+func (db *Database) GetMetadata(ctx context.Context, key string) (*models.GameMetadata, error) {
+	ctx, span := tracing.Start(ctx, "db.GetMetadata")
+	defer span.End()
+
+	doc, err := db.Collection(metadataCol).Doc(key).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var metadata models.GameMetadata
+
+	err = mapstructure.Decode(doc.Data(), &metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metadata, nil
+}
+
+// SetMetadata caches the metadata of a game by its key, replacing the metadata cached before
+func (db *Database) SetMetadata(ctx context.Context, key string, metadata *models.GameMetadata) error {
+	ctx, span := tracing.Start(ctx, "db.SetMetadata")
+	defer span.End()
+
+	_, err := db.Collection(metadataCol).Doc(key).Set(ctx, metadata)
 
 	return err
 }
 
-// SetUsername sets the username for the user, returns error if it is already in use
-func (db *Database) SetUsername(user *models.User) error {
+// SetUsername sets the name of the user, which is lowercased. The name is registered as the user's in the username
+// registry, and the previous name is held for the user for models.NameHold, redirecting to the new name, before anyone
+// else can take it. Returns a request error if the name is taken by another user, or held for them, or if the user
+// changed their name less than models.RenameCooldown ago.
+func (db *Database) SetUsername(ctx context.Context, user *models.User) error {
+	ctx, span := tracing.Start(ctx, "db.SetUsername")
+	defer span.End()
+
 	user.Name = strings.ToLower(user.Name)
-	dbUser, err := db.GetUserByName(user.Name)
-	if err != nil && !errors.Is(err, models.ErrNotFound) {
+	ref := db.Collection(userCol).Doc(user.ID)
+
+	return db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		stored, err := getUserInTx(tx, ref)
+		if err != nil {
+			return err
+		}
+
+		if stored.Name == user.Name {
+			return nil
+		}
+
+		// the cooldown is checked on the stored user, such that concurrent renames can not both pass it
+		err = models.CheckRenameCooldown(stored, user.Name, time.Now())
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+
+		err = db.checkName(tx, user.ID, user.Name, now)
+		if err != nil {
+			return err
+		}
+
+		previous := stored.Name
+		stored.Name = user.Name
+
+		err = db.rename(tx, stored, previous, now)
+		if err != nil {
+			return err
+		}
+
+		return tx.Update(ref, []firestore.Update{
+			{Path: "name", Value: stored.Name},
+			{Path: "nameChangedAt", Value: stored.NameChangedAt},
+			{Path: "previousNames", Value: stored.PreviousNames},
+			{Path: "version", Value: firestore.Increment(1)},
+		})
+	})
+}
+
+// checkName returns errNameInUse if the name is taken by another user than the one with the id, or held for them.
+// Names taken before the username registry existed are only found on the users. Reads in the transaction, so it has to
+// be called before the transaction writes.
+func (db *Database) checkName(tx *firestore.Transaction, id, name string, now int64) error {
+	if name == "" {
+		return nil
+	}
+
+	doc, err := tx.Get(db.Collection(usernameCol).Doc(name))
+	if err == nil {
+		var entry models.Username
+
+		err = mapstructure.Decode(doc.Data(), &entry)
+		if err != nil {
+			return err
+		}
+
+		if !entry.Available(id, now) {
+			return errNameInUse
+		}
+
+		return nil
+	}
+
+	if status.Code(err) != codes.NotFound {
 		return err
 	}
 
-	if dbUser != nil {
-		if dbUser.Name == user.Name {
-			return nil
+	docs, err := tx.Documents(db.Collection(userCol).Where("name", "==", name)).GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range docs {
+		if d.Ref.ID != id {
+			return errNameInUse
+		}
+	}
+
+	return nil
+}
+
+// rename registers the name of the user (if any) as theirs in the transaction, and holds the previous name (if any)
+// for them. The previous name is added to the user's name history, and the time of the change is set on the user,
+// which is stored by the caller.
+func (db *Database) rename(tx *firestore.Transaction, user *models.User, previous string, now int64) error {
+	registry := db.Collection(usernameCol)
+
+	if previous != "" {
+		held := &models.Username{Name: previous, ID: user.ID, HeldUntil: now + int64(models.NameHold/time.Second),
+			Skeleton: models.Skeleton(previous)}
+
+		err := tx.Set(registry.Doc(previous), held)
+		if err != nil {
+			return err
+		}
+
+		user.PreviousNames = append(user.PreviousNames, models.PreviousName{Name: previous, ChangedAt: now})
+	}
+
+	if user.Name != "" {
+		err := tx.Set(registry.Doc(user.Name), &models.Username{Name: user.Name, ID: user.ID, Current: true,
+			Skeleton: models.Skeleton(user.Name)})
+		if err != nil {
+			return err
+		}
+	}
+
+	user.NameChangedAt = now
+
+	return nil
+}
+
+// getUserInTx gets the user of the document in the transaction. Returns models.ErrNotFound if there is none.
+func getUserInTx(tx *firestore.Transaction, ref *firestore.DocumentRef) (*models.User, error) {
+	doc, err := tx.Get(ref)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, models.ErrNotFound
 		}
 
-		return errors.New("name already in use")
+		return nil, err
+	}
+
+	var user models.User
+
+	err = mapstructure.Decode(doc.Data(), &user)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err = db.Collection(userCol).Doc(user.ID).Update(db.ctx, []firestore.Update{
-		{Path: "name", Value: user.Name},
+	return &user, nil
+}
+
+// SetDeleted sets the time the user was deleted and the time the user is purged, which are both 0 if the user is
+// restored. Returns models.ErrNotFound if there is no such user.
+func (db *Database) SetDeleted(ctx context.Context, id string, deletedAt, purgeAt int64) error {
+	ctx, span := tracing.Start(ctx, "db.SetDeleted")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "deletedAt", Value: deletedAt},
+		{Path: "purgeAt", Value: purgeAt},
+		{Path: "version", Value: firestore.Increment(1)},
 	})
+	if status.Code(err) == codes.NotFound {
+		return models.ErrNotFound
+	}
 
 	return err
 }
 
-// DeleteUser deletes a user from the database
-func (db *Database) DeleteUser(id string) error {
-	_, err := db.Collection(userCol).Doc(id).Delete(db.ctx)
-	return err
+// DeleteUser deletes a user from the database along with everything stored for them: the subcollections of the user,
+// their names in the username registry, their jobs and dead letters. Returns the number of documents deleted from
+// each collection. The user document is deleted last, such that a failed deletion can be run again.
+func (db *Database) DeleteUser(ctx context.Context, id string) (map[string]int, error) {
+	ctx, span := tracing.Start(ctx, "db.DeleteUser")
+	defer span.End()
+
+	deleted := make(map[string]int)
+	ref := db.Collection(userCol).Doc(id)
+
+	// subcollections are not deleted with the document
+	for _, col := range []string{importCol, providerCol, manualCol, matchCol, historyCol, gainsCol} {
+		refs, err := ref.Collection(col).DocumentRefs(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+
+		deleted[col], err = deleteRefs(ctx, refs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the names of the user are released right away, and the jobs are deleted as they may contain the user's changes
+	for _, col := range []string{usernameCol, jobCol, deadLetterCol} {
+		field := "userId"
+		if col == usernameCol {
+			field = "id"
+		}
+
+		docs, err := db.Collection(col).Where(field, "==", id).Documents(ctx).GetAll()
+		if err != nil {
+			return nil, err
+		}
+
+		refs := make([]*firestore.DocumentRef, 0, len(docs))
+		for _, doc := range docs {
+			refs = append(refs, doc.Ref)
+		}
+
+		deleted[col], err = deleteRefs(ctx, refs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err := ref.Delete(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deleted[userCol] = 1
+
+	return deleted, nil
+}
+
+// deleteRefs deletes the documents, and returns the number of documents deleted
+func deleteRefs(ctx context.Context, refs []*firestore.DocumentRef) (int, error) {
+	for i, ref := range refs {
+		_, err := ref.Delete(ctx)
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(refs), nil
 }
 
-func (db *Database) DeleteFieldsFromUser(id string, fields []string) error {
+// DeleteFieldsFromUser deletes the given fields from the user. A name deleted is held for the user, like when the
+// name is changed (see SetUsername).
+func (db *Database) DeleteFieldsFromUser(ctx context.Context, id string, fields []string) error {
+	ctx, span := tracing.Start(ctx, "db.DeleteFieldsFromUser")
+	defer span.End()
+
 	if len(fields) > len(deletableFields) {
 		return models.NewReqErrStr("too many fields to delete", "invalid request body: too many specified fields to delete")
 	}
@@ -193,14 +985,206 @@ func (db *Database) DeleteFieldsFromUser(id string, fields []string) error {
 			m[f] = firestore.Delete
 		}
 	}
+	if models.Contains(fields, "displayName") {
+		m["skeleton"] = firestore.Delete
+	}
+	m["version"] = firestore.Increment(1)
+
+	ref := db.Collection(userCol).Doc(id)
+
+	if !models.Contains(fields, "name") {
+		_, err := ref.Set(ctx, m, firestore.MergeAll)
+		return err
+	}
+
+	return db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		stored, err := getUserInTx(tx, ref)
+		if err != nil {
+			return err
+		}
+
+		if stored.Name != "" {
+			previous := stored.Name
+			stored.Name = ""
+
+			err = db.rename(tx, stored, previous, time.Now().Unix())
+			if err != nil {
+				return err
+			}
+
+			m["nameChangedAt"], m["previousNames"] = stored.NameChangedAt, stored.PreviousNames
+		}
+
+		return tx.Set(ref, m, firestore.MergeAll)
+	})
+}
+
+// CreateJob stores a new job
+func (db *Database) CreateJob(ctx context.Context, job *models.Job) error {
+	ctx, span := tracing.Start(ctx, "db.CreateJob")
+	defer span.End()
+
+	_, err := db.Collection(jobCol).Doc(job.ID).Create(ctx, job)
+
+	return err
+}
+
+// GetJob gets a job by its id. Returns models.ErrNotFound if there is none.
+func (db *Database) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	ctx, span := tracing.Start(ctx, "db.GetJob")
+	defer span.End()
+
+	doc, err := db.Collection(jobCol).Doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var job models.Job
+
+	err = mapstructure.Decode(doc.Data(), &job)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// UpdateJob replaces the stored job with the given job
+func (db *Database) UpdateJob(ctx context.Context, job *models.Job) error {
+	ctx, span := tracing.Start(ctx, "db.UpdateJob")
+	defer span.End()
+
+	_, err := db.Collection(jobCol).Doc(job.ID).Set(ctx, job)
+
+	return err
+}
+
+// ClaimJob claims the job which has been due the longest at the time given (unix milliseconds), which is either queued
+// or has a worker whose lease has expired. The job is set as running, its attempts are incremented and its lease
+// lasts until the time given. Finished jobs are never claimed, as they are not due. Returns models.ErrNotFound if no
+// job is due.
+func (db *Database) ClaimJob(ctx context.Context, now, until int64) (*models.Job, error) {
+	ctx, span := tracing.Start(ctx, "db.ClaimJob")
+	defer span.End()
+
+	query := db.Collection(jobCol).Where("runAt", "<=", now).OrderBy("runAt", firestore.Asc).Limit(1)
+
+	var job models.Job
+
+	// the transaction fails if the job is claimed by another worker at the same time, and is then retried
+	err := db.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(query).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return models.ErrNotFound
+		}
+
+		job = models.Job{}
+
+		err = mapstructure.Decode(docs[0].Data(), &job)
+		if err != nil {
+			return err
+		}
+
+		job.Status, job.Attempts, job.RunAt = models.JobRunning, job.Attempts+1, until
+
+		return tx.Set(docs[0].Ref, &job)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// AddDeadLetter adds a copy of the failed job to the dead-letter list
+func (db *Database) AddDeadLetter(ctx context.Context, job *models.Job) error {
+	ctx, span := tracing.Start(ctx, "db.AddDeadLetter")
+	defer span.End()
+
+	_, err := db.Collection(deadLetterCol).Doc(job.ID).Set(ctx, job)
+
+	return err
+}
+
+// GetDeadLetters gets the jobs in the dead-letter list, the most recently failed first
+func (db *Database) GetDeadLetters(ctx context.Context) ([]models.Job, error) {
+	ctx, span := tracing.Start(ctx, "db.GetDeadLetters")
+	defer span.End()
+
+	docs, err := db.Collection(deadLetterCol).OrderBy("updatedAt", firestore.Desc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]models.Job, 0, len(docs))
+	for _, doc := range docs {
+		var job models.Job
+
+		err = mapstructure.Decode(doc.Data(), &job)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// AddAuditRecord adds the record to the audit log, generating its id if it has none.
+// A record with the id of a record already in the log replaces it.
+func (db *Database) AddAuditRecord(ctx context.Context, record *models.AuditRecord) error {
+	ctx, span := tracing.Start(ctx, "db.AddAuditRecord")
+	defer span.End()
+
+	ref := db.Collection(auditCol).NewDoc()
+	if record.ID != "" {
+		ref = db.Collection(auditCol).Doc(record.ID)
+	}
+	record.ID = ref.ID
+
+	_, err := ref.Set(ctx, record)
 
-	_, err := db.Collection(userCol).Doc(id).Set(db.ctx, m, firestore.MergeAll)
 	return err
 }
 
+// GetAuditRecords gets the records of the audit log, the most recent first
+func (db *Database) GetAuditRecords(ctx context.Context) ([]models.AuditRecord, error) {
+	ctx, span := tracing.Start(ctx, "db.GetAuditRecords")
+	defer span.End()
+
+	docs, err := db.Collection(auditCol).OrderBy("at", firestore.Desc).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]models.AuditRecord, 0, len(docs))
+	for _, doc := range docs {
+		var record models.AuditRecord
+
+		err = mapstructure.Decode(doc.Data(), &record)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
 // IsUser checks wether or not the provided user exisits in the database
-func (db *Database) IsUser(id string) (bool, error) {
-	_, err := db.Collection(userCol).Doc(id).Get(db.ctx)
+func (db *Database) IsUser(ctx context.Context, id string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "db.IsUser")
+	defer span.End()
+
+	_, err := db.Collection(userCol).Doc(id).Get(ctx)
 	if err != nil {
 		if status.Code(err) == codes.NotFound {
 			return false, nil
@@ -0,0 +1,123 @@
+// Package events delivers the events happening to users, such as the progress of refreshing their games,
+// to the subscribers of each user. Events are only delivered within the process, to the subscribers connected to it.
+package events
+
+import (
+	"sync"
+
+	"ctp/pkg/models"
+)
+
+// buffer is the number of events kept for a subscriber which has not received them yet.
+// Events sent to a subscriber with a full buffer are dropped, rather than blocking the publisher.
+const buffer = 16
+
+// Hub delivers the events of each user to the subscribers of the user
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan models.Event]bool // by user id, true if every event is received
+	ids         map[chan models.Event][]string        // by subscriber, the ids of the users subscribed to
+}
+
+// NewHub returns a hub without subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[string]map[chan models.Event]bool), ids: make(map[chan models.Event][]string)}
+}
+
+// Subscribe returns a channel receiving every event of the user, and the changes to the total playtime of the
+// followed users (EventTotal), as the other events of a user are only for themselves. The function returned
+// unsubscribes, and must be called when the events are no longer received. The channel is closed when unsubscribed.
+func (h *Hub) Subscribe(id string, follow ...string) (<-chan models.Event, func()) {
+	ch := make(chan models.Event, buffer)
+	ids := append([]string{id}, follow...)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, followed := range follow {
+		h.add(followed, ch, false)
+	}
+	h.add(id, ch, true)
+	h.ids[ch] = ids
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		h.remove(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// Close closes the subscriptions of the user, and stops sending the user's playtime to their followers, e.g. once the
+// user has been purged. Returns the number of subscriptions closed.
+func (h *Hub) Close(id string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closed := 0
+	for ch, all := range h.subscribers[id] {
+		if all {
+			h.remove(ch)
+			closed++
+			continue
+		}
+
+		// the followers keep receiving the playtime of the other users they follow
+		ids := h.ids[ch][:0]
+		for _, followed := range h.ids[ch] {
+			if followed != id {
+				ids = append(ids, followed)
+			}
+		}
+		h.ids[ch] = ids
+	}
+	delete(h.subscribers, id)
+
+	return closed
+}
+
+// Publish sends the event to the subscribers of the user the event happened to
+func (h *Hub) Publish(event models.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch, all := range h.subscribers[event.UserID] {
+		if !all && event.Type != models.EventTotal {
+			continue
+		}
+
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// remove removes the subscriber from every user it is subscribed to, and closes its channel, unless it has been
+// removed already
+func (h *Hub) remove(ch chan models.Event) {
+	ids, ok := h.ids[ch]
+	if !ok {
+		return
+	}
+
+	for _, id := range ids {
+		delete(h.subscribers[id], ch)
+		if len(h.subscribers[id]) == 0 {
+			delete(h.subscribers, id)
+		}
+	}
+	delete(h.ids, ch)
+
+	close(ch)
+}
+
+// add adds the subscriber of the user's events, replacing the subscription if the user is subscribed to already
+func (h *Hub) add(id string, ch chan models.Event, all bool) {
+	if h.subscribers[id] == nil {
+		h.subscribers[id] = make(map[chan models.Event]bool)
+	}
+	h.subscribers[id][ch] = all
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"testing"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receive returns the events received until the channel is closed
+func receive(events <-chan models.Event) []models.Event {
+	var received []models.Event
+	for event := range events {
+		received = append(received, event)
+	}
+
+	return received
+}
+
+func TestHub(t *testing.T) {
+	hub := NewHub()
+
+	user, unsubscribeUser := hub.Subscribe("12345")
+	follower, unsubscribeFollower := hub.Subscribe("67890", "12345")
+	other, unsubscribeOther := hub.Subscribe("00000")
+
+	job := models.Event{Type: models.EventJob, UserID: "12345", Data: &models.Job{ID: "1"}}
+	total := models.Event{Type: models.EventTotal, UserID: "12345", Data: &models.TotalChange{Name: "onijuan"}}
+	own := models.Event{Type: models.EventJob, UserID: "67890"}
+	hub.Publish(job)
+	hub.Publish(total)
+	hub.Publish(own)
+
+	unsubscribeUser()
+	unsubscribeFollower()
+	unsubscribeOther()
+	unsubscribeOther() // unsubscribing again does nothing
+
+	assert.Equal(t, []models.Event{job, total}, receive(user))
+	assert.Equal(t, []models.Event{total, own}, receive(follower))
+	assert.Empty(t, receive(other))
+	assert.Empty(t, hub.subscribers)
+	assert.Empty(t, hub.ids)
+
+	// events are published without subscribers
+	hub.Publish(job)
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub()
+
+	user, unsubscribeUser := hub.Subscribe("12345")
+	follower, unsubscribeFollower := hub.Subscribe("67890", "12345", "00000")
+
+	assert.Equal(t, 1, hub.Close("12345"))
+	assert.Equal(t, 0, hub.Close("12345"))
+
+	// the follower only receives the playtime of the other users followed
+	total := models.Event{Type: models.EventTotal, UserID: "00000", Data: &models.TotalChange{Name: "test"}}
+	hub.Publish(models.Event{Type: models.EventTotal, UserID: "12345", Data: &models.TotalChange{Name: "onijuan"}})
+	hub.Publish(total)
+
+	unsubscribeUser() // unsubscribing a closed subscription does nothing
+	unsubscribeFollower()
+
+	assert.Empty(t, receive(user))
+	assert.Equal(t, []models.Event{total}, receive(follower))
+	assert.Empty(t, hub.subscribers)
+	assert.Empty(t, hub.ids)
+}
+
+func TestHubFull(t *testing.T) {
+	hub := NewHub()
+	events, unsubscribe := hub.Subscribe("12345")
+
+	// the events published to a subscriber which is not receiving them are dropped, rather than blocking
+	for i := 0; i < buffer+10; i++ {
+		hub.Publish(models.Event{Type: models.EventJob, UserID: "12345"})
+	}
+	unsubscribe()
+
+	assert.Len(t, receive(events), buffer)
+}
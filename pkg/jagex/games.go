@@ -0,0 +1,61 @@
+package jagex
+
+import "ctp/pkg/models"
+
+// game contains everything which differs between the Runescape games
+type game struct {
+	name   string
+	skills []string          // in the order of the hiscores, after the overall line
+	urls   map[string]string // the hiscores of each account type, in the JSON format if the game has it
+}
+
+// oldSchoolSkills are the skills of Old School, in the order of the hiscores
+var oldSchoolSkills = []string{
+	"Attack", "Defence", "Strength", "Hitpoints", "Ranged", "Prayer", "Magic", "Cooking", "Woodcutting", "Fletching",
+	"Fishing", "Firemaking", "Crafting", "Smithing", "Mining", "Herblore", "Agility", "Thieving", "Slayer", "Farming",
+	"Runecraft", "Hunter", "Construction",
+}
+
+// rs3Skills are the skills of RuneScape 3, in the order of the hiscores
+var rs3Skills = []string{
+	"Attack", "Defence", "Strength", "Constitution", "Ranged", "Prayer", "Magic", "Cooking", "Woodcutting", "Fletching",
+	"Fishing", "Firemaking", "Crafting", "Smithing", "Mining", "Herblore", "Agility", "Thieving", "Slayer", "Farming",
+	"Runecrafting", "Hunter", "Construction", "Summoning", "Dungeoneering", "Divination", "Invention", "Archaeology",
+	"Necromancy",
+}
+
+// games contains each of the Runescape games, by the game of the account
+var games = map[string]*game{
+	// Old School has hiscores in the JSON format, where the activities are named
+	models.OldSchool: {
+		name:   "Old School Runescape",
+		skills: oldSchoolSkills,
+		urls: map[string]string{
+			normal:  "https://secure.runescape.com/m=hiscore_oldschool/index_lite.json?player=%s",
+			ironman: "https://secure.runescape.com/m=hiscore_oldschool_ironman/index_lite.json?player=%s",
+			hcim:    "https://secure.runescape.com/m=hiscore_oldschool_hardcore_ironman/index_lite.json?player=%s",
+			uim:     "https://secure.runescape.com/m=hiscore_oldschool_ultimate/index_lite.json?player=%s",
+		},
+	},
+	// RuneScape 3 has no ultimate ironman hiscores, and only hiscores in the CSV format
+	models.RuneScape3: {
+		name:   "RuneScape 3",
+		skills: rs3Skills,
+		urls: map[string]string{
+			normal:  "https://secure.runescape.com/m=hiscore/index_lite.ws?player=%s",
+			ironman: "https://secure.runescape.com/m=hiscore_ironman/index_lite.ws?player=%s",
+			hcim:    "https://secure.runescape.com/m=hiscore_hardcore_ironman/index_lite.ws?player=%s",
+		},
+	},
+}
+
+// getGame returns the game of the account, where accounts without a game are Old School
+func getGame(rsAcc *models.RunescapeAccount) (*game, bool) {
+	if rsAcc.Game == "" {
+		return games[models.OldSchool], true
+	}
+
+	g, ok := games[rsAcc.Game]
+
+	return g, ok
+}
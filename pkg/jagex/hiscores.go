@@ -0,0 +1,191 @@
+package jagex
+
+import (
+	"bytes"
+	"context"
+	"ctp/pkg/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// hiscores contains the rows of the hiscores of an account, parsed from either of the formats of the hiscores:
+// CSV (index_lite.ws), where the rows are only identified by their order, or JSON (index_lite.json), where they are named.
+type hiscores struct {
+	Overall    skillRow      `json:"-"`
+	Skills     []skillRow    `json:"skills"`     // skills added to the game after the skills known have no name in the CSV format
+	Activities []activityRow `json:"activities"` // the activities have no names in the CSV format
+}
+
+type skillRow struct {
+	Name  string `json:"name"`
+	Rank  int    `json:"rank"` // -1 if unranked
+	Level int    `json:"level"`
+	XP    int    `json:"xp"`
+}
+
+type activityRow struct {
+	Name  string `json:"name"`
+	Rank  int    `json:"rank"`  // -1 if unranked
+	Score int    `json:"score"` // -1 if unranked
+}
+
+// getHiscores returns the hiscores of the account type for the username
+func (j *Jagex) getHiscores(ctx context.Context, g *game, accountType, username string) (*hiscores, error) {
+	url, ok := g.urls[accountType]
+	if !ok {
+		return nil, fmt.Errorf("invalid account type in getHiscores: %s", accountType)
+	}
+
+	resp, err := j.Get(ctx, fmt.Sprintf(url, username))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = models.CheckStatusCode(resp.StatusCode, "Jagex", "Runescape account not found in the hiscores")
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHiscores(data, g)
+}
+
+// parseHiscores parses the hiscores of the game, in either the CSV or JSON format
+func parseHiscores(data []byte, g *game) (*hiscores, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		return parseJSONHiscores(data)
+	}
+
+	return parseCSVHiscores(string(data), g)
+}
+
+// parseJSONHiscores parses the JSON hiscores, where the first skill is the overall level and XP
+func parseJSONHiscores(data []byte) (*hiscores, error) {
+	var h hiscores
+
+	err := json.Unmarshal(data, &h)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(h.Skills) == 0 || h.Skills[0].Name != "Overall" {
+		return nil, errors.New("no overall row in the hiscores")
+	}
+
+	h.Overall = h.Skills[0]
+	h.Skills = h.Skills[1:]
+
+	return &h, nil
+}
+
+// parseCSVHiscores parses the CSV hiscores, where the first line is the overall rank, level and XP,
+// followed by one line (rank, level and XP) for each skill of the game, and one line (rank and score) for each activity.
+// Lines with rank, level and XP after the skills of the game are skills added after the game was implemented.
+func parseCSVHiscores(data string, g *game) (*hiscores, error) {
+	lines := strings.Split(data, "\n")
+	if len(lines) <= len(g.skills) {
+		return nil, errors.New("wrong number of lines in the hiscores")
+	}
+
+	var h hiscores
+	for i, line := range lines {
+		fields := strings.Split(strings.TrimSpace(line), ",")
+		values := make([]int, len(fields))
+		for k, field := range fields {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value in the hiscores: %w", err)
+			}
+
+			values[k] = value
+		}
+
+		switch {
+		case len(values) == 3 && i == 0:
+			h.Overall = skillRow{Name: "Overall", Rank: values[0], Level: values[1], XP: values[2]}
+		case len(values) == 3 && i <= len(g.skills):
+			h.Skills = append(h.Skills, skillRow{Name: g.skills[i-1], Rank: values[0], Level: values[1], XP: values[2]})
+		case len(values) == 3 && len(h.Activities) == 0:
+			h.Skills = append(h.Skills, skillRow{Rank: values[0], Level: values[1], XP: values[2]})
+		case len(values) == 2 && i > len(g.skills):
+			h.Activities = append(h.Activities, activityRow{Rank: values[0], Score: values[1]})
+		default:
+			return nil, errors.New("wrong number of fields in the hiscores")
+		}
+	}
+
+	return &h, nil
+}
+
+// nameActivities names the activities of hiscores in the CSV format after the activities of the rate model,
+// if the number of activities matches the model. Otherwise the activities can not be identified, and are left unnamed.
+func (h *hiscores) nameActivities(rates *gameRates) {
+	if len(h.Activities) != len(rates.Activities) {
+		return
+	}
+
+	for i := range h.Activities {
+		if h.Activities[i].Name == "" {
+			h.Activities[i].Name = rates.Activities[i].Name
+		}
+	}
+}
+
+// detectAccountType returns the account type of the username, detected by comparing the XP in the hiscores of each
+// account type. Every account is in the normal hiscores, and ironmen are also in the hiscores of their account type.
+// An account which is no longer an ironman (de-ironed, or a hardcore ironman which has died) stays in the hiscores of
+// the previous account type, but the XP is no longer updated there.
+func (j *Jagex) detectAccountType(ctx context.Context, g *game, username string) (string, *hiscores, error) {
+	normalScores, err := j.getHiscores(ctx, g, normal, username)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ironScores, err := j.getHiscoresIfRanked(ctx, g, ironman, username)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// de-ironed accounts are normal accounts
+	if ironScores == nil || ironScores.Overall.XP < normalScores.Overall.XP {
+		return normal, normalScores, nil
+	}
+
+	// the hiscores of ultimate and hardcore ironmen, checked if the game has them
+	for _, accountType := range []string{uim, hcim} {
+		if _, ok := g.urls[accountType]; !ok {
+			continue
+		}
+
+		scores, err := j.getHiscoresIfRanked(ctx, g, accountType, username)
+		if err != nil {
+			return "", nil, err
+		}
+
+		if scores != nil && scores.Overall.XP >= ironScores.Overall.XP {
+			return accountType, scores, nil
+		}
+	}
+
+	return ironman, ironScores, nil
+}
+
+// getHiscoresIfRanked returns the hiscores of the account type, or nil if the username is not in them
+func (j *Jagex) getHiscoresIfRanked(ctx context.Context, g *game, accountType, username string) (*hiscores, error) {
+	h, err := j.getHiscores(ctx, g, accountType, username)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, nil
+	}
+
+	return h, err
+}
@@ -0,0 +1,96 @@
+package jagex
+
+import (
+	"context"
+	"ctp/pkg/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jsonTestData are Old School hiscores in the JSON format, with a skill and a boss unknown to the rate model
+var jsonTestData = `{
+	"skills": [
+		{"id": 0, "name": "Overall", "rank": 6355, "level": 2277, "xp": 387381708},
+		{"id": 1, "name": "Attack", "rank": 57292, "level": 99, "xp": 13034431},
+		{"id": 2, "name": "Defence", "rank": -1, "level": 1, "xp": -1},
+		{"id": 24, "name": "Sailing", "rank": 1, "level": 99, "xp": 13034431}
+	],
+	"activities": [
+		{"id": 0, "name": "League Points", "rank": 1234, "score": 5000},
+		{"id": 1, "name": "Zulrah", "rank": 4321, "score": 100},
+		{"id": 2, "name": "Vorkath", "rank": -1, "score": -1},
+		{"id": 3, "name": "A New Boss", "rank": 1, "score": 1000}
+	]
+}`
+
+func TestParseHiscores(t *testing.T) {
+	// Old School hiscores in the CSV format, where a skill and an activity are added after the rows known
+	withNewRows := strings.Replace(testData, "3494,99,13229636\n", "3494,99,13229636\n1,99,13034431\n", 1) + "\n1,1000"
+
+	var cases = []struct {
+		name               string
+		data               string
+		game               string
+		expectedOverall    skillRow
+		expectedSkills     int
+		expectedActivities int
+		expectedErr        string
+	}{
+		{"Test csv", testData, models.OldSchool, skillRow{Name: "Overall", Rank: 6355, Level: 2277, XP: 387381708}, 23, 11, ""},
+		{"Test csv rs3", rs3TestData, models.RuneScape3, skillRow{Name: "Overall", Rank: 12345, Level: 2400, XP: 1947429986},
+			29, 6, ""},
+		{"Test csv new rows", withNewRows, models.OldSchool, skillRow{Name: "Overall", Rank: 6355, Level: 2277, XP: 387381708},
+			24, 12, ""},
+		{"Test csv windows line endings", strings.Replace(testData, "\n", "\r\n", -1), models.OldSchool,
+			skillRow{Name: "Overall", Rank: 6355, Level: 2277, XP: 387381708}, 23, 11, ""},
+		{"Test json", jsonTestData, models.OldSchool, skillRow{Name: "Overall", Rank: 6355, Level: 2277, XP: 387381708}, 3, 4,
+			""},
+		{"Test empty", "", models.OldSchool, skillRow{}, 0, 0, "wrong number of lines in the hiscores"},
+		{"Test short", "6355,2277,387381708\n57292,99,15553384", models.OldSchool, skillRow{}, 0, 0,
+			"wrong number of lines in the hiscores"},
+		{"Test html", "<html>\n" + strings.Repeat("<p>\n", 30) + "</html>", models.OldSchool, skillRow{}, 0, 0,
+			"invalid value in the hiscores"},
+		{"Test activity among the skills", strings.Replace(testData, "20982,99,18653311", "20982,99", 1), models.OldSchool,
+			skillRow{}, 0, 0, "wrong number of fields in the hiscores"},
+		{"Test json without overall", `{"skills": [{"name": "Attack", "rank": 1, "level": 99, "xp": 13034431}]}`,
+			models.OldSchool, skillRow{}, 0, 0, "no overall row in the hiscores"},
+		{"Test invalid json", `{"skills": [`, models.OldSchool, skillRow{}, 0, 0, "unexpected end of JSON input"},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := parseHiscores([]byte(tc.data), games[tc.game])
+			if tc.expectedErr != "" {
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tc.expectedErr)
+				}
+				assert.Nil(t, h)
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedOverall, h.Overall)
+			assert.Len(t, h.Skills, tc.expectedSkills)
+			assert.Len(t, h.Activities, tc.expectedActivities)
+			assert.Equal(t, "Attack", h.Skills[0].Name)
+		})
+	}
+}
+
+func TestGetRSPlaytimeJSON(t *testing.T) {
+	rsAcc := models.RunescapeAccount{Username: "Test123", AccountType: normal}
+	game, err := New(&mockGetter{data: jsonTestData}, nil).GetRSPlaytime(context.Background(), &rsAcc)
+	require.Nil(t, err)
+
+	// the skill and boss unknown to the rate model, the points and the unranked rows are not counted
+	expectedSkills := []models.SkillPlaytime{{Skill: "Attack", Level: 99, XP: 13034431, Minutes: 8947}}
+	expectedActivities := []models.ActivityPlaytime{{Activity: "Zulrah", Score: 100, Minutes: 171}}
+	assert.Equal(t, expectedSkills, game.Skills)
+	assert.Equal(t, expectedActivities, game.Activities)
+	assert.Equal(t, 9119, game.Minutes)
+	assert.Equal(t, 151, game.Time)
+}
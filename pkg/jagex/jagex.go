@@ -1,23 +1,27 @@
 package jagex
 
 import (
+	"context"
 	"ctp/pkg/models"
+	"ctp/pkg/tracing"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"regexp"
-	"strconv"
-	"strings"
 )
 
 // Jagex is a struct which contains everything necessary to handle a request related to Jagex
 type Jagex struct {
 	models.Getter
+	rates *RateModel // used to estimate the playtime from the hiscores
 }
 
-// New returns a new Jagex instance
-func New(getter models.Getter) *Jagex {
-	return &Jagex{getter}
+// New returns a new Jagex instance estimating the playtime with the rate model, or DefaultRateModel if nil
+func New(getter models.Getter, rates *RateModel) *Jagex {
+	if rates == nil {
+		rates = DefaultRateModel()
+	}
+
+	return &Jagex{Getter: getter, rates: rates}
 }
 
 // the varius types of runescape accounts
@@ -28,55 +32,76 @@ const (
 	uim     = "ultimate ironman"
 )
 
-// relevant urls to check the beforementiond types
-var urls = map[string]string{
-	normal:  "http://services.runescape.com/m=hiscore_oldschool/index_lite.ws?player=%s",
-	ironman: "http://services.runescape.com/m=hiscore_oldschool_ironman/index_lite.ws?player=%s",
-	hcim:    "http://services.runescape.com/m=hiscore_oldschool_hardcore_ironman/index_lite.ws?player=%s",
-	uim:     "http://services.runescape.com/m=hiscore_oldschool_ultimate/index_lite.ws?player=%s",
-}
-
-// GetRSPlaytime returns an estimate for time spent playing Runescape
-func (j *Jagex) GetRSPlaytime(rsAcc *models.RunescapeAccount) (*models.Game, error) {
-	url, ok := urls[rsAcc.AccountType]
+// GetRSPlaytime returns an estimate for time spent playing the Runescape game of the account
+func (j *Jagex) GetRSPlaytime(ctx context.Context, rsAcc *models.RunescapeAccount) (*models.Game, error) {
+	ctx, span := tracing.StartKind(ctx, "jagex.GetRSPlaytime", tracing.KindClient)
+	defer span.End()
 
+	g, ok := getGame(rsAcc)
 	if !ok {
-		return nil, fmt.Errorf("invalid account type in GetRSPlaytime: %s", rsAcc.AccountType)
+		return nil, fmt.Errorf("invalid game in GetRSPlaytime: %s", rsAcc.Game)
 	}
 
-	response, err := j.Get(fmt.Sprintf(url, rsAcc.Username))
+	h, err := j.getHiscores(ctx, g, rsAcc.AccountType, rsAcc.Username)
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
 
-	responseData, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
+	return j.estimate(g, rsAcc.Game, rsAcc.AccountType, h), nil
+}
+
+// estimate returns the game with the playtime estimated from the hiscores.
+// Skills and activities without rates in the rate model (e.g. added to the game after the model) are not counted.
+func (j *Jagex) estimate(g *game, gameName, accountType string, h *hiscores) *models.Game {
+	rates, ok := j.rates.Games[gameName]
+	if !ok {
+		rates = j.rates.Games[models.OldSchool]
 	}
 
-	var time int
-	responseString := string(responseData)
-	lines := strings.Split(responseString, "\n")
-	for i := 0; i < 23; i++ {
-		fields := strings.Split(lines[i], ",")
-		if len(fields) != 3 {
-			return nil, errors.New("wrong number of fields in GetRSPlaytime")
+	game := &models.Game{Name: g.name}
+	var minutes float64
+
+	for _, skill := range h.Skills {
+		// unranked skills have -1 xp
+		if skill.XP <= 0 {
+			continue
+		}
+
+		b := rates.rates(skill.Name, accountType)
+		if b == nil {
+			continue
 		}
 
-		xp, err := strconv.Atoi(fields[2])
-		if err != nil {
-			return nil, err
+		m := b.minutes(skill.XP)
+		minutes += m
+		game.Skills = append(game.Skills, models.SkillPlaytime{Skill: skill.Name, Level: skill.Level, XP: skill.XP,
+			Minutes: int(m)})
+	}
+
+	h.nameActivities(rates)
+	for _, a := range h.Activities {
+		// unranked activities have -1 score
+		rate := rates.activityRate(a.Name)
+		if a.Score <= 0 || rate == 0 {
+			continue
 		}
 
-		time += xpToTime(xp, i, rsAcc.AccountType)
+		m := float64(a.Score) / rate * 60
+		minutes += m
+		game.Activities = append(game.Activities, models.ActivityPlaytime{Activity: a.Name, Score: a.Score, Minutes: int(m)})
 	}
 
-	return &models.Game{Time: time, Name: "Old School Runescape"}, nil
+	game.Minutes = int(minutes)
+	game.Time = game.Minutes / 60
+
+	return game
 }
 
 // validator for runescape username
-func (j *Jagex) ValidateRSAccount(rsAcc *models.RunescapeAccount) error {
+func (j *Jagex) ValidateRSAccount(ctx context.Context, rsAcc *models.RunescapeAccount) error {
+	ctx, span := tracing.StartKind(ctx, "jagex.ValidateRSAccount", tracing.KindClient)
+	defer span.End()
+
 	matched, err := regexp.MatchString("^[A-Za-z0-9_ -]{1,12}$", rsAcc.Username)
 	if err != nil {
 		return err
@@ -85,64 +110,36 @@ func (j *Jagex) ValidateRSAccount(rsAcc *models.RunescapeAccount) error {
 		return models.NewReqErrStr("invalid Runescape account name", "invalid Runescape account name")
 	}
 
-	// unless otherwise specified, the account is assumed to be "normal"
-	if rsAcc.AccountType == "" {
-		rsAcc.AccountType = normal
+	// unless otherwise specified, the account is an Old School account, and the account type is detected
+	if rsAcc.Game == "" {
+		rsAcc.Game = models.OldSchool
 	}
 
-	url, ok := urls[rsAcc.AccountType]
+	g, ok := getGame(rsAcc)
 	if !ok {
-		return models.NewReqErrStr("invalid Runescape account type", "invalid Runescape account type")
+		return models.NewReqErrStr("invalid Runescape game", `invalid Runescape game, expected "oldschool" or "rs3"`)
 	}
 
-	resp, err := j.Get(fmt.Sprintf(url, rsAcc.Username))
-	if err != nil {
-		return err
+	var h *hiscores
+	_, ok = g.urls[rsAcc.AccountType]
+	switch {
+	case rsAcc.AccountType == "":
+		rsAcc.AccountType, h, err = j.detectAccountType(ctx, g, rsAcc.Username)
+	case !ok:
+		return models.NewReqErrStr("invalid Runescape account type", "invalid Runescape account type")
+	default:
+		h, err = j.getHiscores(ctx, g, rsAcc.AccountType, rsAcc.Username)
 	}
-	defer resp.Body.Close()
 
-	err = models.AccValStatusCode(resp.StatusCode, "Jagex", "invalid Runescape account name")
-	if err != nil {
-		return err
+	if errors.Is(err, models.ErrNotFound) {
+		return models.NewReqErr(err, "invalid Runescape account name")
 	}
-
-	responseData, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
 
-	responseString := string(responseData)
-	skills := strings.Split(responseString, "\n")
-	totalFields := strings.Split(skills[0], ",")
-
-	rsAcc.TotalLevel, err = strconv.Atoi(totalFields[1])
-	if err != nil {
-		return err
-	}
-	rsAcc.TotalXP, err = strconv.Atoi(totalFields[2])
-	if err != nil {
-		return err
-	}
+	rsAcc.TotalLevel = h.Overall.Level
+	rsAcc.TotalXP = h.Overall.XP
 
 	return nil
 }
-
-// xpToTime estimates time spent on one skill based on the xp (Experience Points)
-func xpToTime(xp, i int, accountType string) int {
-	if i < 1 || i > 23 {
-		return 0
-	}
-
-	switch accountType {
-	case normal:
-		return xp / normalXPRates[i]
-	case ironman:
-		return xp / ironmanXPRates[i]
-	case hcim:
-		return xp / ironmanXPRates[i] // identical to ironman
-	case uim:
-		return xp / ultimateXPRates[i]
-	}
-
-	return 0
-}
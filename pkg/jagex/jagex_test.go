@@ -1,8 +1,10 @@
 package jagex
 
 import (
+	"context"
 	"ctp/pkg/models"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -14,15 +16,32 @@ import (
 )
 
 type mockGetter struct {
-	err error
+	err       error
+	data      string            // the hiscores returned, testData if empty
+	responses map[string]string // the hiscores returned for each URL instead of data if set, other URLs are not found
+	url       string            // the last requested URL
 }
 
-func (m *mockGetter) Get(url string) (*http.Response, error) {
+func (m *mockGetter) Get(ctx context.Context, url string) (*http.Response, error) {
+	m.url = url
 	if m.err != nil {
 		return nil, m.err
 	}
+
+	data := m.data
+	if data == "" {
+		data = testData
+	}
+
+	if m.responses != nil {
+		var ok bool
+		if data, ok = m.responses[url]; !ok {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: ioutil.NopCloser(strings.NewReader("404"))}, nil
+		}
+	}
+
 	resp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header)}
-	resp.Body = ioutil.NopCloser(strings.NewReader(testData))
+	resp.Body = ioutil.NopCloser(strings.NewReader(data))
 	return resp, nil
 }
 
@@ -62,6 +81,44 @@ var testData = `6355,2277,387381708
 8499,48
 -1,-1`
 
+// rs3TestData is the hiscores of a RuneScape 3 account, with 29 skills followed by the activities
+var rs3TestData = `12345,2400,1947429986
+102220,99,14391160
+194315,99,200000000
+3971,99,200000000
+176007,99,14391160
+16786,91,5346332
+41786,99,200000000
+198766,80,1986068
+-1,1,-1
+154954,99,200000000
+11217,99,13034431
+78976,99,200000000
+8130,99,13034431
+70630,80,1986068
+123929,91,5346332
+155911,99,200000000
+188436,99,14391160
+101610,99,14391160
+-1,1,-1
+187205,80,1986068
+111919,99,200000000
+103538,99,200000000
+190874,80,1986068
+151234,80,1986068
+-1,1,-1
+116555,99,14391160
+35167,99,14391160
+-1,1,-1
+95819,99,14391160
+25548,99,200000000
+-1,-1
+-1,-1
+-1,-1
+-1,-1
+-1,-1
+1234,56`
+
 func TestGetRSPlaytime(t *testing.T) {
 	var cases = []struct {
 		name        string
@@ -73,7 +130,7 @@ func TestGetRSPlaytime(t *testing.T) {
 	}
 
 	mg := &mockGetter{}
-	jagex := New(mg)
+	jagex := New(mg, nil)
 
 	// tc - test cases
 	for _, tc := range cases {
@@ -85,8 +142,9 @@ func TestGetRSPlaytime(t *testing.T) {
 			rsAcc.AccountType = "normal"
 
 			mg.err = tc.getterErr
+			mg.data = ""
 
-			game, err := jagex.GetRSPlaytime(&rsAcc)
+			game, err := jagex.GetRSPlaytime(context.Background(), &rsAcc)
 			assert.Equal(t, tc.expectedErr, err)
 			if tc.expectedErr == nil {
 				if assert.NotNil(t, game) {
@@ -97,34 +155,212 @@ func TestGetRSPlaytime(t *testing.T) {
 	}
 }
 
+func TestGetRSPlaytimeGames(t *testing.T) {
+	var cases = []struct {
+		name         string
+		rsAcc        models.RunescapeAccount
+		data         string
+		expectedURL  string
+		expectedGame *models.Game // only the name and playtime are compared, the skills are tested by TestGetRSPlaytimeSkills
+		expectedErr  error
+	}{
+		{"Test no game", models.RunescapeAccount{Username: "Test123", AccountType: "normal"}, testData,
+			"https://secure.runescape.com/m=hiscore_oldschool/index_lite.json?player=Test123",
+			&models.Game{Name: "Old School Runescape", Time: 3607, Minutes: 216422}, nil},
+		{"Test rs3", models.RunescapeAccount{Username: "Test123", Game: "rs3", AccountType: "normal"}, rs3TestData,
+			"https://secure.runescape.com/m=hiscore/index_lite.ws?player=Test123",
+			&models.Game{Name: "RuneScape 3", Time: 8302, Minutes: 498169}, nil},
+		{"Test rs3 hardcore ironman", models.RunescapeAccount{Username: "Test123", Game: "rs3", AccountType: "hardcore ironman"},
+			rs3TestData, "https://secure.runescape.com/m=hiscore_hardcore_ironman/index_lite.ws?player=Test123",
+			&models.Game{Name: "RuneScape 3", Time: 9750, Minutes: 585008}, nil},
+		{"Test rs3 with old school hiscores", models.RunescapeAccount{Username: "Test123", Game: "rs3", AccountType: "normal"},
+			testData, "https://secure.runescape.com/m=hiscore/index_lite.ws?player=Test123", nil,
+			errors.New("wrong number of fields in the hiscores")},
+		{"Test rs3 ultimate ironman", models.RunescapeAccount{Username: "Test123", Game: "rs3", AccountType: "ultimate ironman"},
+			rs3TestData, "", nil, errors.New("invalid account type in getHiscores: ultimate ironman")},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mg := &mockGetter{data: tc.data}
+
+			game, err := New(mg, nil).GetRSPlaytime(context.Background(), &tc.rsAcc)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedURL, mg.url)
+			if tc.expectedGame == nil {
+				assert.Nil(t, game)
+				return
+			}
+
+			if assert.NotNil(t, game) {
+				assert.Equal(t, tc.expectedGame.Name, game.Name)
+				assert.Equal(t, tc.expectedGame.Time, game.Time)
+				assert.Equal(t, tc.expectedGame.Minutes, game.Minutes)
+			}
+		})
+	}
+}
+
+func TestGetRSPlaytimeSkills(t *testing.T) {
+	// the hiscores of an account with 13 034 431 Attack XP (level 99), 100 Zulrah kills and nothing else
+	lines := []string{"1,99,13034431", "1,99,13034431"}
+	for i := 1; i < len(games[models.OldSchool].skills); i++ {
+		lines = append(lines, "-1,1,0")
+	}
+
+	model := DefaultRateModel()
+	for _, a := range model.Games[models.OldSchool].Activities {
+		if a.Name == "Zulrah" {
+			lines = append(lines, "1,100")
+		} else {
+			lines = append(lines, "-1,-1")
+		}
+	}
+
+	var cases = []struct {
+		name        string
+		accountType string
+		attack      int // the minutes spent training Attack, with the brackets of the account type
+		total       int
+	}{
+		{"Test normal", "normal", 8947, 9119},
+		{"Test hardcore ironman", "hardcore ironman", 10583, 10754},
+		{"Test ironman", "ironman", 8947, 9119}, // no ironman rates for Attack, the normal rates are used
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rsAcc := models.RunescapeAccount{Username: "Test123", AccountType: tc.accountType}
+			game, err := New(&mockGetter{data: strings.Join(lines, "\n")}, model).GetRSPlaytime(context.Background(), &rsAcc)
+			require.Nil(t, err)
+
+			expectedSkills := []models.SkillPlaytime{{Skill: "Attack", Level: 99, XP: 13034431, Minutes: tc.attack}}
+			assert.Equal(t, expectedSkills, game.Skills)
+
+			// Zulrah is killed 35 times per hour
+			expectedActivities := []models.ActivityPlaytime{{Activity: "Zulrah", Score: 100, Minutes: 171}}
+			assert.Equal(t, expectedActivities, game.Activities)
+
+			assert.Equal(t, tc.total, game.Minutes)
+		})
+	}
+}
+
 func TestValidateRSAccount(t *testing.T) {
 	var cases = []struct {
 		name        string
 		rsAcc       models.RunescapeAccount
+		data        string // the hiscores, testData if empty
 		getterErr   error
 		expectedErr error
 	}{
-		{"Test ok", models.RunescapeAccount{Username: "Test123", AccountType: "normal"}, nil, nil},
-		{"Test ok ironman", models.RunescapeAccount{Username: "Test123", AccountType: "ironman"}, nil, nil},
-		{"Test ok hardcore ironman", models.RunescapeAccount{Username: "Test123", AccountType: "hardcore ironman"}, nil, nil},
-		{"Test ok ultimate ironman", models.RunescapeAccount{Username: "Test123", AccountType: "ultimate ironman"}, nil, nil},
-		{"Test invalid account type", models.RunescapeAccount{Username: "Test123", AccountType: "this is invalid"}, nil,
+		{"Test ok", models.RunescapeAccount{Username: "Test123", AccountType: "normal"}, "", nil, nil},
+		{"Test ok ironman", models.RunescapeAccount{Username: "Test123", AccountType: "ironman"}, "", nil, nil},
+		{"Test ok hardcore ironman", models.RunescapeAccount{Username: "Test123", AccountType: "hardcore ironman"}, "", nil, nil},
+		{"Test ok ultimate ironman", models.RunescapeAccount{Username: "Test123", AccountType: "ultimate ironman"}, "", nil, nil},
+		{"Test ok json", models.RunescapeAccount{Username: "Test123", AccountType: "normal"}, jsonTestData, nil, nil},
+		{"Test invalid account type", models.RunescapeAccount{Username: "Test123", AccountType: "this is invalid"}, "", nil,
 			models.NewReqErrStr("invalid Runescape account type", "invalid Runescape account type")},
-		{"Test getter error", models.RunescapeAccount{Username: "Test123", AccountType: "normal"}, errors.New("test"), errors.New("test")},
-		{"Test too long name error", models.RunescapeAccount{Username: "this name is too long", AccountType: "normal"}, nil,
+		{"Test ok rs3", models.RunescapeAccount{Username: "Test123", Game: "rs3", AccountType: "ironman"}, rs3TestData, nil, nil},
+		{"Test rs3 with old school hiscores", models.RunescapeAccount{Username: "Test123", Game: "rs3", AccountType: "ironman"},
+			"", nil, errors.New("wrong number of fields in the hiscores")},
+		{"Test rs3 ultimate ironman", models.RunescapeAccount{Username: "Test123", Game: "rs3", AccountType: "ultimate ironman"},
+			rs3TestData, nil, models.NewReqErrStr("invalid Runescape account type", "invalid Runescape account type")},
+		{"Test invalid game", models.RunescapeAccount{Username: "Test123", Game: "rs2", AccountType: "normal"}, "", nil,
+			models.NewReqErrStr("invalid Runescape game", `invalid Runescape game, expected "oldschool" or "rs3"`)},
+		{"Test getter error", models.RunescapeAccount{Username: "Test123", AccountType: "normal"}, "", errors.New("test"),
+			errors.New("test")},
+		{"Test too long name error", models.RunescapeAccount{Username: "this name is too long", AccountType: "normal"}, "", nil,
 			models.NewReqErrStr("invalid Runescape account name", "invalid Runescape account name")},
 	}
 
 	mg := &mockGetter{}
-	jagex := New(mg)
+	jagex := New(mg, nil)
 
 	// tc - test cases
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			mg.err = tc.getterErr
+			mg.data = tc.data
 
-			err := jagex.ValidateRSAccount(&tc.rsAcc)
+			err := jagex.ValidateRSAccount(context.Background(), &tc.rsAcc)
 			assert.Equal(t, tc.expectedErr, err)
+			if tc.expectedErr == nil {
+				assert.NotZero(t, tc.rsAcc.TotalLevel)
+				assert.NotZero(t, tc.rsAcc.TotalXP)
+			}
 		})
 	}
 }
+
+// testHiscores returns the hiscores (in the JSON format) of an account with the overall XP
+func testHiscores(xp int) string {
+	return strings.Replace(jsonTestData, `"rank": 6355, "level": 2277, "xp": 387381708`,
+		fmt.Sprintf(`"rank": 6355, "level": 2277, "xp": %d`, xp), 1)
+}
+
+func TestValidateRSAccountDetection(t *testing.T) {
+	const url = "https://secure.runescape.com/m=hiscore_oldschool%s/index_lite.json?player=Test123"
+	normalURL := fmt.Sprintf(url, "")
+	ironURL := fmt.Sprintf(url, "_ironman")
+	hcimURL := fmt.Sprintf(url, "_hardcore_ironman")
+	uimURL := fmt.Sprintf(url, "_ultimate")
+
+	var cases = []struct {
+		name        string
+		responses   map[string]string
+		expected    string
+		expectedXP  int
+		expectedErr string
+	}{
+		{"Test normal", map[string]string{normalURL: testHiscores(1000)}, normal, 1000, ""},
+		{"Test ironman", map[string]string{normalURL: testHiscores(1000), ironURL: testHiscores(1000)}, ironman, 1000, ""},
+		{"Test de-ironed", map[string]string{normalURL: testHiscores(1000), ironURL: testHiscores(500)}, normal, 1000, ""},
+		{"Test hardcore ironman", map[string]string{normalURL: testHiscores(1000), ironURL: testHiscores(1000),
+			hcimURL: testHiscores(1000)}, hcim, 1000, ""},
+		{"Test dead hardcore ironman", map[string]string{normalURL: testHiscores(1000), ironURL: testHiscores(1000),
+			hcimURL: testHiscores(500)}, ironman, 1000, ""},
+		{"Test ultimate ironman", map[string]string{normalURL: testHiscores(1000), ironURL: testHiscores(1000),
+			uimURL: testHiscores(1000)}, uim, 1000, ""},
+		{"Test former ultimate ironman", map[string]string{normalURL: testHiscores(1000), ironURL: testHiscores(1000),
+			uimURL: testHiscores(500)}, ironman, 1000, ""},
+		{"Test not found", map[string]string{}, "", 0, "invalid Runescape account name"},
+		{"Test invalid hiscores", map[string]string{normalURL: testHiscores(1000), ironURL: "{}"}, "", 0,
+			"no overall row in the hiscores"},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rsAcc := models.RunescapeAccount{Username: "Test123"}
+			err := New(&mockGetter{responses: tc.responses}, nil).ValidateRSAccount(context.Background(), &rsAcc)
+			if tc.expectedErr != "" {
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tc.expectedErr)
+				}
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, rsAcc.AccountType)
+			assert.Equal(t, models.OldSchool, rsAcc.Game)
+			assert.Equal(t, tc.expectedXP, rsAcc.TotalXP)
+		})
+	}
+}
+
+func TestValidateRSAccountDetectionRS3(t *testing.T) {
+	// RuneScape 3 has no ultimate ironman hiscores, which are therefore not requested
+	mg := &mockGetter{responses: map[string]string{
+		"https://secure.runescape.com/m=hiscore/index_lite.ws?player=Test123":         rs3TestData,
+		"https://secure.runescape.com/m=hiscore_ironman/index_lite.ws?player=Test123": rs3TestData,
+	}}
+
+	rsAcc := models.RunescapeAccount{Username: "Test123", Game: models.RuneScape3}
+	err := New(mg, nil).ValidateRSAccount(context.Background(), &rsAcc)
+	assert.Nil(t, err)
+	assert.Equal(t, ironman, rsAcc.AccountType)
+	assert.Equal(t, "https://secure.runescape.com/m=hiscore_hardcore_ironman/index_lite.ws?player=Test123", mg.url)
+}
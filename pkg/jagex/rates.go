@@ -0,0 +1,216 @@
+package jagex
+
+//go:generate go run ../../tools/embedgen -in xprates.yaml -out xprates_gen.go -pkg jagex -name defaultRateModel -doc "is the default rate model, the content of xprates.yaml"
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"gopkg.in/yaml.v2"
+)
+
+// rateModelVersion is the version of the rate model format supported
+const rateModelVersion = 1
+
+// maxLevel is the highest (virtual) level XP is calculated for
+const maxLevel = 150
+
+// xpTable contains the XP needed for each level, where xpTable[level-1] is the XP needed for the level
+var xpTable = func() []int {
+	table := make([]int, maxLevel)
+
+	var points float64
+	for level := 1; level < maxLevel; level++ {
+		points += math.Floor(float64(level) + 300*math.Pow(2, float64(level)/7))
+		table[level] = int(math.Floor(points / 4))
+	}
+
+	return table
+}()
+
+// fallbacks are the account types each account type uses the rates of, if it has no rates for a skill
+var fallbacks = map[string]string{
+	ironman: normal,
+	hcim:    ironman,
+	uim:     ironman,
+}
+
+// RateModel contains the rates used to estimate the time spent playing each of the Runescape games.
+// It is loaded from a versioned YAML (or JSON) file, see xprates.yaml for the default model.
+type RateModel struct {
+	Version int                   `yaml:"version"`
+	Games   map[string]*gameRates `yaml:"games"` // by the game of the account
+}
+
+// gameRates contains the rates of one of the Runescape games
+type gameRates struct {
+	Skills     map[string]map[string]brackets `yaml:"skills"`     // the brackets of each skill for each account type
+	Activities []activity                     `yaml:"activities"` // in the order of the hiscores, after the skills
+}
+
+// brackets are the XP per hour from the level of each bracket until the level of the next
+type brackets []bracket
+
+type bracket struct {
+	Level int     `yaml:"level"`
+	Rate  float64 `yaml:"rate"` // XP per hour
+}
+
+// activity contains the score (e.g. kill count) per hour of an activity or boss in the hiscores
+type activity struct {
+	Name string  `yaml:"name"`
+	Rate float64 `yaml:"rate"` // 0 if the score does not count as playtime, e.g. ranks
+}
+
+// DefaultRateModel returns the rate model the application is built with
+func DefaultRateModel() *RateModel {
+	model, err := ParseRateModel([]byte(defaultRateModel))
+	if err != nil {
+		panic(fmt.Sprintf("invalid default rate model: %s", err)) // tested by TestDefaultRateModel
+	}
+
+	return model
+}
+
+// LoadRateModel loads the rate model from the YAML (or JSON) file
+func LoadRateModel(path string) (*RateModel, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRateModel(data)
+}
+
+// ParseRateModel parses and validates the YAML (or JSON, which is valid YAML) rate model.
+// Every skill of every game needs rates for normal accounts, as the other account types fall back to them.
+func ParseRateModel(data []byte) (*RateModel, error) {
+	var model RateModel
+
+	err := yaml.UnmarshalStrict(data, &model)
+	if err != nil {
+		return nil, err
+	}
+
+	if model.Version != rateModelVersion {
+		return nil, fmt.Errorf("unsupported rate model version %d, expected %d", model.Version, rateModelVersion)
+	}
+
+	for name, g := range games {
+		rates, ok := model.Games[name]
+		if !ok {
+			return nil, fmt.Errorf("no rates for %s", name)
+		}
+
+		err = rates.validate(g)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rates for %s: %w", name, err)
+		}
+	}
+
+	return &model, nil
+}
+
+// validate checks that the rates are given for each skill of the game, and only for the account types of the game
+func (r *gameRates) validate(g *game) error {
+	if len(r.Skills) != len(g.skills) {
+		return fmt.Errorf("expected rates for %d skills, got %d", len(g.skills), len(r.Skills))
+	}
+
+	for _, skill := range g.skills {
+		accountTypes, ok := r.Skills[skill]
+		if !ok {
+			return fmt.Errorf("no rates for %s", skill)
+		}
+
+		if _, ok := accountTypes[normal]; !ok {
+			return fmt.Errorf("no normal rates for %s", skill)
+		}
+
+		for accountType, b := range accountTypes {
+			if _, ok := g.urls[accountType]; !ok {
+				return fmt.Errorf("invalid account type %q for %s", accountType, skill)
+			}
+
+			err := b.validate()
+			if err != nil {
+				return fmt.Errorf("%s (%s): %w", skill, accountType, err)
+			}
+		}
+	}
+
+	for _, a := range r.Activities {
+		if a.Name == "" || a.Rate < 0 {
+			return fmt.Errorf("invalid activity %q", a.Name)
+		}
+	}
+
+	return nil
+}
+
+// validate checks that the brackets start at level 1, the levels are increasing and the rates are positive
+func (b brackets) validate() error {
+	if len(b) == 0 || b[0].Level != 1 {
+		return errors.New("the first bracket has to start at level 1")
+	}
+
+	for i, br := range b {
+		if br.Rate <= 0 {
+			return fmt.Errorf("the rate of level %d has to be positive", br.Level)
+		}
+
+		if i > 0 && br.Level <= b[i-1].Level || br.Level > maxLevel {
+			return fmt.Errorf("invalid level %d, the levels have to be increasing and at most %d", br.Level, maxLevel)
+		}
+	}
+
+	return nil
+}
+
+// rates returns the brackets of the skill for the account type, falling back to the account type it is based on
+func (r *gameRates) rates(skill, accountType string) brackets {
+	for accountType != "" {
+		if b, ok := r.Skills[skill][accountType]; ok {
+			return b
+		}
+
+		accountType = fallbacks[accountType]
+	}
+
+	return r.Skills[skill][normal]
+}
+
+// minutes returns the minutes it takes to gain the XP, where the XP in each bracket is gained at the rate of the bracket.
+// XP beyond the last bracket is gained at the rate of the last bracket.
+func (b brackets) minutes(xp int) float64 {
+	var hours float64
+
+	for i, br := range b {
+		start := xpTable[br.Level-1]
+		if xp <= start {
+			break
+		}
+
+		end := xp
+		if i+1 < len(b) && xpTable[b[i+1].Level-1] < end {
+			end = xpTable[b[i+1].Level-1]
+		}
+
+		hours += float64(end-start) / br.Rate
+	}
+
+	return hours * 60
+}
+
+// activityRate returns the score per hour of the activity, or 0 if the activity is not counted
+func (r *gameRates) activityRate(name string) float64 {
+	for _, a := range r.Activities {
+		if a.Name == name {
+			return a.Rate
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,145 @@
+package jagex
+
+import (
+	"ctp/pkg/models"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultRateModel(t *testing.T) {
+	// the generated file has to be regenerated with "go generate" whenever xprates.yaml is changed
+	data, err := ioutil.ReadFile("xprates.yaml")
+	require.Nil(t, err)
+	assert.Equal(t, string(data), defaultRateModel)
+
+	model := DefaultRateModel()
+	assert.Equal(t, rateModelVersion, model.Version)
+	for name, g := range games {
+		if assert.Contains(t, model.Games, name) {
+			assert.Len(t, model.Games[name].Skills, len(g.skills))
+		}
+	}
+}
+
+// testRateModel returns a valid rate model in JSON, where the rates of Attack for Old School are replaced by attack
+func testRateModel(attack string) string {
+	skills := func(names []string, attack string) string {
+		var rates []string
+		for _, name := range names {
+			if name == "Attack" && attack != "" {
+				rates = append(rates, `"Attack": `+attack)
+				continue
+			}
+			rates = append(rates, `"`+name+`": {"normal": [{"level": 1, "rate": 10000}]}`)
+		}
+		return "{" + strings.Join(rates, ", ") + "}"
+	}
+
+	return `{"version": 1, "games": {
+		"oldschool": {"skills": ` + skills(oldSchoolSkills, attack) + `, "activities": [{"name": "Zulrah", "rate": 35}]},
+		"rs3": {"skills": ` + skills(rs3Skills, "") + `}}}`
+}
+
+func TestParseRateModel(t *testing.T) {
+	var cases = []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"Test ok", testRateModel(""), ""},
+		{"Test account types", testRateModel(`{"normal": [{"level": 1, "rate": 10000}],
+			"hardcore ironman": [{"level": 1, "rate": 9000}, {"level": 50, "rate": 20000}]}`), ""},
+		{"Test wrong version", strings.Replace(testRateModel(""), `"version": 1`, `"version": 2`, 1),
+			"unsupported rate model version 2"},
+		{"Test unknown field", strings.Replace(testRateModel(""), `"version": 1`, `"version": 1, "test": 1`, 1),
+			"field test not found"},
+		{"Test missing game", `{"version": 1, "games": {}}`, "no rates for"},
+		{"Test missing skill", strings.Replace(testRateModel(""), `"Attack": {"normal": [{"level": 1, "rate": 10000}]}, `,
+			"", 1), "expected rates for 23 skills, got 22"},
+		{"Test no normal rates", testRateModel(`{"ironman": [{"level": 1, "rate": 10000}]}`), "no normal rates for Attack"},
+		{"Test unknown account type", testRateModel(`{"normal": [{"level": 1, "rate": 10000}],
+			"group ironman": [{"level": 1, "rate": 10000}]}`), `invalid account type "group ironman" for Attack`},
+		{"Test no brackets", testRateModel(`{"normal": []}`), "the first bracket has to start at level 1"},
+		{"Test first bracket", testRateModel(`{"normal": [{"level": 10, "rate": 10000}]}`),
+			"the first bracket has to start at level 1"},
+		{"Test decreasing levels", testRateModel(`{"normal": [{"level": 1, "rate": 10000}, {"level": 50, "rate": 20000},
+			{"level": 40, "rate": 30000}]}`), "invalid level 40"},
+		{"Test level too high", testRateModel(`{"normal": [{"level": 1, "rate": 10000}, {"level": 151, "rate": 20000}]}`),
+			"invalid level 151"},
+		{"Test no rate", testRateModel(`{"normal": [{"level": 1, "rate": 0}]}`), "the rate of level 1 has to be positive"},
+		{"Test negative activity", strings.Replace(testRateModel(""), `"rate": 35`, `"rate": -1`, 1),
+			`invalid activity "Zulrah"`},
+		{"Test invalid", "{", "yaml"},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			model, err := ParseRateModel([]byte(tc.data))
+			if tc.err != "" {
+				if assert.NotNil(t, err) {
+					assert.Contains(t, err.Error(), tc.err)
+				}
+				assert.Nil(t, model)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.NotNil(t, model)
+		})
+	}
+}
+
+func TestGameRates_Rates(t *testing.T) {
+	model, err := ParseRateModel([]byte(testRateModel(`{"normal": [{"level": 1, "rate": 1}], "ironman": [{"level": 1, "rate": 2}],
+		"ultimate ironman": [{"level": 1, "rate": 3}]}`)))
+	require.Nil(t, err)
+	rates := model.Games[models.OldSchool]
+
+	var cases = []struct {
+		accountType string
+		expected    float64
+	}{
+		{normal, 1},
+		{ironman, 2},
+		{hcim, 2}, // falls back to ironman
+		{uim, 3},
+		{"", 1},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.accountType, func(t *testing.T) {
+			assert.Equal(t, tc.expected, rates.rates("Attack", tc.accountType)[0].Rate)
+		})
+	}
+}
+
+func TestBrackets_Minutes(t *testing.T) {
+	// level 10 is reached at 1154 XP, and level 20 at 4470 XP
+	b := brackets{{Level: 1, Rate: 1154}, {Level: 10, Rate: 3316}, {Level: 20, Rate: 60}}
+
+	var cases = []struct {
+		name     string
+		xp       int
+		expected float64
+	}{
+		{"Test no xp", 0, 0},
+		{"Test first bracket", 577, 30},
+		{"Test start of second bracket", 1154, 60},
+		{"Test second bracket", 1154 + 1658, 90},
+		{"Test end of second bracket", 4470, 120},
+		{"Test beyond the last bracket", 4470 + 60, 180},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.InDelta(t, tc.expected, b.minutes(tc.xp), 0.001)
+		})
+	}
+}
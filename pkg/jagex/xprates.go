@@ -1,85 +0,0 @@
-package jagex
-
-// normalXPRates for each skill
-var normalXPRates = [...]int{
-	0,
-	90000,
-	90000,
-	90000,
-	300000,
-	150000,
-	200000,
-	100000,
-	400000,
-	70000,
-	250000,
-	70000,
-	200000,
-	150000,
-	250000,
-	60000,
-	200000,
-	44000,
-	100000,
-	50000,
-	100000,
-	50000,
-	120000,
-	400000,
-}
-
-// ironmanXPRates for each skill
-var ironmanXPRates = [...]int{
-	0,
-	90000,
-	90000,
-	90000,
-	250000,
-	120000,
-	150000,
-	90000,
-	350000,
-	50000,
-	200000,
-	60000,
-	150000,
-	125000,
-	200000,
-	50000,
-	150000,
-	44000,
-	90000,
-	50000,
-	100000,
-	50000,
-	100000,
-	300000,
-}
-
-// ultimateXPRates for each skill
-var ultimateXPRates = [...]int{
-	0,
-	70000,
-	70000,
-	70000,
-	200000,
-	100000,
-	125000,
-	80000,
-	300000,
-	50000,
-	175000,
-	50000,
-	125000,
-	100000,
-	150000,
-	50000,
-	125000,
-	44000,
-	80000,
-	45000,
-	90000,
-	50000,
-	100000,
-	250000,
-}
@@ -0,0 +1,385 @@
+// Package jobs runs the jobs of users, such as updating their games, by a pool of workers.
+// The jobs are queued in the database, such that they are kept if the server stops and can be run by any instance.
+// Jobs failing for a reason which may be temporary, such as an external API being down, are retried with backoff,
+// and the jobs failing for reasons other than the user's request are kept in a dead-letter list.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"ctp/pkg/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The defaults of the queue
+const (
+	maxAttempts  = 5                // the number of times a job is started before it fails
+	backoff      = 10 * time.Second // the wait before the first retry, which is doubled for every retry after it
+	maxBackoff   = 10 * time.Minute
+	timeout      = 5 * time.Minute // how long a job may run
+	lease        = timeout + time.Minute
+	pollInterval = 5 * time.Second // how often idle workers look for jobs queued by other instances, or due for a retry
+)
+
+// Handler runs a job of a type, updating the job as it progresses. Calling progress stores the job and sends it to
+// the user. Handlers should be idempotent, as a job is run again if it is retried or its worker stops.
+type Handler func(ctx context.Context, job *models.Job, progress func()) error
+
+// Publisher sends the events of the users to their subscribers
+type Publisher interface {
+	Publish(event models.Event)
+}
+
+// Queue stores jobs in the database, and runs them by the workers started
+type Queue struct {
+	store     models.JobStore
+	publisher Publisher
+	handlers  map[string]Handler
+	untilDone map[string]bool // the job types retried until they are done, see RetryUntilDone
+
+	maxAttempts int
+	backoff     time.Duration
+	timeout     time.Duration
+	lease       time.Duration // the time a worker has claimed a job for, after which it is given to another worker
+	poll        time.Duration
+
+	wake    chan struct{} // wakes an idle worker when a job is enqueued
+	mu      sync.Mutex
+	waiting map[string]chan outcome // by job id, the jobs run by this instance which a request waits for
+	running map[string]bool         // by job id, the jobs being run by the workers of this instance
+}
+
+// outcome is a finished job, and the error of the handler if it failed
+type outcome struct {
+	job *models.Job
+	err error
+}
+
+// New returns a queue storing the jobs in the store, which sends every change to a job to the user by the publisher
+func New(store models.JobStore, publisher Publisher) *Queue {
+	return &Queue{store: store, publisher: publisher, handlers: make(map[string]Handler),
+		untilDone: make(map[string]bool), maxAttempts: maxAttempts,
+		backoff: backoff, timeout: timeout, lease: lease, poll: pollInterval, wake: make(chan struct{}, 1),
+		waiting: make(map[string]chan outcome), running: make(map[string]bool)}
+}
+
+// Handle sets the handler of the jobs of the type. Handlers must be set before the workers are started.
+func (q *Queue) Handle(jobType string, handler Handler) {
+	q.handlers[jobType] = handler
+}
+
+// RetryUntilDone makes the jobs of the type be retried whenever they fail for a reason other than the user's request,
+// rather than failing once they have been started maxAttempts times, for jobs which must not be given up on (such as
+// purging a deleted user). Must be called before the workers are started.
+func (q *Queue) RetryUntilDone(jobType string) {
+	q.untilDone[jobType] = true
+}
+
+// Start starts the workers, which run jobs until the context is cancelled.
+// A job stopped by the context is run again by a worker when the lease of the stopped worker expires.
+func (q *Queue) Start(ctx context.Context, workers int) {
+	for i := 0; i < workers; i++ {
+		go q.work(ctx)
+	}
+}
+
+// Enqueue stores the job, to be run by a worker as soon as possible. Returns a copy of the job as queued.
+func (q *Queue) Enqueue(ctx context.Context, job *models.Job) (*models.Job, error) {
+	return q.Schedule(ctx, job, time.Now())
+}
+
+// Schedule stores the job, to be run by a worker once the given time has passed. Returns a copy of the job as queued.
+func (q *Queue) Schedule(ctx context.Context, job *models.Job, at time.Time) (*models.Job, error) {
+	if job.ID == "" {
+		job.ID = newJobID()
+	}
+
+	now := time.Now()
+	job.Status, job.Attempts, job.RunAt = models.JobQueued, 0, millis(at)
+	job.CreatedAt, job.UpdatedAt = now.Unix(), now.Unix()
+	job.Providers = []models.ProviderProgress{}
+
+	if info := models.GetRequestInfo(ctx); info != nil {
+		job.RequestID = info.ID
+	}
+
+	err := q.store.CreateJob(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	q.publish(job)
+
+	select {
+	case q.wake <- struct{}{}:
+	default: // the workers are woken already
+	}
+
+	return snapshot(job), nil
+}
+
+// Run enqueues the job, and waits until it is finished or the wait is over. Returns the finished job, and the error
+// of the job if it failed: the error itself if the job was run by this instance, and the job's *models.Problem if not.
+// Returns a *models.JobPendingError with the job if it has not finished within the wait.
+func (q *Queue) Run(ctx context.Context, job *models.Job, wait time.Duration) (*models.Job, error) {
+	job.ID = newJobID()
+
+	// waiting for the job before it is queued, as it may be finished by a worker right away
+	ch := make(chan outcome, 1)
+	q.mu.Lock()
+	q.waiting[job.ID] = ch
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		delete(q.waiting, job.ID)
+		q.mu.Unlock()
+	}()
+
+	queued, err := q.Enqueue(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	// the job may be run by another instance, which is only noticed by polling the store
+	ticker := time.NewTicker(q.poll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case done := <-ch:
+			return done.job, done.err
+		case <-ticker.C:
+			// the outcome of a job run by this instance is received from the worker, with the error itself
+			if q.isRunning(job.ID) {
+				continue
+			}
+			select {
+			case done := <-ch:
+				return done.job, done.err
+			default:
+			}
+
+			stored, err := q.store.GetJob(ctx, job.ID)
+			if err == nil && stored.Finished() {
+				return stored, jobError(stored)
+			}
+		case <-timer.C:
+			stored, err := q.store.GetJob(ctx, job.ID)
+			if err != nil {
+				stored = queued
+			}
+
+			return nil, &models.JobPendingError{Job: stored}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Get returns the job with the id. Returns models.ErrNotFound if there is none.
+func (q *Queue) Get(ctx context.Context, id string) (*models.Job, error) {
+	return q.store.GetJob(ctx, id)
+}
+
+// work runs the jobs which are due, one at a time, until the context is cancelled
+func (q *Queue) work(ctx context.Context) {
+	for {
+		now := time.Now()
+
+		job, err := q.store.ClaimJob(ctx, millis(now), millis(now.Add(q.lease)))
+		if err == nil {
+			q.run(ctx, job)
+			continue
+		}
+
+		if !errors.Is(err, models.ErrNotFound) && ctx.Err() == nil {
+			logrus.WithError(err).Warn("Could not claim a job")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-time.After(q.poll):
+		}
+	}
+}
+
+// run runs the job claimed by the worker, and stores the outcome
+func (q *Queue) run(ctx context.Context, job *models.Job) {
+	// the job keeps the request id and user id of the request which created it for logging
+	ctx = models.WithRequestInfo(ctx, &models.RequestInfo{ID: job.RequestID, UserID: job.UserID})
+
+	q.mu.Lock()
+	q.running[job.ID] = true
+	q.mu.Unlock()
+
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, job.ID)
+		q.mu.Unlock()
+	}()
+
+	// the progress of an earlier attempt is not kept
+	job.Providers, job.Result = []models.ProviderProgress{}, nil
+	q.publish(job)
+
+	var err error
+
+	handler, ok := q.handlers[job.Type]
+	if ok {
+		jobCtx, cancel := context.WithTimeout(ctx, q.timeout)
+		err = handler(jobCtx, job, func() { q.update(ctx, job) })
+		cancel()
+	} else {
+		err = fmt.Errorf("unknown job type %q", job.Type)
+	}
+
+	if ctx.Err() != nil {
+		models.Log(ctx).WithField("job", job.ID).Info("Stopped the job, which is run again when the lease expires")
+		return
+	}
+
+	q.finish(ctx, job, err)
+}
+
+// update stores the job, and sends it to the user
+func (q *Queue) update(ctx context.Context, job *models.Job) {
+	job.UpdatedAt = time.Now().Unix()
+
+	err := q.store.UpdateJob(ctx, job)
+	if err != nil {
+		models.Log(ctx).WithError(err).WithField("job", job.ID).Warn("Could not store the progress of the job")
+	}
+
+	q.publish(job)
+}
+
+// finish stores the outcome of the attempt to run the job, which is queued again if it should be retried
+func (q *Queue) finish(ctx context.Context, job *models.Job, err error) {
+	log := models.Log(ctx).WithFields(logrus.Fields{"job": job.ID, "type": job.Type, "attempt": job.Attempts})
+
+	var problem *models.Problem
+	if err != nil {
+		problem = models.ProblemFromError(err)
+	}
+
+	switch {
+	case err == nil:
+		job.Status, job.Error = models.JobDone, nil
+	case retryable(problem) && job.Attempts < q.maxAttempts:
+		delay := q.delay(job.Attempts)
+		job.Status, job.Error, job.RunAt = models.JobQueued, problem, millis(time.Now().Add(delay))
+		log.WithError(err).Warnf("The job failed, retrying in %s", delay)
+	case q.untilDone[job.Type] && problem.Status >= http.StatusInternalServerError:
+		// the job is never failed, so failing once the usual attempts are used up is logged as an error instead
+		delay := q.delay(job.Attempts)
+		job.Status, job.Error, job.RunAt = models.JobQueued, problem, millis(time.Now().Add(delay))
+		if job.Attempts < q.maxAttempts {
+			log.WithError(err).Warnf("The job failed, retrying in %s", delay)
+		} else {
+			log.WithError(err).Errorf("The job keeps failing, retrying in %s", delay)
+		}
+	default:
+		job.Status, job.Error = models.JobFailed, problem
+		log.WithError(err).Warn("The job failed")
+	}
+
+	if job.Finished() {
+		job.RunAt, job.User = 0, nil
+	}
+
+	q.update(ctx, job)
+
+	// failing due to the user's request (e.g. an invalid account) is expected, unlike the other failures
+	if job.Status == models.JobFailed && problem.Status >= http.StatusInternalServerError {
+		if err := q.store.AddDeadLetter(ctx, job); err != nil {
+			log.WithError(err).Error("Could not add the failed job to the dead-letter list")
+		} else {
+			log.Error("Added the failed job to the dead-letter list")
+		}
+	}
+
+	if job.Finished() {
+		q.mu.Lock()
+		ch, ok := q.waiting[job.ID]
+		q.mu.Unlock()
+
+		if ok {
+			ch <- outcome{job: snapshot(job), err: err}
+		}
+	}
+}
+
+// isRunning returns true if the job is being run by a worker of this instance
+func (q *Queue) isRunning(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.running[id]
+}
+
+// delay returns how long to wait before the job is retried, after it has been started the given number of times
+func (q *Queue) delay(attempts int) time.Duration {
+	delay := q.backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+
+	return delay
+}
+
+// publish sends the current state of the job to the user
+func (q *Queue) publish(job *models.Job) {
+	q.publisher.Publish(models.Event{Type: models.EventJob, UserID: job.UserID, Data: snapshot(job)})
+}
+
+// retryable returns true if the problem may be temporary, i.e. an external API failed or timed out
+func retryable(problem *models.Problem) bool {
+	return problem.Status == http.StatusBadGateway || problem.Status == http.StatusGatewayTimeout
+}
+
+// jobError returns the error of the job if it failed, and nil if not
+func jobError(job *models.Job) error {
+	if job.Status != models.JobFailed || job.Error == nil {
+		return nil
+	}
+
+	return job.Error
+}
+
+// snapshot returns a copy of the job, which is not changed as the job continues
+func snapshot(job *models.Job) *models.Job {
+	c := *job
+	c.Providers = append([]models.ProviderProgress{}, job.Providers...)
+
+	return &c
+}
+
+// millis returns the time as unix milliseconds
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// newJobID generates a random job id
+func newJobID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b) // crypto/rand only fails if the OS can't provide randomness at all
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,311 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps the jobs in memory, copying them such that the jobs stored are not changed by the workers
+type memoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]models.Job
+	dead []models.Job
+}
+
+func (m *memoryStore) CreateJob(ctx context.Context, job *models.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.jobs == nil {
+		m.jobs = make(map[string]models.Job)
+	}
+	m.jobs[job.ID] = *snapshot(job)
+
+	return nil
+}
+func (m *memoryStore) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	return snapshot(&job), nil
+}
+func (m *memoryStore) UpdateJob(ctx context.Context, job *models.Job) error {
+	return m.CreateJob(ctx, job)
+}
+func (m *memoryStore) ClaimJob(ctx context.Context, now, until int64) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var due *models.Job
+	for _, job := range m.jobs {
+		if job.RunAt != 0 && job.RunAt <= now && (due == nil || job.RunAt < due.RunAt) {
+			due = snapshot(&job)
+		}
+	}
+	if due == nil {
+		return nil, models.ErrNotFound
+	}
+
+	due.Status, due.Attempts, due.RunAt = models.JobRunning, due.Attempts+1, until
+	m.jobs[due.ID] = *snapshot(due)
+
+	return due, nil
+}
+func (m *memoryStore) AddDeadLetter(ctx context.Context, job *models.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.dead = append(m.dead, *snapshot(job))
+
+	return nil
+}
+func (m *memoryStore) GetDeadLetters(ctx context.Context) ([]models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.dead, nil
+}
+
+// mockPublisher records the statuses of the jobs published
+type mockPublisher struct {
+	mu       sync.Mutex
+	statuses []string
+}
+
+func (m *mockPublisher) Publish(event models.Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.statuses = append(m.statuses, event.Data.(*models.Job).Status)
+}
+
+// newQueue returns a queue with short waits, and a worker running until the test is done
+func newQueue(t *testing.T, store *memoryStore, publisher *mockPublisher, handler Handler) *Queue {
+	q := New(store, publisher)
+	q.backoff, q.poll = time.Millisecond, 10*time.Millisecond
+	q.Handle(models.JobRefresh, handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	q.Start(ctx, 1)
+
+	return q
+}
+
+func TestQueue(t *testing.T) {
+	apiErr := &models.ExternalAPIError{Err: errors.New("test"), API: "Valve", Code: http.StatusServiceUnavailable}
+
+	var cases = []struct {
+		name             string
+		errs             []error // the errors of each attempt, after which the job is done
+		expectedStatus   string
+		expectedAttempts int
+		expectedStatuses []string
+		expectedDead     int
+		expectedErr      error
+	}{
+		{"Test ok", nil, models.JobDone, 1, []string{models.JobQueued, models.JobRunning, models.JobRunning,
+			models.JobDone}, 0, nil},
+		{"Test retried", []error{apiErr, apiErr}, models.JobDone, 3, []string{models.JobQueued, models.JobRunning,
+			models.JobQueued, models.JobRunning, models.JobQueued, models.JobRunning, models.JobRunning, models.JobDone},
+			0, nil},
+		{"Test request error", []error{models.NewReqErrStr("test", "invalid test")}, models.JobFailed, 1,
+			[]string{models.JobQueued, models.JobRunning, models.JobFailed}, 0, models.NewReqErrStr("test", "invalid test")},
+		{"Test unexpected error", []error{errors.New("test")}, models.JobFailed, 1,
+			[]string{models.JobQueued, models.JobRunning, models.JobFailed}, 1, errors.New("test")},
+		{"Test retries exhausted", []error{apiErr, apiErr, apiErr, apiErr, apiErr}, models.JobFailed, maxAttempts,
+			nil, 1, apiErr},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memoryStore{}
+			publisher := &mockPublisher{}
+			q := newQueue(t, store, publisher, func(ctx context.Context, job *models.Job, progress func()) error {
+				if job.Attempts <= len(tc.errs) {
+					return tc.errs[job.Attempts-1]
+				}
+
+				job.Result = &models.JobResult{TotalPlayTime: 5, Games: 2}
+				progress()
+
+				return nil
+			})
+
+			job, err := q.Run(context.Background(), &models.Job{Type: models.JobRefresh, UserID: "12345"}, time.Second)
+			require.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedStatus, job.Status)
+			assert.Equal(t, tc.expectedAttempts, job.Attempts)
+			assert.Zero(t, job.RunAt)
+
+			stored, err := q.Get(context.Background(), job.ID)
+			require.Nil(t, err)
+			assert.Equal(t, job, stored)
+
+			if tc.expectedStatuses != nil {
+				publisher.mu.Lock()
+				assert.Equal(t, tc.expectedStatuses, publisher.statuses)
+				publisher.mu.Unlock()
+			}
+
+			dead, err := store.GetDeadLetters(context.Background())
+			require.Nil(t, err)
+			assert.Len(t, dead, tc.expectedDead)
+		})
+	}
+}
+
+func TestQueueRetryUntilDone(t *testing.T) {
+	var cases = []struct {
+		name             string
+		errs             []error // the errors of each attempt, after which the job is done
+		expectedStatus   string
+		expectedAttempts int
+		expectedErr      error
+	}{
+		{"Test retried past the attempts", []error{errors.New("test"), errors.New("test"), errors.New("test"),
+			errors.New("test"), errors.New("test"), errors.New("test")}, models.JobDone, maxAttempts + 2, nil},
+		{"Test request error", []error{models.NewReqErrStr("test", "invalid test")}, models.JobFailed, 1,
+			models.NewReqErrStr("test", "invalid test")},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memoryStore{}
+			q := New(store, &mockPublisher{})
+			q.backoff, q.poll = time.Millisecond, 10*time.Millisecond
+			q.Handle(models.JobPurge, func(ctx context.Context, job *models.Job, progress func()) error {
+				if job.Attempts <= len(tc.errs) {
+					return tc.errs[job.Attempts-1]
+				}
+
+				return nil
+			})
+			q.RetryUntilDone(models.JobPurge)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			t.Cleanup(cancel)
+			q.Start(ctx, 1)
+
+			job, err := q.Run(context.Background(), &models.Job{Type: models.JobPurge, UserID: "12345"}, 5*time.Second)
+			require.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedStatus, job.Status)
+			assert.Equal(t, tc.expectedAttempts, job.Attempts)
+
+			// the job is not dead-lettered while it is retried, nor when it fails due to the request
+			dead, err := store.GetDeadLetters(context.Background())
+			require.Nil(t, err)
+			assert.Empty(t, dead)
+		})
+	}
+}
+
+func TestQueuePending(t *testing.T) {
+	store := &memoryStore{}
+	release := make(chan struct{})
+	q := newQueue(t, store, &mockPublisher{}, func(ctx context.Context, job *models.Job, progress func()) error {
+		<-release
+		return nil
+	})
+
+	_, err := q.Run(context.Background(), &models.Job{Type: models.JobRefresh, UserID: "12345"}, 50*time.Millisecond)
+
+	var pending *models.JobPendingError
+	require.True(t, errors.As(err, &pending), err)
+	assert.Equal(t, models.JobRunning, pending.Job.Status)
+
+	// the job is still run after the request has stopped waiting for it
+	close(release)
+	assert.Eventually(t, func() bool {
+		job, err := q.Get(context.Background(), pending.Job.ID)
+		return err == nil && job.Status == models.JobDone
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestQueueSchedule(t *testing.T) {
+	store := &memoryStore{}
+	q := newQueue(t, store, &mockPublisher{}, func(ctx context.Context, job *models.Job, progress func()) error {
+		return nil
+	})
+
+	at := time.Now().Add(100 * time.Millisecond)
+	queued, err := q.Schedule(context.Background(), &models.Job{Type: models.JobRefresh, UserID: "12345"}, at)
+	require.Nil(t, err)
+	assert.Equal(t, models.JobQueued, queued.Status)
+	assert.Equal(t, millis(at), queued.RunAt)
+
+	// the job is not run before it is due
+	time.Sleep(50 * time.Millisecond)
+	job, err := q.Get(context.Background(), queued.ID)
+	require.Nil(t, err)
+	assert.Equal(t, models.JobQueued, job.Status)
+
+	assert.Eventually(t, func() bool {
+		job, err := q.Get(context.Background(), queued.ID)
+		return err == nil && job.Status == models.JobDone
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestQueueFinishedElsewhere(t *testing.T) {
+	// without workers, the job is only finished by changing the stored job, as if done by another instance
+	store := &memoryStore{}
+	q := New(store, &mockPublisher{})
+	q.poll = 10 * time.Millisecond
+
+	go func() {
+		for {
+			store.mu.Lock()
+			for id, job := range store.jobs {
+				job.Status, job.Error = models.JobFailed, models.NewProblem(http.StatusNotFound, models.CodeNotFound, "")
+				store.jobs[id] = job
+			}
+			n := len(store.jobs)
+			store.mu.Unlock()
+
+			if n > 0 {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	job, err := q.Run(context.Background(), &models.Job{Type: models.JobRefresh, UserID: "12345"}, time.Second)
+	require.NotNil(t, job)
+	assert.Equal(t, models.JobFailed, job.Status)
+	assert.Equal(t, models.NewProblem(http.StatusNotFound, models.CodeNotFound, ""), err)
+}
+
+func TestDelay(t *testing.T) {
+	var cases = []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{1, backoff},
+		{2, 2 * backoff},
+		{3, 4 * backoff},
+		{10, maxBackoff},
+	}
+
+	q := New(&memoryStore{}, &mockPublisher{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		assert.Equal(t, tc.expected, q.delay(tc.attempts), tc.attempts)
+	}
+}
@@ -0,0 +1,299 @@
+// Package launcher parses the libraries exported from game launchers which only keep the playtime locally,
+// such as GOG Galaxy and Playnite (which also imports the Epic Games Store library), and generic CSV files.
+package launcher
+
+import (
+	"bytes"
+	"ctp/pkg/models"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxGames is the highest number of games accepted in a file
+const MaxGames = 10000
+
+// Parse parses the file exported from a launcher in the given format (one of models.ImportFormats).
+// Every game is tagged with the launcher it is from. Returns a request error if the file is invalid.
+func Parse(format string, data []byte) ([]models.Game, error) {
+	var games []models.Game
+	var err error
+
+	switch format {
+	case models.ImportGOG:
+		games, err = parseGOG(data)
+	case models.ImportPlaynite:
+		games, err = parsePlaynite(data)
+	case models.ImportCSV:
+		games, err = parseCSV(data)
+	default:
+		return nil, models.NewReqErrStr("invalid import format: "+format,
+			fmt.Sprintf("invalid import format, expected one of %s", strings.Join(models.ImportFormats, ", ")))
+	}
+
+	if err != nil {
+		return nil, models.NewReqErr(err, fmt.Sprintf("invalid %s file: %s", format, err))
+	}
+
+	if len(games) > MaxGames {
+		return nil, models.NewReqErrStr("too many games", fmt.Sprintf("invalid %s file: more than %d games", format, MaxGames))
+	}
+
+	return games, nil
+}
+
+// newGame returns a game played for the given minutes, tagged with the launcher
+func newGame(name string, minutes int, lastPlayed int64, source string) models.Game {
+	return models.Game{Name: name, Time: minutes / 60, Minutes: minutes, LastPlayed: lastPlayed, Source: source}
+}
+
+// playniteGame is a game in the JSON export of a Playnite library
+type playniteGame struct {
+	Name         string          `json:"Name"`
+	Playtime     int64           `json:"Playtime"` // seconds
+	LastActivity *time.Time      `json:"LastActivity"`
+	Source       json.RawMessage `json:"Source"` // either the name of the source, or an object containing it
+	Hidden       bool            `json:"Hidden"`
+}
+
+// parsePlaynite parses the JSON export of a Playnite library, which is an array of games.
+// The games are tagged with their source in Playnite (e.g. "epic"), or "playnite" if they have none.
+// Hidden games are left out.
+func parsePlaynite(data []byte) ([]models.Game, error) {
+	var library []playniteGame
+
+	err := json.Unmarshal(data, &library)
+	if err != nil {
+		return nil, err
+	}
+
+	var games []models.Game
+	for _, g := range library {
+		if g.Hidden {
+			continue
+		}
+
+		if g.Name == "" {
+			return nil, errors.New("game without a name")
+		}
+
+		var lastPlayed int64
+		if g.LastActivity != nil {
+			lastPlayed = g.LastActivity.Unix()
+		}
+
+		games = append(games, newGame(g.Name, int(g.Playtime/60), lastPlayed, playniteSource(g.Source)))
+	}
+
+	return games, nil
+}
+
+// playniteSource returns the source of a Playnite game in lower case, which is either a string or an object with a name
+func playniteSource(raw json.RawMessage) string {
+	var name string
+	if json.Unmarshal(raw, &name) != nil {
+		var source struct {
+			Name string `json:"Name"`
+		}
+
+		if json.Unmarshal(raw, &source) == nil {
+			name = source.Name
+		}
+	}
+
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return models.ImportPlaynite
+	}
+
+	return name
+}
+
+// parseGOG parses the CSV (or tab separated) export of the GOG Galaxy database, which has a "title" and "gameMins" column.
+// The games are tagged with "gog", as GOG Galaxy includes the games of the other launchers integrated with it
+// without telling which launcher the playtime is from.
+func parseGOG(data []byte) ([]models.Game, error) {
+	rows, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var games []models.Game
+	for _, row := range rows {
+		title := row.get("title")
+		if title == "" {
+			return nil, fmt.Errorf("line %d: no title", row.line)
+		}
+
+		minutes, err := row.int("gamemins")
+		if err != nil {
+			return nil, err
+		}
+
+		lastPlayed, err := row.time("lastplayed")
+		if err != nil {
+			return nil, err
+		}
+
+		games = append(games, newGame(title, minutes, lastPlayed, models.ImportGOG))
+	}
+
+	return games, nil
+}
+
+// parseCSV parses a generic CSV file, with a "name" column and either a "minutes" or "hours" column.
+// The optional "source" column tags the games with their launcher, defaulting to "csv",
+// and the optional "lastPlayed" column contains when they were last played.
+func parseCSV(data []byte) ([]models.Game, error) {
+	rows, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var games []models.Game
+	for _, row := range rows {
+		name := row.get("name")
+		if name == "" {
+			return nil, fmt.Errorf("line %d: no name", row.line)
+		}
+
+		var minutes int
+		if _, ok := row.columns["hours"]; ok {
+			hours, err := row.float("hours")
+			if err != nil {
+				return nil, err
+			}
+
+			minutes = int(hours * 60)
+		} else {
+			minutes, err = row.int("minutes")
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		lastPlayed, err := row.time("lastplayed")
+		if err != nil {
+			return nil, err
+		}
+
+		source := strings.ToLower(row.get("source"))
+		if source == "" {
+			source = models.ImportCSV
+		}
+
+		games = append(games, newGame(name, minutes, lastPlayed, source))
+	}
+
+	return games, nil
+}
+
+// csvRow is a row of a CSV file with a header
+type csvRow struct {
+	line    int
+	columns map[string]int // the index of each column, by the lower case name in the header
+	fields  []string
+}
+
+// readCSV reads the rows of the CSV file, where the first line is the header.
+// The file is tab separated if the header contains a tab, and comma separated otherwise.
+// Tab separated fields are not quoted, and may therefore contain quotes (e.g. the JSON lists of the GOG Galaxy export).
+func readCSV(data []byte) ([]csvRow, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // byte order mark, added by spreadsheets
+
+	r := csv.NewReader(bytes.NewReader(data))
+	if header := bytes.SplitN(data, []byte("\n"), 2)[0]; bytes.Contains(header, []byte("\t")) {
+		r.Comma = '\t'
+		r.LazyQuotes = true
+	}
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty file")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var rows []csvRow
+	for line := 2; ; line++ {
+		fields, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// the rows of the file are limited, to avoid reading huge files
+		if len(rows) == MaxGames {
+			return nil, fmt.Errorf("more than %d games", MaxGames)
+		}
+
+		rows = append(rows, csvRow{line: line, columns: columns, fields: fields})
+	}
+}
+
+// get returns the trimmed value of the column, or "" if the row or file does not have the column
+func (r *csvRow) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.fields) {
+		return ""
+	}
+
+	return strings.TrimSpace(r.fields[i])
+}
+
+// int returns the value of the column as a non-negative integer, where an empty value is 0
+func (r *csvRow) int(column string) (int, error) {
+	value, err := r.float(column)
+
+	return int(value), err
+}
+
+// float returns the value of the column as a non-negative number, where an empty value is 0
+func (r *csvRow) float(column string) (float64, error) {
+	value := r.get(column)
+	if value == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("line %d: invalid %s %q", r.line, column, value)
+	}
+
+	return f, nil
+}
+
+// time returns the value of the column as unix time, where the value is either unix time, RFC 3339 or a date.
+// An empty value is 0.
+func (r *csvRow) time(column string) (int64, error) {
+	value := r.get(column)
+	if value == "" {
+		return 0, nil
+	}
+
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return unix, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("line %d: invalid %s %q", r.line, column, value)
+}
@@ -0,0 +1,99 @@
+package launcher
+
+import (
+	"ctp/pkg/models"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readFixture(t *testing.T, fixture string) string {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", fixture))
+	require.Nil(t, err)
+
+	return string(data)
+}
+
+func TestParse(t *testing.T) {
+	lastPlayed := func(value string) int64 {
+		parsed, err := time.Parse(time.RFC3339, value)
+		require.Nil(t, err)
+		return parsed.Unix()
+	}
+
+	var cases = []struct {
+		name     string
+		format   string
+		data     string
+		expected []models.Game
+	}{
+		{"Test gog", models.ImportGOG, readFixture(t, "gog.tsv"), []models.Game{
+			{Name: "The Witcher 3: Wild Hunt", Time: 102, Minutes: 6135, LastPlayed: lastPlayed("2023-01-14T20:15:00Z"),
+				Source: "gog"},
+			{Name: "Fortnite", Time: 1, Minutes: 95, Source: "gog"},
+			{Name: "Disco Elysium", Source: "gog"}}},
+		{"Test gog comma separated", models.ImportGOG, "title,gameMins\n\"Baldur's Gate 3, Deluxe\",600\n", []models.Game{
+			{Name: "Baldur's Gate 3, Deluxe", Time: 10, Minutes: 600, Source: "gog"}}},
+		{"Test playnite", models.ImportPlaynite, readFixture(t, "playnite.json"), []models.Game{
+			{Name: "Rocket League", Time: 10, Minutes: 600, LastPlayed: lastPlayed("2023-03-01T18:30:00+01:00"), Source: "epic"},
+			{Name: "Hades", Time: 1, Minutes: 90, Source: "epic"},
+			{Name: "Emulated Game", Minutes: 10, Source: "playnite"}}},
+		{"Test csv", models.ImportCSV, "\xef\xbb\xbfName,Minutes,Source,LastPlayed\nCeleste,125,itch.io,1672531200\nTetris,30,,\n",
+			[]models.Game{
+				{Name: "Celeste", Time: 2, Minutes: 125, LastPlayed: 1672531200, Source: "itch.io"},
+				{Name: "Tetris", Minutes: 30, Source: "csv"}}},
+		{"Test csv hours", models.ImportCSV, "name,hours,lastPlayed\nFactorio,12.5,2023-02-01\n", []models.Game{
+			{Name: "Factorio", Time: 12, Minutes: 750, LastPlayed: lastPlayed("2023-02-01T00:00:00Z"), Source: "csv"}}},
+		{"Test csv without games", models.ImportCSV, "name,minutes\n", nil},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			games, err := Parse(tc.format, []byte(tc.data))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, games)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	var cases = []struct {
+		name     string
+		format   string
+		data     string
+		expected string // contained in the response of the request error
+	}{
+		{"Test invalid format", "steam", "name,minutes\nCeleste,125", "invalid import format"},
+		{"Test empty", models.ImportCSV, "", "empty file"},
+		{"Test csv without name", models.ImportCSV, "name,minutes\n,125", "line 2: no name"},
+		{"Test csv invalid minutes", models.ImportCSV, "name,minutes\nCeleste,a lot", `line 2: invalid minutes "a lot"`},
+		{"Test csv negative hours", models.ImportCSV, "name,hours\nCeleste,-1", `line 2: invalid hours "-1"`},
+		{"Test csv invalid date", models.ImportCSV, "name,minutes,lastPlayed\nCeleste,1,yesterday",
+			`line 2: invalid lastplayed "yesterday"`},
+		{"Test gog without title", models.ImportGOG, "name,gameMins\nCeleste,125", "line 2: no title"},
+		{"Test gog invalid csv", models.ImportGOG, "title,gameMins\n\"Celeste,125", "invalid gog file"},
+		{"Test playnite invalid json", models.ImportPlaynite, `[{"Name": "Celeste"`, "invalid playnite file"},
+		{"Test playnite without name", models.ImportPlaynite, `[{"Playtime": 60}]`, "game without a name"},
+		{"Test too many games", models.ImportCSV, "name\n" + strings.Repeat("Celeste\n", MaxGames+1),
+			"more than 10000 games"},
+		{"Test too many playnite games", models.ImportPlaynite,
+			"[" + strings.Repeat(`{"Name": "Celeste"},`, MaxGames) + `{"Name": "Celeste"}]`, "more than 10000 games"},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			games, err := Parse(tc.format, []byte(tc.data))
+			assert.Nil(t, games)
+			if assert.IsType(t, &models.RequestError{}, err) {
+				assert.Contains(t, err.(*models.RequestError).Response, tc.expected)
+			}
+		})
+	}
+}
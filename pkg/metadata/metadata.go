@@ -0,0 +1,120 @@
+// Package metadata enriches games with metadata (genres, developer, cover image and release year)
+// from a metadata source such as the Steam store, caching the metadata in the database.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"ctp/pkg/catalog"
+	"ctp/pkg/models"
+	"ctp/pkg/tracing"
+)
+
+// maxAge is how long cached metadata is used before it is fetched again
+const maxAge = 30 * 24 * time.Hour
+
+// maxFetches is the highest number of games the metadata is fetched for by each call to Enrich, such that updating
+// a large library does not exceed the rate limit of the source. The other games are enriched by the next updates.
+const maxFetches = 50
+
+// Enricher adds the metadata from a source to games, caching it
+type Enricher struct {
+	source  models.MetadataSource
+	cache   models.MetadataCache
+	catalog *catalog.Catalog
+}
+
+// New returns an enricher adding the metadata from the source, cached in the cache.
+// The catalog gives the external ids of the games which are not from the source's provider, e.g. launcher imports.
+func New(source models.MetadataSource, cache models.MetadataCache, gameCatalog *catalog.Catalog) *Enricher {
+	return &Enricher{source: source, cache: cache, catalog: gameCatalog}
+}
+
+// Enrich sets the metadata of the games known by the source, from the cache if it is recent enough.
+// Metadata which can not be fetched (or read from the cache) is left out and fetched again by the next call,
+// as the metadata is not essential to the games.
+func (e *Enricher) Enrich(ctx context.Context, games []models.Game) {
+	ctx, span := tracing.Start(ctx, "metadata.Enrich")
+	defer span.End()
+
+	var fetches int
+	for i := range games {
+		games[i].Metadata = nil
+
+		id := e.externalID(&games[i])
+		if id == "" {
+			continue
+		}
+
+		key := e.source.Provider() + ":" + id
+		metadata, err := e.cache.GetMetadata(ctx, key)
+		if err != nil && !errors.Is(err, models.ErrNotFound) {
+			models.Log(ctx).WithError(err).WithField("game", key).Warn("Could not get cached game metadata")
+			continue
+		}
+
+		if metadata == nil || time.Since(time.Unix(metadata.FetchedAt, 0)) > maxAge {
+			if fetches == maxFetches {
+				games[i].Metadata = nonEmpty(metadata) // the stale metadata is kept until it is fetched again
+				continue
+			}
+			fetches++
+
+			metadata, err = e.fetch(ctx, key, id)
+			if err != nil {
+				models.Log(ctx).WithError(err).WithField("game", key).Warn("Could not fetch game metadata")
+				continue
+			}
+		}
+
+		games[i].Metadata = nonEmpty(metadata)
+	}
+}
+
+// fetch gets the metadata from the source and caches it. Games unknown to the source are cached with empty metadata,
+// such that they are not fetched again until the metadata is too old.
+func (e *Enricher) fetch(ctx context.Context, key, id string) (*models.GameMetadata, error) {
+	metadata, err := e.source.GetMetadata(ctx, id)
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		metadata = &models.GameMetadata{}
+	case err != nil:
+		return nil, err
+	}
+
+	metadata.FetchedAt = time.Now().Unix()
+
+	err = e.cache.SetMetadata(ctx, key, metadata)
+	if err != nil {
+		models.Log(ctx).WithError(err).WithField("game", key).Warn("Could not cache game metadata")
+	}
+
+	return metadata, nil
+}
+
+// externalID returns the id of the game for the source's provider: the app id of Steam games,
+// or the first id of the game in the catalog. Returns "" if the game has no such id.
+func (e *Enricher) externalID(game *models.Game) string {
+	if e.source.Provider() == "steam" && game.AppID != 0 {
+		return strconv.Itoa(game.AppID)
+	}
+
+	match := e.catalog.Get(game.GameID)
+	if match == nil || len(match.ExternalIDs[e.source.Provider()]) == 0 {
+		return ""
+	}
+
+	return match.ExternalIDs[e.source.Provider()][0]
+}
+
+// nonEmpty returns the metadata, or nil if it is empty
+func nonEmpty(metadata *models.GameMetadata) *models.GameMetadata {
+	if metadata == nil || metadata.Empty() {
+		return nil
+	}
+
+	return metadata
+}
@@ -0,0 +1,153 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"ctp/pkg/catalog"
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockSource is a metadata source knowing the games in metadata by their id
+type mockSource struct {
+	metadata map[string]models.GameMetadata
+	err      error // returned by every request, if set
+	fetched  []string
+}
+
+func (m *mockSource) Provider() string { return "steam" }
+
+func (m *mockSource) GetMetadata(ctx context.Context, id string) (*models.GameMetadata, error) {
+	m.fetched = append(m.fetched, id)
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	metadata, ok := m.metadata[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	return &metadata, nil
+}
+
+// mockCache is a metadata cache in memory
+type mockCache struct {
+	cache map[string]models.GameMetadata
+	err   error // returned by every call, if set
+}
+
+func (m *mockCache) GetMetadata(ctx context.Context, key string) (*models.GameMetadata, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	metadata, ok := m.cache[key]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+
+	return &metadata, nil
+}
+
+func (m *mockCache) SetMetadata(ctx context.Context, key string, metadata *models.GameMetadata) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.cache[key] = *metadata
+
+	return nil
+}
+
+func TestEnrich(t *testing.T) {
+	portal := models.GameMetadata{Genres: []string{"Action"}, Developer: "Valve", ReleaseYear: 2007}
+	witcher := models.GameMetadata{Genres: []string{"RPG"}, Developer: "CD PROJEKT RED", ReleaseYear: 2015}
+	stale := models.GameMetadata{Genres: []string{"Puzzle"}, FetchedAt: time.Now().Add(-2 * maxAge).Unix()}
+	recent := models.GameMetadata{Genres: []string{"Adventure"}, FetchedAt: time.Now().Unix()}
+	unknown := models.GameMetadata{FetchedAt: time.Now().Unix()}
+
+	var cases = []struct {
+		name            string
+		games           []models.Game
+		cache           map[string]models.GameMetadata
+		sourceErr       error
+		cacheErr        error
+		expected        []*models.GameMetadata
+		expectedFetched []string
+	}{
+		{"Test fetch", []models.Game{{Name: "Portal", AppID: 400}}, nil, nil, nil,
+			[]*models.GameMetadata{&portal}, []string{"400"}},
+		{"Test catalog game", []models.Game{{Name: "The Witcher 3", Source: "gog", GameID: "the-witcher-3"}}, nil, nil, nil,
+			[]*models.GameMetadata{&witcher}, []string{"292030"}},
+		{"Test no external id", []models.Game{{Name: "Runescape"}, {Name: "Minecraft", GameID: "minecraft"}}, nil, nil, nil,
+			[]*models.GameMetadata{nil, nil}, nil},
+		{"Test unknown game", []models.Game{{Name: "Unknown", AppID: 1}}, nil, nil, nil,
+			[]*models.GameMetadata{nil}, []string{"1"}},
+		{"Test cached", []models.Game{{Name: "Portal", AppID: 400}}, map[string]models.GameMetadata{"steam:400": recent},
+			nil, nil, []*models.GameMetadata{&recent}, nil},
+		{"Test cached unknown", []models.Game{{Name: "Unknown", AppID: 1}}, map[string]models.GameMetadata{"steam:1": unknown},
+			nil, nil, []*models.GameMetadata{nil}, nil},
+		{"Test stale", []models.Game{{Name: "Portal", AppID: 400}}, map[string]models.GameMetadata{"steam:400": stale},
+			nil, nil, []*models.GameMetadata{&portal}, []string{"400"}},
+		{"Test source error", []models.Game{{Name: "Portal", AppID: 400}}, nil, errors.New("timeout"), nil,
+			[]*models.GameMetadata{nil}, []string{"400"}},
+		{"Test cache error", []models.Game{{Name: "Portal", AppID: 400}}, nil, nil, errors.New("unavailable"),
+			[]*models.GameMetadata{nil}, nil},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			source := &mockSource{metadata: map[string]models.GameMetadata{"400": portal, "292030": witcher}, err: tc.sourceErr}
+			cache := &mockCache{cache: make(map[string]models.GameMetadata), err: tc.cacheErr}
+			for key, metadata := range tc.cache {
+				cache.cache[key] = metadata
+			}
+
+			New(source, cache, catalog.DefaultCatalog()).Enrich(context.Background(), tc.games)
+
+			assert.Equal(t, tc.expectedFetched, source.fetched)
+			for i, game := range tc.games {
+				if tc.expected[i] == nil || game.Metadata == nil {
+					assert.Equal(t, tc.expected[i], game.Metadata)
+					continue
+				}
+
+				assert.Equal(t, tc.expected[i].Genres, game.Metadata.Genres)
+				assert.Equal(t, tc.expected[i].Developer, game.Metadata.Developer)
+				assert.Equal(t, tc.expected[i].ReleaseYear, game.Metadata.ReleaseYear)
+			}
+
+			// fetched metadata is cached, including the games unknown to the source
+			for _, id := range source.fetched {
+				if tc.sourceErr == nil {
+					assert.Contains(t, cache.cache, "steam:"+id)
+				}
+			}
+		})
+	}
+}
+
+func TestEnrich_MaxFetches(t *testing.T) {
+	games := make([]models.Game, maxFetches+1)
+	for i := range games {
+		games[i] = models.Game{Name: fmt.Sprintf("Game %d", i), AppID: i + 1}
+	}
+
+	source := &mockSource{}
+	cache := &mockCache{cache: make(map[string]models.GameMetadata)}
+	e := New(source, cache, catalog.DefaultCatalog())
+
+	e.Enrich(context.Background(), games)
+	assert.Len(t, source.fetched, maxFetches)
+
+	// the other games are fetched by the next call
+	e.Enrich(context.Background(), games)
+	assert.Len(t, source.fetched, maxFetches+1)
+}
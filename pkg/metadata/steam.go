@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"ctp/pkg/models"
+	"ctp/pkg/tracing"
+)
+
+const appDetails = "https://store.steampowered.com/api/appdetails?appids=%s&l=english"
+
+// yearRegexp matches the year in the release dates of the Steam store, which are formatted by the store's locale
+// (e.g. "14 Nov, 2019" or "Nov 14, 2019"), or are only a year or quarter (e.g. "Q3 2024") for upcoming games
+var yearRegexp = regexp.MustCompile(`\b(19|20)\d{2}\b`)
+
+// SteamStore is a metadata source getting the metadata of Steam games from the Steam store, by their app id
+type SteamStore struct {
+	models.Getter
+}
+
+// storeResp is used for decoding the response from appdetails, which is an object by app id
+type storeResp map[string]struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Genres []struct {
+			Description string `json:"description"`
+		} `json:"genres"`
+		Developers  []string `json:"developers"`
+		HeaderImage string   `json:"header_image"`
+		ReleaseDate struct {
+			Date string `json:"date"`
+		} `json:"release_date"`
+	} `json:"data"`
+}
+
+// NewSteamStore returns a metadata source using the Steam store
+func NewSteamStore(getter models.Getter) *SteamStore {
+	return &SteamStore{getter}
+}
+
+// Provider returns "steam", as the games are identified by their Steam app id
+func (s *SteamStore) Provider() string {
+	return "steam"
+}
+
+// GetMetadata gets the metadata of the game with the app id from the Steam store.
+// Returns ErrNotFound if the store does not list the game (e.g. games removed from the store).
+func (s *SteamStore) GetMetadata(ctx context.Context, id string) (*models.GameMetadata, error) {
+	ctx, span := tracing.StartKind(ctx, "metadata.SteamStore.GetMetadata", tracing.KindClient)
+	defer span.End()
+
+	if _, err := strconv.Atoi(id); err != nil {
+		return nil, fmt.Errorf("invalid steam app id %q: %w", id, models.ErrInvalidID)
+	}
+
+	resp, err := s.Get(ctx, fmt.Sprintf(appDetails, url.QueryEscape(id)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = models.CheckStatusCode(resp.StatusCode, "Steam store", "could not get game metadata")
+	if err != nil {
+		return nil, err
+	}
+
+	var sResp storeResp
+	err = json.NewDecoder(resp.Body).Decode(&sResp)
+	if err != nil {
+		return nil, err
+	}
+
+	app, ok := sResp[id]
+	if !ok || !app.Success {
+		return nil, fmt.Errorf("steam app %s: %w", id, models.ErrNotFound)
+	}
+
+	metadata := &models.GameMetadata{Cover: app.Data.HeaderImage}
+	for _, genre := range app.Data.Genres {
+		metadata.Genres = append(metadata.Genres, genre.Description)
+	}
+
+	if len(app.Data.Developers) > 0 {
+		metadata.Developer = app.Data.Developers[0]
+	}
+
+	metadata.ReleaseYear = releaseYear(app.Data.ReleaseDate.Date)
+
+	return metadata, nil
+}
+
+// releaseYear returns the year of the release date, or 0 if it has none (e.g. "Coming soon")
+func releaseYear(date string) int {
+	years := yearRegexp.FindAllString(date, -1)
+	if len(years) == 0 {
+		return 0
+	}
+
+	year, _ := strconv.Atoi(years[len(years)-1])
+
+	return year
+}
@@ -0,0 +1,105 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockStore is a mock http.Get that implements the "Getter" interface,
+// responding with the responses recorded from the Steam store in testdata
+type mockStore struct {
+	status int   // the status code of every response, if set
+	err    error // returned by every request, if set
+}
+
+func (m *mockStore) Get(ctx context.Context, url string) (*http.Response, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	status := http.StatusOK
+	if m.status != 0 {
+		status = m.status
+	}
+
+	id := url[strings.Index(url, "appids=")+len("appids=") : strings.Index(url, "&")]
+
+	f, err := os.Open(filepath.Join("testdata", "appdetails_"+id+".json"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{StatusCode: status, Header: make(http.Header), Body: ioutil.NopCloser(f)}, nil
+}
+
+func TestSteamStore_GetMetadata(t *testing.T) {
+	var cases = []struct {
+		name        string
+		id          string
+		getter      *mockStore
+		expected    *models.GameMetadata
+		expectedErr error
+	}{
+		{"Test ok", "400", &mockStore{}, &models.GameMetadata{
+			Genres:      []string{"Action"},
+			Developer:   "Valve",
+			Cover:       "https://shared.akamai.steamstatic.com/store_item_assets/steam/apps/400/header.jpg?t=1745368554",
+			ReleaseYear: 2007,
+		}, nil},
+		{"Test unknown game", "1", &mockStore{}, nil, models.ErrNotFound},
+		{"Test invalid id", "400&appids=1", &mockStore{}, nil, models.ErrInvalidID},
+		{"Test rate limited", "400", &mockStore{status: http.StatusTooManyRequests}, nil, &models.ExternalAPIError{}},
+		{"Test request error", "400", &mockStore{err: errors.New("timeout")}, nil, errors.New("timeout")},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			metadata, err := NewSteamStore(tc.getter).GetMetadata(context.Background(), tc.id)
+
+			switch expected := tc.expectedErr.(type) {
+			case nil:
+				assert.Nil(t, err)
+			case *models.ExternalAPIError:
+				assert.IsType(t, expected, err)
+			default:
+				if !errors.Is(err, expected) {
+					assert.Equal(t, expected, err)
+				}
+			}
+
+			assert.Equal(t, tc.expected, metadata)
+		})
+	}
+}
+
+func TestReleaseYear(t *testing.T) {
+	var cases = []struct {
+		date     string
+		expected int
+	}{
+		{"10 Oct, 2007", 2007},
+		{"Oct 10, 2007", 2007},
+		{"Q3 2024", 2024},
+		{"2019", 2019},
+		{"Coming soon", 0},
+		{"", 0},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.date, func(t *testing.T) {
+			assert.Equal(t, tc.expected, releaseYear(tc.date))
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+// The actions recorded in the audit log
+const (
+	AuditDelete  = "delete"  // the user asked to be deleted, starting the grace period
+	AuditRestore = "restore" // the user restored their account within the grace period
+	AuditPurge   = "purge"   // the user and all of their data were purged
+)
+
+// AuditRecord is an entry of the audit log, recording an action taken on a user's account.
+// The records only identify the user by id, and are kept after the user is purged.
+type AuditRecord struct {
+	ID        string         `json:"id" firestore:"id"`
+	Action    string         `json:"action" firestore:"action"` // AuditDelete, AuditRestore or AuditPurge
+	UserID    string         `json:"userId" firestore:"userId"`
+	At        int64          `json:"at" firestore:"at"`                         // unix time
+	PurgeAt   int64          `json:"purgeAt,omitempty" firestore:"purgeAt"`     // unix time the user is purged, for AuditDelete
+	RequestID string         `json:"requestId,omitempty" firestore:"requestId"` // the request (or job) which took the action
+	Deleted   map[string]int `json:"deleted,omitempty" firestore:"deleted"`     // the number purged, by collection or kind (e.g. subscriptions)
+}
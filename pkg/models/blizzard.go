@@ -1,14 +1,60 @@
 package models
 
+import (
+	"context"
+	"time"
+)
+
 // Blizzard interface defines all methods which should be provided by blizzard
 type Blizzard interface {
-	GetBlizzardPlaytime(*Overwatch) (*Game, error)
-	ValidateBattleUser(overwatch *Overwatch) error
+	GetBlizzardPlaytime(ctx context.Context, overwatch *Overwatch) (*Game, error)
+	ValidateBattleUser(ctx context.Context, overwatch *Overwatch) error
+	BattleNetAuthURL(userID, region string) (string, string, error)
+	HandleBattleNetCallback(ctx context.Context, code, state, nonce string) (string, *BattleNetAccount, error)
+	GetBattleNetPlaytime(ctx context.Context, account *BattleNetAccount) ([]Game, error)
+}
+
+// BattleNetStateTTL is how long the user has to authorize the application after starting to link their Battle.net account
+const BattleNetStateTTL = 10 * time.Minute
+
+// The titles which are fetched from the Battle.net APIs
+const (
+	WorldOfWarcraft = "World of Warcraft"
+	DiabloIII       = "Diablo III"
+	StarCraftII     = "StarCraft II"
+)
+
+// The states of a linked Battle.net account
+const (
+	BattleNetLinked  = "linked"
+	BattleNetExpired = "expired" // the access token has expired, and the account has to be linked again
+)
+
+// BattleNetAccount contains the Battle.net account linked through OAuth, and the access token used to get the profiles.
+// The token is never returned to the user.
+type BattleNetAccount struct {
+	ID        int64  `json:"id" firestore:"id"`
+	BattleTag string `json:"battleTag" firestore:"battleTag"`
+	Region    string `json:"region" firestore:"region"` // the region of the APIs, us, eu, kr or tw
+	Token     string `json:"-" firestore:"token"`
+	ExpiresAt int64  `json:"expiresAt" firestore:"expiresAt"` // unix time, when the token expires
+	Status    string `json:"status,omitempty" firestore:"status"`
+	Hint      string `json:"hint,omitempty" firestore:"hint"`
+}
+
+// SetStatus sets the state of the account, and the hint telling the user how to fix it (if needed)
+func (a *BattleNetAccount) SetStatus(status string) {
+	a.Status = status
+	a.Hint = ""
+	if status == BattleNetExpired {
+		a.Hint = "The Battle.net authorization has expired, link the account again to update the games."
+	}
 }
 
-// Overwatch struct contains users battle tag and total playtime
+// Overwatch struct contains users battle tag and total playtime.
+// Overwatch 2 profiles are shared by every region, the region is only kept for accounts linked before.
 type Overwatch struct {
 	BattleTag string `json:"battleTag" firebase:"battleTag"`
 	Platform  string `json:"platform" firebase:"platform"`
-	Region    string `json:"region" firebase:"region"`
+	Region    string `json:"region,omitempty" firebase:"region"`
 }
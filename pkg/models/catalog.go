@@ -0,0 +1,25 @@
+package models
+
+// CatalogGame is a game in the game catalog, which identifies the same game across providers.
+// Games are matched to it by the external ids of their provider (e.g. the Steam app id), or by its name and aliases.
+type CatalogGame struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Aliases     []string            `json:"aliases,omitempty"`
+	ExternalIDs map[string][]string `json:"externalIds,omitempty"` // by provider, e.g. "steam": ["730"]
+}
+
+// GameMatch is the user's override of how a game is matched to the catalog, for every game with the same name.
+// The game is matched to the catalog game with the GameID, or never merged with other games if GameID is empty.
+type GameMatch struct {
+	Key    string `json:"key" firestore:"key"` // the normalized name, identifying the match
+	Name   string `json:"name" firestore:"name"`
+	GameID string `json:"gameId" firestore:"gameId"`
+}
+
+// MergedGame is one of the entries of a game listed by several providers, which are merged into one game
+type MergedGame struct {
+	Name    string `json:"name" firestore:"name"`
+	Source  string `json:"source,omitempty" firestore:"source"` // e.g. "steam", "gog" or "manual"
+	Minutes int    `json:"minutes" firestore:"minutes"`
+}
@@ -6,6 +6,7 @@ import (
 
 // Client is an interface which defines all methods a Client should provide
 // namely, getting a resource based on the given url.
+// The request should be created with http.NewRequestWithContext, such that it is cancelled along with the context.
 // http.Client is intended to fulfill the interface, but allow for testing without sending requests to another API
 //  Similar to Getter interface, but handles auth parameter
 type Client interface {
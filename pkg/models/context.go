@@ -0,0 +1,47 @@
+package models
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RequestInfo contains information about the request which should be included when logging.
+// It is set by the request ID middleware, and the UserID is filled in by the AuthMiddleware when the user is authenticated.
+type RequestInfo struct {
+	ID     string
+	UserID string
+	Route  string // the name of the route, set once the request has been routed
+}
+
+// requestInfoKey is used to store the request info in the request context
+const requestInfoKey = CtxKey("requestInfo")
+
+// WithRequestInfo returns a copy of the context containing the request info
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey, info)
+}
+
+// GetRequestInfo returns the request info stored in the context, or nil if there is none
+func GetRequestInfo(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoKey).(*RequestInfo)
+	return info
+}
+
+// Log returns a log entry containing the request id and user id from the context (if any),
+// such that everything logged while handling a request can be correlated
+func Log(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+
+	info := GetRequestInfo(ctx)
+	if info == nil {
+		return entry
+	}
+
+	entry = entry.WithField("request_id", info.ID)
+	if info.UserID != "" {
+		entry = entry.WithField("user_id", info.UserID)
+	}
+
+	return entry
+}
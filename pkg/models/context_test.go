@@ -0,0 +1,38 @@
+package models
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLog(t *testing.T) {
+	var cases = []struct {
+		name           string
+		info           *RequestInfo
+		expectedFields int
+	}{
+		{"Test no request info", nil, 0},
+		{"Test request id", &RequestInfo{ID: "test"}, 1},
+		{"Test request id and user id", &RequestInfo{ID: "test", UserID: "12345"}, 2},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.info != nil {
+				ctx = WithRequestInfo(ctx, tc.info)
+			}
+
+			assert.Equal(t, tc.info, GetRequestInfo(ctx))
+
+			entry := Log(ctx)
+			assert.Len(t, entry.Data, tc.expectedFields)
+			if tc.info != nil {
+				assert.Equal(t, tc.info.ID, entry.Data["request_id"])
+			}
+		})
+	}
+}
@@ -1,19 +1,61 @@
 package models
 
+import "context"
+
 // Database contains all functions a database should provide
 type Database interface {
-	CreateUser(user *User) error
-	GetUserByID(id string) (*User, error)
-	GetUserByName(name string) (*User, error)
-	UpdateUser(user *User) error
-	UpdateGames(user *User) error
-	SetUsername(user *User) error
-	DeleteUser(id string) error
-	DeleteFieldsFromUser(id string, fields []string) error
+	CreateUser(ctx context.Context, user *User) error
+	GetUserByID(ctx context.Context, id string) (*User, error)
+	GetUserByName(ctx context.Context, name string) (*User, error)
+	UpdateUser(ctx context.Context, user *User) error
+	ReplaceUser(ctx context.Context, user *User, version int64) error
+	UpdateGames(ctx context.Context, user *User) error
+	GetImports(ctx context.Context, id string) ([]ImportedLibrary, error)
+	SetImport(ctx context.Context, id string, library *ImportedLibrary) error
+	DeleteImport(ctx context.Context, id, format string) error
+	GetProviderGames(ctx context.Context, id, provider string) (*ProviderGames, error)
+	SetProviderGames(ctx context.Context, id string, games *ProviderGames) error
+	GetManualGames(ctx context.Context, id string) ([]ManualGame, error)
+	GetManualGame(ctx context.Context, id, gameID string) (*ManualGame, error)
+	SetManualGame(ctx context.Context, id string, game *ManualGame) error
+	DeleteManualGame(ctx context.Context, id, gameID string) error
+	GetMatches(ctx context.Context, id string) ([]GameMatch, error)
+	SetMatch(ctx context.Context, id string, match *GameMatch) error
+	DeleteMatch(ctx context.Context, id, key string) error
+	GetSnapshots(ctx context.Context, id, since string) ([]PlaytimeSnapshot, error)
+	SetSnapshot(ctx context.Context, id string, snapshot *PlaytimeSnapshot) error
+	SetYearPlaytime(ctx context.Context, id string, playtime *YearPlaytime) error
+	GetYearPlaytimes(ctx context.Context, year int) (map[string]int, error)
+	GetPublicPlaytimes(ctx context.Context) ([]int, error)
+	GetPublicUserIDs(ctx context.Context) ([]string, error)
+	SetUsername(ctx context.Context, user *User) error
+	GetLookalikes(ctx context.Context, skeleton string) ([]string, error)
+	SetDeleted(ctx context.Context, id string, deletedAt, purgeAt int64) error
+	DeleteUser(ctx context.Context, id string) (map[string]int, error)
+	DeleteFieldsFromUser(ctx context.Context, id string, fields []string) error
+	JobStore
+	AuditLog
+}
+
+// JobStore contains the functions a database should provide to store the job queue
+type JobStore interface {
+	CreateJob(ctx context.Context, job *Job) error
+	GetJob(ctx context.Context, id string) (*Job, error)
+	UpdateJob(ctx context.Context, job *Job) error
+	ClaimJob(ctx context.Context, now, until int64) (*Job, error)
+	AddDeadLetter(ctx context.Context, job *Job) error
+	GetDeadLetters(ctx context.Context) ([]Job, error)
+}
+
+// AuditLog contains the functions a database should provide to keep a record of the deletions of users, which is
+// kept after the users are purged
+type AuditLog interface {
+	AddAuditRecord(ctx context.Context, record *AuditRecord) error
+	GetAuditRecords(ctx context.Context) ([]AuditRecord, error)
 }
 
 // UserValidator defines the function "IsUser", which checks
 // whether or not the given id is a valid user stored in the database
 type UserValidator interface {
-	IsUser(id string) (bool, error)
+	IsUser(ctx context.Context, id string) (bool, error)
 }
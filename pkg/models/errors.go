@@ -9,6 +9,7 @@ import (
 // RequestError indicates the user has made an error in their request
 type RequestError struct {
 	Response string // Response is used to provide feedback to the user about why the request was bad
+	Code     string // Code is an optional machine readable code for the problem, defaulting to CodeBadRequest
 	Err      error
 }
 
@@ -47,6 +48,12 @@ func AccValStatusCode(code int, api, clientResp string) error {
 	return &RequestError{Err: fmt.Errorf("non 200 statuscode from external API: %s (%d)", api, code), Response: clientResp}
 }
 
+// JobPendingError is returned when a job has not finished within the time the request waits for it.
+// The job is still queued or running, and can be followed until it is finished.
+type JobPendingError struct {
+	Job *Job
+}
+
 // Specific errors:
 
 // ErrNotFound indicates that a requested resource was not found
@@ -55,6 +62,18 @@ var ErrNotFound = errors.New("not found")
 // ErrInvalidID indicates the id is invalid and should not be accepted
 var ErrInvalidID = errors.New("invalid id")
 
+// ErrPreconditionFailed indicates that the resource has been modified since the version the request is based on
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrUserDeleted indicates that the user is pending deletion, and can not change anything until they are restored
+var ErrUserDeleted = errors.New("user pending deletion")
+
+// ErrUnsupportedMediaType indicates that the content type of the request body is not supported by the route
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// ErrTokenExpired indicates that the access token for an account linked through OAuth has expired
+var ErrTokenExpired = errors.New("access token expired")
+
 // ErrInvalidAuthState defines the error returned if the state for the authentication request does not match the state stored in the cookie
 var ErrInvalidAuthState = errors.New("invalid authorization state")
 
@@ -80,6 +99,15 @@ func (e *RequestError) Unwrap() error { return e.Err }
 
 func (e *RequestError) Error() string { return e.Err.Error() + ": " + e.Response }
 
+// code returns the machine readable code for the error
+func (e *RequestError) code() string {
+	if e.Code == "" {
+		return CodeBadRequest
+	}
+
+	return e.Code
+}
+
 // Respond returns a string suitable to respond to the user
 func (e *ExternalAPIError) Respond() string { return fmt.Sprintf("Error contacting %s API", e.API) }
 
@@ -89,3 +117,5 @@ func (e *ExternalAPIError) Unwrap() error { return e.Err }
 func (e *ExternalAPIError) Error() string {
 	return "error contacting external API " + e.API + ": " + e.Err.Error()
 }
+
+func (e *JobPendingError) Error() string { return fmt.Sprintf("job %s is %s", e.Job.ID, e.Job.Status) }
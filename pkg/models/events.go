@@ -0,0 +1,87 @@
+package models
+
+// The types of the events sent to the user over the event stream
+const (
+	EventJob   = "job"   // a job of the user has changed, e.g. a provider has been fetched
+	EventTotal = "total" // the total playtime of a user has changed
+)
+
+// The statuses of a job
+const (
+	JobQueued  = "queued" // waiting for a worker, either to run for the first time or to be retried
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// The types of the jobs
+const (
+	JobRefresh  = "refresh"  // updates the user's games
+	JobValidate = "validate" // validates the accounts given by the user, stores them and updates the games if they changed
+	JobPurge    = "purge"    // purges a deleted user and all of their data once the grace period is over
+)
+
+// The statuses of the providers fetched by a refresh
+const (
+	ProviderDone   = "done"
+	ProviderKept   = "kept" // the games from the last update are kept, e.g. as the steam profile is private
+	ProviderFailed = "failed"
+)
+
+// Event is sent to the subscribers of a user when something happens to the user
+type Event struct {
+	Type   string      // one of the event types, e.g. EventJob
+	UserID string      // the user the event happened to
+	Data   interface{} // the content of the event, e.g. a *Job for EventJob
+}
+
+// Job is work done in the background for the user, such as updating their games.
+// Jobs are stored in the database until they are run by a worker, and are retried if they fail for a reason which
+// may be temporary. Every change to the job is sent to the user as an event, ending with the job being done or failed.
+type Job struct {
+	ID        string             `json:"id" firestore:"id"`
+	Type      string             `json:"type" firestore:"type"`           // e.g. JobRefresh
+	Status    string             `json:"status" firestore:"status"`       // JobQueued, JobRunning, JobDone or JobFailed
+	Attempts  int                `json:"attempts" firestore:"attempts"`   // the number of times the job has been started
+	Providers []ProviderProgress `json:"providers" firestore:"providers"` // the providers fetched so far, in the order they are fetched
+	Result    *JobResult         `json:"result,omitempty" firestore:"result"`
+	Error     *Problem           `json:"error,omitempty" firestore:"error"` // why the job failed, or the last attempt failed if queued
+	CreatedAt int64              `json:"createdAt" firestore:"createdAt"`   // unix time
+	UpdatedAt int64              `json:"updatedAt" firestore:"updatedAt"`   // unix time
+
+	UserID    string `json:"-" firestore:"userId"`
+	RequestID string `json:"-" firestore:"requestId"`       // the request which created the job, used when logging
+	RunAt     int64  `json:"-" firestore:"runAt,omitempty"` // unix milliseconds the job is due, or its worker's lease expires. Not set when finished
+
+	// User contains the changes given to a JobValidate, which are cleared as soon as they are stored.
+	// If Replace is set, the user replaces the stored user, as long as it still has the Version.
+	User    *User `json:"-" firestore:"user,omitempty"`
+	Replace bool  `json:"-" firestore:"replace"`
+	Version int64 `json:"-" firestore:"version"`
+}
+
+// Finished returns true if the job is done or failed
+func (j *Job) Finished() bool {
+	return j.Status == JobDone || j.Status == JobFailed
+}
+
+// ProviderProgress is the outcome of fetching the games from a provider in a refresh
+type ProviderProgress struct {
+	Provider string   `json:"provider"` // lol, overwatch, valve, runescape or battlenet
+	Status   string   `json:"status"`   // ProviderDone, ProviderKept or ProviderFailed
+	Games    int      `json:"games"`
+	Error    *Problem `json:"error,omitempty"`
+}
+
+// JobResult is the outcome of a refresh which is done
+type JobResult struct {
+	TotalPlayTime int `json:"totalPlayTime"` // hours
+	Games         int `json:"games"`
+}
+
+// TotalChange is the data of an EventTotal: the new total playtime of a public user
+type TotalChange struct {
+	Name          string `json:"name"`
+	TotalPlayTime int    `json:"totalPlayTime"` // hours
+	Previous      int    `json:"previous"`      // the total playtime before the change
+}
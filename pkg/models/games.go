@@ -0,0 +1,162 @@
+package models
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GameQuery describes how a list of games should be filtered and sorted
+type GameQuery struct {
+	Sort       string // the JSON name of the field to sort by, empty keeps the order
+	Descending bool
+	Name       string // only games with a name containing Name (case insensitive)
+	Platform   string // only games played on the platform (windows, mac, linux or deck)
+	MinMinutes int    // only games played at least MinMinutes
+	Recent     bool   // only games played the last two weeks
+}
+
+// gameSorts contains the "less" function for each of the fields games can be sorted by
+var gameSorts = map[string]func(a, b *Game) bool{
+	"game":            func(a, b *Game) bool { return strings.ToLower(a.Name) < strings.ToLower(b.Name) },
+	"playTime":        func(a, b *Game) bool { return a.Time < b.Time }, // whole hours, games with the same hours keep their order
+	"lastPlayed":      func(a, b *Game) bool { return a.LastPlayed < b.LastPlayed },
+	"playTime2Weeks":  func(a, b *Game) bool { return a.RecentMinutes < b.RecentMinutes },
+	"achievements":    func(a, b *Game) bool { return a.Achievements.progress() < b.Achievements.progress() },
+	"appId":           func(a, b *Game) bool { return a.AppID < b.AppID },
+	"playTimeMinutes": func(a, b *Game) bool { return a.PlayMinutes() < b.PlayMinutes() },
+}
+
+// ParseGameQuery parses the query parameters "sort" (prefixed with "-" for descending order),
+// "name", "platform", "minPlayTime" (minutes) and "recent".
+func ParseGameQuery(values url.Values) (*GameQuery, error) {
+	q := &GameQuery{Name: values.Get("name"), Platform: values.Get("platform")}
+
+	q.Sort = values.Get("sort")
+	if strings.HasPrefix(q.Sort, "-") {
+		q.Sort = strings.TrimPrefix(q.Sort, "-")
+		q.Descending = true
+	}
+
+	if _, ok := gameSorts[q.Sort]; q.Sort != "" && !ok {
+		return nil, NewReqErrStr("invalid sort", "invalid sort: "+q.Sort)
+	}
+
+	switch q.Platform {
+	case "", "windows", "mac", "linux", "deck":
+	default:
+		return nil, NewReqErrStr("invalid platform", "invalid platform: "+q.Platform)
+	}
+
+	var err error
+	if minPlayTime := values.Get("minPlayTime"); minPlayTime != "" {
+		q.MinMinutes, err = strconv.Atoi(minPlayTime)
+		if err != nil {
+			return nil, NewReqErr(err, "invalid minPlayTime")
+		}
+	}
+
+	if recent := values.Get("recent"); recent != "" {
+		q.Recent, err = strconv.ParseBool(recent)
+		if err != nil {
+			return nil, NewReqErr(err, "invalid recent")
+		}
+	}
+
+	return q, nil
+}
+
+// Apply returns the games matching the query, sorted as specified by the query. The given slice is not modified.
+func (q *GameQuery) Apply(games []Game) []Game {
+	result := []Game{}
+
+	for i := range games {
+		if q.matches(&games[i]) {
+			result = append(result, games[i])
+		}
+	}
+
+	if less, ok := gameSorts[q.Sort]; ok {
+		sort.SliceStable(result, func(i, j int) bool {
+			if q.Descending {
+				return less(&result[j], &result[i])
+			}
+
+			return less(&result[i], &result[j])
+		})
+	}
+
+	return result
+}
+
+// matches checks whether the game matches the filters of the query
+func (q *GameQuery) matches(game *Game) bool {
+	if q.Name != "" && !strings.Contains(strings.ToLower(game.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+
+	if q.MinMinutes > 0 && game.PlayMinutes() < q.MinMinutes {
+		return false
+	}
+
+	if q.Recent && game.RecentMinutes == 0 {
+		return false
+	}
+
+	return q.Platform == "" || game.Platforms.minutes(q.Platform) > 0
+}
+
+// PlayMinutes returns the minutes the game has been played. Providers without minutes only have the hours.
+func (g *Game) PlayMinutes() int {
+	if g.Minutes != 0 {
+		return g.Minutes
+	}
+
+	return g.Time * 60
+}
+
+// PlaytimeSelfReported returns true if the playtime of the game is recorded manually: the game is self-reported, or
+// the playtime of the game merged from several entries is the playtime of a manual entry
+func (g *Game) PlaytimeSelfReported() bool {
+	if g.SelfReported {
+		return true
+	}
+
+	for _, entry := range g.Merged {
+		if entry.Source == ManualSource && entry.Minutes >= g.PlayMinutes() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// minutes returns the minutes played on the platform
+func (p *PlatformPlaytime) minutes(platform string) int {
+	if p == nil {
+		return 0
+	}
+
+	switch platform {
+	case "windows":
+		return p.Windows
+	case "mac":
+		return p.Mac
+	case "linux":
+		return p.Linux
+	case "deck":
+		return p.Deck
+	}
+
+	return 0
+}
+
+// progress returns the fraction of the achievements which are unlocked
+func (a *Achievements) progress() float64 {
+	if a == nil || a.Total == 0 {
+		return 0
+	}
+
+	return float64(a.Unlocked) / float64(a.Total)
+}
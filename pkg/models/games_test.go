@@ -0,0 +1,94 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGameQuery(t *testing.T) {
+	var cases = []struct {
+		name        string
+		query       string
+		expected    *GameQuery
+		expectedErr bool
+	}{
+		{"Test empty query", "", &GameQuery{}, false},
+		{"Test ascending sort", "sort=lastPlayed", &GameQuery{Sort: "lastPlayed"}, false},
+		{"Test descending sort", "sort=-playTime", &GameQuery{Sort: "playTime", Descending: true}, false},
+		{"Test filters", "name=test&platform=deck&minPlayTime=30&recent=true",
+			&GameQuery{Name: "test", Platform: "deck", MinMinutes: 30, Recent: true}, false},
+		{"Test invalid sort", "sort=test", nil, true},
+		{"Test invalid platform", "platform=amiga", nil, true},
+		{"Test invalid minPlayTime", "minPlayTime=test", nil, true},
+		{"Test invalid recent", "recent=test", nil, true},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			assert.Nil(t, err)
+
+			q, err := ParseGameQuery(values)
+			if tc.expectedErr {
+				assert.IsType(t, &RequestError{}, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, q)
+		})
+	}
+}
+
+func TestGameQueryApply(t *testing.T) {
+	games := []Game{
+		{Name: "Portal", Time: 10, Minutes: 600, LastPlayed: 300, Platforms: &PlatformPlaytime{Linux: 600},
+			Achievements: &Achievements{Unlocked: 5, Total: 10}},
+		{Name: "Dota 2", Time: 2, Minutes: 150, RecentMinutes: 30, LastPlayed: 500, Platforms: &PlatformPlaytime{Windows: 150}},
+		{Name: "LeagueOfLegends", Time: 5},
+		{Name: "portal 2", Minutes: 20, LastPlayed: 100, Platforms: &PlatformPlaytime{Deck: 20},
+			Achievements: &Achievements{Unlocked: 10, Total: 10}},
+	}
+
+	var cases = []struct {
+		name     string
+		query    GameQuery
+		expected []string
+	}{
+		{"Test no query", GameQuery{}, []string{"Portal", "Dota 2", "LeagueOfLegends", "portal 2"}},
+		{"Test sort by playtime", GameQuery{Sort: "playTime", Descending: true},
+			[]string{"Portal", "LeagueOfLegends", "Dota 2", "portal 2"}},
+		{"Test sort by name", GameQuery{Sort: "game"}, []string{"Dota 2", "LeagueOfLegends", "Portal", "portal 2"}},
+		{"Test sort by last played", GameQuery{Sort: "lastPlayed", Descending: true},
+			[]string{"Dota 2", "Portal", "portal 2", "LeagueOfLegends"}},
+		{"Test sort by achievements", GameQuery{Sort: "achievements", Descending: true},
+			[]string{"portal 2", "Portal", "Dota 2", "LeagueOfLegends"}},
+		{"Test filter by name", GameQuery{Name: "PORTAL"}, []string{"Portal", "portal 2"}},
+		{"Test filter by platform", GameQuery{Platform: "linux"}, []string{"Portal"}},
+		{"Test filter by playtime", GameQuery{MinMinutes: 200}, []string{"Portal", "LeagueOfLegends"}},
+		{"Test filter by recent", GameQuery{Recent: true}, []string{"Dota 2"}},
+		{"Test no matches", GameQuery{Name: "test"}, []string{}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			names := []string{}
+			for _, g := range tc.query.Apply(games) {
+				names = append(names, g.Name)
+			}
+
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+
+	assert.Equal(t, "Portal", games[0].Name, "the given games should not be modified")
+
+	// games with the same whole hours keep their order when sorted by playTime, unlike by playTimeMinutes
+	tied := []Game{{Name: "Portal", Time: 1, Minutes: 70}, {Name: "Dota 2", Time: 1, Minutes: 90}}
+	assert.Equal(t, "Portal", (&GameQuery{Sort: "playTime", Descending: true}).Apply(tied)[0].Name)
+	assert.Equal(t, "Dota 2", (&GameQuery{Sort: "playTimeMinutes", Descending: true}).Apply(tied)[0].Name)
+}
@@ -1,13 +1,35 @@
 package models
 
 import (
+	"context"
 	"net/http"
 )
 
 // Getter is an interface which defines all methods a Getter should provide
 // namely, getting a resource based on the given url.
-// http.Client is intended to fulfill the interface, but allow for testing without sending requests to another API
+// The request should be cancelled if the context is cancelled (e.g. the client disconnects or the server shuts down).
+// HTTPGetter is intended to fulfill the interface, but allow for testing without sending requests to another API
 //  Similar to client interface, but does not handle auth parameter
 type Getter interface {
-	Get(url string) (resp *http.Response, err error)
+	Get(ctx context.Context, url string) (resp *http.Response, err error)
+}
+
+// HTTPGetter fulfills the Getter interface by sending GET requests with a Client (typically a http.Client)
+type HTTPGetter struct {
+	Client
+}
+
+// NewGetter returns a new HTTPGetter using the given client
+func NewGetter(client Client) *HTTPGetter {
+	return &HTTPGetter{client}
+}
+
+// Get sends a GET request to the url, bound to the context
+func (g *HTTPGetter) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return g.Do(req)
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockClient struct {
+	req *http.Request
+	err error
+}
+
+func (m *mockClient) Do(req *http.Request) (*http.Response, error) {
+	m.req = req
+	return &http.Response{StatusCode: http.StatusOK}, m.err
+}
+
+func TestHTTPGetter(t *testing.T) {
+	var cases = []struct {
+		name        string
+		url         string
+		clientErr   error
+		expectedErr bool
+	}{
+		{"Test ok", "http://localhost/test", nil, false},
+		{"Test client error", "http://localhost/test", errors.New("test"), true},
+		{"Test invalid url", "http://[::1", nil, true},
+	}
+
+	type key string
+	client := &mockClient{}
+	getter := NewGetter(client)
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			client.req = nil
+			client.err = tc.clientErr
+			ctx := context.WithValue(context.Background(), key("test"), tc.name)
+
+			_, err := getter.Get(ctx, tc.url)
+			assert.Equal(t, tc.expectedErr, err != nil)
+			if client.req != nil {
+				// the request should be bound to the given context
+				assert.Equal(t, tc.name, client.req.Context().Value(key("test")))
+				assert.Equal(t, http.MethodGet, client.req.Method)
+				assert.Equal(t, tc.url, client.req.URL.String())
+			}
+		})
+	}
+}
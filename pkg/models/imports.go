@@ -0,0 +1,30 @@
+package models
+
+// The formats of the files exported from launchers, which can be imported
+const (
+	ImportGOG      = "gog"      // CSV export of the GOG Galaxy database
+	ImportPlaynite = "playnite" // JSON export of a Playnite library, e.g. with Epic Games Store games
+	ImportCSV      = "csv"      // generic CSV, with a name and playtime for each game
+)
+
+// ImportFormats are the formats of the files which can be imported
+var ImportFormats = []string{ImportGOG, ImportPlaynite, ImportCSV}
+
+// ImportedLibrary contains the games imported from a file exported from a launcher.
+// Importing a file replaces the library imported earlier in the same format.
+// The games are added to the user's games (and total playtime) whenever the games are updated.
+type ImportedLibrary struct {
+	Format     string `json:"format" firestore:"format"`
+	ImportedAt int64  `json:"importedAt" firestore:"importedAt"` // unix time
+	Games      []Game `json:"games" firestore:"games"`
+}
+
+// ProviderGames are the games last fetched from a provider whose games are kept while they can't be fetched (Steam,
+// while the profile is private, and Battle.net, while the token is expired). They are stored as fetched, before they
+// are merged with the games of the other providers.
+type ProviderGames struct {
+	Provider  string `firestore:"provider"`  // valve or battlenet
+	Account   string `firestore:"account"`   // the id of the account the games were fetched for
+	FetchedAt int64  `firestore:"fetchedAt"` // unix time
+	Games     []Game `firestore:"games"`
+}
@@ -1,14 +1,24 @@
 package models
 
+import "context"
+
 // Jagex interface defines all methods which should be provided by jagex
 type Jagex interface {
-	GetRSPlaytime(rsAcc *RunescapeAccount) (*Game, error)
-	ValidateRSAccount(rsAcc *RunescapeAccount) error
+	GetRSPlaytime(ctx context.Context, rsAcc *RunescapeAccount) (*Game, error)
+	ValidateRSAccount(ctx context.Context, rsAcc *RunescapeAccount) error
 }
 
-// RunescapeAccount contains relevant information about a runescape account
+// The Runescape games an account can be linked for
+const (
+	OldSchool  = "oldschool"
+	RuneScape3 = "rs3"
+)
+
+// RunescapeAccount contains relevant information about a runescape account.
+// The game is either OldSchool (the default, also for accounts linked before RuneScape 3 was supported) or RuneScape3.
 type RunescapeAccount struct {
 	Username    string `json:"username" firebase:"username"`
+	Game        string `json:"game" firebase:"game"`
 	AccountType string `json:"accountType" firebase:"accountType"`
 	TotalLevel  int    `json:"totalLevel" firebase:"totalLevel"`
 	TotalXP     int    `json:"totalXP" firebase:"totalXP"`
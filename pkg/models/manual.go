@@ -0,0 +1,58 @@
+package models
+
+// ManualSource is the source of the games recorded manually by the user
+const ManualSource = "manual"
+
+// ManualPlatforms are the platforms a manual game can be played on
+var ManualPlatforms = []string{"pc", "playstation", "xbox", "switch", "mobile", "other"}
+
+// ManualGame is a game the user records the playtime of themselves, for games without an API (e.g. on consoles,
+// or played offline). Manual games are stored apart from the games fetched from the providers, such that updating
+// the games does not overwrite them, and are added to the user's games flagged as self-reported.
+// The playtime is the hours plus the minutes of the sessions.
+type ManualGame struct {
+	ID        string          `json:"id" firestore:"id"`
+	Name      string          `json:"name" firestore:"name"`
+	Platform  string          `json:"platform" firestore:"platform"` // one of ManualPlatforms
+	Hours     float64         `json:"hours" firestore:"hours"`       // the playtime not recorded as sessions
+	Sessions  []ManualSession `json:"sessions,omitempty" firestore:"sessions"`
+	UpdatedAt int64           `json:"updatedAt" firestore:"updatedAt"` // unix time
+}
+
+// ManualSession is a session the user has played a manual game
+type ManualSession struct {
+	Date    int64 `json:"date" firestore:"date"` // unix time
+	Minutes int   `json:"minutes" firestore:"minutes"`
+}
+
+// Minutes returns the minutes the manual game has been played
+func (g *ManualGame) Minutes() int {
+	minutes := int(g.Hours*60 + 0.5)
+	for _, session := range g.Sessions {
+		minutes += session.Minutes
+	}
+
+	return minutes
+}
+
+// Game returns the manual game as a game of the user, flagged as self-reported
+func (g *ManualGame) Game() Game {
+	var lastPlayed int64
+	for _, session := range g.Sessions {
+		if session.Date > lastPlayed {
+			lastPlayed = session.Date
+		}
+	}
+
+	minutes := g.Minutes()
+
+	return Game{
+		Name:         g.Name,
+		Time:         minutes / 60,
+		Minutes:      minutes,
+		LastPlayed:   lastPlayed,
+		Source:       ManualSource,
+		Platform:     g.Platform,
+		SelfReported: true,
+	}
+}
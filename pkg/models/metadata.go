@@ -0,0 +1,78 @@
+package models
+
+import (
+	"context"
+	"sort"
+)
+
+// GameMetadata contains information about a game from a metadata source (e.g. the Steam store),
+// such as its genres and cover image. Empty metadata means the source does not know the game.
+type GameMetadata struct {
+	Genres      []string `json:"genres" firestore:"genres"`
+	Developer   string   `json:"developer,omitempty" firestore:"developer"`
+	Cover       string   `json:"cover,omitempty" firestore:"cover"` // url of the cover image
+	ReleaseYear int      `json:"releaseYear,omitempty" firestore:"releaseYear"`
+	FetchedAt   int64    `json:"-" firestore:"fetchedAt"` // unix time, used to refresh the cached metadata
+}
+
+// Empty checks whether the metadata contains no information about the game
+func (m *GameMetadata) Empty() bool {
+	return len(m.Genres) == 0 && m.Developer == "" && m.Cover == "" && m.ReleaseYear == 0
+}
+
+// MetadataSource is a source of game metadata, where the games are identified by their external id for the source's
+// provider, e.g. the app id for the Steam store. Returns ErrNotFound if the source does not know the game.
+type MetadataSource interface {
+	Provider() string // the provider of the external ids, as in CatalogGame.ExternalIDs
+	GetMetadata(ctx context.Context, id string) (*GameMetadata, error)
+}
+
+// MetadataCache stores the metadata fetched from a metadata source, by the provider and external id of the game
+type MetadataCache interface {
+	GetMetadata(ctx context.Context, key string) (*GameMetadata, error)
+	SetMetadata(ctx context.Context, key string, metadata *GameMetadata) error
+}
+
+// GenrePlaytime contains the playtime of the games of a genre
+type GenrePlaytime struct {
+	Genre   string `json:"genre"`
+	Minutes int    `json:"minutes"`
+	Games   int    `json:"games"`
+}
+
+// PlaytimeByGenre returns the playtime of each genre of the games, sorted by the playtime (and then the genre).
+// Games with several genres count for each of them, and games without metadata are left out.
+func PlaytimeByGenre(games []Game) []GenrePlaytime {
+	genres := make(map[string]*GenrePlaytime)
+	for i := range games {
+		if games[i].Metadata == nil {
+			continue
+		}
+
+		for _, genre := range games[i].Metadata.Genres {
+			g, ok := genres[genre]
+			if !ok {
+				g = &GenrePlaytime{Genre: genre}
+				genres[genre] = g
+			}
+
+			g.Minutes += games[i].PlayMinutes()
+			g.Games++
+		}
+	}
+
+	result := make([]GenrePlaytime, 0, len(genres))
+	for _, g := range genres {
+		result = append(result, *g)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Minutes != result[j].Minutes {
+			return result[i].Minutes > result[j].Minutes
+		}
+
+		return result[i].Genre < result[j].Genre
+	})
+
+	return result
+}
@@ -0,0 +1,48 @@
+package models
+
+import "encoding/json"
+
+// MergePatchContentType is the content type of JSON merge patches
+const MergePatchContentType = "application/merge-patch+json"
+
+// MergePatch applies the JSON merge patch to the JSON document, as described by RFC 7396 (https://tools.ietf.org/html/rfc7396).
+// Members set to null in the patch are removed from the document, objects are merged recursively and every other value is replaced.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+
+	err := json.Unmarshal(doc, &target)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(patch, &p)
+	if err != nil {
+		return nil, NewReqErr(err, "invalid merge patch")
+	}
+
+	return json.Marshal(mergePatch(target, p))
+}
+
+// mergePatch recursively applies the patch to the target
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+
+		t[k] = mergePatch(t[k], v)
+	}
+
+	return t
+}
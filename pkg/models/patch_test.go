@@ -0,0 +1,48 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The cases are the examples from RFC 7396, appendix A
+func TestMergePatch(t *testing.T) {
+	var cases = []struct {
+		name        string
+		doc         string
+		patch       string
+		expected    string
+		expectedErr bool
+	}{
+		{"Test replace value", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`, false},
+		{"Test add value", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`, false},
+		{"Test remove value", `{"a":"b"}`, `{"a":null}`, `{}`, false},
+		{"Test remove one of several values", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`, false},
+		{"Test replace array", `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`, false},
+		{"Test replace with array", `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`, false},
+		{"Test nested object", `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`, false},
+		{"Test arrays are not merged", `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`, false},
+		{"Test non-object patch", `{"a":"foo"}`, `"bar"`, `"bar"`, false},
+		{"Test null patch", `{"a":"foo"}`, `null`, `null`, false},
+		{"Test patch non-object", `{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`, false},
+		{"Test nested null is removed", `{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`, false},
+		{"Test invalid patch", `{}`, `{ this is not valid }`, "", true},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := MergePatch([]byte(tc.doc), []byte(tc.patch))
+			if tc.expectedErr {
+				var reqErr *RequestError
+				assert.True(t, errors.As(err, &reqErr))
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.JSONEq(t, tc.expected, string(result))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ProblemContentType is the content type used for error responses
+const ProblemContentType = "application/problem+json"
+
+// StatusClientClosedRequest is the (non-standard) status of a request cancelled before it was handled, as the client
+// went away or the server shut down. It is not a failure of the server.
+const StatusClientClosedRequest = 499
+
+// Machine readable codes for the problems returned to the user
+const (
+	CodeBadRequest         = "bad_request"
+	CodeForbidden          = "forbidden"
+	CodeUserDeleted        = "user_deleted"
+	CodeNotFound           = "not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodePreconditionFailed = "precondition_failed"
+	CodeUnsupportedMedia   = "unsupported_media_type"
+	CodeInvalidAuthState   = "invalid_auth_state"
+	CodeExternalAPIError   = "external_api_error"
+	CodeExternalAPITimeout = "external_api_timeout"
+	CodeCancelled          = "request_cancelled"
+	CodeInternalError      = "internal_error"
+)
+
+// Problem is an error response as described by RFC 7807 (https://tools.ietf.org/html/rfc7807).
+// Code, Provider, UpstreamStatus and RequestID are extension members.
+type Problem struct {
+	Type           string `json:"type"`
+	Title          string `json:"title"`
+	Status         int    `json:"status"`
+	Detail         string `json:"detail,omitempty"`
+	Instance       string `json:"instance,omitempty"`
+	Code           string `json:"code"`
+	Provider       string `json:"provider,omitempty"`       // the external API which failed, if any
+	UpstreamStatus int    `json:"upstreamStatus,omitempty"` // the status code returned by the external API, if any
+	RequestID      string `json:"requestId,omitempty"`
+}
+
+// NewProblem returns a new problem with the given status, code and detail
+func NewProblem(status int, code, detail string) *Problem {
+	return &Problem{Type: "about:blank", Title: statusText(status), Status: status, Code: code, Detail: detail}
+}
+
+// statusText returns the text of the status code, including StatusClientClosedRequest
+func statusText(status int) string {
+	if status == StatusClientClosedRequest {
+		return "Client Closed Request"
+	}
+
+	return http.StatusText(status)
+}
+
+// ProblemFromError returns a problem describing the error, suitable to respond to the user
+func ProblemFromError(err error) *Problem {
+	var problem *Problem
+	var reqErr *RequestError
+	var apiErr *ExternalAPIError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &problem): // e.g. the error of a job, which has been responded to the user before
+		c := *problem
+		return &c
+	case errors.Is(err, ErrInvalidID):
+		return NewProblem(http.StatusForbidden, CodeForbidden, "")
+	case errors.Is(err, ErrUserDeleted):
+		return NewProblem(http.StatusForbidden, CodeUserDeleted, "the user has been deleted, and has to be restored first")
+	case errors.Is(err, ErrNotFound):
+		return NewProblem(http.StatusNotFound, CodeNotFound, "")
+	case errors.Is(err, ErrPreconditionFailed):
+		return NewProblem(http.StatusPreconditionFailed, CodePreconditionFailed, "the resource has been modified")
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return NewProblem(http.StatusUnsupportedMediaType, CodeUnsupportedMedia, "")
+	case errors.Is(err, ErrInvalidAuthState):
+		return NewProblem(http.StatusBadRequest, CodeInvalidAuthState, "")
+	case errors.As(err, &reqErr):
+		return NewProblem(http.StatusBadRequest, reqErr.code(), reqErr.Response)
+	case errors.As(err, &apiErr):
+		p := NewProblem(http.StatusBadGateway, CodeExternalAPIError, apiErr.Respond())
+		p.Provider = apiErr.API
+		p.UpstreamStatus = apiErr.Code
+
+		return p
+	case errors.Is(err, context.Canceled):
+		return NewProblem(StatusClientClosedRequest, CodeCancelled, "")
+	case errors.As(err, &netErr): // includes context.DeadlineExceeded
+		if netErr.Timeout() {
+			return NewProblem(http.StatusGatewayTimeout, CodeExternalAPITimeout, "")
+		}
+
+		return NewProblem(http.StatusBadGateway, CodeExternalAPIError, "")
+	}
+
+	return NewProblem(http.StatusInternalServerError, CodeInternalError, "")
+}
+
+// Error returns the detail of the problem, or its title if there is none
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+
+	return p.Detail
+}
+
+// WriteProblem writes the problem as an application/problem+json response,
+// filling in the request path and request id (if any)
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	p.Instance = r.URL.Path
+	if info := GetRequestInfo(r.Context()); info != nil {
+		p.RequestID = info.ID
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+
+	err := json.NewEncoder(w).Encode(p)
+	if err != nil {
+		Log(r.Context()).WithError(err).Warn("Could not encode problem")
+	}
+}
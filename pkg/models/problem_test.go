@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockNetErr struct {
+	timeout bool
+}
+
+func (m *mockNetErr) Error() string   { return "test" }
+func (m *mockNetErr) Timeout() bool   { return m.timeout }
+func (m *mockNetErr) Temporary() bool { return false }
+
+func TestProblemFromError(t *testing.T) {
+	var cases = []struct {
+		name             string
+		err              error
+		expectedStatus   int
+		expectedCode     string
+		expectedProvider string
+		expectedDetail   string
+	}{
+		{"Test invalid id", ErrInvalidID, http.StatusForbidden, CodeForbidden, "", ""},
+		{"Test user deleted", ErrUserDeleted, http.StatusForbidden, CodeUserDeleted, "",
+			"the user has been deleted, and has to be restored first"},
+		{"Test not found", ErrNotFound, http.StatusNotFound, CodeNotFound, "", ""},
+		{"Test wrapped not found", fmt.Errorf("test: %w", ErrNotFound), http.StatusNotFound, CodeNotFound, "", ""},
+		{"Test precondition failed", ErrPreconditionFailed, http.StatusPreconditionFailed, CodePreconditionFailed, "",
+			"the resource has been modified"},
+		{"Test unsupported media type", ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, CodeUnsupportedMedia, "", ""},
+		{"Test invalid auth state", ErrInvalidAuthState, http.StatusBadRequest, CodeInvalidAuthState, "", ""},
+		{"Test request error", NewReqErrStr("test", "invalid test"), http.StatusBadRequest, CodeBadRequest, "", "invalid test"},
+		{"Test request error with code", &RequestError{Err: errors.New("test"), Response: "private", Code: "private"},
+			http.StatusBadRequest, "private", "", "private"},
+		{"Test external API error", &ExternalAPIError{Err: errors.New("test"), API: "Valve", Code: http.StatusForbidden},
+			http.StatusBadGateway, CodeExternalAPIError, "Valve", "Error contacting Valve API"},
+		{"Test timeout", &mockNetErr{timeout: true}, http.StatusGatewayTimeout, CodeExternalAPITimeout, "", ""},
+		{"Test deadline exceeded", context.DeadlineExceeded, http.StatusGatewayTimeout, CodeExternalAPITimeout, "", ""},
+		{"Test network error", &mockNetErr{}, http.StatusBadGateway, CodeExternalAPIError, "", ""},
+		{"Test cancelled", fmt.Errorf("test: %w", context.Canceled), StatusClientClosedRequest, CodeCancelled, "", ""},
+		{"Test unexpected error", errors.New("test"), http.StatusInternalServerError, CodeInternalError, "", ""},
+		{"Test problem", fmt.Errorf("test: %w", &Problem{Title: "Bad Gateway", Status: http.StatusBadGateway,
+			Code: CodeExternalAPIError, Provider: "Valve"}), http.StatusBadGateway, CodeExternalAPIError, "Valve", ""},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := ProblemFromError(tc.err)
+			assert.Equal(t, tc.expectedStatus, p.Status)
+			assert.Equal(t, statusText(tc.expectedStatus), p.Title)
+			assert.NotEmpty(t, p.Title)
+			assert.Equal(t, tc.expectedCode, p.Code)
+			assert.Equal(t, tc.expectedProvider, p.Provider)
+			assert.Equal(t, tc.expectedDetail, p.Detail)
+		})
+	}
+}
+
+func TestWriteProblem(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/test", nil)
+	require.Nil(t, err)
+	req = req.WithContext(WithRequestInfo(req.Context(), &RequestInfo{ID: "test-id"}))
+
+	w := httptest.NewRecorder()
+	WriteProblem(w, req, NewProblem(http.StatusNotFound, CodeNotFound, "test"))
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, ProblemContentType, resp.Header.Get("Content-Type"))
+
+	var p Problem
+	err = json.NewDecoder(resp.Body).Decode(&p)
+	require.Nil(t, err)
+	assert.Equal(t, Problem{Type: "about:blank", Title: "Not Found", Status: http.StatusNotFound, Detail: "test",
+		Instance: "/api/v1/test", Code: CodeNotFound, RequestID: "test-id"}, p)
+}
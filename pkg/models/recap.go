@@ -0,0 +1,28 @@
+package models
+
+// Recap is the summary of the user's playtime in a year, derived from the history of their playtime.
+// The history may not cover the whole year, e.g. if the user signed up during the year, in which case the recap
+// is based on the history from the first day recorded.
+type Recap struct {
+	Year        int            `json:"year"`
+	Name        string         `json:"name"`        // the display name of the user, or their handle
+	Minutes     int            `json:"minutes"`     // the playtime gained in the year
+	Games       []GamePlaytime `json:"games"`       // the playtime gained in each game, most played first
+	NewGames    []string       `json:"newGames"`    // the games added in the year, most played first
+	BiggestWeek *RecapWeek     `json:"biggestWeek"` // nil if no playtime was gained
+	Rank        *Rank          `json:"rank"`        // nil if there are no other users to rank among
+	From        string         `json:"from"`        // the first day of the history the recap is based on
+	To          string         `json:"to"`          // the last day of the history the recap is based on
+}
+
+// RecapWeek is the week with the most playtime gained in a year
+type RecapWeek struct {
+	Start   string `json:"start"` // the monday the week starts, formatted as 2006-01-02
+	Minutes int    `json:"minutes"`
+}
+
+// Rank is the position of the user among the public users, by the playtime gained in the year of the recap
+type Rank struct {
+	Rank  int `json:"rank"`  // 1 for the most playtime gained
+	Users int `json:"users"` // the number of users ranked, including the user
+}
@@ -1,10 +1,12 @@
 package models
 
+import "context"
+
 // Riot interface defines all methods which should be provided by riot
 type Riot interface {
-	GetLolPlaytime(reg *SummonerRegistration) (*Game, error)
-	ValidateSummoner(reg *SummonerRegistration) error
-	UpdateKey(key string) error
+	GetLolPlaytime(ctx context.Context, reg *SummonerRegistration) (*Game, error)
+	ValidateSummoner(ctx context.Context, reg *SummonerRegistration) error
+	UpdateKey(ctx context.Context, key string) error
 }
 
 // MatchList contains a list of matches with relevant information
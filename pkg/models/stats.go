@@ -0,0 +1,58 @@
+package models
+
+// Stats contains the statistics derived from the user's games and playtime history
+type Stats struct {
+	TotalMinutes       int                `json:"totalMinutes"`
+	Games              int                `json:"games"`
+	TopGames           []GameShare        `json:"topGames"`           // the most played games
+	Providers          []ProviderPlaytime `json:"providers"`          // sorted by playtime
+	Genres             []GenrePlaytime    `json:"genres"`             // sorted by playtime
+	AverageWeeklyHours float64            `json:"averageWeeklyHours"` // over the history, or the last two weeks without history
+	LongestStreak      int                `json:"longestStreak"`      // the most consecutive days played in the history
+	MostImproved       *GameImprovement   `json:"mostImproved"`       // nil if no game has been played in the last 30 days
+	Percentile         *float64           `json:"percentile"`         // nil if there are no public users to compare with
+	HistoryDays        int                `json:"historyDays"`        // the days covered by the history the stats are based on
+}
+
+// GameShare contains the playtime of a game and its share of the total playtime
+type GameShare struct {
+	Name    string  `json:"game"`
+	Minutes int     `json:"minutes"`
+	Share   float64 `json:"share"` // percent of the total playtime
+}
+
+// ProviderPlaytime contains the playtime of the games from a provider, e.g. "steam", or the launcher a game is
+// imported from
+type ProviderPlaytime struct {
+	Provider string `json:"provider"`
+	Minutes  int    `json:"minutes"`
+	Games    int    `json:"games"`
+}
+
+// GameImprovement contains the game with the most playtime gained the last 30 days, and the playtime gained
+type GameImprovement struct {
+	Name    string `json:"game"`
+	Minutes int    `json:"minutes"`
+}
+
+// PlaytimeSnapshot is the playtime of the user's games on a day, recorded whenever the games are updated,
+// such that the stats can be derived from how the playtime changes over time
+type PlaytimeSnapshot struct {
+	Date    string         `json:"date" firestore:"date"`       // UTC, formatted as 2006-01-02, which identifies the snapshot
+	Minutes int            `json:"minutes" firestore:"minutes"` // the total playtime
+	Games   []GamePlaytime `json:"games" firestore:"games"`
+}
+
+// YearPlaytime is the playtime (minutes) a user gained in a year, as counted by the recap. It is stored with the history
+// whenever a snapshot is recorded, such that the public users can be ranked without querying each user's history.
+type YearPlaytime struct {
+	Year    int `json:"year" firestore:"year"`
+	Minutes int `json:"minutes" firestore:"minutes"`
+}
+
+// GamePlaytime contains the minutes a game has been played, and whether the playtime is recorded manually
+type GamePlaytime struct {
+	Name         string `json:"game" firestore:"name"`
+	Minutes      int    `json:"minutes" firestore:"minutes"`
+	SelfReported bool   `json:"selfReported" firestore:"selfReported"`
+}
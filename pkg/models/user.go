@@ -3,18 +3,184 @@ package models
 // User contains all relevant information about the user
 type User struct {
 	ID            string                `json:"-" firestore:"id"`
-	Name          string                `json:"name,omitempty" firestore:"name"`
+	Name          string                `json:"name,omitempty" firestore:"name"`               // the handle, unique and used in URLs
+	DisplayName   string                `json:"displayName,omitempty" firestore:"displayName"` // shown instead of the handle if set
 	Public        bool                  `json:"public,omitempty" firestore:"public"`
 	TotalGameTime int                   `json:"totalPlayTime" firestore:"totalGameTime"`
 	Lol           *SummonerRegistration `json:"lol,omitempty" firestore:"lol"`
 	Valve         *ValveAccount         `json:"valve,omitempty" firestore:"valve"`
 	Overwatch     *Overwatch            `json:"overwatch,omitempty" firestore:"overwatch"`
 	Runescape     *RunescapeAccount     `json:"runescape,omitempty" firestore:"runescape"`
+	BattleNet     *BattleNetAccount     `json:"battlenet,omitempty" firestore:"battlenet"`
+	Hidden        []string              `json:"hidden,omitempty" firestore:"hidden"` // the fields hidden from other users, see HideableFields
 	Games         []Game                `json:"games" firestore:"games"`
+	Version       int64                 `json:"-" firestore:"version"`       // incremented on every update, used as the ETag of the user
+	NameChangedAt int64                 `json:"-" firestore:"nameChangedAt"` // unix time the name was last changed or removed
+	PreviousNames []PreviousName        `json:"-" firestore:"previousNames"` // oldest first, updated with the name
+	DeletedAt     int64                 `json:"-" firestore:"deletedAt"`     // unix time the user asked to be deleted, 0 unless pending deletion
+	PurgeAt       int64                 `json:"-" firestore:"purgeAt"`       // unix time the user and their data are purged, unless restored before
+	Skeleton      string                `json:"-" firestore:"skeleton"`      // the Skeleton of the display name, set when the user is stored
 }
 
-// Game contains relevant information about a game
+// The fields of the public profile a public user can hide from other users
+const (
+	HideTotal        = "totalPlayTime" // the total playtime
+	HideGames        = "games"         // the games
+	HideSelfReported = "selfReported"  // the playtime recorded manually, in the games and the total
+)
+
+// HideableFields are the fields of the public profile which can be Hidden
+var HideableFields = []string{HideTotal, HideGames, HideSelfReported}
+
+// Publicly returns the user as shown to other users (e.g. on the public profile and the badge), without the fields the
+// user hides. A hidden total playtime is 0, and hidden games are empty.
+func (u *User) Publicly() *User {
+	public := *u
+
+	if Contains(u.Hidden, HideSelfReported) {
+		public.Games = make([]Game, 0, len(u.Games))
+		for i := range u.Games {
+			game, ok := u.Games[i].withoutSelfReported()
+			if !ok {
+				public.TotalGameTime -= u.Games[i].Time
+				continue
+			}
+
+			public.TotalGameTime -= u.Games[i].Time - game.Time
+			public.Games = append(public.Games, game)
+		}
+	}
+
+	if Contains(u.Hidden, HideGames) {
+		public.Games = []Game{}
+	}
+
+	if Contains(u.Hidden, HideTotal) {
+		public.TotalGameTime = 0
+	}
+
+	return &public
+}
+
+// PendingDeletion returns true if the user has been deleted, and can still be restored until PurgeAt
+func (u *User) PendingDeletion() bool {
+	return u.PurgeAt != 0
+}
+
+// Displayed returns the name the user is shown with: their display name, or their handle if they have none
+func (u *User) Displayed() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+
+	return u.Name
+}
+
+// Game contains relevant information about a game.
+// Only Name and Time are set for every provider, the other fields are set if the provider has the information.
+// Games recorded manually by the user are SelfReported.
+// The same game listed by several providers is merged into one game, with the playtime of each entry in Merged.
 type Game struct {
-	Name string `json:"game" firestore:"name"`
-	Time int    `json:"playTime" firestore:"time"`
+	Name          string             `json:"game" firestore:"name"`
+	Time          int                `json:"playTime" firestore:"time"` // hours
+	AppID         int                `json:"appId,omitempty" firestore:"appId"`
+	Icon          string             `json:"icon,omitempty" firestore:"icon"`             // url of the game's icon
+	LastPlayed    int64              `json:"lastPlayed,omitempty" firestore:"lastPlayed"` // unix time
+	Minutes       int                `json:"playTimeMinutes,omitempty" firestore:"minutes"`
+	RecentMinutes int                `json:"playTime2Weeks,omitempty" firestore:"recentMinutes"` // minutes played the last two weeks
+	Platforms     *PlatformPlaytime  `json:"platforms,omitempty" firestore:"platforms"`
+	Achievements  *Achievements      `json:"achievements,omitempty" firestore:"achievements"`
+	Modes         []ModePlaytime     `json:"modes,omitempty" firestore:"modes"`   // playtime per game mode, e.g. for Overwatch
+	Heroes        []HeroPlaytime     `json:"heroes,omitempty" firestore:"heroes"` // playtime per hero and game mode
+	Characters    []Character        `json:"characters,omitempty" firestore:"characters"`
+	Skills        []SkillPlaytime    `json:"skills,omitempty" firestore:"skills"`         // estimated playtime per skill, e.g. for Runescape
+	Activities    []ActivityPlaytime `json:"activities,omitempty" firestore:"activities"` // estimated playtime per activity or boss
+	Source        string             `json:"source,omitempty" firestore:"source"`         // where an imported or manual game is from, e.g. "gog", "epic" or "manual"
+	Platform      string             `json:"platform,omitempty" firestore:"platform"`     // the platform of a manual game, e.g. "playstation"
+	SelfReported  bool               `json:"selfReported" firestore:"selfReported"`
+	GameID        string             `json:"gameId,omitempty" firestore:"gameId"` // the id of the game in the catalog
+	Merged        []MergedGame       `json:"merged,omitempty" firestore:"merged"` // the entries merged into the game
+	Metadata      *GameMetadata      `json:"metadata,omitempty" firestore:"metadata"`
+}
+
+// withoutSelfReported returns the game without the playtime recorded manually, and false if all of it is.
+// A merged game is left with the playtime of the entries which are not recorded manually.
+func (g *Game) withoutSelfReported() (Game, bool) {
+	if g.SelfReported {
+		return Game{}, false
+	}
+
+	game := *g
+	var merged []MergedGame
+	minutes := 0
+	manual := false
+	for _, entry := range g.Merged {
+		if entry.Source == ManualSource {
+			manual = true
+			continue
+		}
+
+		merged = append(merged, entry)
+		if entry.Minutes > minutes {
+			minutes = entry.Minutes
+		}
+	}
+
+	if manual {
+		game.Merged, game.Minutes, game.Time = merged, minutes, minutes/60
+	}
+
+	return game, true
+}
+
+// PlatformPlaytime contains the minutes a game has been played on each platform
+type PlatformPlaytime struct {
+	Windows int `json:"windows" firestore:"windows"`
+	Mac     int `json:"mac" firestore:"mac"`
+	Linux   int `json:"linux" firestore:"linux"`
+	Deck    int `json:"deck" firestore:"deck"` // Steam Deck
+}
+
+// ModePlaytime contains the minutes a game mode has been played
+type ModePlaytime struct {
+	Mode    string `json:"mode" firestore:"mode"`
+	Minutes int    `json:"minutes" firestore:"minutes"`
+}
+
+// HeroPlaytime contains the minutes played with a hero in a game mode
+type HeroPlaytime struct {
+	Hero    string `json:"hero" firestore:"hero"`
+	Mode    string `json:"mode" firestore:"mode"`
+	Minutes int    `json:"minutes" firestore:"minutes"`
+}
+
+// Character is a character (or profile) the user has in a game, e.g. in World of Warcraft.
+// The minutes are only set if the game's API provides (or allows estimating) the playtime of the character.
+type Character struct {
+	Name    string `json:"name" firestore:"name"`
+	Realm   string `json:"realm,omitempty" firestore:"realm"`
+	Class   string `json:"class,omitempty" firestore:"class"`
+	Level   int    `json:"level,omitempty" firestore:"level"`
+	Minutes int    `json:"minutes,omitempty" firestore:"minutes"`
+}
+
+// SkillPlaytime contains the estimated time spent training a skill, from the XP gained in it
+type SkillPlaytime struct {
+	Skill   string `json:"skill" firestore:"skill"`
+	Level   int    `json:"level" firestore:"level"`
+	XP      int    `json:"xp" firestore:"xp"`
+	Minutes int    `json:"minutes" firestore:"minutes"`
+}
+
+// ActivityPlaytime contains the estimated time spent on an activity or boss, from the score (e.g. the kill count)
+type ActivityPlaytime struct {
+	Activity string `json:"activity" firestore:"activity"`
+	Score    int    `json:"score" firestore:"score"`
+	Minutes  int    `json:"minutes" firestore:"minutes"`
+}
+
+// Achievements contains the user's achievement progress in a game
+type Achievements struct {
+	Unlocked int `json:"unlocked" firestore:"unlocked"`
+	Total    int `json:"total" firestore:"total"`
 }
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicly(t *testing.T) {
+	dota := Game{Name: "Dota 2", AppID: 570, Time: 500, Minutes: 30000}
+	zelda := Game{Name: "Zelda", Time: 100, Minutes: 6000, Source: ManualSource, SelfReported: true}
+	portal := Game{Name: "Portal 2", AppID: 620, Time: 60, Minutes: 3600, Merged: []MergedGame{
+		{Name: "Portal 2", Source: "steam", Minutes: 3000},
+		{Name: "Portal 2", Source: ManualSource, Minutes: 3600},
+	}}
+
+	var cases = []struct {
+		name          string
+		hidden        []string
+		expectedTotal int
+		expectedGames []Game
+	}{
+		{"Test nothing hidden", nil, 660, []Game{dota, zelda, portal}},
+		{"Test hidden self-reported", []string{HideSelfReported}, 550, []Game{dota, {Name: "Portal 2", AppID: 620,
+			Time: 50, Minutes: 3000, Merged: []MergedGame{{Name: "Portal 2", Source: "steam", Minutes: 3000}}}}},
+		{"Test hidden games", []string{HideGames}, 660, []Game{}},
+		{"Test hidden total", []string{HideTotal}, 0, []Game{dota, zelda, portal}},
+		{"Test hidden total and self-reported", []string{HideTotal, HideSelfReported}, 0, []Game{dota,
+			{Name: "Portal 2", AppID: 620, Time: 50, Minutes: 3000,
+				Merged: []MergedGame{{Name: "Portal 2", Source: "steam", Minutes: 3000}}}}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			user := &User{Name: "onijuan", Public: true, TotalGameTime: 660, Games: []Game{dota, zelda, portal},
+				Hidden: tc.hidden}
+
+			public := user.Publicly()
+			assert.Equal(t, tc.expectedTotal, public.TotalGameTime)
+			assert.Equal(t, tc.expectedGames, public.Games)
+
+			// the user itself is not changed
+			assert.Equal(t, 660, user.TotalGameTime)
+			assert.Equal(t, []Game{dota, zelda, portal}, user.Games)
+		})
+	}
+}
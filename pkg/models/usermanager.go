@@ -1,15 +1,39 @@
 package models
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+)
 
 // UserManager contains all functions a usermanager is expected to provide for "managing" a user
 type UserManager interface {
-	GetUserByID(id string) (*User, error)
-	GetUserByName(username string) (*User, error)
-	SetUser(user *User) error
-	DeleteUser(id string, fields []string) error
-	UpdateRiotAPIKey(key string) error
-	UpdateGames(id string) error
+	GetUserByID(ctx context.Context, id string) (*User, error)
+	GetUserByName(ctx context.Context, username string) (*User, error)
+	SetUser(ctx context.Context, user *User) error
+	ReplaceUser(ctx context.Context, user *User, version int64) (*User, error)
+	DeleteUser(ctx context.Context, id string, fields []string) error
+	RestoreUser(ctx context.Context, id string) (*User, error)
+	UpdateRiotAPIKey(ctx context.Context, key string) error
+	UpdateGames(ctx context.Context, id string) error
+	RefreshGames(ctx context.Context, id string) (*Job, error)
+	GetJob(ctx context.Context, id, jobID string) (*Job, error)
+	SubscribeEvents(ctx context.Context, id string, follow []string) (<-chan Event, func(), error)
+	GetStats(ctx context.Context, id string) (*Stats, error)
+	GetRecap(ctx context.Context, id string, year int) (*Recap, error)
+	AuthorizeBattleNet(id, region string) (string, string, error)
+	LinkBattleNet(ctx context.Context, code, state, nonce string) (*BattleNetAccount, error)
+	ImportLibrary(ctx context.Context, id, format string, data []byte) (*ImportedLibrary, error)
+	GetImports(ctx context.Context, id string) ([]ImportedLibrary, error)
+	DeleteImport(ctx context.Context, id, format string) error
+	GetManualGames(ctx context.Context, id string) ([]ManualGame, error)
+	GetManualGame(ctx context.Context, id, gameID string) (*ManualGame, error)
+	AddManualGame(ctx context.Context, id string, game *ManualGame) (*ManualGame, error)
+	UpdateManualGame(ctx context.Context, id string, game *ManualGame) (*ManualGame, error)
+	DeleteManualGame(ctx context.Context, id, gameID string) error
+	SearchCatalog(name string) ([]CatalogGame, error)
+	GetMatches(ctx context.Context, id string) ([]GameMatch, error)
+	SetMatch(ctx context.Context, id string, match *GameMatch) (*GameMatch, error)
+	DeleteMatch(ctx context.Context, id, key string) error
 	Redirect(w http.ResponseWriter, r *http.Request)
 	AuthCallback(w http.ResponseWriter, r *http.Request) (string, error)
 }
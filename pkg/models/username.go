@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// RenameCooldown is how long a user has to wait after changing their name before they can change it again.
+// It does not apply to the first name a user takes, and a name can be removed at any time.
+const RenameCooldown = 30 * 24 * time.Hour
+
+// CheckRenameCooldown returns a request error if the user can not change their name to the given (lowercase) name yet,
+// as they changed their name less than RenameCooldown before now
+func CheckRenameCooldown(user *User, name string, now time.Time) error {
+	if name == user.Name || name == "" || user.NameChangedAt == 0 {
+		return nil
+	}
+
+	next := time.Unix(user.NameChangedAt, 0).Add(RenameCooldown)
+	if now.Before(next) {
+		return NewReqErrStr("rename cooldown", fmt.Sprintf(
+			"the name was changed recently, and can not be changed again before %s", next.UTC().Format("2006-01-02")))
+	}
+
+	return nil
+}
+
+// NameHold is how long the previous name of a user is held for them, redirecting to their current name, before
+// anyone else can take it
+const NameHold = 90 * 24 * time.Hour
+
+// Username is an entry of the username registry, which maps every (lowercase) name taken to the user who has it.
+// The registry is what keeps the names unique, for private users as well as public ones.
+type Username struct {
+	Name      string `firestore:"name"`
+	ID        string `firestore:"id"`        // the id of the user
+	Current   bool   `firestore:"current"`   // false if it is a previous name of the user
+	HeldUntil int64  `firestore:"heldUntil"` // unix time a previous name is released, after which anyone can take it
+	Skeleton  string `firestore:"skeleton"`  // the Skeleton of the name, such that lookalikes of the name are found
+}
+
+// Available returns true if the user with the id can take the name at the given unix time
+func (u *Username) Available(id string, now int64) bool {
+	return u.ID == id || (!u.Current && u.HeldUntil <= now)
+}
+
+// PreviousName is a name the user has had
+type PreviousName struct {
+	Name      string `json:"name" firestore:"name"`
+	ChangedAt int64  `json:"changedAt" firestore:"changedAt"` // unix time the user stopped using the name
+}
+
+// lookalikes maps the Cyrillic and Greek letters, and the digits, which look like Latin letters to those letters
+var lookalikes = map[rune]rune{
+	// Cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y',
+	'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'һ': 'h', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w', 'ӏ': 'l',
+	// Greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ζ': 'z', 'η': 'h', 'ι': 'i', 'κ': 'k', 'μ': 'm', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x',
+	// digits
+	'0': 'o', '1': 'l',
+}
+
+// Skeleton returns what the name looks like, such that names which look alike have the same skeleton: lowercase,
+// with lookalike letters replaced by the Latin letters they look like, and without anything but letters and digits
+func Skeleton(name string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(name) {
+		if l, ok := lookalikes[r]; ok {
+			r = l
+		}
+
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
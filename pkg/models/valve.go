@@ -1,10 +1,42 @@
 package models
 
+import (
+	"context"
+	"fmt"
+)
+
 // Valve interface defines all methods which should be provided by valve
 type Valve interface {
-	ValidateValveAccount(username string) (string, error)
-	ValidateValveID(id string) error
-	GetValvePlaytime(ID string) ([]Game, error)
+	ValidateValveAccount(ctx context.Context, username string) (*ValveAccount, error)
+	ValidateValveID(ctx context.Context, id string) (*ValveAccount, error)
+	GetValvePlaytime(ctx context.Context, ID string) ([]Game, error)
+}
+
+// The privacy states of a steam profile
+const (
+	ValvePublic       = "public"       // the playtime is available
+	ValvePrivate      = "private"      // the whole profile is private
+	ValveFriendsOnly  = "friendsOnly"  // the profile is only visible to friends
+	ValveGamesPrivate = "gamesPrivate" // the profile is public, but the game details are private
+)
+
+// valveHints tells the user which steam privacy setting to change for each of the privacy states
+var valveHints = map[string]string{
+	ValvePrivate: `Your Steam profile is private. Set "My profile" and "Game details" to "Public" ` +
+		"in the Steam privacy settings (https://steamcommunity.com/my/edit/settings).",
+	ValveFriendsOnly: `Your Steam profile is only visible to friends. Set "My profile" and "Game details" to "Public" ` +
+		"in the Steam privacy settings (https://steamcommunity.com/my/edit/settings).",
+	ValveGamesPrivate: `Your Steam game details are private. Set "Game details" to "Public" and uncheck ` +
+		`"Always keep my total playtime private" in the Steam privacy settings (https://steamcommunity.com/my/edit/settings).`,
+}
+
+// PrivateProfileError indicates that the playtime is unavailable, as the steam profile (or parts of it) is private
+type PrivateProfileError struct {
+	Status string // one of the privacy states, except ValvePublic
+}
+
+func (e *PrivateProfileError) Error() string {
+	return fmt.Sprintf("steam profile is not public (%s)", e.Status)
 }
 
 // ValveResp is used for testing
@@ -14,8 +46,16 @@ type ValveResp struct {
 
 // ValveGames is used for testing
 type ValveGames struct {
+	AppID           int    `json:"appid"`
 	Name            string `json:"name"`
 	PlaytimeForever int    `json:"playtime_forever"`
+	Playtime2Weeks  int    `json:"playtime_2weeks"`
+	PlaytimeWindows int    `json:"playtime_windows_forever"`
+	PlaytimeMac     int    `json:"playtime_mac_forever"`
+	PlaytimeLinux   int    `json:"playtime_linux_forever"`
+	PlaytimeDeck    int    `json:"playtime_deck_forever"`
+	IconURL         string `json:"img_icon_url"`
+	LastPlayed      int64  `json:"rtime_last_played"`
 }
 
 // ValveResponse is used for testing
@@ -24,8 +64,17 @@ type ValveResponse struct {
 	Games     []ValveGames `json:"games"`
 }
 
-// ValveAccount contains all information about a user relevant to Valve (steam)
+// ValveAccount contains all information about a user relevant to Valve (steam).
+// The status and hint are set when the account is validated or the games are updated, and can not be set by the user.
 type ValveAccount struct {
 	ID       string `json:"id,omitempty" firestore:"id"`
 	Username string `json:"username,omitempty" firestore:"username"`
+	Status   string `json:"status,omitempty" firestore:"status"` // the privacy state of the profile
+	Hint     string `json:"hint,omitempty" firestore:"hint"`     // which privacy setting to change, if the profile is not public
+}
+
+// SetStatus sets the privacy state of the account, and the hint telling the user how to make the profile public
+func (a *ValveAccount) SetStatus(status string) {
+	a.Status = status
+	a.Hint = valveHints[status]
 }
@@ -1,7 +1,9 @@
 package riot
 
 import (
+	"context"
 	"ctp/pkg/models"
+	"ctp/pkg/tracing"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,8 +11,6 @@ import (
 	"net/url"
 	"strings"
 	"sync"
-
-	"github.com/sirupsen/logrus"
 )
 
 // Riot is a struct which contains everything necessary to handle a request related to riot
@@ -31,7 +31,10 @@ func New(client models.Client, apiKey string) *Riot {
 }
 
 // GetLolPlaytime gets playtime on League of Legends
-func (r *Riot) GetLolPlaytime(reg *models.SummonerRegistration) (*models.Game, error) {
+func (r *Riot) GetLolPlaytime(ctx context.Context, reg *models.SummonerRegistration) (*models.Game, error) {
+	ctx, span := tracing.StartKind(ctx, "riot.GetLolPlaytime", tracing.KindClient)
+	defer span.End()
+
 	if reg == nil || reg.SummonerRegion == "" || reg.AccountID == "" {
 		return nil, errors.New("missing summonerinfo")
 	}
@@ -47,7 +50,7 @@ func (r *Riot) GetLolPlaytime(reg *models.SummonerRegistration) (*models.Game, e
 	}
 
 	// create http request
-	req, err := http.NewRequest(http.MethodGet, formatURL.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, formatURL.String(), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -86,7 +89,10 @@ func (r *Riot) GetLolPlaytime(reg *models.SummonerRegistration) (*models.Game, e
 }
 
 // ValidateSummoner validates the summoner
-func (r *Riot) ValidateSummoner(reg *models.SummonerRegistration) error {
+func (r *Riot) ValidateSummoner(ctx context.Context, reg *models.SummonerRegistration) error {
+	ctx, span := tracing.StartKind(ctx, "riot.ValidateSummoner", tracing.KindClient)
+	defer span.End()
+
 	if reg == nil {
 		return errors.New("nil summoner registration")
 	}
@@ -105,7 +111,7 @@ func (r *Riot) ValidateSummoner(reg *models.SummonerRegistration) error {
 	}
 
 	// Use the URL to validate SummonerName against API
-	req, err := http.NewRequest(http.MethodGet, formatURL.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, formatURL.String(), nil)
 	if err != nil {
 		return err
 	}
@@ -141,7 +147,10 @@ func (r *Riot) ValidateSummoner(reg *models.SummonerRegistration) error {
 }
 
 // UpdateKey updates the riot API key
-func (r *Riot) UpdateKey(key string) error {
+func (r *Riot) UpdateKey(ctx context.Context, key string) error {
+	ctx, span := tracing.StartKind(ctx, "riot.UpdateKey", tracing.KindClient)
+	defer span.End()
+
 	// very simple check of the key
 	if !strings.HasPrefix(key, "RGAPI-") || len(key) != 42 {
 		return models.NewReqErrStr("invalid riot API key", "invalid API key")
@@ -155,7 +164,7 @@ func (r *Riot) UpdateKey(key string) error {
 	}
 
 	// Use the URL to validate SummonerName against API
-	req, err := http.NewRequest(http.MethodGet, formatURL.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, formatURL.String(), nil)
 	if err != nil {
 		return err
 	}
@@ -179,6 +188,6 @@ func (r *Riot) UpdateKey(key string) error {
 	r.mutex.Lock()
 	r.apiKey = key
 	r.mutex.Unlock()
-	logrus.Debugf("is good: %s", r.apiKey)
+	models.Log(ctx).Debugf("is good: %s", r.apiKey)
 	return nil
 }
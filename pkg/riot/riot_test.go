@@ -2,6 +2,7 @@ package riot
 
 import (
 	"bytes"
+	"context"
 	"ctp/pkg/models"
 	"encoding/json"
 	"errors"
@@ -74,7 +75,7 @@ func TestRiot_ValidateSummoner(t *testing.T) {
 			client.setup = setup
 
 			// run the function we want to test
-			err := riot.ValidateSummoner(tc.payload)
+			err := riot.ValidateSummoner(context.Background(), tc.payload)
 
 			// if the error we got does not correspond with the expected error, fail test
 			assert.Equal(t, tc.errExpected, err)
@@ -111,7 +112,7 @@ func TestRiot_GetLolPlaytime(t *testing.T) {
 			client.setup = setup
 
 			// run the function we want to test
-			_, err := riot.GetLolPlaytime(tc.payload)
+			_, err := riot.GetLolPlaytime(context.Background(), tc.payload)
 
 			// if the error we got does not correspond with the expected error, fail test
 			assert.Equal(t, tc.errExpected, err)
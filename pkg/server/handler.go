@@ -1,19 +1,21 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"ctp/pkg/card"
 	"ctp/pkg/models"
 
 	"github.com/gorilla/mux"
-	"github.com/sirupsen/logrus"
 )
 
 // Handler embedds the models.UserManager interface
@@ -28,20 +30,128 @@ func newHandler(um models.UserManager) *handler {
 }
 
 // Gets a user by their username. The user has to be public.
+// Previous names of the user redirect to the user's current name as long as they are held for the user.
 func (h *handler) getPublicUser(w http.ResponseWriter, r *http.Request) {
 	username := strings.ToLower(mux.Vars(r)["username"])
 
-	resp, err := h.GetUserByName(username)
+	resp, err := h.GetUserByName(r.Context(), username)
 	if err != nil {
 		logRespond(w, r, err)
 		return
 	}
 
+	if redirectRenamed(w, r, resp) {
+		return
+	}
+
+	resp = resp.Publicly()
 	resp.Public = false // as the user has to be public, this information is not useful
 
 	respond(w, r, resp)
 }
 
+// redirectRenamed redirects to the same route with the user's current name, if the user was found by a previous name
+// given by the "username" route variable. The query is kept. Returns true if the request was redirected.
+func redirectRenamed(w http.ResponseWriter, r *http.Request, user *models.User) bool {
+	vars := mux.Vars(r)
+	if user.Name == strings.ToLower(vars["username"]) {
+		return false
+	}
+
+	var pairs []string
+	for name, value := range vars {
+		if name == "username" {
+			value = user.Name
+		}
+		pairs = append(pairs, name, value)
+	}
+
+	u, err := mux.CurrentRoute(r).URLPath(pairs...)
+	if err != nil {
+		logRespond(w, r, err)
+		return true
+	}
+	u.RawQuery = r.URL.RawQuery
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+
+	return true
+}
+
+// badgeCacheControl lets the badges be cached for an hour, e.g. by the image proxies of forums and GitHub
+const badgeCacheControl = "public, max-age=3600"
+
+// getBadge renders a public user's total playtime and most played games as an image given by the "format" route
+// variable (svg or png), to embed in e.g. forum signatures and READMEs. The "theme" (dark or light) and "size"
+// (small, medium or large) query parameters change the look. Only what the public user shows is rendered, and the
+// image may be cached for an hour, or revalidated with the ETag.
+func (h *handler) getBadge(w http.ResponseWriter, r *http.Request) {
+	themeName, sizeName := r.URL.Query().Get("theme"), r.URL.Query().Get("size")
+	if themeName == "" {
+		themeName = "dark"
+	}
+	if sizeName == "" {
+		sizeName = "medium"
+	}
+
+	theme, ok := card.Themes[themeName]
+	if !ok {
+		logRespond(w, r, models.NewReqErrStr("invalid theme", "invalid theme: "+themeName))
+		return
+	}
+
+	size, ok := card.BadgeSizes[sizeName]
+	if !ok {
+		logRespond(w, r, models.NewReqErrStr("invalid size", "invalid size: "+sizeName))
+		return
+	}
+
+	user, err := h.GetUserByName(r.Context(), strings.ToLower(mux.Vars(r)["username"]))
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	if redirectRenamed(w, r, user) {
+		return
+	}
+
+	tag := etag(user)
+	w.Header().Set("ETag", tag)
+	w.Header().Set("Cache-Control", badgeCacheControl)
+
+	if header := r.Header.Get("If-None-Match"); header != "" && etagMatches(header, tag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	badge := card.Badge(user.Publicly(), theme, size)
+
+	if mux.Vars(r)["format"] == "png" {
+		body, err := badge.PNG()
+		if err != nil {
+			logRespond(w, r, err)
+			return
+		}
+
+		respondFile(w, r, "image/png", "badge.png", body)
+
+		return
+	}
+
+	respondFile(w, r, "image/svg+xml", "badge.svg", badge.SVG())
+}
+
+// openAPI returns the OpenAPI document describing the API (api/openapi.json)
+func (h *handler) openAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err := io.WriteString(w, openAPISpec)
+	if err != nil {
+		models.Log(r.Context()).WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("Could not write response")
+	}
+}
+
 // login redirects to the OAuth provider's (Google's) consent screen for the application.
 func (h *handler) login(w http.ResponseWriter, r *http.Request) {
 	h.Redirect(w, r)
@@ -52,20 +162,20 @@ func (h *handler) login(w http.ResponseWriter, r *http.Request) {
 func (h *handler) authCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	resp, err := h.AuthCallback(w, r)
 	if err != nil {
-		logrus.WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("error getting token")
+		models.Log(r.Context()).WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("error getting token")
 
-		// returning errorcode based on error
-		switch {
-		case errors.Is(err, models.ErrInvalidAuthState):
-			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
-		default:
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		// returning errorcode based on error. Other errors are not exposed, as they may contain details about the OAuth exchange
+		if errors.Is(err, models.ErrInvalidAuthState) {
+			models.WriteProblem(w, r, models.ProblemFromError(err))
+			return
 		}
 
+		models.WriteProblem(w, r, models.NewProblem(http.StatusInternalServerError, models.CodeInternalError, ""))
+
 		return
 	}
 
-	respondPlain(w, r, resp)
+	respond(w, r, &tokenResponse{Token: resp})
 }
 
 // updateUser decodes the body of the request and uses it toupdate the user's information (where allowed)
@@ -91,16 +201,19 @@ func (h *handler) updateUser(w http.ResponseWriter, r *http.Request) {
 	user.Games = nil
 	user.TotalGameTime = 0
 
-	err = h.SetUser(&user)
+	err = h.SetUser(r.Context(), &user)
 	if err != nil {
 		logRespond(w, r, err)
 		return
 	}
 
-	respondPlain(w, r, "Success")
+	respond(w, r, success)
 }
 
-// updateGames updates the playtime for all games in the services registered for the user
+// updateGames updates the playtime for all games in the services registered for the user.
+// The games are updated in the background: the job updating them is responded with 202 Accepted, its progress is sent
+// to the user over the event stream, and it can be polled at /jobs/{job}. If the "wait" query parameter is true, the
+// games are updated before responding, unless they are not updated in time, in which case the job is responded as well.
 func (h *handler) updateGames(w http.ResponseWriter, r *http.Request) {
 	id, err := getID(r)
 	if err != nil {
@@ -108,13 +221,137 @@ func (h *handler) updateGames(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.UpdateGames(id)
+	// the games are updated in the background unless the client asks to wait for them, as waiting holds the
+	// connection for up to jobWait
+	wait := false
+	if value := r.URL.Query().Get("wait"); value != "" {
+		wait, err = strconv.ParseBool(value)
+		if err != nil {
+			logRespond(w, r, models.NewReqErr(err, "invalid wait: "+value))
+			return
+		}
+	}
+
+	if !wait {
+		job, err := h.RefreshGames(r.Context(), id)
+		if err != nil {
+			logRespond(w, r, err)
+			return
+		}
+
+		respondStatus(w, r, http.StatusAccepted, job)
+
+		return
+	}
+
+	err = h.UpdateGames(r.Context(), id)
 	if err != nil {
 		logRespond(w, r, err)
 		return
 	}
 
-	respondPlain(w, r, "Success")
+	respond(w, r, success)
+}
+
+// streamDuration is how long the event stream is kept open, as the response has to be written within the write
+// timeout of the server. Clients reconnect when the stream is closed, as EventSource does.
+const streamDuration = (writeTimeout - 10) * time.Second
+
+// keepAliveInterval is how often a comment is sent over an idle event stream, such that proxies keep it open
+const keepAliveInterval = 15 * time.Second
+
+// getEvents streams the user's events as server-sent events (text/event-stream): the progress of the user's jobs
+// ("job" events), and the changes to the total playtime of the user and of the public users given by the "follow"
+// query parameter, a comma separated list of usernames ("total" events). The data of each event is JSON.
+func (h *handler) getEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logRespond(w, r, errors.New("the response writer does not support streaming"))
+		return
+	}
+
+	var follow []string
+	if value := r.URL.Query().Get("follow"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			follow = append(follow, strings.ToLower(strings.TrimSpace(name)))
+		}
+	}
+
+	events, unsubscribe, err := h.SubscribeEvents(r.Context(), id, follow)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // proxies such as nginx would otherwise buffer the events
+
+	// clients reconnect a second after the stream is closed
+	_, err = io.WriteString(w, "retry: 1000\n\n")
+
+	end := time.NewTimer(streamDuration)
+	defer end.Stop()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for err == nil {
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-end.C:
+			return
+		case <-keepAlive.C:
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			err = writeEvent(w, event)
+		}
+	}
+
+	models.Log(r.Context()).WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("Could not write event")
+}
+
+// writeEvent writes the event as a server-sent event, named by the type of the event, with the data encoded as JSON
+func writeEvent(w io.Writer, event models.Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+
+	return err
+}
+
+// getJob returns the user's job given by the "job" route variable, such that its progress can be polled
+func (h *handler) getJob(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	job, err := h.GetJob(r.Context(), id, mux.Vars(r)["job"])
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	respond(w, r, job)
 }
 
 // getUser retrieves all information about the user themself
@@ -125,7 +362,24 @@ func (h *handler) getUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.GetUserByID(id)
+	resp, err := h.GetUserByID(r.Context(), id)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	respond(w, r, resp)
+}
+
+// getStats returns the stats derived from the user's games and the history of their playtime
+func (h *handler) getStats(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	resp, err := h.GetStats(r.Context(), id)
 	if err != nil {
 		logRespond(w, r, err)
 		return
@@ -134,7 +388,51 @@ func (h *handler) getUser(w http.ResponseWriter, r *http.Request) {
 	respond(w, r, resp)
 }
 
-// deleteUser deletes the user and all information stored about or related to them
+// getRecap returns the recap of the year given by the "year" route variable, as JSON or as a shareable card,
+// depending on the "format" query parameter (json, svg or png)
+func (h *handler) getRecap(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "json", "svg", "png":
+	default:
+		logRespond(w, r, models.NewReqErrStr("invalid format", "invalid format: "+format))
+		return
+	}
+
+	year, _ := strconv.Atoi(mux.Vars(r)["year"]) // the route only matches four digits
+
+	recap, err := h.GetRecap(r.Context(), id, year)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	filename := fmt.Sprintf("recap-%d.%s", year, format)
+
+	switch format {
+	case "svg":
+		respondFile(w, r, "image/svg+xml", filename, card.Recap(recap).SVG())
+	case "png":
+		body, err := card.Recap(recap).PNG()
+		if err != nil {
+			logRespond(w, r, err)
+			return
+		}
+
+		respondFile(w, r, "image/png", filename, body)
+	default:
+		respond(w, r, recap)
+	}
+}
+
+// deleteUser deletes the given fields from the user, or without any fields the user and all information stored about
+// or related to them, which is purged once the grace period is over (see restoreMe)
 func (h *handler) deleteUser(w http.ResponseWriter, r *http.Request) {
 	id, err := getID(r)
 	if err != nil {
@@ -152,13 +450,13 @@ func (h *handler) deleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.DeleteUser(id, fields)
+	err = h.DeleteUser(r.Context(), id, fields)
 	if err != nil {
 		logRespond(w, r, err)
 		return
 	}
 
-	respondPlain(w, r, "Success")
+	respond(w, r, success)
 }
 
 // updateKey is a hack. It is used to update the Riot API key, because it is only valid for 24h.
@@ -170,73 +468,99 @@ func (h *handler) updateKey(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.UpdateRiotAPIKey(string(body))
+	err = h.UpdateRiotAPIKey(r.Context(), string(body))
 	if err != nil {
 		logRespond(w, r, err)
 		return
 	}
 
-	respondPlain(w, r, "Success")
+	respond(w, r, success)
 }
 
-// respond is used for every response which should be JSON encoded.
-func respond(w http.ResponseWriter, r *http.Request, resp interface{}) {
-	w.Header().Set("Content-Type", "application/json")
+// statusResponse is the response for successful requests which have nothing else to return
+type statusResponse struct {
+	Status string `json:"status"`
+}
 
-	err := json.NewEncoder(w).Encode(resp)
+// tokenResponse contains the token returned after logging in
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+// success is returned for successful requests which have nothing else to return
+var success = &statusResponse{Status: "success"}
+
+// respond is used for every successful response, which are all JSON encoded.
+// The response is encoded before anything is written, such that an error can still be returned if encoding fails.
+func respond(w http.ResponseWriter, r *http.Request, resp interface{}) {
+	respondStatus(w, r, http.StatusOK, resp)
+}
 
+// respondStatus responds with the status code and the response encoded as JSON
+func respondStatus(w http.ResponseWriter, r *http.Request, status int, resp interface{}) {
+	body, err := json.Marshal(resp)
 	if err != nil {
-		logrus.WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("Could not encode response")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		models.Log(r.Context()).WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("Could not encode response")
+		models.WriteProblem(w, r, models.NewProblem(http.StatusInternalServerError, models.CodeInternalError, ""))
 
 		return
 	}
-}
 
-// respondPlain responds in plaintext, merely writing the resp
-func respondPlain(w http.ResponseWriter, r *http.Request, resp string) {
-	_, err := fmt.Fprint(w, resp)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, err = w.Write(append(body, '\n'))
 	if err != nil {
-		logrus.WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("Could not encode response")
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		models.Log(r.Context()).WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("Could not write response")
+	}
+}
 
-		return
+// respondFile writes the body with the content type, as a file to be shown inline or saved with the filename
+func respondFile(w http.ResponseWriter, r *http.Request, contentType, filename string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+
+	_, err := w.Write(body)
+	if err != nil {
+		models.Log(r.Context()).WithError(err).WithField("route", mux.CurrentRoute(r).GetName()).Warn("Could not write response")
 	}
 }
 
-// logRespond handles errors. It logs the error and returns an appropriate errormessage and status code based on the error.
+// logRespond handles errors. It logs the error and responds with a problem (application/problem+json),
+// with status code and machine readable code based on the error.
+// A job which has not finished within the time waited for it is responded with 202 Accepted instead.
 func logRespond(w http.ResponseWriter, r *http.Request, err error) {
-	logrus.WithField("route", mux.CurrentRoute(r).GetName()).Warn(err)
-
-	var reqErr *models.RequestError
-	var apiErr *models.ExternalAPIError
-	netErr, netErrOK := err.(net.Error)
-
-	switch {
-	case errors.Is(err, models.ErrInvalidID):
-		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
-	case errors.Is(err, models.ErrNotFound):
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
-	case errors.As(err, &reqErr):
-		http.Error(w, fmt.Sprintf("%s: %s", http.StatusText(http.StatusBadRequest), reqErr.Response), http.StatusBadRequest)
-	case errors.As(err, &apiErr):
-		http.Error(w, fmt.Sprintf("%s: %s", http.StatusText(http.StatusBadGateway), apiErr.Respond()), http.StatusBadGateway)
-	case netErrOK:
-		if netErr.Timeout() {
-			http.Error(w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
-			return
-		}
+	// the request has not failed, but its job is still running and can be followed until it is finished
+	var pending *models.JobPendingError
+	if errors.As(err, &pending) {
+		respondStatus(w, r, http.StatusAccepted, pending.Job)
+		return
+	}
 
-		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
-	default:
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	log := models.Log(r.Context()).WithField("route", mux.CurrentRoute(r).GetName())
+
+	// the client has gone away (or the server is shutting down), which is not a failure of the server
+	if errors.Is(err, context.Canceled) {
+		log.WithError(err).Debug("The request was cancelled")
+	} else {
+		log.Warn(err)
 	}
+
+	models.WriteProblem(w, r, models.ProblemFromError(err))
 }
 
 // notFound handles all requests which don't hit any of the routes defined in the router
 func (h *handler) notFound(w http.ResponseWriter, r *http.Request) {
-	logrus.WithField("request", r.RequestURI).Debug("Not found handler")
-	http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	setRoute(r, "notFound")
+	models.Log(r.Context()).WithField("request", r.RequestURI).Debug("Not found handler")
+	models.WriteProblem(w, r, models.NewProblem(http.StatusNotFound, models.CodeNotFound, ""))
+}
+
+// methodNotAllowed handles all requests which match the path of a route, but not the method
+func (h *handler) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	setRoute(r, "methodNotAllowed")
+	models.Log(r.Context()).WithField("request", r.RequestURI).Debug("Method not allowed handler")
+	models.WriteProblem(w, r, models.NewProblem(http.StatusMethodNotAllowed, models.CodeMethodNotAllowed, ""))
 }
 
 // getID retrieves the user's id from the context of the request.
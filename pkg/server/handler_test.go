@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -19,21 +18,144 @@ import (
 )
 
 type mockUserManager struct {
-	user     *models.User
-	response string
-	err      error
-}
-
-func (m *mockUserManager) GetUserByID(id string) (*models.User, error)         { return m.user, m.err }
-func (m *mockUserManager) GetUserByName(username string) (*models.User, error) { return m.user, m.err }
-func (m *mockUserManager) SetUser(user *models.User) error                     { return m.err }
-func (m *mockUserManager) DeleteUser(id string, fields []string) error         { return m.err }
-func (m *mockUserManager) UpdateGames(id string) error                         { return m.err }
-func (m *mockUserManager) UpdateRiotAPIKey(key string) error                   { return m.err }
-func (m *mockUserManager) Redirect(w http.ResponseWriter, r *http.Request)     {}
+	user       *models.User
+	response   string
+	err        error
+	replaceErr error
+	imports    []models.ImportedLibrary
+	manual     []models.ManualGame
+	matches    []models.GameMatch
+	stats      *models.Stats
+	recap      *models.Recap
+	job        *models.Job
+	events     []models.Event // the events sent to the subscriber, before the subscription ends
+	follow     []string       // the usernames given to SubscribeEvents
+	nonce      string         // the nonce given to LinkBattleNet
+}
+
+func (m *mockUserManager) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	return m.user, m.err
+}
+func (m *mockUserManager) GetUserByName(ctx context.Context, username string) (*models.User, error) {
+	return m.user, m.err
+}
+func (m *mockUserManager) SetUser(ctx context.Context, user *models.User) error { return m.err }
+func (m *mockUserManager) ReplaceUser(ctx context.Context, user *models.User, version int64) (*models.User, error) {
+	if m.replaceErr != nil {
+		return nil, m.replaceErr
+	}
+
+	user.Version = version + 1
+	m.user = user
+
+	return user, nil
+}
+func (m *mockUserManager) DeleteUser(ctx context.Context, id string, fields []string) error {
+	return m.err
+}
+func (m *mockUserManager) RestoreUser(ctx context.Context, id string) (*models.User, error) {
+	return m.user, m.err
+}
+func (m *mockUserManager) UpdateGames(ctx context.Context, id string) error { return m.err }
+func (m *mockUserManager) GetStats(ctx context.Context, id string) (*models.Stats, error) {
+	return m.stats, m.err
+}
+func (m *mockUserManager) RefreshGames(ctx context.Context, id string) (*models.Job, error) {
+	return m.job, m.err
+}
+func (m *mockUserManager) GetJob(ctx context.Context, id, jobID string) (*models.Job, error) {
+	return m.job, m.err
+}
+func (m *mockUserManager) SubscribeEvents(ctx context.Context, id string, follow []string) (<-chan models.Event, func(), error) {
+	m.follow = follow
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+
+	events := make(chan models.Event, len(m.events))
+	for _, event := range m.events {
+		events <- event
+	}
+	close(events)
+
+	return events, func() {}, nil
+}
+func (m *mockUserManager) GetRecap(ctx context.Context, id string, year int) (*models.Recap, error) {
+	return m.recap, m.err
+}
+func (m *mockUserManager) UpdateRiotAPIKey(ctx context.Context, key string) error { return m.err }
+func (m *mockUserManager) Redirect(w http.ResponseWriter, r *http.Request)        {}
 func (m *mockUserManager) AuthCallback(w http.ResponseWriter, r *http.Request) (string, error) {
 	return m.response, m.err
 }
+func (m *mockUserManager) AuthorizeBattleNet(id, region string) (string, string, error) {
+	return m.response, "nonce", m.err
+}
+func (m *mockUserManager) LinkBattleNet(ctx context.Context, code, state, nonce string) (*models.BattleNetAccount, error) {
+	m.nonce = nonce
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.user.BattleNet, nil
+}
+
+func (m *mockUserManager) ImportLibrary(ctx context.Context, id, format string, data []byte) (*models.ImportedLibrary, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &models.ImportedLibrary{Format: format, Games: []models.Game{{Name: string(data), Source: format}}}, nil
+}
+func (m *mockUserManager) GetImports(ctx context.Context, id string) ([]models.ImportedLibrary, error) {
+	return m.imports, m.err
+}
+func (m *mockUserManager) DeleteImport(ctx context.Context, id, format string) error { return m.err }
+func (m *mockUserManager) GetManualGames(ctx context.Context, id string) ([]models.ManualGame, error) {
+	return m.manual, m.err
+}
+func (m *mockUserManager) GetManualGame(ctx context.Context, id, gameID string) (*models.ManualGame, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &models.ManualGame{ID: gameID, Name: "Zelda", Platform: "switch", Hours: 10}, nil
+}
+func (m *mockUserManager) AddManualGame(ctx context.Context, id string, game *models.ManualGame) (*models.ManualGame, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	game.ID = "game1"
+	return game, nil
+}
+func (m *mockUserManager) UpdateManualGame(ctx context.Context, id string, game *models.ManualGame) (*models.ManualGame, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return game, nil
+}
+func (m *mockUserManager) DeleteManualGame(ctx context.Context, id, gameID string) error {
+	return m.err
+}
+func (m *mockUserManager) SearchCatalog(name string) ([]models.CatalogGame, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if name != "portal" {
+		return nil, nil
+	}
+	return []models.CatalogGame{{ID: "portal", Name: "Portal", ExternalIDs: map[string][]string{"steam": {"400"}}}}, nil
+}
+func (m *mockUserManager) GetMatches(ctx context.Context, id string) ([]models.GameMatch, error) {
+	return m.matches, m.err
+}
+func (m *mockUserManager) SetMatch(ctx context.Context, id string, match *models.GameMatch) (*models.GameMatch, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	match.Key = strings.ToLower(match.Name)
+	return match, nil
+}
+func (m *mockUserManager) DeleteMatch(ctx context.Context, id, key string) error { return m.err }
 
 func TestHandler(t *testing.T) {
 	var cases = []struct {
@@ -70,13 +192,18 @@ func TestHandler(t *testing.T) {
 		{"Test invalid json request body for POST /user", nil, "/api/v1/user", `{ this is an invalid request body }`,
 			http.MethodPost, http.StatusBadRequest},
 		{"Test ok return for DELETE /user", nil, "/api/v1/user", "", http.MethodDelete, http.StatusOK},
-		{"Test ok return for POST /updategames", nil, "/api/v1/updategames", "", http.MethodPost, http.StatusOK},
+		{"Test ok return for POST /updategames", nil, "/api/v1/updategames?wait=true", "", http.MethodPost, http.StatusOK},
 		{"Test ok return for GET /authcallback", nil, "/api/v1/authcallback", "", http.MethodGet, http.StatusOK},
 
 		// as the redirection is never called (due to mocking), login only returns statusOK
 		{"Test ok return for GET /login", nil, "/api/v1/login", "", http.MethodGet, http.StatusOK},
 		{"Test ok return for GET /user/{username}", nil, "/api/v1/user/test", "", http.MethodGet, http.StatusOK},
-		{"Test invalid username GET /user/{username}", nil, "/api/v1/user/012345678901234567890", "", http.MethodGet, http.StatusNotFound},
+		{"Test invalid username GET /user/{username}", nil, "/api/v1/user/0123456789012345678901234567890", "", http.MethodGet, http.StatusNotFound},
+		{"Test invalid method PUT /user", nil, "/api/v1/user", "", http.MethodPut, http.StatusMethodNotAllowed},
+		{"Test invalid auth state GET /authcallback", models.ErrInvalidAuthState, "/api/v1/authcallback", "", http.MethodGet,
+			http.StatusBadRequest},
+		{"Test unexpected error GET /authcallback", errors.New("test"), "/api/v1/authcallback", "", http.MethodGet,
+			http.StatusInternalServerError},
 	}
 
 	um := &mockUserManager{}
@@ -94,6 +221,7 @@ func TestHandler(t *testing.T) {
 			// Initializing mock structs with random data
 			err := faker.FakeData(&um.user)
 			require.Nil(t, err)
+			um.user.Name = "test" // other names are previous names, which redirect
 			err = faker.FakeData(&um.response)
 			require.Nil(t, err)
 
@@ -113,7 +241,19 @@ func TestHandler(t *testing.T) {
 
 			// Body should only be parsed if expected to succeed, and it actually succeeded
 			//  should assert.Equal regardless of expected status
-			if !assert.Equal(t, tc.expectedStatus, resp.StatusCode) || tc.expectedStatus != http.StatusOK {
+			if !assert.Equal(t, tc.expectedStatus, resp.StatusCode) {
+				return
+			}
+
+			// every error should be returned as a problem with the same status code
+			if tc.expectedStatus != http.StatusOK {
+				assert.Equal(t, models.ProblemContentType, resp.Header.Get("Content-Type"))
+				var problem models.Problem
+				err = json.NewDecoder(resp.Body).Decode(&problem)
+				assert.Nil(t, err)
+				assert.Equal(t, tc.expectedStatus, problem.Status)
+				assert.NotEmpty(t, problem.Code)
+
 				return
 			}
 
@@ -124,13 +264,15 @@ func TestHandler(t *testing.T) {
 				removeIgnoredOutput(um.user, tc.url)
 				assert.Equal(t, um.user, userResp)
 			} else if tc.url == "/api/v1/authcallback" {
-				body, err := ioutil.ReadAll(resp.Body)
+				var token tokenResponse
+				err = json.NewDecoder(resp.Body).Decode(&token)
 				assert.Nil(t, err)
-				assert.Equal(t, um.response, string(body))
+				assert.Equal(t, um.response, token.Token)
 			} else if tc.url != "/api/v1/login" {
-				body, err := ioutil.ReadAll(resp.Body)
+				var status statusResponse
+				err = json.NewDecoder(resp.Body).Decode(&status)
 				assert.Nil(t, err)
-				assert.Equal(t, "Success", string(body))
+				assert.Equal(t, *success, status)
 			}
 		})
 	}
@@ -140,11 +282,12 @@ func TestHandler(t *testing.T) {
 func mockRouter(h *handler) *mux.Router {
 	r := mux.NewRouter()
 	r.NotFoundHandler = http.HandlerFunc(h.notFound)
+	r.MethodNotAllowedHandler = http.HandlerFunc(h.methodNotAllowed)
 
 	get := r.PathPrefix("/api/v1").Methods(http.MethodGet).Subrouter()
 	get.HandleFunc("/login", h.login).Name("login")
 	get.HandleFunc("/authcallback", h.authCallbackHandler).Name("authCallback")
-	get.HandleFunc("/user/{username:[a-zA-Z0-9 ]{1,15}}", h.getPublicUser).Name("getPublicUser")
+	get.HandleFunc("/user/"+usernameVar, h.getPublicUser).Name("getPublicUser")
 
 	auth := r.PathPrefix("/api/v1/").Subrouter()
 	auth.HandleFunc("/user", h.getUser).Methods(http.MethodGet).Name("getUser")
@@ -155,11 +298,379 @@ func mockRouter(h *handler) *mux.Router {
 	return r
 }
 
-// userID and valveID is not returned to the user. valveID is only used to differentiate the games internaly
+// userID and valveID is not returned to the user. valveID is only used to differentiate the games internaly.
+// The version is only returned as the ETag in version 2 of the API, and the Battle.net token is never returned.
 func removeIgnoredOutput(user *models.User, url string) {
 	user.ID = ""
+	user.Version = 0
+	user.NameChangedAt, user.PreviousNames = 0, nil // the name history is only returned in version 2 of the API
+	user.DeletedAt, user.PurgeAt = 0, 0             // the deletion is only returned in version 2 of the API
+	user.Skeleton = ""                              // only stored, to find lookalike names
+	if user.BattleNet != nil {
+		user.BattleNet.Token = ""
+	}
 
 	if strings.Contains(url, "/api/v1/user/") { // true means it's a test for /api/v1/user/{username}
 		user.Public = false // public should then be ignored as it is not returned
 	}
+
+	// empty breakdowns are omitted, and therefore decoded as nil
+	for i := range user.Games {
+		if len(user.Games[i].Modes) == 0 {
+			user.Games[i].Modes = nil
+		}
+		if len(user.Games[i].Heroes) == 0 {
+			user.Games[i].Heroes = nil
+		}
+		if len(user.Games[i].Characters) == 0 {
+			user.Games[i].Characters = nil
+		}
+		if len(user.Games[i].Skills) == 0 {
+			user.Games[i].Skills = nil
+		}
+		if len(user.Games[i].Activities) == 0 {
+			user.Games[i].Activities = nil
+		}
+		if len(user.Games[i].Merged) == 0 {
+			user.Games[i].Merged = nil
+		}
+		if user.Games[i].Metadata != nil {
+			user.Games[i].Metadata.FetchedAt = 0 // only used to refresh the cached metadata
+		}
+	}
+}
+
+func TestHandlerStats(t *testing.T) {
+	var cases = []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"Test ok", nil, http.StatusOK},
+		{"Test not found", models.ErrNotFound, http.StatusNotFound},
+		{"Test invalid id", models.ErrInvalidID, http.StatusForbidden},
+	}
+
+	percentile := 87.5
+	um := &mockUserManager{stats: &models.Stats{
+		TotalMinutes: 600,
+		Games:        1,
+		TopGames:     []models.GameShare{{Name: "Portal", Minutes: 600, Share: 100}},
+		Providers:    []models.ProviderPlaytime{{Provider: "steam", Minutes: 600, Games: 1}},
+		Genres:       []models.GenrePlaytime{{Genre: "Action", Minutes: 600, Games: 1}},
+		MostImproved: &models.GameImprovement{Name: "Portal", Minutes: 60},
+		Percentile:   &percentile,
+	}}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			um.err = tc.err
+
+			req, err := http.NewRequest(http.MethodGet, "/api/v1/user/stats", nil)
+			require.Nil(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			// the stats are responded, rather than "stats" being taken as the username of a public user
+			var stats models.Stats
+			err = json.NewDecoder(w.Body).Decode(&stats)
+			assert.Nil(t, err)
+			assert.Equal(t, um.stats, &stats)
+		})
+	}
+}
+
+func TestHandlerBadge(t *testing.T) {
+	var cases = []struct {
+		name           string
+		path           string
+		header         string // the If-None-Match header
+		err            error
+		expectedStatus int
+		expectedType   string
+	}{
+		{"Test svg", "/api/v1/user/onijuan/badge.svg", "", nil, http.StatusOK, "image/svg+xml"},
+		{"Test png", "/api/v1/user/onijuan/badge.png?theme=light&size=large", "", nil, http.StatusOK, "image/png"},
+		{"Test small", "/api/v1/user/onijuan/badge.svg?size=small", "", nil, http.StatusOK, "image/svg+xml"},
+		{"Test not modified", "/api/v1/user/onijuan/badge.svg", `"3"`, nil, http.StatusNotModified, ""},
+		{"Test modified", "/api/v1/user/onijuan/badge.svg", `"2"`, nil, http.StatusOK, "image/svg+xml"},
+		{"Test invalid theme", "/api/v1/user/onijuan/badge.svg?theme=pink", "", nil, http.StatusBadRequest, ""},
+		{"Test invalid size", "/api/v1/user/onijuan/badge.svg?size=huge", "", nil, http.StatusBadRequest, ""},
+		{"Test invalid format", "/api/v1/user/onijuan/badge.gif", "", nil, http.StatusNotFound, ""},
+		{"Test private or no user", "/api/v1/user/onijuan/badge.svg", "", models.ErrNotFound, http.StatusNotFound, ""},
+	}
+
+	um := &mockUserManager{user: &models.User{Name: "onijuan", Public: true, TotalGameTime: 10, Version: 3,
+		Games: []models.Game{{Name: "Portal", AppID: 400, Minutes: 600}}}}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			um.err = tc.err
+
+			req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.Nil(t, err)
+			req.Header.Set("If-None-Match", tc.header)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.err != nil || tc.expectedStatus >= 400 {
+				return
+			}
+
+			// the badge is cached, and revalidated with the ETag
+			assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+			assert.Equal(t, "public, max-age=3600", w.Header().Get("Cache-Control"))
+
+			if tc.expectedStatus == http.StatusNotModified {
+				assert.Empty(t, w.Body.Bytes())
+				return
+			}
+
+			assert.Equal(t, tc.expectedType, w.Header().Get("Content-Type"))
+			assert.NotEmpty(t, w.Body.Bytes())
+		})
+	}
+}
+
+func TestHandlerRenamed(t *testing.T) {
+	var cases = []struct {
+		name             string
+		path             string
+		expectedLocation string
+	}{
+		{"Test public user", "/api/v1/user/OldName", "/api/v1/user/new%20name"},
+		{"Test badge", "/api/v1/user/oldname/badge.png?theme=light", "/api/v1/user/new%20name/badge.png?theme=light"},
+		{"Test public user v2", "/api/v2/users/oldname?sort=game", "/api/v2/users/new%20name?sort=game"},
+	}
+
+	// the user is found by a previous name, which redirects to the current name
+	um := &mockUserManager{user: &models.User{Name: "new name", Public: true, PreviousNames: []models.PreviousName{
+		{Name: "oldname", ChangedAt: 1571443200}}}}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.Nil(t, err)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, tc.expectedLocation, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHandlerRecap(t *testing.T) {
+	var cases = []struct {
+		name                string
+		path                string
+		err                 error
+		expectedStatus      int
+		expectedType        string
+		expectedDisposition string
+	}{
+		{"Test json", "/api/v1/user/recap/2024", nil, http.StatusOK, "application/json", ""},
+		{"Test json format", "/api/v1/user/recap/2024?format=json", nil, http.StatusOK, "application/json", ""},
+		{"Test svg", "/api/v1/user/recap/2024?format=svg", nil, http.StatusOK, "image/svg+xml",
+			`inline; filename="recap-2024.svg"`},
+		{"Test png", "/api/v1/user/recap/2024?format=png", nil, http.StatusOK, "image/png",
+			`inline; filename="recap-2024.png"`},
+		{"Test invalid format", "/api/v1/user/recap/2024?format=gif", nil, http.StatusBadRequest, "", ""},
+		{"Test invalid year", "/api/v1/user/recap/24", nil, http.StatusNotFound, "", ""},
+		{"Test no history", "/api/v1/user/recap/2024", models.ErrNotFound, http.StatusNotFound, "", ""},
+		{"Test future year", "/api/v1/user/recap/2999",
+			models.NewReqErrStr("future year", "invalid year: 2999 has not started"), http.StatusBadRequest, "", ""},
+	}
+
+	um := &mockUserManager{recap: &models.Recap{
+		Year:        2024,
+		Name:        "Onijuan",
+		Minutes:     600,
+		Games:       []models.GamePlaytime{{Name: "Portal", Minutes: 600}},
+		NewGames:    []string{"Portal"},
+		BiggestWeek: &models.RecapWeek{Start: "2024-11-18", Minutes: 600},
+		Rank:        &models.Rank{Rank: 1, Users: 2},
+		From:        "2024-01-01",
+		To:          "2024-11-20",
+	}}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			um.err = tc.err
+
+			req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.Nil(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, tc.expectedType, w.Header().Get("Content-Type"))
+			assert.Equal(t, tc.expectedDisposition, w.Header().Get("Content-Disposition"))
+
+			if tc.expectedType == "application/json" {
+				var recap models.Recap
+				err = json.NewDecoder(w.Body).Decode(&recap)
+				assert.Nil(t, err)
+				assert.Equal(t, um.recap, &recap)
+			}
+		})
+	}
+}
+
+func TestHandlerUpdateGamesWait(t *testing.T) {
+	var cases = []struct {
+		name           string
+		path           string
+		err            error
+		expectedStatus int
+		expectedJob    bool
+	}{
+		{"Test background by default", "/api/v1/updategames", nil, http.StatusAccepted, true},
+		{"Test not waiting", "/api/v1/updategames?wait=false", nil, http.StatusAccepted, true},
+		{"Test wait", "/api/v1/updategames?wait=true", nil, http.StatusOK, false},
+		{"Test invalid wait", "/api/v1/updategames?wait=maybe", nil, http.StatusBadRequest, false},
+		{"Test no user", "/api/v1/updategames", models.ErrNotFound, http.StatusNotFound, false},
+		{"Test not updated in time", "/api/v1/updategames?wait=1", &models.JobPendingError{}, http.StatusAccepted, true},
+	}
+
+	um := &mockUserManager{job: &models.Job{ID: "1a2b3c", Type: models.JobRefresh, Status: models.JobRunning,
+		Providers: []models.ProviderProgress{}}}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			um.err = tc.err
+			if pending, ok := tc.err.(*models.JobPendingError); ok {
+				pending.Job = um.job
+			}
+
+			req, err := http.NewRequest(http.MethodPost, tc.path, nil)
+			require.Nil(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || !tc.expectedJob {
+				return
+			}
+
+			var job models.Job
+			err = json.NewDecoder(w.Body).Decode(&job)
+			assert.Nil(t, err)
+			assert.Equal(t, um.job, &job)
+		})
+	}
+}
+
+func TestHandlerJob(t *testing.T) {
+	var cases = []struct {
+		name           string
+		path           string
+		err            error
+		expectedStatus int
+	}{
+		{"Test ok", "/api/v1/jobs/0123456789abcdef01234567", nil, http.StatusOK},
+		{"Test not found", "/api/v1/jobs/0123456789abcdef01234567", models.ErrNotFound, http.StatusNotFound},
+		{"Test invalid id", "/api/v1/jobs/not-a-job", nil, http.StatusNotFound},
+	}
+
+	um := &mockUserManager{job: &models.Job{ID: "0123456789abcdef01234567", Type: models.JobValidate,
+		Status: models.JobQueued, Attempts: 1, Providers: []models.ProviderProgress{},
+		Error: models.NewProblem(http.StatusBadGateway, models.CodeExternalAPIError, "Error contacting Valve API")}}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			um.err = tc.err
+
+			req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.Nil(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var job models.Job
+			err = json.NewDecoder(w.Body).Decode(&job)
+			assert.Nil(t, err)
+			assert.Equal(t, um.job, &job)
+		})
+	}
+}
+
+func TestHandlerEvents(t *testing.T) {
+	var cases = []struct {
+		name           string
+		path           string
+		err            error
+		expectedStatus int
+		expectedFollow []string
+	}{
+		{"Test events", "/api/v1/events", nil, http.StatusOK, nil},
+		{"Test follow", "/api/v1/events?follow=Onijuan,%20test", nil, http.StatusOK, []string{"onijuan", "test"}},
+		{"Test follow not public", "/api/v1/events?follow=private", models.ErrNotFound, http.StatusNotFound,
+			[]string{"private"}},
+	}
+
+	um := &mockUserManager{events: []models.Event{
+		{Type: models.EventJob, UserID: "12345", Data: &models.Job{ID: "1a2b3c", Type: models.JobRefresh,
+			Status: models.JobDone, Attempts: 1, Providers: []models.ProviderProgress{{Provider: "lol",
+				Status: models.ProviderDone, Games: 1}}, Result: &models.JobResult{TotalPlayTime: 3, Games: 1},
+			CreatedAt: 1571443200, UpdatedAt: 1571443205}},
+		{Type: models.EventTotal, UserID: "67890", Data: &models.TotalChange{Name: "onijuan", TotalPlayTime: 10,
+			Previous: 8}},
+	}}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			um.err = tc.err
+
+			req, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			require.Nil(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+			// the stream ends when the subscription ends, which the mock does after the events
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.expectedFollow, um.follow)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+			assert.True(t, w.Flushed)
+			assert.Equal(t, "retry: 1000\n\n"+
+				"event: job\n"+
+				`data: {"id":"1a2b3c","type":"refresh","status":"done","attempts":1,"providers":[{"provider":"lol",`+
+				`"status":"done","games":1}],"result":{"totalPlayTime":3,"games":1},"createdAt":1571443200,`+
+				`"updatedAt":1571443205}`+"\n\n"+
+				"event: total\n"+
+				`data: {"name":"onijuan","totalPlayTime":10,"previous":8}`+"\n\n", w.Body.String())
+		})
+	}
 }
@@ -0,0 +1,947 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"ctp/pkg/models"
+
+	"github.com/gorilla/mux"
+)
+
+// battleNetStateCookie keeps the nonce of the Battle.net OAuth state in the browser which started linking the account,
+// such that the callback is only accepted from that browser
+const battleNetStateCookie = "battlenetstate"
+
+// meV2 is the representation of the user themselves in version 2 of the API.
+// Every member is always present, such that a JSON merge patch can set (or remove) any of them.
+type meV2 struct {
+	Name          string                `json:"name"`
+	DisplayName   string                `json:"displayName"`
+	Public        bool                  `json:"public"`
+	TotalPlayTime int                   `json:"totalPlayTime"` // read only
+	Hidden        []string              `json:"hidden"`        // the fields hidden from other users, see models.HideableFields
+	PreviousNames []models.PreviousName `json:"previousNames"` // read only, oldest first
+	PurgeAt       *int64                `json:"purgeAt"`       // read only, unix time the deleted user is purged. Null unless deleted
+	Accounts      accountsV2            `json:"accounts"`
+}
+
+// accountsV2 contains the accounts the user has linked. Accounts which are not linked are null.
+// The JSON names of the members are the providers used in /api/v2/me/accounts/{provider}.
+type accountsV2 struct {
+	Lol       *models.SummonerRegistration `json:"lol"`
+	Valve     *models.ValveAccount         `json:"valve"`
+	Overwatch *models.Overwatch            `json:"overwatch"`
+	Runescape *models.RunescapeAccount     `json:"runescape"`
+	BattleNet *models.BattleNetAccount     `json:"battlenet"` // linked through /me/accounts/battlenet/authorize
+}
+
+// battleNetAuthorization contains the URL the user should visit to authorize linking their Battle.net account
+type battleNetAuthorization struct {
+	URL string `json:"url"`
+}
+
+// gamesV2 is the representation of the user's games in version 2 of the API
+type gamesV2 struct {
+	TotalPlayTime int           `json:"totalPlayTime"`
+	Games         []models.Game `json:"games"`
+}
+
+// genresV2 contains the playtime of the user's games by genre
+type genresV2 struct {
+	Genres []models.GenrePlaytime `json:"genres"`
+}
+
+// importsV2 contains the libraries the user has imported from launchers
+type importsV2 struct {
+	Imports []models.ImportedLibrary `json:"imports"`
+}
+
+// manualGamesV2 contains the games the user records the playtime of manually
+type manualGamesV2 struct {
+	Games []models.ManualGame `json:"games"`
+}
+
+// catalogV2 contains the games in the catalog matching a search
+type catalogV2 struct {
+	Games []models.CatalogGame `json:"games"`
+}
+
+// matchesV2 contains the user's overrides of how games are matched to the catalog
+type matchesV2 struct {
+	Matches []models.GameMatch `json:"matches"`
+}
+
+// maxUploadSize is the largest file (in bytes) which can be imported
+const maxUploadSize = 10 << 20
+
+// publicUserV2 is the representation of a public user in version 2 of the API
+type publicUserV2 struct {
+	Name          string        `json:"name"`
+	DisplayName   string        `json:"displayName,omitempty"`
+	TotalPlayTime int           `json:"totalPlayTime"`
+	Hidden        []string      `json:"hidden,omitempty"` // the fields hidden by the user
+	Games         []models.Game `json:"games"`
+}
+
+// newMeV2 returns the representation of the user themselves
+func newMeV2(user *models.User) *meV2 {
+	previous := user.PreviousNames
+	if previous == nil {
+		previous = []models.PreviousName{}
+	}
+
+	hidden := user.Hidden
+	if hidden == nil {
+		hidden = []string{}
+	}
+
+	var purgeAt *int64
+	if user.PendingDeletion() {
+		purgeAt = &user.PurgeAt
+	}
+
+	return &meV2{
+		Name:          user.Name,
+		DisplayName:   user.DisplayName,
+		Public:        user.Public,
+		TotalPlayTime: user.TotalGameTime,
+		Hidden:        hidden,
+		PreviousNames: previous,
+		PurgeAt:       purgeAt,
+		Accounts: accountsV2{
+			Lol:       user.Lol,
+			Valve:     user.Valve,
+			Overwatch: user.Overwatch,
+			Runescape: user.Runescape,
+			BattleNet: user.BattleNet,
+		},
+	}
+}
+
+// newGamesV2 returns the representation of the user's games. Games is never null.
+func newGamesV2(user *models.User) *gamesV2 {
+	games := user.Games
+	if games == nil {
+		games = []models.Game{}
+	}
+
+	return &gamesV2{TotalPlayTime: user.TotalGameTime, Games: games}
+}
+
+// getMe returns the user themselves
+func (h *handler) getMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	respondVersioned(w, r, user, newMeV2(user))
+}
+
+// patchMe applies a JSON merge patch to the user themselves. Members set to null are removed.
+func (h *handler) patchMe(w http.ResponseWriter, r *http.Request) {
+	patch, err := readBody(r, models.MergePatchContentType)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	user, ok := h.replaceMe(w, r, func(me *meV2) error {
+		return mergePatchInto(me, patch)
+	})
+	if !ok {
+		return
+	}
+
+	respondVersioned(w, r, user, newMeV2(user))
+}
+
+// deleteMe deletes the user and all information stored about them. The user can be restored by restoreMe until they
+// are purged, once the grace period is over.
+func (h *handler) deleteMe(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	err := checkIfMatch(r, user)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	err = h.DeleteUser(r.Context(), user.ID, nil)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restoreMe restores the user after they deleted themselves, as long as they have not been purged
+func (h *handler) restoreMe(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	user, err := h.RestoreUser(r.Context(), id)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	respondVersioned(w, r, user, newMeV2(user))
+}
+
+// getAccount returns one of the accounts linked by the user
+func (h *handler) getAccount(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	accounts, err := accountMap(&newMeV2(user).Accounts)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	account := accounts[mux.Vars(r)["provider"]]
+	if isNull(account) {
+		logRespond(w, r, models.ErrNotFound)
+		return
+	}
+
+	respondVersioned(w, r, user, account)
+}
+
+// putAccount links an account, replacing the account already linked for the provider (if any)
+func (h *handler) putAccount(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r, "application/json")
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	h.modifyAccount(w, r, func(account json.RawMessage) (json.RawMessage, error) {
+		if isNull(body) {
+			return nil, models.NewReqErrStr("null account", "invalid request body: use DELETE to remove the account")
+		}
+
+		return body, nil
+	})
+}
+
+// patchAccount applies a JSON merge patch to an account linked by the user
+func (h *handler) patchAccount(w http.ResponseWriter, r *http.Request) {
+	patch, err := readBody(r, models.MergePatchContentType)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	h.modifyAccount(w, r, func(account json.RawMessage) (json.RawMessage, error) {
+		if isNull(account) {
+			return nil, models.ErrNotFound
+		}
+
+		return models.MergePatch(account, patch)
+	})
+}
+
+// deleteAccount removes an account linked by the user
+func (h *handler) deleteAccount(w http.ResponseWriter, r *http.Request) {
+	_, ok := h.replaceMe(w, r, func(me *meV2) error {
+		accounts, err := accountMap(&me.Accounts)
+		if err != nil {
+			return err
+		}
+
+		provider := mux.Vars(r)["provider"]
+		if isNull(accounts[provider]) {
+			return models.ErrNotFound
+		}
+
+		accounts[provider] = nil
+
+		return setAccounts(&me.Accounts, accounts)
+	})
+	if !ok {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getGames returns the user's games and total playtime. The games can be filtered and sorted, see models.ParseGameQuery.
+func (h *handler) getGames(w http.ResponseWriter, r *http.Request) {
+	query, err := models.ParseGameQuery(r.URL.Query())
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	games := newGamesV2(user)
+	games.Games = query.Apply(games.Games)
+
+	respondVersioned(w, r, user, games)
+}
+
+// getGenres returns the playtime of the user's games by genre, most played first
+func (h *handler) getGenres(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	respondVersioned(w, r, user, &genresV2{Genres: models.PlaytimeByGenre(user.Games)})
+}
+
+// refreshGames fetches new data from the accounts linked by the user, and returns the updated games
+func (h *handler) refreshGames(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	err = h.UpdateGames(r.Context(), id)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	respondVersioned(w, r, user, newGamesV2(user))
+}
+
+// getPublicUserV2 returns a public user by their username. The games can be filtered and sorted like in getGames.
+// Previous names of the user redirect to the user's current name as long as they are held for the user.
+func (h *handler) getPublicUserV2(w http.ResponseWriter, r *http.Request) {
+	query, err := models.ParseGameQuery(r.URL.Query())
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	user, err := h.GetUserByName(r.Context(), strings.ToLower(mux.Vars(r)["username"]))
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	if redirectRenamed(w, r, user) {
+		return
+	}
+
+	public := user.Publicly()
+	games := query.Apply(public.Games)
+	respondVersioned(w, r, user, &publicUserV2{Name: public.Name, DisplayName: public.DisplayName,
+		TotalPlayTime: public.TotalGameTime, Hidden: public.Hidden, Games: games})
+}
+
+// authorizeBattleNet returns the URL the user should visit to link their Battle.net account.
+// The region of the account can be given by the "region" query parameter, defaulting to "us".
+func (h *handler) authorizeBattleNet(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	u, nonce, err := h.AuthorizeBattleNet(id, r.URL.Query().Get("region"))
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	// the callback is a top level navigation from Battle.net, which only carries cookies which are not strictly same site
+	http.SetCookie(w, &http.Cookie{
+		Name:     battleNetStateCookie,
+		Value:    nonce,
+		Path:     "/api/v2/battlenet/callback",
+		MaxAge:   int(models.BattleNetStateTTL / time.Second),
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	respond(w, r, &battleNetAuthorization{URL: u})
+}
+
+// battleNetCallback handles the callback when the user is redirected back from Battle.net, and returns the linked account.
+// The user is identified by the state, as the request is not authenticated, which is only accepted along with the nonce
+// kept by the browser which started linking the account.
+func (h *handler) battleNetCallback(w http.ResponseWriter, r *http.Request) {
+	// removing the cookie as the state should not be used again, regardless of whether it is valid or not
+	http.SetCookie(w, &http.Cookie{Name: battleNetStateCookie, Path: "/api/v2/battlenet/callback", MaxAge: -1})
+
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		logRespond(w, r, models.NewReqErrStr("battle.net authorization denied: "+query.Get("error"),
+			"the Battle.net account was not authorized"))
+		return
+	}
+
+	cookie, err := r.Cookie(battleNetStateCookie)
+	if err != nil {
+		logRespond(w, r, models.ErrInvalidAuthState)
+		return
+	}
+
+	account, err := h.LinkBattleNet(r.Context(), query.Get("code"), query.Get("state"), cookie.Value)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	respond(w, r, account)
+}
+
+// getImports returns the libraries the user has imported from launchers. Imports is never null.
+func (h *handler) getImports(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	imports, err := h.GetImports(r.Context(), user.ID)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	if imports == nil {
+		imports = []models.ImportedLibrary{}
+	}
+
+	// importing a library updates the games, and thereby the version of the user
+	respondVersioned(w, r, user, &importsV2{Imports: imports})
+}
+
+// importLibrary imports the file exported from a launcher, in the format given by the "format" route variable.
+// The file is either the body of the request, or the "file" field of a multipart form.
+func (h *handler) importLibrary(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	data, err := readUpload(w, r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	library, err := h.ImportLibrary(r.Context(), id, mux.Vars(r)["format"], data)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	respond(w, r, library)
+}
+
+// deleteImport removes the library imported in the format given by the "format" route variable
+func (h *handler) deleteImport(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	err = h.DeleteImport(r.Context(), id, mux.Vars(r)["format"])
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getManualGames returns the games the user records the playtime of manually
+func (h *handler) getManualGames(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	games, err := h.GetManualGames(r.Context(), user.ID)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	if games == nil {
+		games = []models.ManualGame{}
+	}
+
+	// changing the manual games updates the games, and thereby the version of the user
+	respondVersioned(w, r, user, &manualGamesV2{Games: games})
+}
+
+// addManualGame records a new game the user tracks the playtime of manually
+func (h *handler) addManualGame(w http.ResponseWriter, r *http.Request) {
+	h.storeManualGame(w, r, func(ctx context.Context, id string, game *models.ManualGame) (*models.ManualGame, error) {
+		return h.AddManualGame(ctx, id, game)
+	})
+}
+
+// getManualGame returns the manual game given by the "gameId" route variable
+func (h *handler) getManualGame(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	game, err := h.GetManualGame(r.Context(), id, mux.Vars(r)["gameId"])
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	respond(w, r, game)
+}
+
+// putManualGame replaces the manual game given by the "gameId" route variable
+func (h *handler) putManualGame(w http.ResponseWriter, r *http.Request) {
+	h.storeManualGame(w, r, func(ctx context.Context, id string, game *models.ManualGame) (*models.ManualGame, error) {
+		game.ID = mux.Vars(r)["gameId"]
+		return h.UpdateManualGame(ctx, id, game)
+	})
+}
+
+// deleteManualGame removes the manual game given by the "gameId" route variable
+func (h *handler) deleteManualGame(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	err = h.DeleteManualGame(r.Context(), id, mux.Vars(r)["gameId"])
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// storeManualGame decodes the manual game in the body of the request, stores it and responds with the stored game
+func (h *handler) storeManualGame(w http.ResponseWriter, r *http.Request,
+	store func(ctx context.Context, id string, game *models.ManualGame) (*models.ManualGame, error)) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	body, err := readBody(r, "application/json")
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	var game models.ManualGame
+
+	err = decodeStrict(body, &game)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	stored, err := store(r.Context(), id, &game)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	respond(w, r, stored)
+}
+
+// searchCatalog returns the games in the catalog with a name or alias containing the "name" query parameter
+func (h *handler) searchCatalog(w http.ResponseWriter, r *http.Request) {
+	games, err := h.SearchCatalog(r.URL.Query().Get("name"))
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	if games == nil {
+		games = []models.CatalogGame{}
+	}
+
+	respond(w, r, &catalogV2{Games: games})
+}
+
+// getMatches returns the user's overrides of how games are matched to the catalog
+func (h *handler) getMatches(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	matches, err := h.GetMatches(r.Context(), user.ID)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	if matches == nil {
+		matches = []models.GameMatch{}
+	}
+
+	// changing the matches updates the games, and thereby the version of the user
+	respondVersioned(w, r, user, &matchesV2{Matches: matches})
+}
+
+// setMatch stores the user's override of how the games with a name are matched to the catalog
+func (h *handler) setMatch(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	body, err := readBody(r, "application/json")
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	var match models.GameMatch
+
+	err = decodeStrict(body, &match)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	stored, err := h.SetMatch(r.Context(), id, &match)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	respond(w, r, stored)
+}
+
+// deleteMatch removes the override given by the "key" route variable, such that the games are matched by the catalog
+func (h *handler) deleteMatch(w http.ResponseWriter, r *http.Request) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	err = h.DeleteMatch(r.Context(), id, mux.Vars(r)["key"])
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentUser gets the authenticated user. If it fails, the error is responded and false is returned.
+func (h *handler) currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	id, err := getID(r)
+	if err != nil {
+		logRespond(w, r, err)
+		return nil, false
+	}
+
+	user, err := h.GetUserByID(r.Context(), id)
+	if err != nil {
+		logRespond(w, r, err)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// replaceMe modifies the representation of the user themselves, and replaces the stored user with the result.
+// The request is rejected if it has an If-Match header which does not match the stored user,
+// or if the user is modified by another request before it is replaced.
+// If it fails, the error is responded and false is returned.
+func (h *handler) replaceMe(w http.ResponseWriter, r *http.Request, modify func(me *meV2) error) (*models.User, bool) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	err := checkIfMatch(r, user)
+	if err != nil {
+		logRespond(w, r, err)
+		return nil, false
+	}
+
+	me := newMeV2(user)
+
+	err = modify(me)
+	if err != nil {
+		logRespond(w, r, err)
+		return nil, false
+	}
+
+	if me.TotalPlayTime != user.TotalGameTime {
+		logRespond(w, r, models.NewReqErrStr("read only field", "invalid request body: totalPlayTime can not be modified"))
+		return nil, false
+	}
+
+	if !reflect.DeepEqual(me.PreviousNames, newMeV2(user).PreviousNames) {
+		logRespond(w, r, models.NewReqErrStr("read only field", "invalid request body: previousNames can not be modified"))
+		return nil, false
+	}
+
+	if !reflect.DeepEqual(me.PurgeAt, newMeV2(user).PurgeAt) {
+		logRespond(w, r, models.NewReqErrStr("read only field", "invalid request body: purgeAt can not be modified"))
+		return nil, false
+	}
+
+	replacement := *user
+	replacement.Name = me.Name
+	replacement.DisplayName = me.DisplayName
+	replacement.Public = me.Public
+	replacement.Hidden = me.Hidden
+	replacement.Lol = me.Accounts.Lol
+	replacement.Valve = me.Accounts.Valve
+	replacement.Overwatch = me.Accounts.Overwatch
+	replacement.Runescape = me.Accounts.Runescape
+	replacement.BattleNet = me.Accounts.BattleNet
+
+	// the Battle.net account is linked through OAuth, and can only be kept as is or removed
+	if me.Accounts.BattleNet != nil && !sameBattleNet(me.Accounts.BattleNet, user.BattleNet) {
+		logRespond(w, r, models.NewReqErrStr("battle.net account modified",
+			"invalid request body: Battle.net accounts are linked through /api/v2/me/accounts/battlenet/authorize"))
+		return nil, false
+	}
+
+	updated, err := h.ReplaceUser(r.Context(), &replacement, user.Version)
+	if err != nil {
+		logRespond(w, r, err)
+		return nil, false
+	}
+
+	return updated, true
+}
+
+// modifyAccount replaces the account given by the "provider" route variable with the result of modify,
+// and responds with the updated account
+func (h *handler) modifyAccount(w http.ResponseWriter, r *http.Request, modify func(account json.RawMessage) (json.RawMessage, error)) {
+	provider := mux.Vars(r)["provider"]
+
+	user, ok := h.replaceMe(w, r, func(me *meV2) error {
+		accounts, err := accountMap(&me.Accounts)
+		if err != nil {
+			return err
+		}
+
+		accounts[provider], err = modify(accounts[provider])
+		if err != nil {
+			return err
+		}
+
+		return setAccounts(&me.Accounts, accounts)
+	})
+	if !ok {
+		return
+	}
+
+	accounts, err := accountMap(&newMeV2(user).Accounts)
+	if err != nil {
+		logRespond(w, r, err)
+		return
+	}
+
+	respondVersioned(w, r, user, accounts[provider])
+}
+
+// accountMap returns the accounts as a map from the provider to the JSON encoded account
+func accountMap(accounts *accountsV2) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]json.RawMessage
+
+	err = json.Unmarshal(raw, &m)
+
+	return m, err
+}
+
+// setAccounts decodes the map from the provider to the JSON encoded account into accounts
+func setAccounts(accounts *accountsV2, m map[string]json.RawMessage) error {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	*accounts = accountsV2{}
+
+	return decodeStrict(raw, accounts)
+}
+
+// sameBattleNet returns true if the account is the stored Battle.net account, which is never given the token
+func sameBattleNet(account, stored *models.BattleNetAccount) bool {
+	if stored == nil {
+		return false
+	}
+
+	withoutToken := *stored
+	withoutToken.Token = account.Token
+
+	return *account == withoutToken
+}
+
+// mergePatchInto applies the JSON merge patch to the JSON representation of the user, and decodes the result back into me.
+// Members removed by the patch are reset to their zero value.
+func mergePatchInto(me *meV2, patch []byte) error {
+	doc, err := json.Marshal(me)
+	if err != nil {
+		return err
+	}
+
+	patched, err := models.MergePatch(doc, patch)
+	if err != nil {
+		return err
+	}
+
+	*me = meV2{}
+
+	return decodeStrict(patched, me)
+}
+
+// decodeStrict decodes the JSON data into v, rejecting unknown members
+func decodeStrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err != nil {
+		return models.NewReqErr(err, "invalid request body: "+err.Error())
+	}
+
+	return nil
+}
+
+// readBody reads the body of the request, which has to be of the given content type
+func readBody(r *http.Request, contentType string) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != contentType {
+		return nil, fmt.Errorf("expected %s: %w", contentType, models.ErrUnsupportedMediaType)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, models.NewReqErr(err, "invalid request body")
+	}
+
+	return body, nil
+}
+
+// readUpload reads the file uploaded as the "file" field of a multipart form, or as the body of the request
+func readUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	tooLarge := fmt.Sprintf("invalid request body: the file is larger than %d MB", maxUploadSize>>20)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, models.NewReqErr(err, tooLarge)
+		}
+
+		return body, nil
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, models.NewReqErr(err, `invalid request body: expected the file in the "file" field`)
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, models.NewReqErr(err, tooLarge)
+	}
+
+	return data, nil
+}
+
+// isNull checks whether the JSON value is missing or null
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+// etag returns the entity tag for the user's version. Every representation of the user shares the version.
+func etag(user *models.User) string {
+	return fmt.Sprintf(`"%d"`, user.Version)
+}
+
+// etagMatches checks whether any of the entity tags in the If-Match or If-None-Match header matches tag.
+// If weak is true, weak entity tags (W/"...") are compared as if they were strong.
+func etagMatches(header, tag string, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkIfMatch returns models.ErrPreconditionFailed if the request has an If-Match header which does not match the user
+func checkIfMatch(r *http.Request, user *models.User) error {
+	header := r.Header.Get("If-Match")
+	if header != "" && !etagMatches(header, etag(user), false) {
+		return models.ErrPreconditionFailed
+	}
+
+	return nil
+}
+
+// respondVersioned responds with a representation of the user, setting the ETag header.
+// If the request has an If-None-Match header matching the user, 304 Not Modified is returned without a body.
+func respondVersioned(w http.ResponseWriter, r *http.Request, user *models.User, resp interface{}) {
+	tag := etag(user)
+	w.Header().Set("ETag", tag)
+
+	header := r.Header.Get("If-None-Match")
+	if r.Method == http.MethodGet && header != "" && etagMatches(header, tag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	respond(w, r, resp)
+}
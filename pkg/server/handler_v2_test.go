@@ -0,0 +1,638 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ctp/pkg/models"
+
+	"github.com/bxcodec/faker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerV2(t *testing.T) {
+	var cases = []struct {
+		name           string
+		method         string
+		url            string
+		contentType    string
+		header         string // the If-Match header, or If-None-Match for GET requests
+		reqBody        string
+		unlinked       bool // whether the user has no accounts linked
+		replaceErr     error
+		expectedStatus int
+	}{
+		{"Test ok GET /me", http.MethodGet, "/api/v2/me", "", "", "", false, nil, http.StatusOK},
+		{"Test not modified GET /me", http.MethodGet, "/api/v2/me", "", `"3"`, "", false, nil, http.StatusNotModified},
+		{"Test modified GET /me", http.MethodGet, "/api/v2/me", "", `"2"`, "", false, nil, http.StatusOK},
+		{"Test ok PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "",
+			`{"public": false, "name": null, "accounts": {"lol": null}}`, false, nil, http.StatusOK},
+		{"Test matching If-Match PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, `"1", "3"`,
+			`{"public": true}`, false, nil, http.StatusOK},
+		{"Test any If-Match PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "*",
+			`{"public": true}`, false, nil, http.StatusOK},
+		{"Test mismatching If-Match PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, `"2"`,
+			`{"public": true}`, false, nil, http.StatusPreconditionFailed},
+		{"Test weak If-Match PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, `W/"3"`,
+			`{"public": true}`, false, nil, http.StatusPreconditionFailed},
+		{"Test concurrent modification PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "",
+			`{"public": true}`, false, models.ErrPreconditionFailed, http.StatusPreconditionFailed},
+		{"Test wrong content type PATCH /me", http.MethodPatch, "/api/v2/me", "application/json", "",
+			`{"public": true}`, false, nil, http.StatusUnsupportedMediaType},
+		{"Test read only PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "",
+			`{"totalPlayTime": 1000000}`, false, nil, http.StatusBadRequest},
+		{"Test display name PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "",
+			`{"displayName": "José Müller"}`, false, nil, http.StatusOK},
+		{"Test read only name history PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "",
+			`{"previousNames": []}`, false, nil, http.StatusBadRequest},
+		{"Test read only purge time PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "",
+			`{"purgeAt": 1}`, false, nil, http.StatusBadRequest},
+		{"Test unknown member PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "",
+			`{"games": []}`, false, nil, http.StatusBadRequest},
+		{"Test invalid patch PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "",
+			`{ this is not valid }`, false, nil, http.StatusBadRequest},
+		{"Test ok DELETE /me", http.MethodDelete, "/api/v2/me", "", `"3"`, "", false, nil, http.StatusNoContent},
+		{"Test mismatching If-Match DELETE /me", http.MethodDelete, "/api/v2/me", "", `"2"`, "", false, nil,
+			http.StatusPreconditionFailed},
+		{"Test ok POST /me/restore", http.MethodPost, "/api/v2/me/restore", "", "", "", false, nil, http.StatusOK},
+		{"Test invalid method GET /me/restore", http.MethodGet, "/api/v2/me/restore", "", "", "", false, nil,
+			http.StatusMethodNotAllowed},
+		{"Test invalid method PUT /me", http.MethodPut, "/api/v2/me", "", "", "", false, nil, http.StatusMethodNotAllowed},
+		{"Test ok GET /me/accounts/lol", http.MethodGet, "/api/v2/me/accounts/lol", "", "", "", false, nil, http.StatusOK},
+		{"Test unlinked GET /me/accounts/lol", http.MethodGet, "/api/v2/me/accounts/lol", "", "", "", true, nil,
+			http.StatusNotFound},
+		{"Test unknown provider GET /me/accounts/test", http.MethodGet, "/api/v2/me/accounts/test", "", "", "", false, nil,
+			http.StatusNotFound},
+		{"Test ok PUT /me/accounts/valve", http.MethodPut, "/api/v2/me/accounts/valve", "application/json", "",
+			`{"username": "test"}`, true, nil, http.StatusOK},
+		{"Test null PUT /me/accounts/valve", http.MethodPut, "/api/v2/me/accounts/valve", "application/json", "",
+			`null`, false, nil, http.StatusBadRequest},
+		{"Test unknown member PUT /me/accounts/valve", http.MethodPut, "/api/v2/me/accounts/valve", "application/json", "",
+			`{"test": "test"}`, false, nil, http.StatusBadRequest},
+		{"Test ok PATCH /me/accounts/runescape", http.MethodPatch, "/api/v2/me/accounts/runescape",
+			models.MergePatchContentType, "", `{"accountType": "ironman"}`, false, nil, http.StatusOK},
+		{"Test unlinked PATCH /me/accounts/runescape", http.MethodPatch, "/api/v2/me/accounts/runescape",
+			models.MergePatchContentType, "", `{"accountType": "ironman"}`, true, nil, http.StatusNotFound},
+		{"Test ok DELETE /me/accounts/overwatch", http.MethodDelete, "/api/v2/me/accounts/overwatch", "", "", "", false, nil,
+			http.StatusNoContent},
+		{"Test unlinked DELETE /me/accounts/overwatch", http.MethodDelete, "/api/v2/me/accounts/overwatch", "", "", "", true,
+			nil, http.StatusNotFound},
+		{"Test ok GET /me/accounts/battlenet", http.MethodGet, "/api/v2/me/accounts/battlenet", "", "", "", false, nil,
+			http.StatusOK},
+		{"Test modified PUT /me/accounts/battlenet", http.MethodPut, "/api/v2/me/accounts/battlenet", "application/json", "",
+			`{"id": 1, "battleTag": "Test#1234", "region": "us", "expiresAt": 1}`, false, nil, http.StatusBadRequest},
+		{"Test modified PATCH /me", http.MethodPatch, "/api/v2/me", models.MergePatchContentType, "",
+			`{"accounts": {"battlenet": {"region": "test"}}}`, false, nil, http.StatusBadRequest},
+		{"Test ok DELETE /me/accounts/battlenet", http.MethodDelete, "/api/v2/me/accounts/battlenet", "", "", "", false, nil,
+			http.StatusNoContent},
+		{"Test unlinked DELETE /me/accounts/battlenet", http.MethodDelete, "/api/v2/me/accounts/battlenet", "", "", "", true,
+			nil, http.StatusNotFound},
+		{"Test ok GET /me/games", http.MethodGet, "/api/v2/me/games", "", "", "", false, nil, http.StatusOK},
+		{"Test sorted and filtered GET /me/games", http.MethodGet, "/api/v2/me/games?sort=-lastPlayed&platform=linux&minPlayTime=60",
+			"", "", "", false, nil, http.StatusOK},
+		{"Test invalid sort GET /me/games", http.MethodGet, "/api/v2/me/games?sort=test", "", "", "", false, nil,
+			http.StatusBadRequest},
+		{"Test ok POST /me/games/refresh", http.MethodPost, "/api/v2/me/games/refresh", "", "", "", false, nil, http.StatusOK},
+		{"Test ok GET /users/{username}", http.MethodGet, "/api/v2/users/test", "", "", "", false, nil, http.StatusOK},
+		{"Test sorted GET /users/{username}", http.MethodGet, "/api/v2/users/test?sort=game", "", "", "", false, nil,
+			http.StatusOK},
+		{"Test invalid filter GET /users/{username}", http.MethodGet, "/api/v2/users/test?recent=test", "", "", "", false, nil,
+			http.StatusBadRequest},
+	}
+
+	um := &mockUserManager{}
+	r := newRouter(newHandler(um), &mockMW{})
+	k := models.CtxKey("id")
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Initializing mock structs with random data
+			err := faker.FakeData(&um.user)
+			require.Nil(t, err)
+			um.user.Name = "test" // other names are previous names, which redirect
+			um.user.Version = 3
+			um.user.PreviousNames = []models.PreviousName{{Name: "onijuan", ChangedAt: 1}} // never patched to []
+			um.user.DeletedAt, um.user.PurgeAt = 0, 0
+			um.replaceErr = tc.replaceErr
+			if tc.unlinked {
+				um.user.Lol, um.user.Valve, um.user.Overwatch, um.user.Runescape, um.user.BattleNet = nil, nil, nil, nil, nil
+			}
+
+			// Making and serving request
+			req, err := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.reqBody))
+			require.Nil(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.method == http.MethodGet {
+				req.Header.Set("If-None-Match", tc.header)
+			} else {
+				req.Header.Set("If-Match", tc.header)
+			}
+
+			req = req.WithContext(context.WithValue(req.Context(), k, "12345"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			if !assert.Equal(t, tc.expectedStatus, resp.StatusCode) {
+				return
+			}
+
+			switch tc.expectedStatus {
+			case http.StatusOK:
+				assert.Equal(t, etag(um.user), resp.Header.Get("ETag"))
+				assert.True(t, json.Valid(w.Body.Bytes()))
+			case http.StatusNotModified, http.StatusNoContent:
+				assert.Empty(t, w.Body.Bytes())
+			default:
+				assert.Equal(t, models.ProblemContentType, resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestHandlerV2Patch(t *testing.T) {
+	um := &mockUserManager{}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	err := faker.FakeData(&um.user)
+	require.Nil(t, err)
+	um.user.Public = true
+	stored := *um.user
+
+	req, err := http.NewRequest(http.MethodPatch, "/api/v2/me",
+		strings.NewReader(`{"public": false, "name": null, "hidden": ["games"],
+			"accounts": {"lol": null, "runescape": {"accountType": "ironman"}}}`))
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", models.MergePatchContentType)
+	req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// the user passed to ReplaceUser should only differ in the patched members
+	assert.False(t, um.user.Public)
+	assert.Empty(t, um.user.Name)
+	assert.Equal(t, []string{models.HideGames}, um.user.Hidden)
+	assert.Nil(t, um.user.Lol)
+	assert.Equal(t, "ironman", um.user.Runescape.AccountType)
+	assert.Equal(t, stored.Runescape.Username, um.user.Runescape.Username)
+	assert.Equal(t, stored.Valve, um.user.Valve)
+	assert.Equal(t, stored.Overwatch, um.user.Overwatch)
+	assert.Equal(t, stored.BattleNet.BattleTag, um.user.BattleNet.BattleTag)
+	assert.Equal(t, stored.Games, um.user.Games)
+	assert.Equal(t, stored.Version+1, um.user.Version)
+}
+
+func TestHandlerPublicUserHidden(t *testing.T) {
+	var cases = []struct {
+		name          string
+		url           string
+		hidden        []string
+		expectedTotal int
+		expectedGames []string
+	}{
+		{"Test nothing hidden v1", "/api/v1/user/test", nil, 110, []string{"Dota 2", "Zelda"}},
+		{"Test hidden self-reported v1", "/api/v1/user/test", []string{models.HideSelfReported}, 100, []string{"Dota 2"}},
+		{"Test hidden total v1", "/api/v1/user/test", []string{models.HideTotal}, 0, []string{"Dota 2", "Zelda"}},
+		{"Test hidden self-reported v2", "/api/v2/users/test", []string{models.HideSelfReported}, 100, []string{"Dota 2"}},
+		{"Test hidden games v2", "/api/v2/users/test", []string{models.HideGames}, 110, []string{}},
+	}
+
+	um := &mockUserManager{}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			um.user = &models.User{Name: "test", Public: true, TotalGameTime: 110, Hidden: tc.hidden, Games: []models.Game{
+				{Name: "Dota 2", AppID: 570, Time: 100, Minutes: 6000},
+				{Name: "Zelda", Time: 10, Minutes: 600, Source: models.ManualSource, SelfReported: true},
+			}}
+
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.Nil(t, err)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var resp struct {
+				TotalPlayTime int           `json:"totalPlayTime"`
+				Hidden        []string      `json:"hidden"`
+				Games         []models.Game `json:"games"`
+			}
+			err = json.NewDecoder(w.Body).Decode(&resp)
+			require.Nil(t, err)
+
+			assert.Equal(t, tc.expectedTotal, resp.TotalPlayTime)
+			assert.Equal(t, tc.hidden, resp.Hidden)
+			names := []string{}
+			for _, game := range resp.Games {
+				names = append(names, game.Name)
+			}
+			assert.Equal(t, tc.expectedGames, names)
+		})
+	}
+}
+
+func TestHandlerBattleNet(t *testing.T) {
+	var cases = []struct {
+		name           string
+		method         string
+		url            string
+		cookie         string // the nonce kept in the state cookie, if any
+		err            error
+		expectedStatus int
+	}{
+		{"Test ok POST /me/accounts/battlenet/authorize", http.MethodPost, "/api/v2/me/accounts/battlenet/authorize?region=eu",
+			"", nil, http.StatusOK},
+		{"Test disabled POST /me/accounts/battlenet/authorize", http.MethodPost, "/api/v2/me/accounts/battlenet/authorize",
+			"", models.NewReqErr(models.ErrNotFound, "Battle.net linking is not enabled"), http.StatusNotFound},
+		{"Test ok GET /battlenet/callback", http.MethodGet, "/api/v2/battlenet/callback?code=test&state=test", "nonce",
+			nil, http.StatusOK},
+		{"Test no cookie GET /battlenet/callback", http.MethodGet, "/api/v2/battlenet/callback?code=test&state=test", "",
+			nil, http.StatusBadRequest},
+		{"Test invalid state GET /battlenet/callback", http.MethodGet, "/api/v2/battlenet/callback?code=test&state=test",
+			"nonce", models.ErrInvalidAuthState, http.StatusBadRequest},
+		{"Test denied GET /battlenet/callback", http.MethodGet, "/api/v2/battlenet/callback?error=access_denied&state=test",
+			"nonce", nil, http.StatusBadRequest},
+	}
+
+	um := &mockUserManager{}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := faker.FakeData(&um.user)
+			require.Nil(t, err)
+			um.response = "https://oauth.battle.net/authorize"
+			um.err = tc.err
+			um.nonce = ""
+
+			req, err := http.NewRequest(tc.method, tc.url, nil)
+			require.Nil(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: battleNetStateCookie, Value: tc.cookie})
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			// the state cookie is set when authorizing, and removed by the callback regardless of the outcome
+			cookies := w.Result().Cookies()
+			if tc.method == http.MethodGet {
+				require.Len(t, cookies, 1)
+				assert.Equal(t, battleNetStateCookie, cookies[0].Name)
+				assert.True(t, cookies[0].MaxAge < 0)
+			}
+
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if tc.method == http.MethodPost {
+				require.Len(t, cookies, 1)
+				assert.Equal(t, battleNetStateCookie, cookies[0].Name)
+				assert.Equal(t, "nonce", cookies[0].Value)
+				assert.True(t, cookies[0].HttpOnly)
+				assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+
+				var authorization battleNetAuthorization
+				err = json.NewDecoder(w.Body).Decode(&authorization)
+				assert.Nil(t, err)
+				assert.Equal(t, um.response, authorization.URL)
+				return
+			}
+
+			// the nonce of the cookie is verified against the state
+			assert.Equal(t, tc.cookie, um.nonce)
+
+			// the token is never returned
+			var account models.BattleNetAccount
+			err = json.NewDecoder(w.Body).Decode(&account)
+			assert.Nil(t, err)
+			expected := *um.user.BattleNet
+			expected.Token = ""
+			assert.Equal(t, expected, account)
+		})
+	}
+}
+
+func TestHandlerImports(t *testing.T) {
+	// the multipart form with the uploaded file
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, err := mw.CreateFormFile("file", "library.csv")
+	require.Nil(t, err)
+	_, err = fw.Write([]byte("Celeste"))
+	require.Nil(t, err)
+	require.Nil(t, mw.Close())
+
+	var cases = []struct {
+		name           string
+		method         string
+		url            string
+		contentType    string
+		reqBody        string
+		err            error
+		expectedStatus int
+		expectedGame   string // the game imported, given by the mock as the content of the file
+	}{
+		{"Test ok GET /me/imports", http.MethodGet, "/api/v2/me/imports", "", "", nil, http.StatusOK, ""},
+		{"Test ok POST /me/imports/csv", http.MethodPost, "/api/v2/me/imports/csv", "text/csv", "Celeste", nil,
+			http.StatusOK, "Celeste"},
+		{"Test multipart POST /me/imports/csv", http.MethodPost, "/api/v2/me/imports/csv", mw.FormDataContentType(),
+			form.String(), nil, http.StatusOK, "Celeste"},
+		{"Test multipart without file POST /me/imports/gog", http.MethodPost, "/api/v2/me/imports/gog",
+			mw.FormDataContentType(), "", nil, http.StatusBadRequest, ""},
+		{"Test too large POST /me/imports/playnite", http.MethodPost, "/api/v2/me/imports/playnite", "application/json",
+			strings.Repeat(" ", maxUploadSize+1), nil, http.StatusBadRequest, ""},
+		{"Test invalid file POST /me/imports/playnite", http.MethodPost, "/api/v2/me/imports/playnite", "application/json",
+			"[", models.NewReqErrStr("test", "invalid playnite file"), http.StatusBadRequest, ""},
+		{"Test unknown format POST /me/imports/steam", http.MethodPost, "/api/v2/me/imports/steam", "text/csv", "Celeste",
+			nil, http.StatusNotFound, ""},
+		{"Test ok DELETE /me/imports/gog", http.MethodDelete, "/api/v2/me/imports/gog", "", "", nil, http.StatusNoContent, ""},
+		{"Test not imported DELETE /me/imports/gog", http.MethodDelete, "/api/v2/me/imports/gog", "", "", models.ErrNotFound,
+			http.StatusNotFound, ""},
+	}
+
+	um := &mockUserManager{}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := faker.FakeData(&um.user)
+			require.Nil(t, err)
+			um.err = tc.err
+			um.imports = []models.ImportedLibrary{{Format: models.ImportCSV, ImportedAt: 1672531200,
+				Games: []models.Game{{Name: "Celeste", Time: 2, Minutes: 125, Source: "itch.io"}}}}
+
+			req, err := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.reqBody))
+			require.Nil(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if tc.method == http.MethodGet {
+				var imports importsV2
+				err = json.NewDecoder(w.Body).Decode(&imports)
+				assert.Nil(t, err)
+				assert.Equal(t, um.imports, imports.Imports)
+				return
+			}
+
+			var library models.ImportedLibrary
+			err = json.NewDecoder(w.Body).Decode(&library)
+			assert.Nil(t, err)
+			if assert.Len(t, library.Games, 1) {
+				assert.Equal(t, tc.expectedGame, library.Games[0].Name)
+			}
+		})
+	}
+}
+
+func TestHandlerManualGames(t *testing.T) {
+	var cases = []struct {
+		name           string
+		method         string
+		url            string
+		contentType    string
+		reqBody        string
+		err            error
+		expectedStatus int
+		expected       *models.ManualGame // the game responded, if not the list of games
+	}{
+		{"Test ok GET /me/manual-games", http.MethodGet, "/api/v2/me/manual-games", "", "", nil, http.StatusOK, nil},
+		{"Test ok POST /me/manual-games", http.MethodPost, "/api/v2/me/manual-games", "application/json",
+			`{"name": "Halo", "platform": "xbox", "hours": 1.5, "sessions": [{"date": 1672531200, "minutes": 60}]}`, nil,
+			http.StatusOK, &models.ManualGame{ID: "game1", Name: "Halo", Platform: "xbox", Hours: 1.5,
+				Sessions: []models.ManualSession{{Date: 1672531200, Minutes: 60}}}},
+		{"Test unknown member POST /me/manual-games", http.MethodPost, "/api/v2/me/manual-games", "application/json",
+			`{"name": "Halo", "console": "xbox"}`, nil, http.StatusBadRequest, nil},
+		{"Test invalid game POST /me/manual-games", http.MethodPost, "/api/v2/me/manual-games", "application/json",
+			`{"name": "", "platform": "xbox"}`, models.NewReqErrStr("no name", "invalid manual game: no name"),
+			http.StatusBadRequest, nil},
+		{"Test wrong content type POST /me/manual-games", http.MethodPost, "/api/v2/me/manual-games", "text/plain",
+			`{"name": "Halo", "platform": "xbox"}`, nil, http.StatusUnsupportedMediaType, nil},
+		{"Test ok GET /me/manual-games/game1", http.MethodGet, "/api/v2/me/manual-games/game1", "", "", nil,
+			http.StatusOK, &models.ManualGame{ID: "game1", Name: "Zelda", Platform: "switch", Hours: 10}},
+		{"Test not found GET /me/manual-games/game2", http.MethodGet, "/api/v2/me/manual-games/game2", "", "",
+			models.ErrNotFound, http.StatusNotFound, nil},
+		{"Test ok PUT /me/manual-games/game1", http.MethodPut, "/api/v2/me/manual-games/game1", "application/json",
+			`{"id": "game2", "name": "Zelda", "platform": "switch", "hours": 12}`, nil, http.StatusOK,
+			&models.ManualGame{ID: "game1", Name: "Zelda", Platform: "switch", Hours: 12}},
+		{"Test invalid id PUT /me/manual-games/game_1", http.MethodPut, "/api/v2/me/manual-games/game_1",
+			"application/json", `{"name": "Zelda", "platform": "switch"}`, nil, http.StatusNotFound, nil},
+		{"Test ok DELETE /me/manual-games/game1", http.MethodDelete, "/api/v2/me/manual-games/game1", "", "", nil,
+			http.StatusNoContent, nil},
+		{"Test not found DELETE /me/manual-games/game2", http.MethodDelete, "/api/v2/me/manual-games/game2", "", "",
+			models.ErrNotFound, http.StatusNotFound, nil},
+	}
+
+	um := &mockUserManager{}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := faker.FakeData(&um.user)
+			require.Nil(t, err)
+			um.err = tc.err
+			um.manual = []models.ManualGame{{ID: "game1", Name: "Zelda", Platform: "switch", Hours: 10, UpdatedAt: 1672531200}}
+
+			req, err := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.reqBody))
+			require.Nil(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if tc.expected == nil {
+				var games manualGamesV2
+				err = json.NewDecoder(w.Body).Decode(&games)
+				assert.Nil(t, err)
+				assert.Equal(t, um.manual, games.Games)
+				return
+			}
+
+			var game models.ManualGame
+			err = json.NewDecoder(w.Body).Decode(&game)
+			assert.Nil(t, err)
+			assert.Equal(t, *tc.expected, game)
+		})
+	}
+}
+
+func TestHandlerCatalog(t *testing.T) {
+	var cases = []struct {
+		name           string
+		url            string
+		err            error
+		expectedStatus int
+		expected       []models.CatalogGame
+	}{
+		{"Test ok", "/api/v2/catalog?name=portal", nil, http.StatusOK,
+			[]models.CatalogGame{{ID: "portal", Name: "Portal", ExternalIDs: map[string][]string{"steam": {"400"}}}}},
+		{"Test no results", "/api/v2/catalog?name=zelda", nil, http.StatusOK, []models.CatalogGame{}},
+		{"Test no name", "/api/v2/catalog", models.NewReqErrStr("no name", "invalid name"), http.StatusBadRequest, nil},
+	}
+
+	um := &mockUserManager{}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			um.err = tc.err
+
+			req, err := http.NewRequest(http.MethodGet, tc.url, nil)
+			require.Nil(t, err)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var catalog catalogV2
+			err = json.NewDecoder(w.Body).Decode(&catalog)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, catalog.Games)
+		})
+	}
+}
+
+func TestHandlerMatches(t *testing.T) {
+	var cases = []struct {
+		name           string
+		method         string
+		url            string
+		contentType    string
+		reqBody        string
+		err            error
+		expectedStatus int
+		expected       *models.GameMatch // the match responded, if not the list of matches
+	}{
+		{"Test ok GET /me/matches", http.MethodGet, "/api/v2/me/matches", "", "", nil, http.StatusOK, nil},
+		{"Test ok POST /me/matches", http.MethodPost, "/api/v2/me/matches", "application/json",
+			`{"name": "Witcher III", "gameId": "the-witcher-3"}`, nil, http.StatusOK,
+			&models.GameMatch{Key: "witcher iii", Name: "Witcher III", GameID: "the-witcher-3"}},
+		{"Test unknown member POST /me/matches", http.MethodPost, "/api/v2/me/matches", "application/json",
+			`{"name": "Witcher III", "id": "the-witcher-3"}`, nil, http.StatusBadRequest, nil},
+		{"Test unknown game POST /me/matches", http.MethodPost, "/api/v2/me/matches", "application/json",
+			`{"name": "Witcher III", "gameId": "witcher-4"}`, models.NewReqErrStr("unknown game", "invalid match"),
+			http.StatusBadRequest, nil},
+		{"Test ok DELETE /me/matches/witcheriii", http.MethodDelete, "/api/v2/me/matches/witcheriii", "", "", nil,
+			http.StatusNoContent, nil},
+		{"Test not found DELETE /me/matches/witcheriii", http.MethodDelete, "/api/v2/me/matches/witcheriii", "", "",
+			models.ErrNotFound, http.StatusNotFound, nil},
+	}
+
+	um := &mockUserManager{}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := faker.FakeData(&um.user)
+			require.Nil(t, err)
+			um.err = tc.err
+			um.matches = []models.GameMatch{{Key: "halo", Name: "Halo"}}
+
+			req, err := http.NewRequest(tc.method, tc.url, strings.NewReader(tc.reqBody))
+			require.Nil(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if tc.expected == nil {
+				var matches matchesV2
+				err = json.NewDecoder(w.Body).Decode(&matches)
+				assert.Nil(t, err)
+				assert.Equal(t, um.matches, matches.Matches)
+				return
+			}
+
+			var match models.GameMatch
+			err = json.NewDecoder(w.Body).Decode(&match)
+			assert.Nil(t, err)
+			assert.Equal(t, *tc.expected, match)
+		})
+	}
+}
+
+func TestHandlerGenres(t *testing.T) {
+	var cases = []struct {
+		name           string
+		games          []models.Game
+		err            error
+		expectedStatus int
+		expected       []models.GenrePlaytime
+	}{
+		{"Test ok", []models.Game{
+			{Name: "Portal", Minutes: 120, Metadata: &models.GameMetadata{Genres: []string{"Action", "Puzzle"}}},
+			{Name: "Dota 2", Minutes: 600, Metadata: &models.GameMetadata{Genres: []string{"Action", "Strategy"}}},
+			{Name: "Runescape", Minutes: 6000},
+		}, nil, http.StatusOK, []models.GenrePlaytime{
+			{Genre: "Action", Minutes: 720, Games: 2},
+			{Genre: "Strategy", Minutes: 600, Games: 1},
+			{Genre: "Puzzle", Minutes: 120, Games: 1},
+		}},
+		{"Test no metadata", []models.Game{{Name: "Runescape", Minutes: 6000}}, nil, http.StatusOK, []models.GenrePlaytime{}},
+		{"Test no user", nil, models.ErrNotFound, http.StatusNotFound, nil},
+	}
+
+	um := &mockUserManager{}
+	r := newRouter(newHandler(um), &mockMW{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			um.user = &models.User{ID: "12345", Games: tc.games}
+			um.err = tc.err
+
+			req, err := http.NewRequest(http.MethodGet, "/api/v2/me/genres", nil)
+			require.Nil(t, err)
+			req = req.WithContext(context.WithValue(req.Context(), models.CtxKey("id"), "12345"))
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if !assert.Equal(t, tc.expectedStatus, w.Code) || tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var genres genresV2
+			err = json.NewDecoder(w.Body).Decode(&genres)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expected, genres.Genres)
+		})
+	}
+}
@@ -1,16 +1,167 @@
 package server
 
 import (
+	"crypto/rand"
+	"ctp/pkg/models"
+	"ctp/pkg/tracing"
+	"encoding/hex"
 	"net/http"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 )
 
-// log logs requests received with the routeName
-func log(next http.Handler) http.Handler {
+const requestIDHeader = "X-Request-ID"
+
+// validRequestID limits which request ids received from clients are accepted, to avoid polluting the logs
+var validRequestID = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,64}$`)
+
+// requestID makes sure every request has an id, which is added to the request info in the context and returned in the response.
+// If the client (or a proxy) provides a valid X-Request-ID header, it is used. Otherwise a new id is generated.
+func requestID(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		logrus.WithFields(logrus.Fields{"route": mux.CurrentRoute(r).GetName()}).Debugf("Request received")
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		ctx := models.WithRequestInfo(r.Context(), &models.RequestInfo{ID: id})
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// routeName records the name of the route matched by the router in the request info, for the middleware wrapping the
+// router, which runs before the request is routed
+func routeName(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setRoute(r, mux.CurrentRoute(r).GetName())
 		next.ServeHTTP(w, r)
 	})
 }
+
+// setRoute sets the name of the route in the request info, if any
+func setRoute(r *http.Request, name string) {
+	if info := models.GetRequestInfo(r.Context()); info != nil {
+		info.Route = name
+	}
+}
+
+// route returns the name of the route the request was handled by, or "" if it has not been routed
+func route(r *http.Request) string {
+	if info := models.GetRequestInfo(r.Context()); info != nil {
+		return info.Route
+	}
+
+	return ""
+}
+
+// trace starts a server span for the request, continuing the trace from the traceparent header if present.
+// The span is named by the route once the request has been handled, as it is started before the request is routed.
+func trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tracing.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, span := tracing.StartRemote(r.Context(), r.Method, r.Header.Get("traceparent"))
+		defer span.End()
+
+		span.SetAttribute("http.method", r.Method)
+		if info := models.GetRequestInfo(ctx); info != nil {
+			span.SetAttribute("request.id", info.ID)
+		}
+
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sr, r.WithContext(ctx))
+
+		if name := route(r); name != "" {
+			span.SetName(name)
+			span.SetAttribute("http.route", name)
+		}
+
+		span.SetAttribute("http.status_code", strconv.Itoa(sr.status))
+		if sr.status >= http.StatusInternalServerError {
+			span.SetError(&httpStatusError{sr.status})
+		}
+	})
+}
+
+// accessLog logs every request with the route name, status code, duration, bytes written and user id (if authenticated)
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sr, r)
+
+		fields := logrus.Fields{
+			"route":       route(r),
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      sr.status,
+			"duration_ms": time.Since(start).Milliseconds(),
+			"bytes":       sr.bytes,
+		}
+		if span := tracing.FromContext(r.Context()); span != nil {
+			fields["trace_id"] = span.TraceID()
+		}
+
+		// the request info is read after the request is handled, as the user id is set by the auth middleware
+		models.Log(r.Context()).WithFields(fields).Info("Request handled")
+	})
+}
+
+// statusRecorder wraps a http.ResponseWriter, recording the status code and the number of bytes written
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+// WriteHeader records the status code before writing it
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status = code
+		s.wroteHeader = true
+	}
+
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the number of bytes written
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+
+	return n, err
+}
+
+// Flush sends the response written so far to the client, such that streamed responses (the events) are not held back
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		s.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// httpStatusError is used to mark spans for failed requests
+type httpStatusError struct {
+	status int
+}
+
+func (e *httpStatusError) Error() string { return http.StatusText(e.status) }
+
+// newRequestID generates a random request id
+func newRequestID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b) // crypto/rand only fails if the OS can't provide randomness at all
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"ctp/pkg/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	var cases = []struct {
+		name       string
+		header     string
+		expectSame bool
+	}{
+		{"Test provided request id", "abc-123_DEF.456", true},
+		{"Test no request id", "", false},
+		{"Test invalid request id", "this is not valid\n", false},
+		{"Test too long request id", "01234567890123456789012345678901234567890123456789012345678901234567890123456789", false},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctxID string
+			h := requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				info := models.GetRequestInfo(r.Context())
+				require.NotNil(t, info)
+				ctxID = info.ID
+			}))
+
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			require.Nil(t, err)
+			req.Header.Set(requestIDHeader, tc.header)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			respID := w.Result().Header.Get(requestIDHeader)
+			assert.Equal(t, ctxID, respID)
+			assert.True(t, validRequestID.MatchString(respID))
+			if tc.expectSame {
+				assert.Equal(t, tc.header, respID)
+			} else {
+				assert.NotEqual(t, tc.header, respID)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var cases = []struct {
+		name           string
+		status         int
+		body           string
+		expectedStatus int
+	}{
+		{"Test implicit status", 0, "test", http.StatusOK},
+		{"Test explicit status", http.StatusTeapot, "test", http.StatusTeapot},
+		{"Test no body", http.StatusNoContent, "", http.StatusNoContent},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var sr *statusRecorder
+			var info *models.RequestInfo
+			r := mux.NewRouter()
+			r.Use(routeName)
+			r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if tc.status != 0 {
+					w.WriteHeader(tc.status)
+				}
+				if tc.body != "" {
+					_, err := w.Write([]byte(tc.body))
+					assert.Nil(t, err)
+				}
+			}).Name("test")
+
+			h := requestID(trace(accessLog(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				sr, _ = w.(*statusRecorder)
+				info = models.GetRequestInfo(req.Context())
+				r.ServeHTTP(w, req)
+			}))))
+
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			require.Nil(t, err)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			require.NotNil(t, sr)
+			assert.Equal(t, tc.expectedStatus, sr.status)
+			assert.Equal(t, len(tc.body), sr.bytes)
+			require.NotNil(t, info)
+			assert.Equal(t, "test", info.Route)
+		})
+	}
+}
+
+func TestWithMiddleware(t *testing.T) {
+	var cases = []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"Test matched route", http.MethodGet, "/api/v1/openapi.json", http.StatusOK},
+		{"Test not found", http.MethodGet, "/api/v1/doesnotexist", http.StatusNotFound},
+		{"Test method not allowed", http.MethodDelete, "/api/v1/openapi.json", http.StatusMethodNotAllowed},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := withMiddleware(newRouter(newHandler(&mockUserManager{}), &mockMW{}))
+
+			req, err := http.NewRequest(tc.method, tc.path, nil)
+			require.Nil(t, err)
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expectedStatus, w.Code)
+			id := w.Result().Header.Get(requestIDHeader)
+			assert.True(t, validRequestID.MatchString(id))
+			if tc.expectedStatus != http.StatusOK {
+				p := &models.Problem{}
+				require.Nil(t, json.NewDecoder(w.Body).Decode(p))
+				assert.Equal(t, id, p.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"ctp/pkg/models"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]struct{ OperationID string } `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			GoType     string                 `json:"x-go-type"`
+			Required   []string               `json:"required"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// removes the regular expressions from path variables, e.g. "{username:[a-z]+}" becomes "{username}"
+var pathVarRegexp = regexp.MustCompile(`\{([^:}]+):[^/]*\}`)
+
+// Every route in the router should be documented with the route's name as operationId, and every documented operation should exist
+func TestOpenAPIRoutes(t *testing.T) {
+	var doc openAPIDoc
+	err := json.Unmarshal([]byte(openAPISpec), &doc)
+	require.Nil(t, err)
+
+	r := newRouter(newHandler(&mockUserManager{}), &mockMW{})
+	routes := make(map[string]bool)
+
+	err = r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetName() == "" {
+			return nil
+		}
+
+		tpl, err := route.GetPathTemplate()
+		require.Nil(t, err)
+		tpl = pathVarRegexp.ReplaceAllString(tpl, "{$1}")
+
+		// the methods of the routes in the GET subrouter are set on the ancestor route
+		methods, err := route.GetMethods()
+		for i := len(ancestors) - 1; err != nil && i >= 0; i-- {
+			methods, err = ancestors[i].GetMethods()
+		}
+		require.Nil(t, err, route.GetName())
+
+		for _, method := range methods {
+			op, ok := doc.Paths[tpl][strings.ToLower(method)]
+			if assert.True(t, ok, "%s %s is not documented", method, tpl) {
+				assert.Equal(t, route.GetName(), op.OperationID)
+			}
+			routes[method+" "+tpl] = true
+		}
+
+		return nil
+	})
+	require.Nil(t, err)
+
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			assert.True(t, routes[strings.ToUpper(method)+" "+path], "%s %s is documented, but not routed", method, path)
+		}
+	}
+}
+
+// The properties of the schemas should match the JSON names of the fields of the types they describe, and the required
+// properties should be fields always encoded
+func TestOpenAPISchemas(t *testing.T) {
+	var doc openAPIDoc
+	err := json.Unmarshal([]byte(openAPISpec), &doc)
+	require.Nil(t, err)
+
+	types := map[string]reflect.Type{
+		"models.User":                 reflect.TypeOf(models.User{}),
+		"models.Game":                 reflect.TypeOf(models.Game{}),
+		"models.PlatformPlaytime":     reflect.TypeOf(models.PlatformPlaytime{}),
+		"models.Achievements":         reflect.TypeOf(models.Achievements{}),
+		"models.ModePlaytime":         reflect.TypeOf(models.ModePlaytime{}),
+		"models.HeroPlaytime":         reflect.TypeOf(models.HeroPlaytime{}),
+		"models.Character":            reflect.TypeOf(models.Character{}),
+		"models.SkillPlaytime":        reflect.TypeOf(models.SkillPlaytime{}),
+		"models.ActivityPlaytime":     reflect.TypeOf(models.ActivityPlaytime{}),
+		"models.ImportedLibrary":      reflect.TypeOf(models.ImportedLibrary{}),
+		"models.ManualGame":           reflect.TypeOf(models.ManualGame{}),
+		"models.ManualSession":        reflect.TypeOf(models.ManualSession{}),
+		"models.MergedGame":           reflect.TypeOf(models.MergedGame{}),
+		"models.CatalogGame":          reflect.TypeOf(models.CatalogGame{}),
+		"models.GameMatch":            reflect.TypeOf(models.GameMatch{}),
+		"models.SummonerRegistration": reflect.TypeOf(models.SummonerRegistration{}),
+		"models.ValveAccount":         reflect.TypeOf(models.ValveAccount{}),
+		"models.Overwatch":            reflect.TypeOf(models.Overwatch{}),
+		"models.RunescapeAccount":     reflect.TypeOf(models.RunescapeAccount{}),
+		"models.BattleNetAccount":     reflect.TypeOf(models.BattleNetAccount{}),
+		"models.Problem":              reflect.TypeOf(models.Problem{}),
+		"models.GameMetadata":         reflect.TypeOf(models.GameMetadata{}),
+		"models.GenrePlaytime":        reflect.TypeOf(models.GenrePlaytime{}),
+		"models.Stats":                reflect.TypeOf(models.Stats{}),
+		"models.GameShare":            reflect.TypeOf(models.GameShare{}),
+		"models.ProviderPlaytime":     reflect.TypeOf(models.ProviderPlaytime{}),
+		"models.GameImprovement":      reflect.TypeOf(models.GameImprovement{}),
+		"models.Recap":                reflect.TypeOf(models.Recap{}),
+		"models.GamePlaytime":         reflect.TypeOf(models.GamePlaytime{}),
+		"models.RecapWeek":            reflect.TypeOf(models.RecapWeek{}),
+		"models.Rank":                 reflect.TypeOf(models.Rank{}),
+		"models.Job":                  reflect.TypeOf(models.Job{}),
+		"models.ProviderProgress":     reflect.TypeOf(models.ProviderProgress{}),
+		"models.JobResult":            reflect.TypeOf(models.JobResult{}),
+		"models.TotalChange":          reflect.TypeOf(models.TotalChange{}),
+		"models.PreviousName":         reflect.TypeOf(models.PreviousName{}),
+		// schemas without x-go-type describe the responses of the server, and are generated for the client
+		"Status":      reflect.TypeOf(statusResponse{}),
+		"Token":       reflect.TypeOf(tokenResponse{}),
+		"Me":          reflect.TypeOf(meV2{}),
+		"Accounts":    reflect.TypeOf(accountsV2{}),
+		"GameList":    reflect.TypeOf(gamesV2{}),
+		"PublicUser":  reflect.TypeOf(publicUserV2{}),
+		"Imports":     reflect.TypeOf(importsV2{}),
+		"ManualGames": reflect.TypeOf(manualGamesV2{}),
+		"Catalog":     reflect.TypeOf(catalogV2{}),
+		"Matches":     reflect.TypeOf(matchesV2{}),
+		"Genres":      reflect.TypeOf(genresV2{}),
+
+		"BattleNetAuthorization": reflect.TypeOf(battleNetAuthorization{}),
+	}
+
+	for name, schema := range doc.Components.Schemas {
+		goType := schema.GoType
+		if goType == "" {
+			goType = name
+		}
+
+		t.Run(name, func(t *testing.T) {
+			typ, ok := types[goType]
+			require.True(t, ok, "unknown type %s", goType)
+
+			fields, omitted := make(map[string]bool), make(map[string]bool)
+			for i := 0; i < typ.NumField(); i++ {
+				tag := strings.Split(typ.Field(i).Tag.Get("json"), ",")
+				if tag[0] != "" && tag[0] != "-" {
+					fields[tag[0]] = true
+					omitted[tag[0]] = models.Contains(tag[1:], "omitempty")
+				}
+			}
+
+			for prop := range schema.Properties {
+				assert.True(t, fields[prop], "%s has no field %s", goType, prop)
+			}
+			for _, prop := range schema.Required {
+				assert.Contains(t, schema.Properties, prop, "%s requires the undocumented %s", name, prop)
+				assert.False(t, omitted[prop], "%s requires %s, which is omitted when empty", name, prop)
+			}
+		})
+	}
+}
+
+// The games in every public representation (the users, the recaps and the snapshots) should flag self-reported playtime
+func TestOpenAPISelfReported(t *testing.T) {
+	var doc openAPIDoc
+	err := json.Unmarshal([]byte(openAPISpec), &doc)
+	require.Nil(t, err)
+
+	for _, name := range []string{"Game", "GamePlaytime"} {
+		assert.Contains(t, doc.Components.Schemas[name].Required, "selfReported", name)
+	}
+
+	// tc - test cases
+	tc := []interface{}{
+		models.Game{Name: "Celeste", Time: 5},
+		models.GamePlaytime{Name: "Celeste", Minutes: 300},
+	}
+
+	for _, v := range tc {
+		b, err := json.Marshal(v)
+		require.Nil(t, err)
+
+		var fields map[string]interface{}
+		require.Nil(t, json.Unmarshal(b, &fields))
+		assert.Equal(t, false, fields["selfReported"], "%T", v)
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	r := newRouter(newHandler(&mockUserManager{}), &mockMW{})
+
+	req, err := http.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	require.Nil(t, err)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Result().Header.Get("Content-Type"))
+	assert.True(t, json.Valid(w.Body.Bytes()))
+}
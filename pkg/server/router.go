@@ -7,30 +7,91 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// accountPath is the path of the accounts a user can link, one for each provider
+const accountPath = "/me/accounts/{provider:lol|valve|overwatch|runescape|battlenet}"
+
+// importPath is the path of the libraries imported from launchers, one for each of models.ImportFormats
+const importPath = "/me/imports/{format:gog|playnite|csv}"
+
+// manualGamePath is the path of a game the user records the playtime of manually, identified by its firestore id
+const manualGamePath = "/me/manual-games/{gameId:[a-zA-Z0-9]{1,32}}"
+
+// usernameVar is the route variable of a user's handle, regardless of case. Spaces are allowed as well, as the handles
+// taken before the slug rules may have them.
+const usernameVar = "{username:[a-zA-Z0-9 _-]{1,30}}"
+
+// withMiddleware wraps the router with the middleware every request passes: every request is given a request id,
+// traced (if enabled) and logged by the access log middleware. The router is wrapped, rather than using the middleware
+// on it, such that requests which match no route (404 and 405), or are rejected by the authentication middleware,
+// pass the middleware as well.
+func withMiddleware(r *mux.Router) http.Handler {
+	return requestID(trace(accessLog(r)))
+}
+
 // NewRouter creates a new router
 func newRouter(h *handler, amw models.AuthMiddleware) *mux.Router {
 	r := mux.NewRouter()
 	r.NotFoundHandler = http.HandlerFunc(h.notFound)
+	r.MethodNotAllowedHandler = http.HandlerFunc(h.methodNotAllowed)
+
+	// the stats are routed before /user/{username}, which would otherwise take "stats" as a username
+	r.Handle("/api/v1/user/stats", amw.Auth(http.HandlerFunc(h.getStats))).Methods(http.MethodGet).Name("getStats")
 
 	get := r.PathPrefix("/api/v1").Methods(http.MethodGet).Subrouter()
+	get.HandleFunc("/openapi.json", h.openAPI).Name("getOpenAPI")
 	get.HandleFunc("/login", h.login).Name("login")
 	get.HandleFunc("/authcallback", h.authCallbackHandler).Name("authCallback")
-	get.HandleFunc("/user/{username:[a-zA-Z0-9 ]{1,15}}", h.getPublicUser).Name("getPublicUser")
+	get.HandleFunc("/user/"+usernameVar, h.getPublicUser).Name("getPublicUser")
+	get.HandleFunc("/user/"+usernameVar+"/badge.{format:svg|png}", h.getBadge).Name("getBadge")
 
 	auth := r.PathPrefix("/api/v1/").Subrouter()
 	auth.HandleFunc("/user", h.getUser).Methods(http.MethodGet).Name("getUser")
 	auth.HandleFunc("/user", h.updateUser).Methods(http.MethodPost).Name("updateUser")
 	auth.HandleFunc("/user", h.deleteUser).Methods(http.MethodDelete).Name("deleteUser")
+	auth.HandleFunc("/user/recap/{year:[0-9]{4}}", h.getRecap).Methods(http.MethodGet).Name("getRecap")
 	auth.HandleFunc("/updategames", h.updateGames).Methods(http.MethodPost).Name("updateGames")
+	auth.HandleFunc("/events", h.getEvents).Methods(http.MethodGet).Name("getEvents")
+	auth.HandleFunc("/jobs/{job:[0-9a-f]{24}}", h.getJob).Methods(http.MethodGet).Name("getJob")
 	auth.HandleFunc("/riotapikey", h.updateKey).Methods(http.MethodPost).Name("updateKey")
 
-	// loggin every request using the log middleware
-	get.Use(log)
+	// version 2 of the API, where each route is a resource. Version 1 is kept for existing clients.
+	getV2 := r.PathPrefix("/api/v2").Methods(http.MethodGet).Subrouter()
+	getV2.HandleFunc("/users/"+usernameVar, h.getPublicUserV2).Name("getPublicUserV2")
+	getV2.HandleFunc("/battlenet/callback", h.battleNetCallback).Name("battleNetCallback")
+	getV2.HandleFunc("/catalog", h.searchCatalog).Name("searchCatalog")
+
+	authV2 := r.PathPrefix("/api/v2/").Subrouter()
+	authV2.HandleFunc("/me", h.getMe).Methods(http.MethodGet).Name("getMe")
+	authV2.HandleFunc("/me", h.patchMe).Methods(http.MethodPatch).Name("patchMe")
+	authV2.HandleFunc("/me", h.deleteMe).Methods(http.MethodDelete).Name("deleteMe")
+	authV2.HandleFunc("/me/restore", h.restoreMe).Methods(http.MethodPost).Name("restoreMe")
+	authV2.HandleFunc(accountPath, h.getAccount).Methods(http.MethodGet).Name("getAccount")
+	authV2.HandleFunc(accountPath, h.putAccount).Methods(http.MethodPut).Name("putAccount")
+	authV2.HandleFunc(accountPath, h.patchAccount).Methods(http.MethodPatch).Name("patchAccount")
+	authV2.HandleFunc(accountPath, h.deleteAccount).Methods(http.MethodDelete).Name("deleteAccount")
+	authV2.HandleFunc("/me/accounts/battlenet/authorize", h.authorizeBattleNet).Methods(http.MethodPost).Name("authorizeBattleNet")
+	authV2.HandleFunc("/me/games", h.getGames).Methods(http.MethodGet).Name("getGames")
+	authV2.HandleFunc("/me/games/refresh", h.refreshGames).Methods(http.MethodPost).Name("refreshGames")
+	authV2.HandleFunc("/me/genres", h.getGenres).Methods(http.MethodGet).Name("getGenres")
+	authV2.HandleFunc("/me/imports", h.getImports).Methods(http.MethodGet).Name("getImports")
+	authV2.HandleFunc(importPath, h.importLibrary).Methods(http.MethodPost).Name("importLibrary")
+	authV2.HandleFunc(importPath, h.deleteImport).Methods(http.MethodDelete).Name("deleteImport")
+	authV2.HandleFunc("/me/manual-games", h.getManualGames).Methods(http.MethodGet).Name("getManualGames")
+	authV2.HandleFunc("/me/manual-games", h.addManualGame).Methods(http.MethodPost).Name("addManualGame")
+	authV2.HandleFunc(manualGamePath, h.getManualGame).Methods(http.MethodGet).Name("getManualGame")
+	authV2.HandleFunc(manualGamePath, h.putManualGame).Methods(http.MethodPut).Name("putManualGame")
+	authV2.HandleFunc(manualGamePath, h.deleteManualGame).Methods(http.MethodDelete).Name("deleteManualGame")
+	authV2.HandleFunc("/me/matches", h.getMatches).Methods(http.MethodGet).Name("getMatches")
+	authV2.HandleFunc("/me/matches", h.setMatch).Methods(http.MethodPost).Name("setMatch")
+	authV2.HandleFunc("/me/matches/{key}", h.deleteMatch).Methods(http.MethodDelete).Name("deleteMatch")
+
+	// the name of the route is recorded for the middleware wrapping the router (see withMiddleware)
+	r.Use(routeName)
 
-	// users are first authenticated using the authentication middleware (checks the "Authorization" header for valid token).
-	// any successful requests are logged using the log middleware.
+	// users are authenticated using the authentication middleware (checks the "Authorization" header for valid token).
 	// The AuthMiddleware interface is implemented by the Authenticator in the auth package.
-	auth.Use(amw.Auth, log)
+	auth.Use(amw.Auth)
+	authV2.Use(amw.Auth)
 
 	return r
 }
@@ -1,7 +1,12 @@
 package server
 
+// The OpenAPI document served by the server and the client in pkg/client are generated from api/openapi.json
+//go:generate go run ../../tools/apigen -spec ../../api/openapi.json -server openapi_gen.go -client ../client/client_gen.go
+
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"time"
 
@@ -10,8 +15,9 @@ import (
 
 const writeTimeout, readTimeout, idleTimeout = 60, 60, 60
 
-// New creates a new http server
-func New(port int, um models.UserManager, auth models.AuthMiddleware) *http.Server {
+// New creates a new http server.
+// The context of every request is derived from ctx, such that cancelling it cancels all in-flight requests.
+func New(ctx context.Context, port int, um models.UserManager, auth models.AuthMiddleware) *http.Server {
 	handler := newHandler(um)
 	router := newRouter(handler, auth)
 
@@ -22,6 +28,7 @@ func New(port int, um models.UserManager, auth models.AuthMiddleware) *http.Serv
 		WriteTimeout: time.Second * writeTimeout,
 		ReadTimeout:  time.Second * readTimeout,
 		IdleTimeout:  time.Second * idleTimeout,
-		Handler:      router, // Passing mux router as handler
+		Handler:      withMiddleware(router), // Passing mux router as handler
+		BaseContext:  func(net.Listener) context.Context { return ctx },
 	}
 }
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,6 +10,6 @@ import (
 // This is not a good test. It shouldn't be necessary to test a function nearly devoid of actual logic.
 // This test is however added as the only metric used is testcoverage.
 func TestNew(t *testing.T) {
-	server := New(80, &mockUserManager{}, &mockMW{})
+	server := New(context.Background(), 80, &mockUserManager{}, &mockMW{})
 	assert.NotNil(t, server)
 }
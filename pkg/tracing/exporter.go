@@ -0,0 +1,224 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"ctp/pkg/models"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const batchSize, queueSize = 100, 2048
+const flushInterval = 5 * time.Second
+const exportTimeout = 10 * time.Second
+
+// exporter batches finished spans and sends them to an OpenTelemetry collector
+// using the OTLP/HTTP JSON encoding: https://opentelemetry.io/docs/specs/otlp/#otlphttp
+type exporter struct {
+	models.Client
+	url     string
+	service string
+	spans   chan *Span
+	done    chan struct{}
+}
+
+// Setup enables tracing, exporting spans to the collector at the given endpoint (e.g. "http://localhost:4318").
+// The returned function stops the exporter after sending any remaining spans, and should be called on shutdown.
+func Setup(client models.Client, endpoint, service string) func(ctx context.Context) error {
+	e := &exporter{
+		Client:  client,
+		url:     strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service: service,
+		spans:   make(chan *Span, queueSize),
+		done:    make(chan struct{}),
+	}
+
+	expMutex.Lock()
+	exp = e
+	expMutex.Unlock()
+
+	go e.run()
+
+	return func(ctx context.Context) error {
+		expMutex.Lock()
+		exp = nil
+		expMutex.Unlock()
+		close(e.spans)
+
+		select {
+		case <-e.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// export queues the span to be sent. Spans are dropped if the queue is full, as tracing should never block requests.
+func (e *exporter) export(s *Span) {
+	select {
+	case e.spans <- s:
+	default:
+		logrus.Debug("tracing queue full, dropping span")
+	}
+}
+
+// run sends the queued spans in batches, either when the batch is full or the flush interval has passed
+func (e *exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	for {
+		select {
+		case s, ok := <-e.spans:
+			if !ok {
+				e.send(batch)
+				return
+			}
+
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				e.send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// send posts the batch to the collector. Errors are only logged, as the spans are of no use to anyone else.
+func (e *exporter) send(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		logrus.WithError(err).Warn("Could not encode spans")
+		return
+	}
+
+	// the export is not part of any request, but is bound by a timeout such that an unresponsive collector does not
+	// block the exporter (and thereby drop every span queued meanwhile)
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		logrus.WithError(err).Warn("Could not create request for exporting spans")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.Do(req)
+	if err != nil {
+		logrus.WithError(err).Warn("Could not export spans")
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		logrus.Warnf("Could not export spans, got status code %d from collector", resp.StatusCode)
+	}
+}
+
+// The following structs mirror the OTLP JSON encoding of ExportTraceServiceRequest
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+// encode converts the spans to the OTLP JSON format
+func (e *exporter) encode(batch []*Span) *otlpRequest {
+	var rs otlpResourceSpans
+	rs.Resource.Attributes = attributes(map[string]string{"service.name": e.service})
+
+	var ss otlpScopeSpans
+	ss.Scope.Name = e.service
+
+	for _, s := range batch {
+		s.mutex.Lock()
+		span := otlpSpan{
+			TraceID:           s.traceID,
+			SpanID:            s.spanID,
+			ParentSpanID:      s.parentID,
+			Name:              s.name,
+			Kind:              s.kind,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributes(s.attrs),
+		}
+		span.Status.Code = s.status
+		span.Status.Message = s.message
+		s.mutex.Unlock()
+
+		ss.Spans = append(ss.Spans, span)
+	}
+
+	rs.ScopeSpans = []otlpScopeSpans{ss}
+
+	return &otlpRequest{ResourceSpans: []otlpResourceSpans{rs}}
+}
+
+// attributes converts the map to a list of OTLP attributes, sorted by key
+func attributes(m map[string]string) []otlpAttribute {
+	attrs := make([]otlpAttribute, 0, len(m))
+	for k, v := range m {
+		var attr otlpAttribute
+		attr.Key = k
+		attr.Value.StringValue = v
+		attrs = append(attrs, attr)
+	}
+
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+
+	return attrs
+}
@@ -0,0 +1,190 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds, as defined by OpenTelemetry
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// status codes, as defined by OpenTelemetry
+const (
+	statusUnset = 0
+	statusOK    = 1
+	statusError = 2
+)
+
+// Span is a single timed operation within a trace.
+// A nil span is valid and does nothing, which is what is returned when tracing is disabled.
+type Span struct {
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	status   int
+	message  string
+
+	mutex sync.Mutex
+	ended bool
+}
+
+// spanKey is used to store the active span in a context
+type spanKey struct{}
+
+// exp is the exporter spans are sent to when they end. Tracing is disabled while it is nil.
+// The mutex makes sure no span is exported while the exporter is being shut down.
+var exp *exporter
+var expMutex sync.RWMutex
+
+// Enabled returns whether or not spans are currently being recorded
+func Enabled() bool {
+	expMutex.RLock()
+	defer expMutex.RUnlock()
+
+	return exp != nil
+}
+
+// Start starts a new internal span as a child of the span in the context (if any)
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return StartKind(ctx, name, KindInternal)
+}
+
+// StartKind starts a new span of the given kind as a child of the span in the context (if any)
+func StartKind(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if !Enabled() {
+		return ctx, nil
+	}
+
+	span := &Span{spanID: randomHex(8), name: name, kind: kind, start: time.Now(), attrs: make(map[string]string)}
+
+	if parent := FromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		span.traceID = randomHex(16)
+	}
+
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// StartRemote starts a new server span, continuing the trace described by the W3C traceparent header if it is valid
+// https://www.w3.org/TR/trace-context/#traceparent-header
+func StartRemote(ctx context.Context, name, traceparent string) (context.Context, *Span) {
+	ctx, span := StartKind(ctx, name, KindServer)
+	if span == nil {
+		return ctx, nil
+	}
+
+	parts := strings.Split(traceparent, "-")
+	if len(parts) == 4 && len(parts[1]) == 32 && len(parts[2]) == 16 && isHex(parts[1]) && isHex(parts[2]) {
+		span.traceID = parts[1]
+		span.parentID = parts[2]
+	}
+
+	return ctx, span
+}
+
+// FromContext returns the active span in the context, or nil if there is none
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// TraceID returns the id of the trace the span belongs to
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+
+	return s.traceID
+}
+
+// SetName changes the name of the span, for spans which are named by what is only known after they started
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	s.name = name
+	s.mutex.Unlock()
+}
+
+// SetAttribute adds an attribute to the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	s.attrs[key] = value
+	s.mutex.Unlock()
+}
+
+// SetError marks the span as failed if err is not nil
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	s.status = statusError
+	s.message = err.Error()
+	s.mutex.Unlock()
+}
+
+// End ends the span and passes it on to the exporter. Calling End more than once has no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	if s.status == statusUnset && s.kind == KindServer {
+		s.status = statusOK
+	}
+	s.mutex.Unlock()
+
+	expMutex.RLock()
+	if exp != nil {
+		exp.export(s)
+	}
+	expMutex.RUnlock()
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b) // crypto/rand only fails if the OS can't provide randomness at all
+	return hex.EncodeToString(b)
+}
+
+// isHex checks that the string only contains lower case hexadecimal characters
+func isHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
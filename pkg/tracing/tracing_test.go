@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartRemote(t *testing.T) {
+	var cases = []struct {
+		name            string
+		traceparent     string
+		expectedTraceID string
+		expectedParent  string
+	}{
+		{"Test valid traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+		{"Test invalid traceparent", "00-not-a-valid-header", "", ""},
+		{"Test upper case traceparent", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01", "", ""},
+		{"Test no traceparent", "", "", ""},
+	}
+
+	shutdown := Setup(http.DefaultClient, "http://localhost:0", "test")
+	defer func() { _ = shutdown(context.Background()) }()
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, span := StartRemote(context.Background(), "test", tc.traceparent)
+			require.NotNil(t, span)
+			assert.Equal(t, span, FromContext(ctx))
+			assert.Len(t, span.TraceID(), 32)
+
+			if tc.expectedTraceID != "" {
+				assert.Equal(t, tc.expectedTraceID, span.TraceID())
+				assert.Equal(t, tc.expectedParent, span.parentID)
+			} else {
+				assert.Empty(t, span.parentID)
+			}
+
+			_, child := Start(ctx, "child")
+			assert.Equal(t, span.TraceID(), child.TraceID())
+			assert.Equal(t, span.spanID, child.parentID)
+		})
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	ctx := context.Background()
+	newCtx, span := Start(ctx, "test")
+	assert.Nil(t, span)
+	assert.Equal(t, ctx, newCtx)
+
+	// none of these should panic on a nil span
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("test"))
+	span.End()
+	assert.Empty(t, span.TraceID())
+}
+
+func TestExport(t *testing.T) {
+	received := make(chan otlpRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		var req otlpRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		received <- req
+	}))
+	defer collector.Close()
+
+	shutdown := Setup(collector.Client(), collector.URL, "test")
+
+	ctx, span := StartRemote(context.Background(), "server", "")
+	span.SetAttribute("http.method", http.MethodGet)
+	_, child := Start(ctx, "child")
+	child.SetError(errors.New("test"))
+	child.End()
+	span.End()
+	span.End() // ending twice should not export the span twice
+
+	require.NoError(t, shutdown(context.Background()))
+
+	req := <-received
+	require.Len(t, req.ResourceSpans, 1)
+	require.Len(t, req.ResourceSpans[0].ScopeSpans, 1)
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "child", spans[0].Name)
+	assert.Equal(t, statusError, spans[0].Status.Code)
+	assert.Equal(t, "test", spans[0].Status.Message)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+
+	assert.Equal(t, "server", spans[1].Name)
+	assert.Equal(t, KindServer, spans[1].Kind)
+	assert.Equal(t, statusOK, spans[1].Status.Code)
+	require.Len(t, spans[1].Attributes, 1)
+	assert.Equal(t, "http.method", spans[1].Attributes[0].Key)
+
+	assert.False(t, Enabled())
+}
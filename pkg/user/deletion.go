@@ -0,0 +1,144 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ctp/pkg/models"
+)
+
+// deletionGrace is how long a deleted user can restore their account before it is purged, unless set otherwise
+const deletionGrace = 30 * 24 * time.Hour
+
+// SetDeletionGrace sets how long a deleted user can restore their account before the user and all of their data are
+// purged. The users deleted before it is set keep the grace period they were given.
+func (m *Manager) SetDeletionGrace(grace time.Duration) {
+	m.grace = grace
+}
+
+// deleteUser marks the user as deleted, and schedules a job purging the user once the grace period is over.
+// The user is hidden from the other users right away, but can restore their account until it is purged.
+// Deleting a user which is already pending deletion does nothing.
+func (m *Manager) deleteUser(ctx context.Context, id string) error {
+	user, err := m.db.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if user.PendingDeletion() {
+		return nil
+	}
+
+	now := time.Now()
+	purgeAt := now.Add(m.grace)
+
+	err = m.db.SetDeleted(ctx, id, now.Unix(), purgeAt.Unix())
+	if err != nil {
+		return err
+	}
+
+	_, err = m.jobs.Schedule(ctx, &models.Job{Type: models.JobPurge, UserID: id}, purgeAt)
+	if err != nil {
+		// the user is restored, as they would otherwise never be purged
+		if err := m.db.SetDeleted(ctx, id, 0, 0); err != nil {
+			models.Log(ctx).WithError(err).Error("Could not restore the user after failing to schedule the purge")
+		}
+
+		return err
+	}
+
+	m.analytics.Forget(id)
+	m.audit(ctx, &models.AuditRecord{Action: models.AuditDelete, UserID: id, At: now.Unix(), PurgeAt: purgeAt.Unix()})
+
+	return nil
+}
+
+// RestoreUser restores the user which is pending deletion, cancelling the purge. Returns the restored user, or a
+// request error if the user is not pending deletion or the grace period is over.
+func (m *Manager) RestoreUser(ctx context.Context, id string) (*models.User, error) {
+	user, err := m.db.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	switch {
+	case !user.PendingDeletion():
+		return nil, models.NewReqErrStr("user not deleted", "the user is not pending deletion")
+	case user.PurgeAt <= now.Unix():
+		return nil, models.NewReqErrStr("grace period over", "the user can no longer be restored, as it is being purged")
+	}
+
+	// the scheduled purge does nothing once the user is no longer pending deletion
+	err = m.db.SetDeleted(ctx, id, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	m.analytics.Forget(id)
+	m.audit(ctx, &models.AuditRecord{Action: models.AuditRestore, UserID: id, At: now.Unix()})
+
+	return m.db.GetUserByID(ctx, id)
+}
+
+// purge runs a JobPurge, deleting the user and everything stored for them once the grace period is over, and adds
+// the purge to the audit log. The job does nothing if the user has been restored, or has been deleted again since
+// (which is purged by a later job).
+func (m *Manager) purge(ctx context.Context, job *models.Job, progress func()) error {
+	user, err := m.db.GetUserByID(ctx, job.UserID)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil // purged by an earlier attempt
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !user.PendingDeletion() || user.PurgeAt > now.Unix() {
+		return nil
+	}
+
+	deleted, err := m.db.DeleteUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	// the Battle.net access token is deleted with the user document. It is not revoked, as the Battle.net client has no
+	// revocation to call, but it expires by itself (ExpiresAt).
+	deleted["battleNetTokens"] = 0
+	if user.BattleNet != nil && user.BattleNet.Token != "" {
+		deleted["battleNetTokens"] = 1
+	}
+
+	// the state kept in the process is dropped as well: the percentiles and ranks of the other users no longer
+	// include the user, and the event streams of the user (and the user's playtime sent to their followers) are closed
+	deleted["cachedGains"] = m.analytics.Forget(user.ID)
+	deleted["subscriptions"] = m.events.Close(user.ID)
+
+	// the record has the id of the job, such that a retry replaces it
+	err = m.db.AddAuditRecord(ctx, &models.AuditRecord{ID: job.ID, Action: models.AuditPurge, UserID: user.ID,
+		At: now.Unix(), RequestID: job.RequestID, Deleted: deleted})
+	if err != nil {
+		return err
+	}
+
+	// the job is kept, but no longer refers to the user
+	job.UserID = ""
+
+	return nil
+}
+
+// audit adds the record to the audit log, with the id of the request. Failing to add it is logged, rather than
+// failing the request, as the action has been taken already.
+func (m *Manager) audit(ctx context.Context, record *models.AuditRecord) {
+	if info := models.GetRequestInfo(ctx); info != nil {
+		record.RequestID = info.ID
+	}
+
+	err := m.db.AddAuditRecord(ctx, record)
+	if err != nil {
+		models.Log(ctx).WithError(err).WithField("action", record.Action).Error("Could not add the record to the audit log")
+	}
+}
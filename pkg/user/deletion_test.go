@@ -0,0 +1,239 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteUser(t *testing.T) {
+	var cases = []struct {
+		name            string
+		purgeAt         int64 // of the stored user
+		err             error
+		expectedErr     error
+		expectedPurgeAt time.Duration // from now, 0 if the user is not deleted by the request
+	}{
+		{"Test ok", 0, nil, nil, deletionGrace},
+		{"Test pending deletion", 1, nil, nil, 0},
+		{"Test no user", 0, models.ErrNotFound, models.ErrNotFound, 0},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDB{user: &models.User{ID: "12345", Name: "onijuan", PurgeAt: tc.purgeAt}, err: tc.err}
+			um := newManager(t, db, &mockOrganizer{})
+
+			err := um.DeleteUser(context.Background(), "12345", nil)
+			require.Equal(t, tc.expectedErr, err)
+
+			db.mu.Lock()
+			jobs := db.jobs
+			db.mu.Unlock()
+
+			if tc.expectedPurgeAt == 0 {
+				assert.Equal(t, tc.purgeAt, db.user.PurgeAt)
+				assert.Empty(t, jobs)
+				assert.Empty(t, db.audit)
+				return
+			}
+
+			purgeAt := time.Now().Add(tc.expectedPurgeAt).Unix()
+			assert.InDelta(t, purgeAt, db.user.PurgeAt, 1)
+			assert.NotZero(t, db.user.DeletedAt)
+
+			// the purge is scheduled for the end of the grace period
+			require.Len(t, jobs, 1)
+			for _, job := range jobs {
+				assert.Equal(t, models.JobPurge, job.Type)
+				assert.Equal(t, models.JobQueued, job.Status)
+				assert.Equal(t, "12345", job.UserID)
+				assert.InDelta(t, db.user.PurgeAt*1000, job.RunAt, 1000)
+			}
+
+			require.Len(t, db.audit, 1)
+			assert.Equal(t, models.AuditDelete, db.audit[0].Action)
+			assert.Equal(t, db.user.PurgeAt, db.audit[0].PurgeAt)
+		})
+	}
+}
+
+func TestRestoreUser(t *testing.T) {
+	now := time.Now().Unix()
+
+	var cases = []struct {
+		name        string
+		purgeAt     int64
+		expectedErr error
+	}{
+		{"Test ok", now + 3600, nil},
+		{"Test not deleted", 0, &models.RequestError{}},
+		{"Test grace period over", now - 1, &models.RequestError{}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDB{user: &models.User{ID: "12345", DeletedAt: now, PurgeAt: tc.purgeAt}}
+			um := newManager(t, db, &mockOrganizer{})
+
+			user, err := um.RestoreUser(context.Background(), "12345")
+			if tc.expectedErr != nil {
+				var reqErr *models.RequestError
+				assert.True(t, errors.As(err, &reqErr), err)
+				assert.Equal(t, tc.purgeAt, db.user.PurgeAt)
+				assert.Empty(t, db.audit)
+
+				return
+			}
+
+			require.Nil(t, err)
+			assert.False(t, user.PendingDeletion())
+			assert.Zero(t, user.DeletedAt)
+
+			require.Len(t, db.audit, 1)
+			assert.Equal(t, models.AuditRestore, db.audit[0].Action)
+		})
+	}
+}
+
+func TestDeletedUserWrites(t *testing.T) {
+	ctx := context.Background()
+
+	var cases = []struct {
+		name  string
+		write func(um *Manager) error
+	}{
+		{"Test set user", func(um *Manager) error {
+			return um.SetUser(ctx, &models.User{ID: "12345", Public: true})
+		}},
+		{"Test replace user", func(um *Manager) error {
+			_, err := um.ReplaceUser(ctx, &models.User{ID: "12345", Name: "onijuan", Public: true}, 1)
+			return err
+		}},
+		{"Test delete fields", func(um *Manager) error { return um.DeleteUser(ctx, "12345", []string{"valve"}) }},
+		{"Test update games", func(um *Manager) error { return um.UpdateGames(ctx, "12345") }},
+		{"Test refresh games", func(um *Manager) error {
+			_, err := um.RefreshGames(ctx, "12345")
+			return err
+		}},
+		{"Test delete import", func(um *Manager) error { return um.DeleteImport(ctx, "12345", "playnite") }},
+		{"Test add manual game", func(um *Manager) error {
+			_, err := um.AddManualGame(ctx, "12345", &models.ManualGame{Name: "Test", Platform: "pc", Hours: 1})
+			return err
+		}},
+		{"Test update manual game", func(um *Manager) error {
+			_, err := um.UpdateManualGame(ctx, "12345", &models.ManualGame{ID: "1", Name: "Test", Platform: "pc", Hours: 1})
+			return err
+		}},
+		{"Test delete manual game", func(um *Manager) error { return um.DeleteManualGame(ctx, "12345", "1") }},
+		{"Test set match", func(um *Manager) error {
+			_, err := um.SetMatch(ctx, "12345", &models.GameMatch{Name: "Test"})
+			return err
+		}},
+		{"Test delete match", func(um *Manager) error { return um.DeleteMatch(ctx, "12345", "test") }},
+		{"Test refresh queued before the deletion", func(um *Manager) error {
+			_, err := um.updateGames(ctx, "12345", func(models.ProviderProgress) {})
+			return err
+		}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDB{user: &models.User{ID: "12345", Name: "onijuan", Version: 1, DeletedAt: time.Now().Unix(),
+				PurgeAt: time.Now().Add(time.Hour).Unix()}}
+			um := New(db, &mockOrganizer{}, nil, nil)
+
+			err := tc.write(um)
+			assert.Equal(t, models.ErrUserDeleted, err)
+
+			// nothing is stored, and no job is queued for the user
+			assert.Nil(t, db.updated)
+			assert.Empty(t, db.manual)
+			assert.Empty(t, db.jobs)
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	now := time.Now().Unix()
+
+	var cases = []struct {
+		name           string
+		purgeAt        int64
+		err            error
+		expectedPurged bool
+	}{
+		{"Test ok", now - 1, nil, true},
+		{"Test restored", 0, nil, false},
+		{"Test deleted again", now + 3600, nil, false},
+		{"Test purged already", 0, models.ErrNotFound, false},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDB{user: &models.User{ID: "12345", PurgeAt: tc.purgeAt,
+				BattleNet: &models.BattleNetAccount{ID: 1, Token: "token"}}, err: tc.err}
+			um := newManager(t, db, &mockOrganizer{})
+			events, unsubscribe := um.events.Subscribe("12345")
+			defer unsubscribe()
+
+			job := &models.Job{ID: "0123456789abcdef01234567", Type: models.JobPurge, UserID: "12345", RequestID: "request"}
+
+			err := um.purge(context.Background(), job, func() {})
+			require.Nil(t, err)
+			assert.Equal(t, tc.expectedPurged, db.purged)
+
+			if !tc.expectedPurged {
+				assert.Equal(t, "12345", job.UserID)
+				assert.Empty(t, db.audit)
+				return
+			}
+
+			// the event stream of the user is closed
+			_, open := <-events
+			assert.False(t, open)
+
+			// the job no longer refers to the user, who is only known by the audit log
+			assert.Empty(t, job.UserID)
+			assert.Equal(t, []models.AuditRecord{{ID: job.ID, Action: models.AuditPurge, UserID: "12345", At: db.audit[0].At,
+				RequestID: "request", Deleted: map[string]int{"users": 1, "history": 2, "battleNetTokens": 1,
+					"cachedGains": 0, "subscriptions": 1}}}, db.audit)
+		})
+	}
+}
+
+func TestPurgeRetried(t *testing.T) {
+	db := &mockDB{user: &models.User{ID: "12345", PurgeAt: time.Now().Unix() - 1}, deleteErr: errors.New("test")}
+	um := newManager(t, db, &mockOrganizer{})
+
+	queued, err := um.jobs.Enqueue(context.Background(), &models.Job{Type: models.JobPurge, UserID: "12345"})
+	require.Nil(t, err)
+
+	// the purge failing on the database is queued again, rather than failed, as the user can no longer be restored
+	var job models.Job
+	assert.Eventually(t, func() bool {
+		db.mu.Lock()
+		defer db.mu.Unlock()
+
+		job = db.jobs[queued.ID]
+		return job.Attempts == 1 && job.Status == models.JobQueued
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Greater(t, job.RunAt, time.Now().UnixNano()/int64(time.Millisecond))
+	if assert.NotNil(t, job.Error) {
+		assert.Equal(t, http.StatusInternalServerError, job.Error.Status)
+	}
+	assert.False(t, db.purged)
+	assert.Empty(t, db.dead)
+}
@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"ctp/pkg/models"
+
+	"golang.org/x/text/secure/precis"
+)
+
+// maxDisplayName is the most characters a display name may have, after it is normalized
+const maxDisplayName = 32
+
+// lookalikeScripts are the scripts with letters that look like each other, e.g. the Latin "a" and the Cyrillic "а"
+var lookalikeScripts = []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic, unicode.Greek}
+
+// normalizeDisplayName returns the display name normalized by the PRECIS Nickname profile (RFC 8266): Unicode
+// normalized (NFKC), with spaces trimmed and collapsed, and without control or invisible characters. Returns a request
+// error if the name is invalid, too long, mixes lookalike scripts in a word (e.g. a Latin name with a Cyrillic "а"),
+// or could be mistaken for a reserved name.
+func normalizeDisplayName(name string) (string, error) {
+	normalized, err := precis.Nickname.String(name)
+	if err != nil {
+		return "", models.NewReqErr(err, "invalid display name: the name has characters which are not allowed")
+	}
+
+	if utf8.RuneCountInString(normalized) > maxDisplayName {
+		return "", models.NewReqErrStr("invalid display name", "invalid display name: the name is too long")
+	}
+
+	if mixesScripts(normalized) {
+		return "", models.NewReqErrStr("confusable display name",
+			"invalid display name: a word of the name mixes letters of scripts which look alike")
+	}
+
+	if models.Contains(reservedNames, models.Skeleton(normalized)) {
+		return "", models.NewReqErrStr("reserved display name", "invalid display name: the name is reserved")
+	}
+
+	return normalized, nil
+}
+
+// displayName returns the display name of the user with the id normalized (see normalizeDisplayName). Returns a request
+// error if the name is invalid, or looks like (or is) the handle or display name of another user.
+func (m *Manager) displayName(ctx context.Context, id, name string) (string, error) {
+	normalized, err := normalizeDisplayName(name)
+	if err != nil {
+		return "", err
+	}
+
+	ids, err := m.db.GetLookalikes(ctx, models.Skeleton(normalized))
+	if err != nil {
+		return "", err
+	}
+
+	for _, other := range ids {
+		if other != id {
+			return "", models.NewReqErrStr("lookalike display name",
+				"invalid display name: the name looks like the name of another user")
+		}
+	}
+
+	return normalized, nil
+}
+
+// mixesScripts returns true if a word of the name has letters of more than one of the lookalike scripts
+func mixesScripts(name string) bool {
+	for _, word := range strings.Fields(name) {
+		var found *unicode.RangeTable
+
+		for _, r := range word {
+			for _, script := range lookalikeScripts {
+				if !unicode.Is(script, r) {
+					continue
+				}
+
+				if found != nil && found != script {
+					return true
+				}
+				found = script
+			}
+		}
+	}
+
+	return false
+}
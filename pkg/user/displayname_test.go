@@ -0,0 +1,94 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeDisplayName(t *testing.T) {
+	confusable := models.NewReqErrStr("confusable display name",
+		"invalid display name: a word of the name mixes letters of scripts which look alike")
+	reserved := models.NewReqErrStr("reserved display name", "invalid display name: the name is reserved")
+
+	var cases = []struct {
+		name        string
+		displayName string
+		expected    string
+		expectedErr error
+	}{
+		{"Test ascii", "Onijuan", "Onijuan", nil},
+		{"Test accents", "José Müller", "José Müller", nil},
+		{"Test normalized", "  Ｊosé   Müller ", "José Müller", nil},
+		{"Test cyrillic", "Дмитрий", "Дмитрий", nil},
+		{"Test greek", "Αλέξανδρος", "Αλέξανδρος", nil},
+		{"Test japanese", "マリオ Mario", "マリオ Mario", nil},
+		{"Test emoji", "👾 Gamer", "👾 Gamer", nil},
+		{"Test scripts in separate words", "Дмитрий Ivanov", "Дмитрий Ivanov", nil},
+		{"Test mixed scripts", "Oniјuan", "", confusable}, // with a Cyrillic "ј"
+		{"Test mixed greek", "Οnijuan", "", confusable},   // with a Greek "Ο"
+		{"Test reserved", "Admin", "", reserved},
+		{"Test reserved lookalike", "ѕуѕтем", "", reserved},
+		{"Test reserved spaced", "S u p p o r t", "", reserved},
+		{"Test reserved digits", "r00t", "", reserved},
+		{"Test too long", "Onijuan Onijuan Onijuan Onijuan O", "", models.NewReqErrStr("invalid display name",
+			"invalid display name: the name is too long")},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			normalized, err := normalizeDisplayName(tc.displayName)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expected, normalized)
+		})
+	}
+
+	// invisible and control characters are not allowed, nor are empty names
+	for _, name := range []string{"Oni\u200bjuan", "Oni\x00juan", "   "} {
+		_, err := normalizeDisplayName(name)
+		var reqErr *models.RequestError
+		assert.True(t, errors.As(err, &reqErr), name)
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	lookalike := models.NewReqErrStr("lookalike display name",
+		"invalid display name: the name looks like the name of another user")
+
+	var cases = []struct {
+		name        string
+		displayName string
+		dbErr       error
+		expected    string
+		expectedErr error
+	}{
+		{"Test unique", "Luigi", nil, "Luigi", nil},
+		{"Test other user", "Onijuan", nil, "", lookalike},
+		{"Test lookalike spaced", "Oni Juan", nil, "", lookalike},
+		{"Test lookalike digits", "0NIJUAN", nil, "", lookalike},
+		{"Test lookalike of own name", "Mari0", nil, "Mari0", nil},
+		{"Test invalid", "Admin", nil, "", models.NewReqErrStr("reserved display name",
+			"invalid display name: the name is reserved")},
+		{"Test dbErr", "Luigi", errors.New("test"), "", errors.New("test")},
+	}
+
+	// the handle or display name of user 67890 is "onijuan", and the user's own is "mario"
+	db := &mockDB{lookalike: map[string][]string{"onijuan": {"67890"}, "mario": {"12345"}}}
+	um := New(db, &mockOrganizer{}, nil, nil)
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.err = tc.dbErr
+
+			displayName, err := um.displayName(context.Background(), "12345", tc.displayName)
+			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expected, displayName)
+		})
+	}
+}
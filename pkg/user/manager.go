@@ -1,36 +1,74 @@
 package user
 
 import (
+	"context"
+	"ctp/pkg/analytics"
+	"ctp/pkg/catalog"
+	"ctp/pkg/events"
+	"ctp/pkg/jobs"
+	"ctp/pkg/launcher"
+	"ctp/pkg/metadata"
 	"ctp/pkg/models"
 	"errors"
+	"fmt"
 	"net/http"
+	"reflect"
 	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // Manager is a struct which contains everything necessary
 type Manager struct {
 	models.Organizer
-	db models.Database
+	db        models.Database
+	catalog   *catalog.Catalog
+	metadata  *metadata.Enricher // nil if the games are not enriched with metadata
+	analytics *analytics.Analyzer
+	events    *events.Hub
+	jobs      *jobs.Queue
+	grace     time.Duration // how long a deleted user can be restored before it is purged
 }
 
 // New returns a new user manager instance.
 // The manager takes a db and organizer. It embedds the organizer to simplify calls
 // Organizer is used to simplify the passing of all interfaces to the handler.
-func New(db models.Database, organizer models.Organizer) *Manager {
-	m := &Manager{db: db}
+// The catalog is used to merge the duplicate entries of games, using the default catalog if nil.
+// The enricher adds metadata (e.g. genres) to the games when they are updated, which is disabled if nil.
+// The jobs of the users are queued in the db, and are run once the workers are started by StartJobs.
+// Deleted users are purged after a grace period of 30 days, unless set otherwise by SetDeletionGrace.
+func New(db models.Database, organizer models.Organizer, gameCatalog *catalog.Catalog, enricher *metadata.Enricher) *Manager {
+	if gameCatalog == nil {
+		gameCatalog = catalog.DefaultCatalog()
+	}
+
+	m := &Manager{db: db, catalog: gameCatalog, metadata: enricher, analytics: analytics.New(db), events: events.NewHub(),
+		grace: deletionGrace}
 	m.Organizer = organizer
 
+	m.jobs = jobs.New(db, m.events)
+	m.jobs.Handle(models.JobRefresh, m.refresh)
+	m.jobs.Handle(models.JobValidate, m.validate)
+	m.jobs.Handle(models.JobPurge, m.purge)
+
+	// a user whose purge failed can no longer be restored, so the purge is retried until the user is purged
+	m.jobs.RetryUntilDone(models.JobPurge)
+
 	return m
 }
 
 // GetUserByID gets the relevant info for the given user by id
-func (m *Manager) GetUserByID(id string) (*models.User, error) {
-	return m.db.GetUserByID(id)
+func (m *Manager) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	return m.db.GetUserByID(ctx, id)
 }
 
-// GetUserByName gets the relevant info for the given user by username
-func (m *Manager) GetUserByName(username string) (*models.User, error) {
-	user, err := m.db.GetUserByName(username)
+// GetUserByName gets the relevant info for the given user by their handle, regardless of case. A previous name of the
+// user gets the user as long as the name is held for them, in which case the name of the user returned is their
+// current name.
+func (m *Manager) GetUserByName(ctx context.Context, username string) (*models.User, error) {
+	user, err := m.db.GetUserByName(ctx, strings.ToLower(username))
 	if err != nil {
 		return nil, err
 	}
@@ -38,88 +76,702 @@ func (m *Manager) GetUserByName(username string) (*models.User, error) {
 	return user, nil
 }
 
-// SetUser updates a given user
-func (m *Manager) SetUser(user *models.User) error {
-	gameChanges, err := m.validateUserInfo(user)
+// SetUser updates a given user. The user is validated and stored by a job, which also updates the games if the
+// accounts have changed. Returns a *models.JobPendingError if the job has not finished within jobWait.
+func (m *Manager) SetUser(ctx context.Context, user *models.User) error {
+	err := validateHidden(user.Hidden)
 	if err != nil {
 		return err
 	}
 
-	err = m.db.UpdateUser(user)
+	err = m.requireUser(ctx, user.ID)
 	if err != nil {
 		return err
 	}
 
-	// Updates games if there have been a change in game providers
-	if gameChanges {
-		return m.UpdateGames(user.ID)
+	// Battle.net accounts are only linked through OAuth
+	user.BattleNet = nil
+
+	_, err = m.jobs.Run(ctx, &models.Job{Type: models.JobValidate, UserID: user.ID, User: user}, jobWait)
+
+	return err
+}
+
+// setUser validates the given user and updates the stored user. Returns true if there have been a change in game providers
+func (m *Manager) setUser(ctx context.Context, user *models.User) (bool, error) {
+	gameChanges, err := m.validateUserInfo(ctx, user)
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return gameChanges, m.db.UpdateUser(ctx, user)
+}
+
+// ReplaceUser replaces the user's name, display name, visibility and accounts, as long as the stored user still has the given version.
+// Accounts which have changed are validated, and the games are updated if any of them changed, by a job.
+// The games and total game time can not be replaced. Returns the stored user after the update,
+// or a *models.JobPendingError if the job has not finished within jobWait.
+func (m *Manager) ReplaceUser(ctx context.Context, user *models.User, version int64) (*models.User, error) {
+	dbUser, err := m.db.GetUserByID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbUser.PendingDeletion() {
+		return nil, models.ErrUserDeleted
+	}
+
+	// failing early to avoid validating the accounts, the version is checked again when the user is stored
+	if dbUser.Version != version {
+		return nil, models.ErrPreconditionFailed
+	}
+
+	user.Name = strings.ToLower(user.Name)
+	err = checkRename(user.Name, dbUser, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateHidden(user.Hidden)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisplayName != "" && user.DisplayName != dbUser.DisplayName {
+		user.DisplayName, err = m.displayName(ctx, user.ID, user.DisplayName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	job := &models.Job{Type: models.JobValidate, UserID: user.ID, User: user, Replace: true, Version: version}
+
+	_, err = m.jobs.Run(ctx, job, jobWait)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.db.GetUserByID(ctx, user.ID)
 }
 
-// DeleteUser deletes the user with the given id
-func (m *Manager) DeleteUser(id string, fields []string) error {
+// replaceUser validates the accounts which have changed, and replaces the stored user as long as it still has the
+// given version. Returns true if any of the accounts changed.
+func (m *Manager) replaceUser(ctx context.Context, user *models.User, version int64) (bool, error) {
+	dbUser, err := m.db.GetUserByID(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if dbUser.PendingDeletion() {
+		return false, models.ErrUserDeleted
+	}
+
+	if dbUser.Version != version {
+		return false, models.ErrPreconditionFailed
+	}
+
+	// Battle.net accounts are only linked through OAuth, the stored account (and token) is kept unless it is removed
+	if user.BattleNet != nil {
+		user.BattleNet = dbUser.BattleNet
+	}
+
+	gameChanges, err := m.validateChangedAccounts(ctx, user, dbUser)
+	if err != nil {
+		return false, err
+	}
+
+	user.Games = dbUser.Games
+	user.TotalGameTime = dbUser.TotalGameTime
+
+	return gameChanges, m.db.ReplaceUser(ctx, user, version)
+}
+
+// DeleteUser deletes the given fields from the user with the given id. Without any fields, the user is deleted, and
+// is purged along with all of their data once the grace period is over, unless the user is restored before.
+func (m *Manager) DeleteUser(ctx context.Context, id string, fields []string) error {
 	if len(fields) == 0 {
-		return m.db.DeleteUser(id)
+		return m.deleteUser(ctx, id)
 	}
 
-	err := m.db.DeleteFieldsFromUser(id, fields)
+	err := m.requireUser(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	return m.UpdateGames(id) // Updates the games for the user, as some game providers may have been deleted
+	err = m.db.DeleteFieldsFromUser(ctx, id, fields)
+	if err != nil {
+		return err
+	}
+
+	return m.UpdateGames(ctx, id) // Updates the games for the user, as some game providers may have been deleted
 }
 
-// UpdateGames updates all games the user has registered
-func (m *Manager) UpdateGames(id string) error {
-	user, err := m.db.GetUserByID(id)
+// UpdateGames updates all games the user has registered by a job, waiting for it to finish.
+// Returns a *models.JobPendingError if the job has not finished within jobWait.
+func (m *Manager) UpdateGames(ctx context.Context, id string) error {
+	// a missing or deleted user fails the request, rather than the job
+	err := m.requireUser(ctx, id)
 	if err != nil {
 		return err
 	}
 
+	_, err = m.jobs.Run(ctx, &models.Job{Type: models.JobRefresh, UserID: id}, jobWait)
+	return err
+}
+
+// updateGames updates all games the user has registered, and returns the updated user.
+// The outcome of fetching the games from each provider is reported to progress as soon as it is known.
+// If the total playtime of a public user changes, it is sent to the user's followers.
+func (m *Manager) updateGames(ctx context.Context, id string, progress func(models.ProviderProgress)) (*models.User, error) {
+	user, err := m.db.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// a refresh queued before the user was deleted does not change the user pending deletion
+	if user.PendingDeletion() {
+		return nil, models.ErrUserDeleted
+	}
+
+	previous := user.Publicly().TotalGameTime
+
 	var updatedGames []models.Game
 
 	if user.Lol != nil {
-		lolGame, err := m.GetLolPlaytime(user.Lol)
+		lolGame, err := m.GetLolPlaytime(ctx, user.Lol)
+		progress(fetched("lol", 1, err))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		updatedGames = append(updatedGames, *lolGame)
 	}
 
 	if user.Overwatch != nil {
-		ow, err := m.GetBlizzardPlaytime(user.Overwatch)
+		ow, err := m.GetBlizzardPlaytime(ctx, user.Overwatch)
+		progress(fetched("overwatch", 1, err))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		updatedGames = append(updatedGames, *ow)
 	}
 
 	if user.Valve != nil {
-		games, err := m.GetValvePlaytime(user.Valve.ID)
-		if err != nil {
-			return err
+		games, err := m.GetValvePlaytime(ctx, user.Valve.ID)
+
+		var private *models.PrivateProfileError
+		switch {
+		case errors.As(err, &private):
+			// the account is kept linked with the games from the last update, until the profile is made public
+			games, err = m.keptGames(ctx, user, "valve", user.Valve.ID, steamGames)
+			if err != nil {
+				return nil, err
+			}
+
+			user.Valve.SetStatus(private.Status)
+			progress(models.ProviderProgress{Provider: "valve", Status: models.ProviderKept, Games: len(games)})
+		case err != nil:
+			progress(fetched("valve", 0, err))
+			return nil, err
+		default:
+			err = m.keepGames(ctx, id, "valve", user.Valve.ID, games)
+			if err != nil {
+				return nil, err
+			}
+
+			user.Valve.SetStatus(models.ValvePublic)
+			progress(fetched("valve", len(games), nil))
 		}
 
 		updatedGames = append(games, updatedGames...)
 	}
 
 	if user.Runescape != nil {
-		rs, err := m.GetRSPlaytime(user.Runescape)
+		rs, err := m.GetRSPlaytime(ctx, user.Runescape)
+		progress(fetched("runescape", 1, err))
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		updatedGames = append(updatedGames, *rs)
 	}
 
-	user.Games = updatedGames
+	if user.BattleNet != nil {
+		games, err := m.GetBattleNetPlaytime(ctx, user.BattleNet)
+
+		switch {
+		case errors.Is(err, models.ErrTokenExpired):
+			// the account is kept linked with the games from the last update, until the account is linked again
+			games, err = m.keptGames(ctx, user, "battlenet", strconv.FormatInt(user.BattleNet.ID, 10), battleNetGames)
+			if err != nil {
+				return nil, err
+			}
+
+			user.BattleNet.SetStatus(models.BattleNetExpired)
+			progress(models.ProviderProgress{Provider: "battlenet", Status: models.ProviderKept, Games: len(games)})
+		case err != nil:
+			progress(fetched("battlenet", 0, err))
+			return nil, err
+		default:
+			err = m.keepGames(ctx, id, "battlenet", strconv.FormatInt(user.BattleNet.ID, 10), games)
+			if err != nil {
+				return nil, err
+			}
+
+			user.BattleNet.SetStatus(models.BattleNetLinked)
+			progress(fetched("battlenet", len(games), nil))
+		}
+
+		updatedGames = append(updatedGames, games...)
+	}
+
+	// the games imported from launchers are kept until they are imported again or removed
+	imports, err := m.db.GetImports(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, library := range imports {
+		updatedGames = append(updatedGames, library.Games...)
+	}
+
+	// the games recorded manually are never fetched, and therefore never overwritten
+	manual, err := m.db.GetManualGames(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range manual {
+		updatedGames = append(updatedGames, manual[i].Game())
+	}
+
+	// the same game from several providers is merged, as matched by the catalog and the user
+	matches, err := m.db.GetMatches(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Games = m.catalog.Merge(updatedGames, matches)
+
+	if m.metadata != nil {
+		m.metadata.Enrich(ctx, user.Games)
+	}
 
-	return m.db.UpdateGames(user)
+	err = m.db.UpdateGames(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// the history is only used for the stats, which are left without the snapshot rather than failing the update
+	err = m.analytics.Record(ctx, user)
+	if err != nil {
+		models.Log(ctx).WithError(err).Warn("Could not record the playtime history")
+	}
+
+	// the followers are sent the total as shown on the public profile, unless the user hides it
+	if total := user.Publicly().TotalGameTime; user.Public && !models.Contains(user.Hidden, models.HideTotal) && total != previous {
+		m.events.Publish(models.Event{Type: models.EventTotal, UserID: id,
+			Data: &models.TotalChange{Name: user.Name, TotalPlayTime: total, Previous: previous}})
+	}
+
+	return user, nil
+}
+
+// fetched returns the progress of a provider the games have been fetched from, or failed to be fetched from
+func fetched(provider string, games int, err error) models.ProviderProgress {
+	if err != nil {
+		return models.ProviderProgress{Provider: provider, Status: models.ProviderFailed, Error: models.ProblemFromError(err)}
+	}
+
+	return models.ProviderProgress{Provider: provider, Status: models.ProviderDone, Games: games}
+}
+
+// GetStats returns the stats derived from the user's games and the history of their playtime
+func (m *Manager) GetStats(ctx context.Context, id string) (*models.Stats, error) {
+	user, err := m.db.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.analytics.Stats(ctx, user)
+}
+
+// GetRecap returns the summary of the user's playtime in the year
+func (m *Manager) GetRecap(ctx context.Context, id string, year int) (*models.Recap, error) {
+	user, err := m.db.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.analytics.Recap(ctx, user, year)
+}
+
+// requireUser returns models.ErrNotFound if the user does not exist (e.g. has been purged), such that data stored in
+// the subcollections of the user, or merged into the user, does not create a user which does not exist.
+// Returns models.ErrUserDeleted if the user is pending deletion, as they can not change anything until restored.
+func (m *Manager) requireUser(ctx context.Context, id string) error {
+	user, err := m.db.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if user.PendingDeletion() {
+		return models.ErrUserDeleted
+	}
+
+	return nil
+}
+
+// keepGames stores the games fetched from the provider for the account, which are kept while they can't be fetched
+func (m *Manager) keepGames(ctx context.Context, id, provider, account string, games []models.Game) error {
+	return m.db.SetProviderGames(ctx, id, &models.ProviderGames{Provider: provider, Account: account,
+		FetchedAt: time.Now().Unix(), Games: games})
+}
+
+// keptGames returns the games last fetched from the provider for the account, as they were fetched. The games of users
+// which have not been stored since they were fetched are taken from the user's (merged) games by fromProvider.
+// Returns no games if they were fetched for another account.
+func (m *Manager) keptGames(ctx context.Context, user *models.User, provider, account string,
+	fromProvider func(games []models.Game) []models.Game) ([]models.Game, error) {
+	stored, err := m.db.GetProviderGames(ctx, user.ID, provider)
+	switch {
+	case err == nil && stored.Account == account:
+		return stored.Games, nil
+	case err == nil:
+		return nil, nil
+	case !errors.Is(err, models.ErrNotFound):
+		return nil, err
+	}
+
+	return fromProvider(user.Games), nil
+}
+
+// steamGames returns the Steam entries of the merged games, which are the only games with an app id
+func steamGames(games []models.Game) []models.Game {
+	var steam []models.Game
+	for _, game := range games {
+		if game.AppID != 0 {
+			steam = append(steam, unmerged(game, "steam"))
+		}
+	}
+
+	return steam
+}
+
+// battleNetGames returns the Battle.net entries of the merged games
+func battleNetGames(games []models.Game) []models.Game {
+	var battleNet []models.Game
+	for _, game := range games {
+		if models.Contains([]string{models.WorldOfWarcraft, models.DiabloIII, models.StarCraftII}, game.Name) {
+			battleNet = append(battleNet, unmerged(game, ""))
+		}
+	}
+
+	return battleNet
+}
+
+// unmerged returns the entry of the merged game from the source, without the playtime of the other entries merged into
+// it and without what was added when the games were merged. Only the name and playtime of the entry are known.
+func unmerged(game models.Game, source string) models.Game {
+	for _, entry := range game.Merged {
+		if entry.Source == source {
+			game.Name, game.Minutes, game.Time = entry.Name, entry.Minutes, entry.Minutes/60
+			break
+		}
+	}
+
+	game.Merged, game.GameID, game.Metadata = nil, "", nil
+
+	return game
+}
+
+// AuthorizeBattleNet returns the URL the user is redirected to, to link their Battle.net account in the given region,
+// along with the nonce the browser of the user has to keep until the callback
+func (m *Manager) AuthorizeBattleNet(id, region string) (string, string, error) {
+	return m.BattleNetAuthURL(id, region)
+}
+
+// LinkBattleNet links the Battle.net account authorized by the user who started linking it, and updates their games.
+// The nonce is the one kept by the browser since authorizing, which has to match the state.
+func (m *Manager) LinkBattleNet(ctx context.Context, code, state, nonce string) (*models.BattleNetAccount, error) {
+	id, account, err := m.HandleBattleNetCallback(ctx, code, state, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.requireUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.db.UpdateUser(ctx, &models.User{ID: id, BattleNet: account})
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.UpdateGames(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// ImportLibrary imports the file exported from a launcher in the given format, replacing the library imported earlier
+// in the same format. Returns the imported library, while the user's games are updated by a job queued in the background.
+func (m *Manager) ImportLibrary(ctx context.Context, id, format string, data []byte) (*models.ImportedLibrary, error) {
+	games, err := launcher.Parse(format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.requireUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	library := &models.ImportedLibrary{Format: format, ImportedAt: time.Now().Unix(), Games: games}
+
+	err = m.db.SetImport(ctx, id, library)
+	if err != nil {
+		return nil, err
+	}
+
+	m.refreshLater(ctx, id)
+
+	return library, nil
+}
+
+// GetImports returns the libraries the user has imported from launchers
+func (m *Manager) GetImports(ctx context.Context, id string) ([]models.ImportedLibrary, error) {
+	return m.db.GetImports(ctx, id)
+}
+
+// DeleteImport removes the library imported in the given format. The user's games are updated by a job queued in the
+// background.
+func (m *Manager) DeleteImport(ctx context.Context, id, format string) error {
+	err := m.requireUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = m.db.DeleteImport(ctx, id, format)
+	if err != nil {
+		return err
+	}
+
+	m.refreshLater(ctx, id)
+
+	return nil
+}
+
+// SearchCatalog returns the games in the catalog with a name or alias containing the name
+func (m *Manager) SearchCatalog(name string) ([]models.CatalogGame, error) {
+	if catalog.Key(name) == "" {
+		return nil, models.NewReqErrStr("no name", "invalid name: the name has to contain a letter or digit")
+	}
+
+	return m.catalog.Search(name), nil
+}
+
+// GetMatches returns the user's overrides of how games are matched to the catalog
+func (m *Manager) GetMatches(ctx context.Context, id string) ([]models.GameMatch, error) {
+	return m.db.GetMatches(ctx, id)
+}
+
+// SetMatch stores the user's override of how the games with the name are matched to the catalog, either to the
+// catalog game with the id, or to none if the id is empty, such that they are never merged with other games.
+// Confirming a match is setting it to the game it is already matched to. Updates the user's games.
+func (m *Manager) SetMatch(ctx context.Context, id string, match *models.GameMatch) (*models.GameMatch, error) {
+	match.Name = strings.TrimSpace(match.Name)
+	match.Key = catalog.Key(match.Name)
+	if match.Key == "" {
+		return nil, models.NewReqErrStr("no name", "invalid match: the name has to contain a letter or digit")
+	}
+
+	if match.GameID != "" && m.catalog.Get(match.GameID) == nil {
+		return nil, models.NewReqErrStr("unknown game: "+match.GameID, "invalid match: no game in the catalog with the id "+match.GameID)
+	}
+
+	err := m.requireUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.db.SetMatch(ctx, id, match)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.UpdateGames(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
+
+// DeleteMatch removes the user's override of how games are matched to the catalog, and updates the user's games
+func (m *Manager) DeleteMatch(ctx context.Context, id, key string) error {
+	err := m.requireUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = m.db.DeleteMatch(ctx, id, key)
+	if err != nil {
+		return err
+	}
+
+	return m.UpdateGames(ctx, id)
+}
+
+// The limits of the games recorded manually
+const (
+	maxManualGames    = 1000
+	maxManualSessions = 1000
+	maxManualName     = 100    // characters
+	maxManualHours    = 100000 // hours not recorded as sessions
+)
+
+// GetManualGames returns the games the user records the playtime of manually
+func (m *Manager) GetManualGames(ctx context.Context, id string) ([]models.ManualGame, error) {
+	return m.db.GetManualGames(ctx, id)
+}
+
+// GetManualGame returns a game the user records the playtime of manually
+func (m *Manager) GetManualGame(ctx context.Context, id, gameID string) (*models.ManualGame, error) {
+	return m.db.GetManualGame(ctx, id, gameID)
+}
+
+// AddManualGame validates and stores a new game the user records the playtime of manually, and queues updating the
+// user's games. Returns the stored game, with its id.
+func (m *Manager) AddManualGame(ctx context.Context, id string, game *models.ManualGame) (*models.ManualGame, error) {
+	err := validateManualGame(game)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.requireUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	games, err := m.db.GetManualGames(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(games) >= maxManualGames {
+		return nil, models.NewReqErrStr("too many manual games",
+			fmt.Sprintf("invalid request: no more than %d manual games can be recorded", maxManualGames))
+	}
+
+	game.ID = ""
+
+	return m.setManualGame(ctx, id, game)
+}
+
+// UpdateManualGame validates and replaces a game the user records the playtime of manually, identified by its id,
+// and queues updating the user's games. Returns models.ErrNotFound if the user has no such game.
+func (m *Manager) UpdateManualGame(ctx context.Context, id string, game *models.ManualGame) (*models.ManualGame, error) {
+	err := validateManualGame(game)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.requireUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = m.db.GetManualGame(ctx, id, game.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.setManualGame(ctx, id, game)
+}
+
+// setManualGame stores the manual game and returns it, with its id. The user's games are updated by a job queued in the
+// background.
+func (m *Manager) setManualGame(ctx context.Context, id string, game *models.ManualGame) (*models.ManualGame, error) {
+	game.UpdatedAt = time.Now().Unix()
+
+	err := m.db.SetManualGame(ctx, id, game)
+	if err != nil {
+		return nil, err
+	}
+
+	m.refreshLater(ctx, id)
+
+	return game, nil
+}
+
+// DeleteManualGame removes a game the user records the playtime of manually. The user's games are updated by a job
+// queued in the background.
+func (m *Manager) DeleteManualGame(ctx context.Context, id, gameID string) error {
+	err := m.requireUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = m.db.DeleteManualGame(ctx, id, gameID)
+	if err != nil {
+		return err
+	}
+
+	m.refreshLater(ctx, id)
+
+	return nil
+}
+
+// validateHidden checks that the fields hidden from other users are fields which can be hidden
+func validateHidden(hidden []string) error {
+	for _, field := range hidden {
+		if !models.Contains(models.HideableFields, field) {
+			return models.NewReqErrStr("invalid hidden field: "+field, fmt.Sprintf(
+				"invalid hidden field: %s, must be one of %s", field, strings.Join(models.HideableFields, ", ")))
+		}
+	}
+
+	return nil
+}
+
+// validateManualGame checks that the manual game has a name, a valid platform and a realistic playtime.
+// The name is trimmed, and sessions can not be in the future.
+func validateManualGame(game *models.ManualGame) error {
+	game.Name = strings.TrimSpace(game.Name)
+
+	switch {
+	case game.Name == "":
+		return models.NewReqErrStr("no name", "invalid manual game: no name")
+	case utf8.RuneCountInString(game.Name) > maxManualName:
+		return models.NewReqErrStr("name too long", fmt.Sprintf("invalid manual game: name longer than %d characters", maxManualName))
+	case !models.Contains(models.ManualPlatforms, game.Platform):
+		return models.NewReqErrStr("invalid platform: "+game.Platform, fmt.Sprintf(
+			"invalid manual game: platform must be one of %s", strings.Join(models.ManualPlatforms, ", ")))
+	case game.Hours < 0 || game.Hours > maxManualHours:
+		return models.NewReqErrStr("invalid hours", fmt.Sprintf("invalid manual game: hours must be between 0 and %d", maxManualHours))
+	case len(game.Sessions) > maxManualSessions:
+		return models.NewReqErrStr("too many sessions", fmt.Sprintf("invalid manual game: more than %d sessions", maxManualSessions))
+	}
+
+	now := time.Now().Unix()
+	for i, session := range game.Sessions {
+		if session.Minutes <= 0 || session.Minutes > 24*60 {
+			return models.NewReqErrStr("invalid session minutes",
+				fmt.Sprintf("invalid manual game: the minutes of session %d must be between 1 and 1440", i+1))
+		}
+
+		if session.Date <= 0 || session.Date > now {
+			return models.NewReqErrStr("invalid session date",
+				fmt.Sprintf("invalid manual game: the date of session %d must be in the past", i+1))
+		}
+	}
+
+	return nil
 }
 
 // Redirect redirects the user to oauth providers
@@ -134,7 +786,7 @@ func (m *Manager) AuthCallback(w http.ResponseWriter, r *http.Request) (string,
 		return "", err
 	}
 
-	err = m.db.CreateUser(&models.User{ID: id})
+	err = m.db.CreateUser(r.Context(), &models.User{ID: id})
 	if err != nil {
 		return "", err
 	}
@@ -148,24 +800,54 @@ func (m *Manager) AuthCallback(w http.ResponseWriter, r *http.Request) (string,
 }
 
 // UpdateRiotAPIKey updates
-func (m *Manager) UpdateRiotAPIKey(key string) error {
-	return m.UpdateKey(key)
+func (m *Manager) UpdateRiotAPIKey(ctx context.Context, key string) error {
+	return m.UpdateKey(ctx, key)
 }
 
-// validateUserName checks if the name entered is a valid name for a user
+// reservedNames can not be taken by users, as they would be mistaken for the service itself or clash with its paths
+var reservedNames = []string{"admin", "administrator", "anonymous", "api", "badge", "events", "jobs", "login", "me",
+	"moderator", "null", "root", "stats", "support", "system", "undefined"}
+
+// handlePattern is the slug rule of the handles: lowercase letters and digits, where words may be separated by a
+// hyphen or an underscore
+var handlePattern = regexp.MustCompile("^[a-z0-9]+([-_][a-z0-9]+)*$")
+
+// validateUserName checks if the (lowercase) name entered is a valid handle for a user, returning a request error if not.
+// Handles are used in URLs, while the display name of the user may be any (Unicode) name.
 func validateUserName(name string) error {
-	re := regexp.MustCompile("^[a-zA-Z0-9 ]{1,15}$")
-	if !re.MatchString(name) {
-		return errors.New("invalid username")
+	if len(name) < 3 || len(name) > 30 || !handlePattern.MatchString(name) {
+		return models.NewReqErrStr("invalid username", "invalid username: the name must be 3 to 30 letters or digits, "+
+			"where words may be separated by a hyphen or an underscore")
+	}
+
+	if models.Contains(reservedNames, strings.ToLower(name)) {
+		return models.NewReqErrStr("reserved username", "invalid username: the name is reserved")
 	}
 
 	return nil
 }
 
+// checkRename returns a request error if the stored user can not change their name to the given (lowercase) name:
+// if the name is invalid or reserved, or the user changed their name less than models.RenameCooldown ago.
+// Keeping the name, and removing it, is always allowed. The cooldown is checked again by the database when the name is
+// stored, as the user may have been renamed since.
+func checkRename(name string, dbUser *models.User, now time.Time) error {
+	if name == dbUser.Name || name == "" {
+		return nil
+	}
+
+	err := validateUserName(name)
+	if err != nil {
+		return err
+	}
+
+	return models.CheckRenameCooldown(dbUser, name, now)
+}
+
 // validateUserInfo checks whether or not any information has been updated,
 // and validates the updated information
-func (m *Manager) validateUserInfo(user *models.User) (bool, error) {
-	dbUser, err := m.db.GetUserByID(user.ID)
+func (m *Manager) validateUserInfo(ctx context.Context, user *models.User) (bool, error) {
+	dbUser, err := m.db.GetUserByID(ctx, user.ID)
 	if err != nil {
 		return false, err
 	}
@@ -173,13 +855,17 @@ func (m *Manager) validateUserInfo(user *models.User) (bool, error) {
 		dbUser = &models.User{} // makes sure that there is no invalid nilpointer dereferense
 	}
 
-	if user.Name != "" && user.Name != dbUser.Name {
-		err = validateUserName(user.Name)
+	user.Name = strings.ToLower(user.Name)
+	rename := user.Name != "" && user.Name != dbUser.Name
+	if rename {
+		err = checkRename(user.Name, dbUser, time.Now())
 		if err != nil {
 			return false, err
 		}
+	}
 
-		err = m.db.SetUsername(user)
+	if user.DisplayName != "" && user.DisplayName != dbUser.DisplayName {
+		user.DisplayName, err = m.displayName(ctx, user.ID, user.DisplayName)
 		if err != nil {
 			return false, err
 		}
@@ -187,35 +873,84 @@ func (m *Manager) validateUserInfo(user *models.User) (bool, error) {
 
 	// validating each property
 	// if the property is nil, or the same as stored in the database, it is considered valid
-	lol, err := m.validateLol(user.Lol, dbUser.Lol)
+	lol, err := m.validateLol(ctx, user.Lol, dbUser.Lol)
 	if err != nil {
 		return false, err
 	}
-	ow, err := m.validateOW(user.Overwatch, dbUser.Overwatch)
+	ow, err := m.validateOW(ctx, user.Overwatch, dbUser.Overwatch)
 	if err != nil {
 		return false, err
 	}
-	valve, err := m.validateValve(user.Valve, dbUser.Valve)
+	valve, err := m.validateValve(ctx, user.Valve, dbUser.Valve)
 	if err != nil {
 		return false, err
 	}
-	rs, err := m.validateRS(user.Runescape, dbUser.Runescape)
+	rs, err := m.validateRS(ctx, user.Runescape, dbUser.Runescape)
 	if err != nil {
 		return false, err
 	}
 
+	// the name is only changed once every account is valid, as the change starts the cooldown
+	if rename {
+		err = m.db.SetUsername(ctx, user)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	changes := lol || ow || valve || rs
 	return changes, nil
 }
 
+// validateChangedAccounts validates the accounts which are different from the ones stored in the database.
+// Returns true if any of the accounts have been changed or removed.
+func (m *Manager) validateChangedAccounts(ctx context.Context, user, dbUser *models.User) (bool, error) {
+	var changes bool
+
+	if !reflect.DeepEqual(user.Lol, dbUser.Lol) {
+		changes = true
+		if _, err := m.validateLol(ctx, user.Lol, nil); err != nil {
+			return false, err
+		}
+	}
+
+	if !reflect.DeepEqual(user.Overwatch, dbUser.Overwatch) {
+		changes = true
+		if _, err := m.validateOW(ctx, user.Overwatch, nil); err != nil {
+			return false, err
+		}
+	}
+
+	if !reflect.DeepEqual(user.Valve, dbUser.Valve) {
+		changes = true
+		if _, err := m.validateValve(ctx, user.Valve, nil); err != nil {
+			return false, err
+		}
+	}
+
+	if !reflect.DeepEqual(user.Runescape, dbUser.Runescape) {
+		changes = true
+		if _, err := m.validateRS(ctx, user.Runescape, nil); err != nil {
+			return false, err
+		}
+	}
+
+	// the Battle.net account can only be kept or removed, which does not need to be validated
+	if !reflect.DeepEqual(user.BattleNet, dbUser.BattleNet) {
+		changes = true
+	}
+
+	return changes, nil
+}
+
 // checking that league of legends is set and that it's different from what is already stored
 // if there are no changes, it doesn't need to be validated
-func (m *Manager) validateLol(reg, dbReg *models.SummonerRegistration) (bool, error) {
+func (m *Manager) validateLol(ctx context.Context, reg, dbReg *models.SummonerRegistration) (bool, error) {
 	if reg == nil || reg == dbReg {
 		return false, nil
 	}
 
-	err := m.ValidateSummoner(reg)
+	err := m.ValidateSummoner(ctx, reg)
 	if err != nil {
 		return false, err
 	}
@@ -224,12 +959,12 @@ func (m *Manager) validateLol(reg, dbReg *models.SummonerRegistration) (bool, er
 }
 
 // if there are no changes, it doesn't need to be validated
-func (m *Manager) validateOW(ow, dbOW *models.Overwatch) (bool, error) {
+func (m *Manager) validateOW(ctx context.Context, ow, dbOW *models.Overwatch) (bool, error) {
 	if ow == nil || ow == dbOW {
 		return false, nil
 	}
 
-	err := m.ValidateBattleUser(ow)
+	err := m.ValidateBattleUser(ctx, ow)
 	if err != nil {
 		return false, err
 	}
@@ -238,38 +973,39 @@ func (m *Manager) validateOW(ow, dbOW *models.Overwatch) (bool, error) {
 }
 
 // if there are no changes, it doesn't need to be validated
-func (m *Manager) validateValve(valve, dbValve *models.ValveAccount) (bool, error) {
+func (m *Manager) validateValve(ctx context.Context, valve, dbValve *models.ValveAccount) (bool, error) {
 	if valve == nil || valve == dbValve {
 		return false, nil
 	}
 
+	var acc *models.ValveAccount
 	var err error
 	switch {
 	case valve.ID != "":
-		err = m.ValidateValveID(valve.ID)
-		if err != nil {
-			return false, err
-		}
-		valve.Username = "" // the username is not validated, nor needed. It is therefor removed
+		// the username is not validated, nor needed. It is therefor removed
+		acc, err = m.ValidateValveID(ctx, valve.ID)
 	case valve.Username != "":
-		valve.ID, err = m.ValidateValveAccount(valve.Username)
-		if err != nil {
-			return false, err
-		}
+		acc, err = m.ValidateValveAccount(ctx, valve.Username)
 	default:
 		return false, models.NewReqErrStr("invalid steam account", "invalid steam account information")
 	}
 
+	if err != nil {
+		return false, err
+	}
+
+	*valve = *acc
+
 	return true, nil
 }
 
 // if there are no changes, it doesn't need to be validated
-func (m *Manager) validateRS(rs, dbRS *models.RunescapeAccount) (bool, error) {
+func (m *Manager) validateRS(ctx context.Context, rs, dbRS *models.RunescapeAccount) (bool, error) {
 	if rs == nil || rs == dbRS {
 		return false, nil
 	}
 
-	err := m.ValidateRSAccount(rs)
+	err := m.ValidateRSAccount(ctx, rs)
 	if err != nil {
 		return false, err
 	}
@@ -1,12 +1,16 @@
 package user
 
 import (
+	"context"
 	"ctp/pkg/models"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bxcodec/faker"
 	"github.com/stretchr/testify/assert"
@@ -14,71 +18,377 @@ import (
 )
 
 type mockDB struct {
-	err  error
-	user *models.User
+	err       error
+	user      *models.User
+	updated   *models.User                    // the user given to UpdateGames
+	imports   []models.ImportedLibrary        // the libraries imported, by SetImport
+	manual    []models.ManualGame             // the manual games, by SetManualGame
+	matches   []models.GameMatch              // the matches, by SetMatch
+	snapshots []models.PlaytimeSnapshot       // the history, by SetSnapshot
+	public    []int                           // the total playtime of the public users
+	purged    bool                            // whether DeleteUser has been called
+	providers map[string]models.ProviderGames // the games last fetched from Steam and Battle.net, by SetProviderGames
+	audit     []models.AuditRecord            // the audit log, by AddAuditRecord
+	renamed   string                          // the name given to SetUsername
+	deleteErr error                           // returned by DeleteUser, instead of err
+	lookalike map[string][]string             // the IDs of the users with a name of the skeleton, by the skeleton
+
+	mu   sync.Mutex            // guards the jobs, which are used by the workers of the manager
+	jobs map[string]models.Job // the jobs, by id
+	dead []models.Job          // the dead-letter list
+}
+
+func (m *mockDB) CreateUser(ctx context.Context, user *models.User) error { return m.err }
+func (m *mockDB) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	return m.user, m.err
+}
+func (m *mockDB) GetUserByName(ctx context.Context, name string) (*models.User, error) {
+	return m.user, m.err
+}
+func (m *mockDB) UpdateUser(ctx context.Context, user *models.User) error { return m.err }
+func (m *mockDB) ReplaceUser(ctx context.Context, user *models.User, version int64) error {
+	return m.err
+}
+func (m *mockDB) UpdateGames(ctx context.Context, user *models.User) error {
+	m.updated = user
+
+	// the total playtime is set by the database, as it is calculated when the games are stored
+	user.TotalGameTime = 0
+	for _, game := range user.Games {
+		user.TotalGameTime += game.Time
+	}
+
+	return m.err
+}
+func (m *mockDB) GetImports(ctx context.Context, id string) ([]models.ImportedLibrary, error) {
+	return m.imports, m.err
+}
+func (m *mockDB) SetImport(ctx context.Context, id string, library *models.ImportedLibrary) error {
+	for i := range m.imports {
+		if m.imports[i].Format == library.Format {
+			m.imports[i] = *library
+			return m.err
+		}
+	}
+	m.imports = append(m.imports, *library)
+	return m.err
+}
+func (m *mockDB) DeleteImport(ctx context.Context, id, format string) error {
+	for i := range m.imports {
+		if m.imports[i].Format == format {
+			m.imports = append(m.imports[:i], m.imports[i+1:]...)
+			return m.err
+		}
+	}
+	return models.ErrNotFound
+}
+func (m *mockDB) GetProviderGames(ctx context.Context, id, provider string) (*models.ProviderGames, error) {
+	games, ok := m.providers[provider]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &games, m.err
+}
+func (m *mockDB) SetProviderGames(ctx context.Context, id string, games *models.ProviderGames) error {
+	if m.providers == nil {
+		m.providers = make(map[string]models.ProviderGames)
+	}
+	m.providers[games.Provider] = *games
+	return m.err
+}
+func (m *mockDB) GetManualGames(ctx context.Context, id string) ([]models.ManualGame, error) {
+	return m.manual, m.err
+}
+func (m *mockDB) GetManualGame(ctx context.Context, id, gameID string) (*models.ManualGame, error) {
+	for i := range m.manual {
+		if m.manual[i].ID == gameID {
+			return &m.manual[i], m.err
+		}
+	}
+	return nil, models.ErrNotFound
+}
+func (m *mockDB) SetManualGame(ctx context.Context, id string, game *models.ManualGame) error {
+	for i := range m.manual {
+		if m.manual[i].ID == game.ID {
+			m.manual[i] = *game
+			return m.err
+		}
+	}
+	game.ID = fmt.Sprintf("game%d", len(m.manual)+1)
+	m.manual = append(m.manual, *game)
+	return m.err
+}
+func (m *mockDB) DeleteManualGame(ctx context.Context, id, gameID string) error {
+	for i := range m.manual {
+		if m.manual[i].ID == gameID {
+			m.manual = append(m.manual[:i], m.manual[i+1:]...)
+			return m.err
+		}
+	}
+	return models.ErrNotFound
+}
+func (m *mockDB) GetMatches(ctx context.Context, id string) ([]models.GameMatch, error) {
+	return m.matches, m.err
+}
+func (m *mockDB) SetMatch(ctx context.Context, id string, match *models.GameMatch) error {
+	for i := range m.matches {
+		if m.matches[i].Key == match.Key {
+			m.matches[i] = *match
+			return m.err
+		}
+	}
+	m.matches = append(m.matches, *match)
+	return m.err
+}
+func (m *mockDB) DeleteMatch(ctx context.Context, id, key string) error {
+	for i := range m.matches {
+		if m.matches[i].Key == key {
+			m.matches = append(m.matches[:i], m.matches[i+1:]...)
+			return m.err
+		}
+	}
+	return models.ErrNotFound
+}
+func (m *mockDB) GetSnapshots(ctx context.Context, id, since string) ([]models.PlaytimeSnapshot, error) {
+	var snapshots []models.PlaytimeSnapshot
+	for _, snapshot := range m.snapshots {
+		if snapshot.Date >= since {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	return snapshots, m.err
+}
+func (m *mockDB) SetSnapshot(ctx context.Context, id string, snapshot *models.PlaytimeSnapshot) error {
+	for i := range m.snapshots {
+		if m.snapshots[i].Date == snapshot.Date {
+			m.snapshots[i] = *snapshot
+			return m.err
+		}
+	}
+
+	m.snapshots = append(m.snapshots, *snapshot)
+
+	return m.err
+}
+func (m *mockDB) SetYearPlaytime(ctx context.Context, id string, playtime *models.YearPlaytime) error {
+	return m.err
+}
+func (m *mockDB) GetYearPlaytimes(ctx context.Context, year int) (map[string]int, error) {
+	return nil, m.err
+}
+func (m *mockDB) GetPublicPlaytimes(ctx context.Context) ([]int, error)  { return m.public, m.err }
+func (m *mockDB) GetPublicUserIDs(ctx context.Context) ([]string, error) { return nil, m.err }
+func (m *mockDB) SetUsername(ctx context.Context, user *models.User) error {
+	m.renamed = user.Name
+	return m.err
+}
+func (m *mockDB) GetLookalikes(ctx context.Context, skeleton string) ([]string, error) {
+	return m.lookalike[skeleton], m.err
+}
+func (m *mockDB) OverwriteUser(ctx context.Context, user *models.User) error { return m.err }
+func (m *mockDB) SetDeleted(ctx context.Context, id string, deletedAt, purgeAt int64) error {
+	if m.err == nil {
+		m.user.DeletedAt, m.user.PurgeAt = deletedAt, purgeAt
+	}
+	return m.err
+}
+func (m *mockDB) DeleteUser(ctx context.Context, id string) (map[string]int, error) {
+	if m.deleteErr != nil {
+		return nil, m.deleteErr
+	}
+
+	m.purged = true
+	return map[string]int{"users": 1, "history": 2}, m.err
+}
+func (m *mockDB) DeleteFieldsFromUser(ctx context.Context, id string, fields []string) error {
+	return m.err
 }
+func (m *mockDB) CreateJob(ctx context.Context, job *models.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-func (m *mockDB) CreateUser(user *models.User) error                    { return m.err }
-func (m *mockDB) GetUserByID(id string) (*models.User, error)           { return m.user, m.err }
-func (m *mockDB) GetUserByName(name string) (*models.User, error)       { return m.user, m.err }
-func (m *mockDB) UpdateUser(user *models.User) error                    { return m.err }
-func (m *mockDB) UpdateGames(user *models.User) error                   { return m.err }
-func (m *mockDB) SetUsername(user *models.User) error                   { return m.err }
-func (m *mockDB) OverwriteUser(user *models.User) error                 { return m.err }
-func (m *mockDB) DeleteUser(id string) error                            { return m.err }
-func (m *mockDB) DeleteFieldsFromUser(id string, fields []string) error { return m.err }
+	if m.jobs == nil {
+		m.jobs = make(map[string]models.Job)
+	}
+	m.jobs[job.ID] = copyJob(job)
+
+	return nil
+}
+func (m *mockDB) GetJob(ctx context.Context, id string) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	job = copyJob(&job)
+
+	return &job, nil
+}
+func (m *mockDB) UpdateJob(ctx context.Context, job *models.Job) error { return m.CreateJob(ctx, job) }
+func (m *mockDB) ClaimJob(ctx context.Context, now, until int64) (*models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, job := range m.jobs {
+		if job.RunAt != 0 && job.RunAt <= now {
+			job.Status, job.Attempts, job.RunAt = models.JobRunning, job.Attempts+1, until
+			m.jobs[id] = job
+			job = copyJob(&job)
+
+			return &job, nil
+		}
+	}
+
+	return nil, models.ErrNotFound
+}
+func (m *mockDB) AddDeadLetter(ctx context.Context, job *models.Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.dead = append(m.dead, copyJob(job))
+
+	return nil
+}
+func (m *mockDB) GetDeadLetters(ctx context.Context) ([]models.Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.dead, nil
+}
+
+func (m *mockDB) AddAuditRecord(ctx context.Context, record *models.AuditRecord) error {
+	m.audit = append(m.audit, *record)
+	return m.err
+}
+func (m *mockDB) GetAuditRecords(ctx context.Context) ([]models.AuditRecord, error) {
+	return m.audit, m.err
+}
+
+// copyJob returns a copy of the job, such that the stored jobs are not changed by the workers
+func copyJob(job *models.Job) models.Job {
+	c := *job
+	c.Providers = append([]models.ProviderProgress{}, job.Providers...)
+
+	return c
+}
+
+// newManager returns a manager with a worker running its jobs until the test is done
+func newManager(t *testing.T, db *mockDB, org *mockOrganizer) *Manager {
+	um := New(db, org, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	um.StartJobs(ctx, 1)
+
+	return um
+}
+
+// runQueued runs the refresh job queued for the user, as a worker of a manager started by newManager would
+func runQueued(t *testing.T, um *Manager, db *mockDB) {
+	require.Len(t, db.jobs, 1)
+	for _, job := range db.jobs {
+		job := job
+		assert.Equal(t, models.JobRefresh, job.Type)
+		assert.Equal(t, models.JobQueued, job.Status)
+		require.Nil(t, um.refresh(context.Background(), &job, func() {}))
+	}
+}
 
 type mockOrganizer struct {
-	valve   []models.Game
-	valveID string
-	lol     *models.Game
-	rs      *models.Game
-	ow      *models.Game
-	rsAcc   *models.RunescapeAccount
-	id      string
-	token   string
-	err     error
-}
-
-func (m *mockOrganizer) ValidateValveAccount(username string) (string, error) { return m.valveID, m.err }
-func (m *mockOrganizer) ValidateValveID(id string) error                      { return m.err }
-func (m *mockOrganizer) GetValvePlaytime(id string) ([]models.Game, error)    { return m.valve, m.err }
-func (m *mockOrganizer) GetLolPlaytime(reg *models.SummonerRegistration) (*models.Game, error) {
+	valve        []models.Game
+	valveID      string
+	valveErr     error // error returned by GetValvePlaytime, instead of err
+	lol          *models.Game
+	rs           *models.Game
+	ow           *models.Game
+	battleNet    []models.Game
+	battleNetErr error // error returned by GetBattleNetPlaytime, instead of err
+	rsAcc        *models.RunescapeAccount
+	id           string
+	token        string
+	err          error
+}
+
+func (m *mockOrganizer) ValidateValveAccount(ctx context.Context, username string) (*models.ValveAccount, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &models.ValveAccount{ID: m.valveID, Username: username, Status: models.ValvePublic}, nil
+}
+func (m *mockOrganizer) ValidateValveID(ctx context.Context, id string) (*models.ValveAccount, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &models.ValveAccount{ID: id, Status: models.ValvePublic}, nil
+}
+func (m *mockOrganizer) GetValvePlaytime(ctx context.Context, id string) ([]models.Game, error) {
+	if m.valveErr != nil {
+		return nil, m.valveErr
+	}
+	return m.valve, m.err
+}
+func (m *mockOrganizer) GetLolPlaytime(ctx context.Context, reg *models.SummonerRegistration) (*models.Game, error) {
 	return m.lol, m.err
 }
-func (m *mockOrganizer) ValidateSummoner(reg *models.SummonerRegistration) error { return m.err }
-func (m *mockOrganizer) UpdateKey(key string) error                              { return m.err }
-func (m *mockOrganizer) GetRSPlaytime(rsAcc *models.RunescapeAccount) (*models.Game, error) {
+func (m *mockOrganizer) ValidateSummoner(ctx context.Context, reg *models.SummonerRegistration) error {
+	return m.err
+}
+func (m *mockOrganizer) UpdateKey(ctx context.Context, key string) error { return m.err }
+func (m *mockOrganizer) GetRSPlaytime(ctx context.Context, rsAcc *models.RunescapeAccount) (*models.Game, error) {
 	return m.rs, m.err
 }
-func (m *mockOrganizer) ValidateRSAccount(rsAcc *models.RunescapeAccount) error { return m.err }
-func (m *mockOrganizer) GetBlizzardPlaytime(*models.Overwatch) (*models.Game, error) {
+func (m *mockOrganizer) ValidateRSAccount(ctx context.Context, rsAcc *models.RunescapeAccount) error {
+	return m.err
+}
+func (m *mockOrganizer) GetBlizzardPlaytime(ctx context.Context, ow *models.Overwatch) (*models.Game, error) {
 	return m.ow, m.err
 }
-func (m *mockOrganizer) ValidateBattleUser(overwatch *models.Overwatch) error { return m.err }
-func (m *mockOrganizer) GetNewToken(id string) (string, error)                { return m.token, m.err }
-func (m *mockOrganizer) AuthRedirect(w http.ResponseWriter, r *http.Request)  {}
+func (m *mockOrganizer) ValidateBattleUser(ctx context.Context, ow *models.Overwatch) error {
+	return m.err
+}
+func (m *mockOrganizer) BattleNetAuthURL(userID, region string) (string, string, error) {
+	return "https://oauth.battle.net/authorize", "nonce", m.err
+}
+func (m *mockOrganizer) HandleBattleNetCallback(ctx context.Context, code, state, nonce string) (string,
+	*models.BattleNetAccount, error) {
+	if m.err != nil {
+		return "", nil, m.err
+	}
+	return m.id, &models.BattleNetAccount{ID: 1, BattleTag: "Test#1234", Region: "eu", Token: "token"}, nil
+}
+func (m *mockOrganizer) GetBattleNetPlaytime(ctx context.Context, account *models.BattleNetAccount) ([]models.Game, error) {
+	if m.battleNetErr != nil {
+		return nil, m.battleNetErr
+	}
+	return m.battleNet, m.err
+}
+func (m *mockOrganizer) GetNewToken(id string) (string, error)               { return m.token, m.err }
+func (m *mockOrganizer) AuthRedirect(w http.ResponseWriter, r *http.Request) {}
 func (m *mockOrganizer) HandleOAuth2Callback(w http.ResponseWriter, r *http.Request) (string, error) {
 	return m.id, m.err
 }
 
 func TestSetUser(t *testing.T) {
 	var cases = []struct {
-		name        string
-		orgErr      error
-		dbErr       error
-		dbUserEqual bool
-		expectedErr error
+		name            string
+		orgErr          error
+		dbErr           error
+		dbUserEqual     bool
+		expectedErr     error
+		expectedRenamed string // the name stored by SetUsername
 	}{
-		{"Test ok", nil, nil, false, nil},
-		{"Test orgErr", errors.New("test"), nil, false, errors.New("test")},
-		{"Test dbErr", nil, errors.New("test"), false, errors.New("test")},
-		{"Test dbuser equal", nil, nil, true, nil},
+		{"Test ok", nil, nil, false, nil, "testuser123"},
+		{"Test orgErr", errors.New("test"), nil, false, errors.New("test"), ""},
+		{"Test dbErr", nil, errors.New("test"), false, errors.New("test"), ""},
+		{"Test dbuser equal", nil, nil, true, nil, ""},
 	}
 
 	db := &mockDB{}
 	org := &mockOrganizer{}
-	um := New(db, org)
+	um := newManager(t, db, org)
 
 	// tc - test cases
 	for _, tc := range cases {
@@ -87,22 +397,707 @@ func TestSetUser(t *testing.T) {
 			err := faker.FakeData(&user)
 			assert.NoError(t, err)
 			user.Name = "testuser123"
+			user.DeletedAt, user.PurgeAt = 0, 0
+			user.Hidden = []string{models.HideSelfReported}
 
 			if tc.dbUserEqual {
 				db.user = user
 			} else {
 				err = faker.FakeData(&db.user)
 				assert.NoError(t, err)
+				db.user.NameChangedAt = 0
+				db.user.DeletedAt, db.user.PurgeAt = 0, 0
 			}
 			db.err = tc.dbErr
+			db.renamed = ""
 			fakeOrg(t, org, tc.orgErr)
 
-			err = um.SetUser(user)
+			// the name is not changed unless every account is valid
+			err = um.SetUser(context.Background(), user)
 			assert.Equal(t, tc.expectedErr, err)
+			assert.Equal(t, tc.expectedRenamed, db.renamed)
 		})
 	}
 }
 
+func TestReplaceUser(t *testing.T) {
+	var cases = []struct {
+		name        string
+		orgErr      error
+		dbErr       error
+		username    string
+		version     int64
+		changeLol   bool
+		expectedErr error
+	}{
+		{"Test ok", nil, nil, "testuser123", 1, false, nil},
+		{"Test ok changed account", nil, nil, "testuser123", 1, true, nil},
+		{"Test remove name", nil, nil, "", 1, false, nil},
+		{"Test modified since", nil, nil, "testuser123", 0, false, models.ErrPreconditionFailed},
+		{"Test invalid name", nil, nil, "not a valid name!", 1, false, models.NewReqErrStr("invalid username",
+			"invalid username: the name must be 3 to 30 letters or digits, where words may be separated by a hyphen or "+
+				"an underscore")},
+		{"Test reserved name", nil, nil, "admin", 1, false,
+			models.NewReqErrStr("reserved username", "invalid username: the name is reserved")},
+		{"Test orgErr changed account", errors.New("test"), nil, "testuser123", 1, true, errors.New("test")},
+		{"Test orgErr unchanged account", errors.New("test"), nil, "testuser123", 1, false, nil},
+		{"Test dbErr", nil, errors.New("test"), "testuser123", 1, false, errors.New("test")},
+	}
+
+	db := &mockDB{}
+	org := &mockOrganizer{}
+	um := newManager(t, db, org)
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := faker.FakeData(&db.user)
+			require.NoError(t, err)
+			db.user.Name = "testuser123"
+			db.user.Version = 1
+			db.user.NameChangedAt = 0
+			db.user.DeletedAt, db.user.PurgeAt = 0, 0
+			db.user.Hidden = []string{models.HideSelfReported}
+			db.err = tc.dbErr
+			fakeOrg(t, org, tc.orgErr)
+
+			// the user is a copy of the stored user, with the changes of the test case
+			user := *db.user
+			user.Name = tc.username
+			if tc.changeLol {
+				user.Lol = &models.SummonerRegistration{SummonerName: "test", SummonerRegion: "EUW1"}
+			}
+
+			resp, err := um.ReplaceUser(context.Background(), &user, tc.version)
+			if assert.Equal(t, tc.expectedErr, err) && err == nil {
+				assert.Equal(t, db.user, resp)
+			}
+		})
+	}
+}
+
+func TestValidateHidden(t *testing.T) {
+	var cases = []struct {
+		name        string
+		hidden      []string
+		expectedErr bool
+	}{
+		{"Test none", nil, false},
+		{"Test all", models.HideableFields, false},
+		{"Test unknown field", []string{models.HideGames, "valve"}, true},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateHidden(tc.hidden)
+			var reqErr *models.RequestError
+			assert.Equal(t, tc.expectedErr, errors.As(err, &reqErr), err)
+		})
+	}
+}
+
+func TestCheckRename(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	recently := now.Add(-24 * time.Hour).Unix()
+	cooldown := models.NewReqErrStr("rename cooldown",
+		"the name was changed recently, and can not be changed again before 2024-03-30")
+	invalid := models.NewReqErrStr("invalid username", "invalid username: the name must be 3 to 30 letters or digits, "+
+		"where words may be separated by a hyphen or an underscore")
+
+	var cases = []struct {
+		name        string
+		newName     string
+		dbUser      *models.User
+		expectedErr error
+	}{
+		{"Test first name", "onijuan", &models.User{}, nil},
+		{"Test rename", "onijuan", &models.User{Name: "test", NameChangedAt: now.Add(-models.RenameCooldown).Unix()},
+			nil},
+		{"Test legacy rename", "onijuan", &models.User{Name: "test"}, nil},
+		{"Test unchanged", "test", &models.User{Name: "test", NameChangedAt: recently}, nil},
+		{"Test removed", "", &models.User{Name: "test", NameChangedAt: recently}, nil},
+		{"Test cooldown", "onijuan", &models.User{Name: "test", NameChangedAt: recently}, cooldown},
+		{"Test cooldown after removing", "onijuan", &models.User{NameChangedAt: recently}, cooldown},
+		{"Test separated", "oni-juan_2", &models.User{}, nil},
+		{"Test invalid", "onijuan!", &models.User{}, invalid},
+		{"Test space", "oni juan", &models.User{}, invalid},
+		{"Test repeated separator", "oni--juan", &models.User{}, invalid},
+		{"Test trailing separator", "onijuan_", &models.User{}, invalid},
+		{"Test too short", "oj", &models.User{}, invalid},
+		{"Test too long", "onijuan-onijuan-onijuan-onijuan", &models.User{}, invalid},
+		{"Test reserved", "stats", &models.User{},
+			models.NewReqErrStr("reserved username", "invalid username: the name is reserved")},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedErr, checkRename(tc.newName, tc.dbUser, now))
+		})
+	}
+}
+
+func TestUpdateGames(t *testing.T) {
+	var cases = []struct {
+		name           string
+		valveErr       error
+		expectedStatus string
+		expectedGames  []string
+		expectedErr    error
+	}{
+		{"Test ok", nil, models.ValvePublic, []string{"New Steam Game", "LeagueOfLegends"}, nil},
+		{"Test games private", &models.PrivateProfileError{Status: models.ValveGamesPrivate}, models.ValveGamesPrivate,
+			[]string{"Old Steam Game", "LeagueOfLegends"}, nil},
+		{"Test profile private", &models.PrivateProfileError{Status: models.ValvePrivate}, models.ValvePrivate,
+			[]string{"Old Steam Game", "LeagueOfLegends"}, nil},
+		{"Test valve error", errors.New("test"), "", nil, errors.New("test")},
+	}
+
+	db := &mockDB{}
+	org := &mockOrganizer{}
+	um := newManager(t, db, org)
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.user = &models.User{
+				ID:    "12345",
+				Lol:   &models.SummonerRegistration{SummonerName: "test", SummonerRegion: "EUW1"},
+				Valve: &models.ValveAccount{ID: "76561197960287930"},
+				Games: []models.Game{{Name: "Old Steam Game", AppID: 1, Time: 2}, {Name: "LeagueOfLegends", Time: 1}},
+			}
+			db.updated, db.providers = nil, nil
+			org.err = nil
+			org.valveErr = tc.valveErr
+			org.lol = &models.Game{Name: "LeagueOfLegends", Time: 3}
+			org.valve = []models.Game{{Name: "New Steam Game", AppID: 2, Time: 4}}
+
+			err := um.UpdateGames(context.Background(), db.user.ID)
+			if !assert.Equal(t, tc.expectedErr, err) || err != nil {
+				return
+			}
+
+			var names []string
+			for _, game := range db.updated.Games {
+				names = append(names, game.Name)
+			}
+			assert.Equal(t, tc.expectedGames, names)
+			assert.Equal(t, tc.expectedStatus, db.updated.Valve.Status)
+			assert.Equal(t, tc.expectedStatus != models.ValvePublic, db.updated.Valve.Hint != "")
+
+			// the games are stored as fetched, to be kept while the profile is private
+			if tc.valveErr == nil {
+				assert.Equal(t, org.valve, db.providers["valve"].Games)
+			}
+		})
+	}
+}
+
+func TestUpdateGamesBattleNet(t *testing.T) {
+	var cases = []struct {
+		name           string
+		battleNetErr   error
+		expectedStatus string
+		expectedGames  []string
+		expectedErr    error
+	}{
+		{"Test ok", nil, models.BattleNetLinked, []string{models.StarCraftII}, nil},
+		{"Test token expired", fmt.Errorf("test: %w", models.ErrTokenExpired), models.BattleNetExpired,
+			[]string{models.WorldOfWarcraft}, nil},
+		{"Test battle.net error", errors.New("test"), "", nil, errors.New("test")},
+	}
+
+	db := &mockDB{}
+	org := &mockOrganizer{}
+	um := newManager(t, db, org)
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.user = &models.User{
+				ID:        "12345",
+				BattleNet: &models.BattleNetAccount{ID: 1, BattleTag: "Test#1234", Region: "eu"},
+				Games:     []models.Game{{Name: models.WorldOfWarcraft}, {Name: "Old Steam Game", AppID: 1, Time: 2}},
+			}
+			db.updated, db.providers = nil, nil
+			org.err = nil
+			org.battleNetErr = tc.battleNetErr
+			org.battleNet = []models.Game{{Name: models.StarCraftII, Time: 4}}
+
+			err := um.UpdateGames(context.Background(), db.user.ID)
+			if !assert.Equal(t, tc.expectedErr, err) || err != nil {
+				return
+			}
+
+			var names []string
+			for _, game := range db.updated.Games {
+				names = append(names, game.Name)
+			}
+			assert.Equal(t, tc.expectedGames, names)
+			assert.Equal(t, tc.expectedStatus, db.updated.BattleNet.Status)
+			assert.Equal(t, tc.expectedStatus == models.BattleNetExpired, db.updated.BattleNet.Hint != "")
+		})
+	}
+}
+
+func TestKeptGames(t *testing.T) {
+	merged := models.Game{Name: "Portal", AppID: 400, Time: 10, Minutes: 600, GameID: "portal",
+		Metadata: &models.GameMetadata{Developer: "Valve"}, Merged: []models.MergedGame{
+			{Name: "Portal", Source: "steam", Minutes: 120}, {Name: "Portal", Source: "gog", Minutes: 600}}}
+	stored := models.ProviderGames{Provider: "valve", Account: "76561197960287930",
+		Games: []models.Game{{Name: "Portal", AppID: 400, Time: 2, Minutes: 125}}}
+
+	var cases = []struct {
+		name     string
+		stored   *models.ProviderGames
+		expected []models.Game
+	}{
+		{"Test stored", &stored, stored.Games},
+		{"Test other account", &models.ProviderGames{Provider: "valve", Account: "1", Games: stored.Games}, nil},
+		{"Test not stored", nil, []models.Game{{Name: "Portal", AppID: 400, Time: 2, Minutes: 120}}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDB{}
+			if tc.stored != nil {
+				db.providers = map[string]models.ProviderGames{"valve": *tc.stored}
+			}
+			um := newManager(t, db, &mockOrganizer{})
+
+			// the merged games are never kept as they are, as their playtime includes the other entries
+			user := &models.User{ID: "12345", Games: []models.Game{merged, {Name: "Witcher 3", Source: "gog", Time: 5}}}
+
+			games, err := um.keptGames(context.Background(), user, "valve", "76561197960287930", steamGames)
+			require.Nil(t, err)
+			assert.Equal(t, tc.expected, games)
+		})
+	}
+}
+
+func TestLinkBattleNet(t *testing.T) {
+	var cases = []struct {
+		name        string
+		orgErr      error
+		dbErr       error
+		expectedErr error
+	}{
+		{"Test ok", nil, nil, nil},
+		{"Test invalid state", models.ErrInvalidAuthState, nil, models.ErrInvalidAuthState},
+		{"Test deleted user", nil, models.ErrNotFound, models.ErrNotFound},
+	}
+
+	db := &mockDB{}
+	org := &mockOrganizer{}
+	um := newManager(t, db, org)
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.err = tc.dbErr
+			db.user = &models.User{ID: "12345", BattleNet: &models.BattleNetAccount{ID: 1, BattleTag: "Test#1234", Region: "eu"}}
+			db.updated = nil
+			org.id = "12345"
+			org.err = tc.orgErr
+
+			account, err := um.LinkBattleNet(context.Background(), "code", "state", "nonce")
+			if !assert.Equal(t, tc.expectedErr, err) || err != nil {
+				assert.Nil(t, db.updated)
+				return
+			}
+
+			assert.Equal(t, "Test#1234", account.BattleTag)
+			assert.NotNil(t, db.updated)
+		})
+	}
+}
+
+func TestImportLibrary(t *testing.T) {
+	var cases = []struct {
+		name            string
+		format          string
+		data            string
+		expectedFormats []string // the formats of the stored libraries
+		expectedGames   []string // the games after the import
+		expectedErr     bool
+	}{
+		{"Test ok", models.ImportCSV, "name,minutes\nNew Game,120", []string{models.ImportGOG, models.ImportCSV},
+			[]string{"LeagueOfLegends", "Old GOG Game", "New Game"}, false},
+		{"Test replaces the earlier import", models.ImportGOG, "title,gameMins\nNew GOG Game,60", []string{models.ImportGOG},
+			[]string{"LeagueOfLegends", "New GOG Game"}, false},
+		{"Test invalid file", models.ImportCSV, "minutes\n120", []string{models.ImportGOG}, nil, true},
+		{"Test invalid format", "steam", "name,minutes\nNew Game,120", []string{models.ImportGOG}, nil, true},
+	}
+
+	db := &mockDB{}
+	org := &mockOrganizer{lol: &models.Game{Name: "LeagueOfLegends", Time: 3}}
+	um := New(db, org, nil, nil) // the queued jobs are run by the test
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.user = &models.User{ID: "12345", Lol: &models.SummonerRegistration{SummonerName: "test", SummonerRegion: "EUW1"}}
+			db.updated, db.jobs = nil, nil
+			db.imports = []models.ImportedLibrary{
+				{Format: models.ImportGOG, Games: []models.Game{{Name: "Old GOG Game", Source: "gog"}}}}
+
+			library, err := um.ImportLibrary(context.Background(), db.user.ID, tc.format, []byte(tc.data))
+
+			var formats []string
+			for _, l := range db.imports {
+				formats = append(formats, l.Format)
+			}
+			assert.Equal(t, tc.expectedFormats, formats)
+
+			if tc.expectedErr {
+				assert.IsType(t, &models.RequestError{}, err)
+				assert.Empty(t, db.jobs)
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, tc.format, library.Format)
+			assert.NotZero(t, library.ImportedAt)
+
+			// the games are updated in the background, without the providers being fetched by the request
+			assert.Nil(t, db.updated)
+			runQueued(t, um, db)
+
+			var names []string
+			for _, game := range db.updated.Games {
+				names = append(names, game.Name)
+			}
+			assert.Equal(t, tc.expectedGames, names)
+		})
+	}
+}
+
+func TestDeleteImport(t *testing.T) {
+	var cases = []struct {
+		name          string
+		format        string
+		expectedGames []string
+		expectedErr   error
+	}{
+		{"Test ok", models.ImportGOG, []string{"Playnite Game"}, nil},
+		{"Test not imported", models.ImportCSV, nil, models.ErrNotFound},
+	}
+
+	db := &mockDB{}
+	um := New(db, &mockOrganizer{}, nil, nil) // the queued jobs are run by the test
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.user = &models.User{ID: "12345"}
+			db.updated, db.jobs = nil, nil
+			db.imports = []models.ImportedLibrary{
+				{Format: models.ImportGOG, Games: []models.Game{{Name: "GOG Game", Source: "gog"}}},
+				{Format: models.ImportPlaynite, Games: []models.Game{{Name: "Playnite Game", Source: "epic"}}},
+			}
+
+			err := um.DeleteImport(context.Background(), db.user.ID, tc.format)
+			if !assert.Equal(t, tc.expectedErr, err) || err != nil {
+				assert.Empty(t, db.jobs)
+				return
+			}
+
+			assert.Nil(t, db.updated)
+			runQueued(t, um, db)
+
+			var names []string
+			for _, game := range db.updated.Games {
+				names = append(names, game.Name)
+			}
+			assert.Equal(t, tc.expectedGames, names)
+		})
+	}
+}
+
+func TestAddManualGame(t *testing.T) {
+	now := time.Now().Unix()
+
+	var cases = []struct {
+		name        string
+		game        models.ManualGame
+		expected    *models.Game // the game added to the user's games
+		expectedErr bool
+	}{
+		{"Test ok", models.ManualGame{Name: " Zelda ", Platform: "switch", Hours: 10.5},
+			&models.Game{Name: "Zelda", Time: 10, Minutes: 630, Source: "manual", Platform: "switch", SelfReported: true},
+			false},
+		{"Test sessions", models.ManualGame{Name: "Halo", Platform: "xbox", Hours: 1,
+			Sessions: []models.ManualSession{{Date: now - 100, Minutes: 90}, {Date: now - 200, Minutes: 30}}},
+			&models.Game{Name: "Halo", Time: 3, Minutes: 180, LastPlayed: now - 100, Source: "manual", Platform: "xbox",
+				SelfReported: true}, false},
+		{"Test no name", models.ManualGame{Name: " ", Platform: "pc"}, nil, true},
+		{"Test name too long", models.ManualGame{Name: strings.Repeat("å", 101), Platform: "pc"}, nil, true},
+		{"Test invalid platform", models.ManualGame{Name: "Zelda", Platform: "wii"}, nil, true},
+		{"Test negative hours", models.ManualGame{Name: "Zelda", Platform: "switch", Hours: -1}, nil, true},
+		{"Test too many hours", models.ManualGame{Name: "Zelda", Platform: "switch", Hours: 100001}, nil, true},
+		{"Test session too long", models.ManualGame{Name: "Zelda", Platform: "switch",
+			Sessions: []models.ManualSession{{Date: now - 100, Minutes: 24*60 + 1}}}, nil, true},
+		{"Test session in the future", models.ManualGame{Name: "Zelda", Platform: "switch",
+			Sessions: []models.ManualSession{{Date: now + 3600, Minutes: 60}}}, nil, true},
+	}
+
+	db := &mockDB{}
+	um := New(db, &mockOrganizer{}, nil, nil) // the queued jobs are run by the test
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.user = &models.User{ID: "12345"}
+			db.updated, db.jobs = nil, nil
+			db.manual = nil
+
+			game, err := um.AddManualGame(context.Background(), db.user.ID, &tc.game)
+			if tc.expectedErr {
+				assert.IsType(t, &models.RequestError{}, err)
+				assert.Nil(t, db.manual)
+				assert.Empty(t, db.jobs)
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, "game1", game.ID)
+			assert.NotZero(t, game.UpdatedAt)
+			assert.Equal(t, []models.ManualGame{*game}, db.manual)
+
+			// the games are updated in the background, without the providers being fetched by the request
+			assert.Nil(t, db.updated)
+			runQueued(t, um, db)
+			assert.Equal(t, []models.Game{*tc.expected}, db.updated.Games)
+		})
+	}
+}
+
+func TestUpdateManualGame(t *testing.T) {
+	var cases = []struct {
+		name          string
+		game          models.ManualGame
+		expectedGames []models.Game
+		expectedErr   error
+	}{
+		{"Test ok", models.ManualGame{ID: "game1", Name: "Zelda", Platform: "switch", Hours: 2}, []models.Game{
+			{Name: "Zelda", Time: 2, Minutes: 120, Source: "manual", Platform: "switch", SelfReported: true},
+			{Name: "Halo", Time: 1, Minutes: 60, Source: "manual", Platform: "xbox", SelfReported: true}}, nil},
+		{"Test not found", models.ManualGame{ID: "game3", Name: "Zelda", Platform: "switch", Hours: 2}, nil,
+			models.ErrNotFound},
+	}
+
+	db := &mockDB{}
+	um := New(db, &mockOrganizer{}, nil, nil) // the queued jobs are run by the test
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.user = &models.User{ID: "12345"}
+			db.updated, db.jobs = nil, nil
+			db.manual = []models.ManualGame{
+				{ID: "game1", Name: "Zelda", Platform: "switch", Hours: 1},
+				{ID: "game2", Name: "Halo", Platform: "xbox", Hours: 1},
+			}
+
+			game, err := um.UpdateManualGame(context.Background(), db.user.ID, &tc.game)
+			if !assert.Equal(t, tc.expectedErr, err) || err != nil {
+				assert.Empty(t, db.jobs)
+				return
+			}
+
+			assert.Equal(t, tc.game.ID, game.ID)
+			assert.Nil(t, db.updated)
+			runQueued(t, um, db)
+			assert.Equal(t, tc.expectedGames, db.updated.Games)
+		})
+	}
+}
+
+func TestDeleteManualGame(t *testing.T) {
+	var cases = []struct {
+		name          string
+		gameID        string
+		expectedGames []string
+		expectedErr   error
+	}{
+		{"Test ok", "game1", []string{"LeagueOfLegends", "Halo"}, nil},
+		{"Test not found", "game3", nil, models.ErrNotFound},
+	}
+
+	db := &mockDB{}
+	org := &mockOrganizer{lol: &models.Game{Name: "LeagueOfLegends", Time: 3}}
+	um := New(db, org, nil, nil) // the queued jobs are run by the test
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.user = &models.User{ID: "12345", Lol: &models.SummonerRegistration{SummonerName: "test", SummonerRegion: "EUW1"}}
+			db.updated, db.jobs = nil, nil
+			db.manual = []models.ManualGame{
+				{ID: "game1", Name: "Zelda", Platform: "switch", Hours: 1},
+				{ID: "game2", Name: "Halo", Platform: "xbox", Hours: 1},
+			}
+
+			err := um.DeleteManualGame(context.Background(), db.user.ID, tc.gameID)
+			if !assert.Equal(t, tc.expectedErr, err) || err != nil {
+				assert.Empty(t, db.jobs)
+				return
+			}
+
+			assert.Nil(t, db.updated)
+			runQueued(t, um, db)
+
+			var names []string
+			for _, game := range db.updated.Games {
+				names = append(names, game.Name)
+			}
+			assert.Equal(t, tc.expectedGames, names)
+		})
+	}
+}
+
+func TestSetMatch(t *testing.T) {
+	var cases = []struct {
+		name          string
+		match         models.GameMatch
+		expectedGames []models.Game
+		expectedErr   bool
+	}{
+		{"Test merged with the catalog game", models.GameMatch{Name: " Witcher III ", GameID: "the-witcher-3"},
+			[]models.Game{{Name: "The Witcher 3: Wild Hunt", AppID: 292030, Time: 2, Minutes: 120, GameID: "the-witcher-3",
+				Merged: []models.MergedGame{
+					{Name: "The Witcher 3: Wild Hunt", Source: "steam", Minutes: 60},
+					{Name: "Witcher III", Source: "gog", Minutes: 120}}}}, false},
+		{"Test kept separate", models.GameMatch{Name: "Witcher III"}, []models.Game{
+			{Name: "The Witcher 3: Wild Hunt", AppID: 292030, Time: 1, Minutes: 60, GameID: "the-witcher-3"},
+			{Name: "Witcher III", Time: 2, Minutes: 120, Source: "gog"}}, false},
+		{"Test unknown game", models.GameMatch{Name: "Witcher III", GameID: "witcher-4"}, nil, true},
+		{"Test no name", models.GameMatch{Name: "!", GameID: "the-witcher-3"}, nil, true},
+	}
+
+	db := &mockDB{}
+	org := &mockOrganizer{valve: []models.Game{{Name: "The Witcher 3: Wild Hunt", AppID: 292030, Time: 1, Minutes: 60}}}
+	um := newManager(t, db, org)
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.user = &models.User{ID: "12345", Valve: &models.ValveAccount{ID: "1"}}
+			db.updated = nil
+			db.matches = nil
+			db.imports = []models.ImportedLibrary{{Format: models.ImportGOG, Games: []models.Game{
+				{Name: "Witcher III", Time: 2, Minutes: 120, Source: "gog"}}}}
+
+			match, err := um.SetMatch(context.Background(), db.user.ID, &tc.match)
+			if tc.expectedErr {
+				assert.IsType(t, &models.RequestError{}, err)
+				assert.Nil(t, db.matches)
+				assert.Nil(t, db.updated)
+				return
+			}
+
+			require.Nil(t, err)
+			assert.Equal(t, "witcheriii", match.Key)
+			assert.Equal(t, "Witcher III", match.Name)
+			assert.Equal(t, []models.GameMatch{*match}, db.matches)
+			assert.Equal(t, tc.expectedGames, db.updated.Games)
+		})
+	}
+}
+
+func TestDeleteMatch(t *testing.T) {
+	var cases = []struct {
+		name          string
+		key           string
+		expectedGames []string
+		expectedErr   error
+	}{
+		{"Test ok", "halo", []string{"Halo", "Halo"}, nil},
+		{"Test not found", "zelda", nil, models.ErrNotFound},
+	}
+
+	db := &mockDB{}
+	um := newManager(t, db, &mockOrganizer{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db.user = &models.User{ID: "12345"}
+			db.updated = nil
+			db.matches = []models.GameMatch{{Key: "halo", Name: "Halo"}}
+			db.manual = []models.ManualGame{
+				{ID: "game1", Name: "Halo", Platform: "xbox", Hours: 1},
+				{ID: "game2", Name: "Halo", Platform: "pc", Hours: 2},
+			}
+
+			err := um.DeleteMatch(context.Background(), db.user.ID, tc.key)
+			if !assert.Equal(t, tc.expectedErr, err) || err != nil {
+				assert.Nil(t, db.updated)
+				return
+			}
+
+			// the games are no longer kept separate, but merged
+			var names []string
+			for _, game := range db.updated.Games {
+				for _, merged := range game.Merged {
+					names = append(names, merged.Name)
+				}
+			}
+			assert.Equal(t, tc.expectedGames, names)
+		})
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	db := &mockDB{user: &models.User{ID: "12345"}, public: []int{0, 100}}
+	db.manual = []models.ManualGame{{ID: "game1", Name: "Halo", Platform: "xbox", Hours: 10}}
+	um := newManager(t, db, &mockOrganizer{})
+
+	// updating the games records the history the stats are derived from
+	err := um.UpdateGames(context.Background(), db.user.ID)
+	require.Nil(t, err)
+	require.Len(t, db.snapshots, 1)
+	assert.Equal(t, 600, db.snapshots[0].Minutes)
+
+	db.user = db.updated
+	db.user.TotalGameTime = 10
+
+	stats, err := um.GetStats(context.Background(), db.user.ID)
+	require.Nil(t, err)
+	assert.Equal(t, 600, stats.TotalMinutes)
+	assert.Equal(t, []models.ProviderPlaytime{{Provider: "manual", Minutes: 600, Games: 1}}, stats.Providers)
+	assert.Equal(t, 50.0, *stats.Percentile)
+
+	db.err = models.ErrNotFound
+
+	_, err = um.GetStats(context.Background(), db.user.ID)
+	assert.Equal(t, models.ErrNotFound, err)
+}
+
+func TestGetRecap(t *testing.T) {
+	db := &mockDB{user: &models.User{ID: "12345", Name: "Onijuan"}, snapshots: []models.PlaytimeSnapshot{
+		{Date: "2023-12-31", Minutes: 600, Games: []models.GamePlaytime{{Name: "Halo", Minutes: 600}}},
+		{Date: "2024-06-01", Minutes: 900, Games: []models.GamePlaytime{{Name: "Halo", Minutes: 900}}},
+	}}
+	um := newManager(t, db, &mockOrganizer{})
+
+	recap, err := um.GetRecap(context.Background(), db.user.ID, 2024)
+	require.Nil(t, err)
+	assert.Equal(t, "Onijuan", recap.Name)
+	assert.Equal(t, 300, recap.Minutes)
+	assert.Equal(t, []models.GamePlaytime{{Name: "Halo", Minutes: 300}}, recap.Games)
+
+	_, err = um.GetRecap(context.Background(), db.user.ID, 2020)
+	assert.True(t, errors.Is(err, models.ErrNotFound), err)
+
+	db.err = models.ErrNotFound
+
+	_, err = um.GetRecap(context.Background(), db.user.ID, 2024)
+	assert.Equal(t, models.ErrNotFound, err)
+}
+
 func TestAuthCallback(t *testing.T) {
 	var cases = []struct {
 		name        string
@@ -117,7 +1112,7 @@ func TestAuthCallback(t *testing.T) {
 
 	db := &mockDB{}
 	org := &mockOrganizer{}
-	um := New(db, org)
+	um := newManager(t, db, org)
 
 	// tc - test cases
 	for _, tc := range cases {
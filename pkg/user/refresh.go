@@ -0,0 +1,128 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"ctp/pkg/models"
+)
+
+// jobWait is how long a request waits for its job to finish, leaving time to respond within the write timeout of the server
+const jobWait = 40 * time.Second
+
+// maxFollow is the highest number of users whose total playtime can be followed in one subscription
+const maxFollow = 25
+
+// StartJobs starts the workers running the jobs of the users, until the context is cancelled
+func (m *Manager) StartJobs(ctx context.Context, workers int) {
+	m.jobs.Start(ctx, workers)
+}
+
+// RefreshGames queues a job updating the user's games, and returns the job without waiting for it.
+// Every change to the job, such as a provider being fetched, is sent to the user's subscribers as an event.
+func (m *Manager) RefreshGames(ctx context.Context, id string) (*models.Job, error) {
+	// a missing or deleted user fails the request, rather than the job
+	err := m.requireUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.jobs.Enqueue(ctx, &models.Job{Type: models.JobRefresh, UserID: id})
+}
+
+// refreshLater queues a job updating the user's games without waiting for it, after a change which is stored already.
+// The change does not fail if the job can not be queued, as the games are updated by the next refresh anyway.
+func (m *Manager) refreshLater(ctx context.Context, id string) {
+	_, err := m.jobs.Enqueue(ctx, &models.Job{Type: models.JobRefresh, UserID: id})
+	if err != nil {
+		models.Log(ctx).WithError(err).Warn("Could not queue updating the games")
+	}
+}
+
+// GetJob returns the user's job with the given id. Returns models.ErrNotFound if the user has no such job.
+func (m *Manager) GetJob(ctx context.Context, id, jobID string) (*models.Job, error) {
+	job, err := m.jobs.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the jobs of other users are not revealed to exist
+	if job.UserID != id {
+		return nil, models.ErrNotFound
+	}
+
+	return job, nil
+}
+
+// refresh runs a JobRefresh, updating the user's games. Every provider fetched is added to the job.
+func (m *Manager) refresh(ctx context.Context, job *models.Job, progress func()) error {
+	user, err := m.updateGames(ctx, job.UserID, func(provider models.ProviderProgress) {
+		job.Providers = append(job.Providers, provider)
+		progress()
+	})
+	if err != nil {
+		return err
+	}
+
+	job.Result = &models.JobResult{TotalPlayTime: user.TotalGameTime, Games: len(user.Games)}
+
+	return nil
+}
+
+// validate runs a JobValidate, validating and storing the changes given by the user, and updating the games if any
+// of the accounts changed
+func (m *Manager) validate(ctx context.Context, job *models.Job, progress func()) error {
+	// the changes are cleared as soon as they are stored, such that a retry only updates the games
+	if job.User != nil {
+		var changes bool
+		var err error
+
+		if job.Replace {
+			changes, err = m.replaceUser(ctx, job.User, job.Version)
+		} else {
+			changes, err = m.setUser(ctx, job.User)
+		}
+		if err != nil {
+			return err
+		}
+
+		job.User = nil
+		progress()
+
+		if !changes {
+			return nil
+		}
+	}
+
+	return m.refresh(ctx, job, progress)
+}
+
+// SubscribeEvents returns a channel receiving the events of the user, and the changes to the total playtime of
+// the followed public users, given by their usernames (models.ErrNotFound if any of them is not public).
+// The function returned unsubscribes, closing the channel.
+func (m *Manager) SubscribeEvents(ctx context.Context, id string, follow []string) (<-chan models.Event, func(), error) {
+	if len(follow) > maxFollow {
+		return nil, nil, models.NewReqErrStr("too many users followed", fmt.Sprintf("at most %d users can be followed", maxFollow))
+	}
+
+	// the events of a purged user are not streamed, as their token keeps working until it expires
+	_, err := m.db.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]string, 0, len(follow))
+	for _, name := range follow {
+		user, err := m.db.GetUserByName(ctx, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("followed user %q: %w", name, err) // not found unless public
+		}
+
+		ids = append(ids, user.ID)
+	}
+
+	events, unsubscribe := m.events.Subscribe(id, ids...)
+
+	return events, unsubscribe, nil
+}
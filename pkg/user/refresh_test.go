@@ -0,0 +1,196 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"ctp/pkg/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshGames(t *testing.T) {
+	var cases = []struct {
+		name           string
+		lolErr         error
+		expectedJobs   []models.Job
+		expectedTotals []models.TotalChange
+	}{
+		{"Test ok", nil, []models.Job{
+			{Type: models.JobRefresh, Status: models.JobQueued, Providers: []models.ProviderProgress{}},
+			{Type: models.JobRefresh, Status: models.JobRunning, Attempts: 1, Providers: []models.ProviderProgress{}},
+			{Type: models.JobRefresh, Status: models.JobRunning, Attempts: 1, Providers: []models.ProviderProgress{
+				{Provider: "lol", Status: models.ProviderDone, Games: 1},
+			}},
+			{Type: models.JobRefresh, Status: models.JobRunning, Attempts: 1, Providers: []models.ProviderProgress{
+				{Provider: "lol", Status: models.ProviderDone, Games: 1},
+				{Provider: "valve", Status: models.ProviderKept, Games: 1},
+			}},
+			{Type: models.JobRefresh, Status: models.JobDone, Attempts: 1, Providers: []models.ProviderProgress{
+				{Provider: "lol", Status: models.ProviderDone, Games: 1},
+				{Provider: "valve", Status: models.ProviderKept, Games: 1},
+			}, Result: &models.JobResult{TotalPlayTime: 5, Games: 2}},
+		}, []models.TotalChange{{Name: "onijuan", TotalPlayTime: 5, Previous: 3}}},
+		{"Test provider error", models.NewReqErrStr("invalid summoner", "summoner not found"), []models.Job{
+			{Type: models.JobRefresh, Status: models.JobQueued, Providers: []models.ProviderProgress{}},
+			{Type: models.JobRefresh, Status: models.JobRunning, Attempts: 1, Providers: []models.ProviderProgress{}},
+			{Type: models.JobRefresh, Status: models.JobRunning, Attempts: 1, Providers: []models.ProviderProgress{
+				{Provider: "lol", Status: models.ProviderFailed,
+					Error: models.NewProblem(http.StatusBadRequest, models.CodeBadRequest, "summoner not found")},
+			}},
+			{Type: models.JobRefresh, Status: models.JobFailed, Attempts: 1, Providers: []models.ProviderProgress{
+				{Provider: "lol", Status: models.ProviderFailed,
+					Error: models.NewProblem(http.StatusBadRequest, models.CodeBadRequest, "summoner not found")},
+			}, Error: models.NewProblem(http.StatusBadRequest, models.CodeBadRequest, "summoner not found")},
+		}, nil},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDB{user: &models.User{
+				ID:            "12345",
+				Name:          "onijuan",
+				Public:        true,
+				TotalGameTime: 3,
+				Lol:           &models.SummonerRegistration{SummonerName: "test", SummonerRegion: "EUW1"},
+				Valve:         &models.ValveAccount{ID: "76561197960287930"},
+				Games:         []models.Game{{Name: "Old Steam Game", AppID: 1, Time: 2}, {Name: "LeagueOfLegends", Time: 1}},
+			}}
+			org := &mockOrganizer{lol: &models.Game{Name: "LeagueOfLegends", Time: 3}, err: tc.lolErr,
+				valveErr: &models.PrivateProfileError{Status: models.ValvePrivate}}
+			um := newManager(t, db, org)
+
+			events, unsubscribe, err := um.SubscribeEvents(context.Background(), "12345", nil)
+			require.Nil(t, err)
+			defer unsubscribe()
+
+			job, err := um.RefreshGames(context.Background(), "12345")
+			require.Nil(t, err)
+			assert.Equal(t, models.JobQueued, job.Status)
+			assert.Len(t, job.ID, 24)
+
+			// the events are received until the job is done or failed
+			var jobs []models.Job
+			var totals []models.TotalChange
+			for len(jobs) == 0 || !jobs[len(jobs)-1].Finished() {
+				select {
+				case event := <-events:
+					switch data := event.Data.(type) {
+					case *models.Job:
+						assert.Equal(t, job.ID, data.ID)
+						assert.Equal(t, "12345", data.UserID)
+
+						// the fields which are not known in advance
+						data.ID, data.UserID, data.CreatedAt, data.UpdatedAt, data.RunAt = "", "", 0, 0, 0
+						jobs = append(jobs, *data)
+					case *models.TotalChange:
+						totals = append(totals, *data)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("the job was not done within a second")
+				}
+			}
+
+			assert.Equal(t, tc.expectedJobs, jobs)
+			assert.Equal(t, tc.expectedTotals, totals)
+		})
+	}
+}
+
+func TestRefreshGamesNoUser(t *testing.T) {
+	um := newManager(t, &mockDB{err: models.ErrNotFound}, &mockOrganizer{})
+
+	_, err := um.RefreshGames(context.Background(), "12345")
+	assert.Equal(t, models.ErrNotFound, err)
+}
+
+func TestGetJob(t *testing.T) {
+	var cases = []struct {
+		name        string
+		id          string
+		jobID       string
+		expectedErr error
+	}{
+		{"Test ok", "12345", "0123456789abcdef01234567", nil},
+		{"Test other user", "67890", "0123456789abcdef01234567", models.ErrNotFound},
+		{"Test no job", "12345", "76543210fedcba9876543210", models.ErrNotFound},
+	}
+
+	db := &mockDB{}
+	err := db.CreateJob(context.Background(), &models.Job{ID: "0123456789abcdef01234567", Type: models.JobRefresh,
+		Status: models.JobDone, UserID: "12345"})
+	require.Nil(t, err)
+
+	um := newManager(t, db, &mockOrganizer{})
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			job, err := um.GetJob(context.Background(), tc.id, tc.jobID)
+			if !assert.Equal(t, tc.expectedErr, err) || err != nil {
+				return
+			}
+
+			assert.Equal(t, tc.jobID, job.ID)
+		})
+	}
+}
+
+func TestSubscribeEvents(t *testing.T) {
+	var cases = []struct {
+		name        string
+		follow      []string
+		err         error
+		expectedErr error
+	}{
+		{"Test no follow", nil, nil, nil},
+		{"Test follow", []string{"onijuan"}, nil, nil},
+		{"Test no user", nil, models.ErrNotFound, models.ErrNotFound},
+		{"Test not public", []string{"onijuan"}, models.ErrNotFound, models.ErrNotFound},
+		{"Test too many", make([]string, maxFollow+1), nil, &models.RequestError{}},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDB{user: &models.User{ID: "67890", Name: "onijuan", Public: true}, err: tc.err}
+			um := newManager(t, db, &mockOrganizer{})
+
+			events, unsubscribe, err := um.SubscribeEvents(context.Background(), "12345", tc.follow)
+			if tc.expectedErr != nil {
+				var reqErr *models.RequestError
+				if errors.As(tc.expectedErr, &reqErr) {
+					assert.True(t, errors.As(err, &reqErr), err)
+				} else {
+					assert.True(t, errors.Is(err, tc.expectedErr), err)
+				}
+
+				return
+			}
+
+			require.Nil(t, err)
+
+			// only the total playtime of the followed users is received, and every event of the user themselves
+			um.events.Publish(models.Event{Type: models.EventJob, UserID: "67890"})
+			um.events.Publish(models.Event{Type: models.EventTotal, UserID: "67890"})
+			um.events.Publish(models.Event{Type: models.EventJob, UserID: "12345"})
+			unsubscribe()
+
+			var received []models.Event
+			for event := range events {
+				received = append(received, event)
+			}
+
+			expected := []models.Event{{Type: models.EventJob, UserID: "12345"}}
+			if len(tc.follow) > 0 {
+				expected = append([]models.Event{{Type: models.EventTotal, UserID: "67890"}}, expected...)
+			}
+			assert.Equal(t, expected, received)
+		})
+	}
+}
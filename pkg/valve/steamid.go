@@ -0,0 +1,125 @@
+package valve
+
+import (
+	"ctp/pkg/models"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A SteamID64 consists of the universe (8 bits), the account type (4 bits), the instance (20 bits) and the account ID (32 bits).
+// Only individual accounts in the public universe, using the desktop instance, are valid steam accounts for users.
+const (
+	universePublic  = 1
+	typeIndividual  = 1
+	instanceDesktop = 1
+
+	individualBase = universePublic<<56 | typeIndividual<<52 | instanceDesktop<<32 // 76561197960265728
+)
+
+var (
+	steamID2Regexp = regexp.MustCompile(`^STEAM_([0-5]):([01]):(\d+)$`)
+	steamID3Regexp = regexp.MustCompile(`^\[([A-Za-z]):([0-5]):(\d+)(:\d+)?\]$`)
+	vanityRegexp   = regexp.MustCompile(`^[A-Za-z0-9_-]{2,32}$`)
+)
+
+// ParseSteamID parses a steam account given as a community profile URL (/id/ or /profiles/), SteamID64,
+// SteamID2 (STEAM_0:X:Y), SteamID3 ([U:1:N]), 32-bit account ID or vanity name.
+// It returns either the SteamID64 of the account, or the vanity name which has to be resolved using the Steam API.
+func ParseSteamID(input string) (id, vanity string, err error) {
+	input = strings.TrimSpace(input)
+
+	if path, ok := communityPath(input); ok {
+		switch {
+		case strings.HasPrefix(path, "id/"):
+			input = strings.TrimPrefix(path, "id/")
+			if !vanityRegexp.MatchString(input) {
+				return "", "", models.NewReqErrStr("invalid steam account", "invalid steam profile url")
+			}
+			return "", input, nil
+		case strings.HasPrefix(path, "profiles/"):
+			input = strings.TrimPrefix(path, "profiles/")
+		default:
+			return "", "", models.NewReqErrStr("invalid steam account", "invalid steam profile url")
+		}
+	}
+
+	if m := steamID2Regexp.FindStringSubmatch(input); m != nil {
+		// the universe is 0 for public accounts in SteamID2 from older games
+		if m[1] != "0" && m[1] != "1" {
+			return "", "", models.NewReqErrStr("invalid steam id", "invalid steam id: wrong universe")
+		}
+
+		accountID, err := strconv.ParseUint(m[3], 10, 31)
+		if err != nil {
+			return "", "", models.NewReqErr(err, "invalid steam id")
+		}
+
+		return fromAccountID(accountID<<1 | uint64(m[2][0]-'0'))
+	}
+
+	if m := steamID3Regexp.FindStringSubmatch(input); m != nil {
+		if m[1] != "U" {
+			return "", "", models.NewReqErrStr("invalid steam id", "invalid steam id: not an individual account")
+		}
+
+		if m[2] != "1" || (m[4] != "" && m[4] != ":1") {
+			return "", "", models.NewReqErrStr("invalid steam id", "invalid steam id: wrong universe or instance")
+		}
+
+		accountID, err := strconv.ParseUint(m[3], 10, 32)
+		if err != nil {
+			return "", "", models.NewReqErr(err, "invalid steam id")
+		}
+
+		return fromAccountID(accountID)
+	}
+
+	if number, err := strconv.ParseUint(input, 10, 64); err == nil {
+		if number <= 1<<32-1 {
+			return fromAccountID(number)
+		}
+
+		return fromSteamID64(number)
+	}
+
+	if strings.HasPrefix(input, "STEAM_") || strings.HasPrefix(input, "[") || !vanityRegexp.MatchString(input) {
+		return "", "", models.NewReqErrStr("invalid steam account", "invalid steam account: "+input)
+	}
+
+	return "", input, nil
+}
+
+// communityPath returns the path of a steam community profile URL (e.g. "id/name" or "profiles/7656...") without slashes
+func communityPath(input string) (string, bool) {
+	raw := input
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || (u.Hostname() != "steamcommunity.com" && u.Hostname() != "www.steamcommunity.com") {
+		return "", false
+	}
+
+	return strings.Trim(u.Path, "/"), true
+}
+
+// fromAccountID returns the SteamID64 of the individual account with the 32-bit account ID
+func fromAccountID(accountID uint64) (string, string, error) {
+	if accountID == 0 || accountID > 1<<32-1 {
+		return "", "", models.NewReqErrStr("invalid steam id", "invalid steam id: invalid account id")
+	}
+
+	return strconv.FormatUint(individualBase+accountID, 10), "", nil
+}
+
+// fromSteamID64 checks the universe, account type and instance of the SteamID64
+func fromSteamID64(id uint64) (string, string, error) {
+	if id>>56 != universePublic || id>>52&0xF != typeIndividual || id>>32&0xFFFFF != instanceDesktop {
+		return "", "", models.NewReqErrStr("invalid steam id", "invalid steam id: not an individual account")
+	}
+
+	return fromAccountID(id & (1<<32 - 1))
+}
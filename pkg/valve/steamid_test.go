@@ -0,0 +1,54 @@
+package valve
+
+import (
+	"ctp/pkg/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSteamID(t *testing.T) {
+	var cases = []struct {
+		name           string
+		input          string
+		expectedID     string
+		expectedVanity string
+		expectedErr    bool
+	}{
+		{"Test SteamID64", "76561197960287930", "76561197960287930", "", false},
+		{"Test SteamID2", "STEAM_0:0:11101", "76561197960287930", "", false},
+		{"Test SteamID2 universe 1", "STEAM_1:0:11101", "76561197960287930", "", false},
+		{"Test SteamID3", "[U:1:22202]", "76561197960287930", "", false},
+		{"Test SteamID3 with instance", "[U:1:22202:1]", "76561197960287930", "", false},
+		{"Test account ID", "22202", "76561197960287930", "", false},
+		{"Test profiles URL", "https://steamcommunity.com/profiles/76561197960287930/", "76561197960287930", "", false},
+		{"Test profiles URL with SteamID3", "steamcommunity.com/profiles/[U:1:22202]", "76561197960287930", "", false},
+		{"Test id URL", "https://steamcommunity.com/id/gabelogannewell/", "", "gabelogannewell", false},
+		{"Test vanity", " gabelogannewell ", "", "gabelogannewell", false},
+		{"Test zero account ID", "0", "", "", true},
+		{"Test group SteamID64", "103582791429521408", "", "", true},
+		{"Test wrong universe SteamID64", "148618791998193690", "", "", true},
+		{"Test wrong universe SteamID2", "STEAM_2:0:11101", "", "", true},
+		{"Test group SteamID3", "[g:1:4]", "", "", true},
+		{"Test wrong universe SteamID3", "[U:2:22202]", "", "", true},
+		{"Test malformed SteamID2", "STEAM_0:2:11101", "", "", true},
+		{"Test unknown URL", "https://steamcommunity.com/groups/test", "", "", true},
+		{"Test invalid vanity", "not a vanity", "", "", true},
+		{"Test empty", "", "", "", true},
+	}
+
+	// tc - test cases
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			id, vanity, err := ParseSteamID(tc.input)
+			if tc.expectedErr {
+				assert.IsType(t, &models.RequestError{}, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedID, id)
+			assert.Equal(t, tc.expectedVanity, vanity)
+		})
+	}
+}
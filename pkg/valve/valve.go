@@ -1,22 +1,38 @@
 package valve
 
 import (
+	"context"
 	"ctp/pkg/models"
+	"ctp/pkg/tracing"
 	"encoding/json"
 	"fmt"
-	"strings"
-
-	"github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"sync"
 )
 
 const getOwnedGames = "http://api.steampowered.com/IPlayerService/GetOwnedGames/v0001/?key=%s&format=json&steamid=%s&include_appinfo=true"
 const privateSteamAccount = "http://api.steampowered.com/ISteamUser/GetPlayerSummaries/v0002/?key=%s&steamids=%s"
 const validateValveAccount = "http://api.steampowered.com/ISteamUser/ResolveVanityURL/v0001/?key=%s&vanityurl=%s"
+const getPlayerAchievements = "http://api.steampowered.com/ISteamUserStats/GetPlayerAchievements/v0001/?key=%s&steamid=%s&appid=%d"
+const getSchemaForGame = "http://api.steampowered.com/ISteamUserStats/GetSchemaForGame/v2/?key=%s&appid=%d"
+const iconURL = "https://media.steampowered.com/steamcommunity/public/images/apps/%d/%s.jpg"
+
+// the communityvisibilitystate of a profile, where every other value means that the profile is private
+const (
+	visibilityFriendsOnly = 2
+	visibilityPublic      = 3
+)
+
+// achievementWorkers is the number of games for which achievements are fetched concurrently
+const achievementWorkers = 4
 
 // Valve is a struct which contains everything necessary to handle a request related to valve
 type Valve struct {
 	models.Getter
-	apiKey string
+	apiKey       string
+	achievements bool     // whether or not to get the achievement progress for each game
+	totals       sync.Map // the number of achievements in each game (by app id), which rarely changes
 }
 
 // steamResp is used for decoding the response from steam
@@ -30,6 +46,15 @@ type steamPrivateCheck struct {
 	} `json:"response"`
 }
 
+// ownedGames is used for decoding the response from GetOwnedGames.
+// The game count is nil if the games are not visible, which differs from a public profile without any games.
+type ownedGames struct {
+	Response struct {
+		GameCount *int                `json:"game_count"`
+		Games     []models.ValveGames `json:"games"`
+	} `json:"response"`
+}
+
 // steamResp is used for decoding the response from steam
 type steamResp struct {
 	Response struct {
@@ -38,20 +63,50 @@ type steamResp struct {
 	} `json:"response"`
 }
 
-// New returns a new valve instance
-func New(getter models.Getter, apiKey string) *Valve {
-	v := &Valve{apiKey: apiKey}
+// New returns a new valve instance.
+// If achievements is true, the achievement progress is fetched for every game played, which requires two requests per game.
+func New(getter models.Getter, apiKey string, achievements bool) *Valve {
+	v := &Valve{apiKey: apiKey, achievements: achievements}
 	v.Getter = getter
 
 	return v
 }
 
-// ValidateValveAccount validates the steam account and returns the valve 64 bit ID
-func (v *Valve) ValidateValveAccount(username string) (string, error) {
+// ValidateValveAccount validates the steam account and returns it with the valve 64 bit ID and privacy state.
+// The account may be given as anything accepted by ParseSteamID, vanity names are resolved using the Steam API.
+// Private profiles are accepted, such that the playtime is updated as soon as the profile is made public.
+func (v *Valve) ValidateValveAccount(ctx context.Context, username string) (*models.ValveAccount, error) {
+	ctx, span := tracing.StartKind(ctx, "valve.ValidateValveAccount", tracing.KindClient)
+	defer span.End()
+
 	if username == "" {
-		return "", models.NewReqErrStr("invalid steam account", "invalid steam account")
+		return nil, models.NewReqErrStr("invalid steam account", "invalid steam account")
+	}
+
+	id, vanity, err := ParseSteamID(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if vanity != "" {
+		id, err = v.resolveVanity(ctx, vanity)
+		if err != nil {
+			return nil, err
+		}
 	}
-	resp, err := v.Get(fmt.Sprintf(validateValveAccount, v.apiKey, username))
+
+	acc, err := v.account(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	acc.Username = username
+
+	return acc, nil
+}
+
+// resolveVanity returns the 64 bit ID of the account with the vanity name
+func (v *Valve) resolveVanity(ctx context.Context, vanity string) (string, error) {
+	resp, err := v.Get(ctx, fmt.Sprintf(validateValveAccount, v.apiKey, url.QueryEscape(vanity)))
 	if err != nil {
 		return "", err
 	}
@@ -72,49 +127,71 @@ func (v *Valve) ValidateValveAccount(username string) (string, error) {
 		return "", models.NewReqErrStr("invalid steam account", "invalid steam account")
 	}
 
-	if !strings.HasPrefix(sResp.Response.ID64, "7656119") {
+	// the resolved ID should always be a valid SteamID64, but it is checked in case the API changes
+	id, _, err := ParseSteamID(sResp.Response.ID64)
+	if err != nil || id == "" {
 		return "", models.NewReqErrStr("invalid steam account", "invalid steam account")
 	}
 
-	err = v.checkPrivateProfile(sResp.Response.ID64)
+	return id, nil
+}
+
+// ValidateValveID validates the steam account id and returns the account with the 64-bit steam ID and privacy state.
+// The id may be a SteamID64, SteamID2, SteamID3, 32-bit account ID or profile URL (/profiles/), but not a vanity name.
+func (v *Valve) ValidateValveID(ctx context.Context, id string) (*models.ValveAccount, error) {
+	ctx, span := tracing.StartKind(ctx, "valve.ValidateValveID", tracing.KindClient)
+	defer span.End()
+
+	id, vanity, err := ParseSteamID(id)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return sResp.Response.ID64, nil
-}
-
-// ValidateValveID validates the 64-bit steam account id
-func (v *Valve) ValidateValveID(id string) error {
-	// all steam 64id starts with this number
-	if !strings.HasPrefix(id, "7656119") {
-		return models.NewReqErrStr("invalid steam id", "invalid steam id")
+	if vanity != "" {
+		return nil, models.NewReqErrStr("invalid steam id", "invalid steam id")
 	}
 
-	resp, err := v.Get(fmt.Sprintf(getOwnedGames, v.apiKey, id))
+	return v.account(ctx, id)
+}
+
+// account returns the account with the given 64-bit steam ID, with the privacy state of the profile
+func (v *Valve) account(ctx context.Context, id string) (*models.ValveAccount, error) {
+	resp, err := v.Get(ctx, fmt.Sprintf(getOwnedGames, v.apiKey, id))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	err = models.AccValStatusCode(resp.StatusCode, "Valve", "invalid steam username")
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var owned ownedGames
+	err = json.NewDecoder(resp.Body).Decode(&owned)
+	if err != nil {
+		return nil, err
 	}
 
-	err = v.checkPrivateProfile(id)
+	status, err := v.privacy(ctx, id, owned.Response.GameCount != nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	acc := &models.ValveAccount{ID: id}
+	acc.SetStatus(status)
+
+	return acc, nil
 }
 
 // GetValvePlaytime gets playtime on steam for specified game
-func (v *Valve) GetValvePlaytime(id string) ([]models.Game, error) {
-	logrus.Debug("GetSteamPlaytime")
+func (v *Valve) GetValvePlaytime(ctx context.Context, id string) ([]models.Game, error) {
+	ctx, span := tracing.StartKind(ctx, "valve.GetValvePlaytime", tracing.KindClient)
+	defer span.End()
+
+	models.Log(ctx).Debug("GetSteamPlaytime")
 
-	resp, err := v.Get(fmt.Sprintf(getOwnedGames, v.apiKey, id))
+	resp, err := v.Get(ctx, fmt.Sprintf(getOwnedGames, v.apiKey, id))
 
 	if err != nil {
 		return nil, err
@@ -127,51 +204,220 @@ func (v *Valve) GetValvePlaytime(id string) ([]models.Game, error) {
 	}
 
 	// decoding response into valvegames
-	var valvegames models.ValveResp
+	var valvegames ownedGames
 	err = json.NewDecoder(resp.Body).Decode(&valvegames)
 
 	if err != nil {
 		return nil, err
 	}
 
+	// the games are left out of the response if the profile or game details are not public
+	if valvegames.Response.GameCount == nil {
+		status, err := v.privacy(ctx, id, false)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, &models.PrivateProfileError{Status: status}
+	}
+
 	var games []models.Game
-	// iterates over all games an user has played, takes out playtime and appid and appends it to the player's game array
+	// iterates over all games an user has played, and appends them to the player's game array.
+	// Games which have never been played are skipped, while games played less than an hour are kept (with the minutes played).
 	for _, game := range valvegames.Response.Games {
-		var tmpGame models.Game
-		tmpGame.Name = game.Name
-		tmpGame.Time = game.PlaytimeForever / 60
+		if game.PlaytimeForever == 0 {
+			continue
+		}
 
-		if tmpGame.Time != 0 {
-			games = append(games, tmpGame)
+		tmpGame := models.Game{
+			Name:          game.Name,
+			Time:          game.PlaytimeForever / 60,
+			AppID:         game.AppID,
+			LastPlayed:    game.LastPlayed,
+			Minutes:       game.PlaytimeForever,
+			RecentMinutes: game.Playtime2Weeks,
+			Platforms: &models.PlatformPlaytime{
+				Windows: game.PlaytimeWindows,
+				Mac:     game.PlaytimeMac,
+				Linux:   game.PlaytimeLinux,
+				Deck:    game.PlaytimeDeck,
+			},
+		}
+
+		if game.IconURL != "" {
+			tmpGame.Icon = fmt.Sprintf(iconURL, game.AppID, game.IconURL)
+		}
+
+		games = append(games, tmpGame)
+	}
+
+	if v.achievements {
+		err = v.addAchievements(ctx, id, games)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	return games, nil
 }
 
-func (v *Valve) checkPrivateProfile(id string) error {
-	// Checking whether or not the passed in steam account is private
+// addAchievements sets the achievement progress for each of the games which has achievements.
+// Failing to get the achievements for a single game (e.g. if the user's game details are private) is only logged,
+// such that the playtime is still updated.
+func (v *Valve) addAchievements(ctx context.Context, id string, games []models.Game) error {
+	ctx, span := tracing.Start(ctx, "valve.addAchievements")
+	defer span.End()
+
+	jobs := make(chan *models.Game)
+	var wg sync.WaitGroup
+
+	for i := 0; i < achievementWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for game := range jobs {
+				achievements, err := v.getAchievements(ctx, id, game.AppID)
+				if err != nil {
+					models.Log(ctx).WithError(err).WithField("appId", game.AppID).Debug("Unable to get achievements")
+					continue
+				}
+
+				game.Achievements = achievements
+			}
+		}()
+	}
 
-	// uses the steam api key comined with the vertfied id to check the account state,
-	// if the acount state if anything but public, we are unable to display any infomaiton about this user
+	for i := range games {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- &games[i]
+	}
+	close(jobs)
+	wg.Wait()
 
-	resp, err := v.Get(fmt.Sprintf(privateSteamAccount, v.apiKey, id))
+	return ctx.Err()
+}
+
+// playerAchievements is used for decoding the response from GetPlayerAchievements
+type playerAchievements struct {
+	PlayerStats struct {
+		Achievements []struct {
+			Achieved int `json:"achieved"`
+		} `json:"achievements"`
+		Success bool `json:"success"`
+	} `json:"playerstats"`
+}
+
+// gameSchema is used for decoding the response from GetSchemaForGame
+type gameSchema struct {
+	Game struct {
+		AvailableGameStats struct {
+			Achievements []struct {
+				Name string `json:"name"`
+			} `json:"achievements"`
+		} `json:"availableGameStats"`
+	} `json:"game"`
+}
+
+// getAchievements returns the user's achievement progress in the game, or nil if the game has no achievements
+func (v *Valve) getAchievements(ctx context.Context, id string, appID int) (*models.Achievements, error) {
+	total, err := v.getAchievementTotal(ctx, appID)
+	if err != nil || total == 0 {
+		return nil, err
+	}
+
+	resp, err := v.Get(ctx, fmt.Sprintf(getPlayerAchievements, v.apiKey, id, appID))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	var sResp steamPrivateCheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, models.NewAPIErr(fmt.Errorf("non 200 statuscode getting achievements (%d)", resp.StatusCode), "Valve")
+	}
+
+	var stats playerAchievements
+	err = json.NewDecoder(resp.Body).Decode(&stats)
+	if err != nil {
+		return nil, err
+	}
+
+	if !stats.PlayerStats.Success {
+		return nil, models.NewAPIErr(fmt.Errorf("unable to get achievements for app %d", appID), "Valve")
+	}
+
+	achievements := &models.Achievements{Total: total}
+	for _, a := range stats.PlayerStats.Achievements {
+		achievements.Unlocked += a.Achieved
+	}
+
+	return achievements, nil
+}
+
+// getAchievementTotal returns the number of achievements in the game. The number is cached, as it rarely changes.
+func (v *Valve) getAchievementTotal(ctx context.Context, appID int) (int, error) {
+	if total, ok := v.totals.Load(appID); ok {
+		return total.(int), nil
+	}
+
+	resp, err := v.Get(ctx, fmt.Sprintf(getSchemaForGame, v.apiKey, appID))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, models.NewAPIErr(fmt.Errorf("non 200 statuscode getting schema (%d)", resp.StatusCode), "Valve")
+	}
+
+	var schema gameSchema
+	err = json.NewDecoder(resp.Body).Decode(&schema)
+	if err != nil {
+		return 0, err
+	}
+
+	total := len(schema.Game.AvailableGameStats.Achievements)
+	v.totals.Store(appID, total)
+
+	return total, nil
+}
+
+// privacy returns the privacy state of the profile. If the profile is public, but the owned games are not visible,
+// the game details are private.
+func (v *Valve) privacy(ctx context.Context, id string, gamesVisible bool) (string, error) {
+	resp, err := v.Get(ctx, fmt.Sprintf(privateSteamAccount, v.apiKey, id))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
 
-	// decodes the response into rRep, and struct of that json variant
+	err = models.CheckStatusCode(resp.StatusCode, "Valve", "invalid steam id")
+	if err != nil {
+		return "", err
+	}
+
+	var sResp steamPrivateCheck
 	err = json.NewDecoder(resp.Body).Decode(&sResp)
 	if err != nil {
-		return err
+		return "", err
 	}
-	// if the visibility code is anything but 3 the account is not public
-	if sResp.Response.Players[0].VisibilityCode != 3 {
-		return models.NewReqErrStr("private steam account", "private steam account")
+
+	// no players are returned if there is no account with the id
+	if len(sResp.Response.Players) == 0 {
+		return "", models.NewReqErr(fmt.Errorf("steam account %s: %w", id, models.ErrNotFound), "steam account not found")
+	}
+
+	switch sResp.Response.Players[0].VisibilityCode {
+	case visibilityPublic:
+		if !gamesVisible {
+			return models.ValveGamesPrivate, nil
+		}
+
+		return models.ValvePublic, nil
+	case visibilityFriendsOnly:
+		return models.ValveFriendsOnly, nil
 	}
-	// returns nil if no errors detected
-	return nil
+
+	return models.ValvePrivate, nil
 }
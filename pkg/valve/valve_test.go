@@ -2,12 +2,14 @@ package valve
 
 import (
 	"bytes"
+	"context"
 	"ctp/pkg/models"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/bxcodec/faker"
@@ -30,16 +32,17 @@ type respSetup struct {
 
 type testResp struct {
 	Response struct {
-		ID64    string `json:"steamid"`
-		Code    int    `json:"success"`
-		Players []struct {
+		ID64      string `json:"steamid"`
+		Code      int    `json:"success"`
+		GameCount *int   `json:"game_count,omitempty"`
+		Players   []struct {
 			ID64           string `json:"steamid"`
 			VisibilityCode int    `json:"communityvisibilitystate"`
 		} `json:"players"`
 	} `json:"response"`
 }
 
-func (m *mockGetter) Get(url string) (*http.Response, error) {
+func (m *mockGetter) Get(ctx context.Context, url string) (*http.Response, error) {
 	// if an error is set, return it
 	if m.setup.err != nil {
 		return nil, m.setup.err
@@ -74,11 +77,13 @@ func TestValve_ValidateValveAccount(t *testing.T) {
 		ID64          string
 		codeResp      int
 		vCode         int
+		gamesPrivate  bool
 		respError     error
 		expectedError error
+		expectedState string
 		statusCode    int
 	}{
-		{name: "Test OK", username: "Onijuan", ID64: "7656119arstarst", vCode: 3, codeResp: 1, expectedError: nil, statusCode: http.StatusOK},
+		{name: "Test OK", username: "Onijuan", ID64: "76561197960287930", vCode: 3, codeResp: 1, expectedError: nil, statusCode: http.StatusOK},
 		{name: "Test no username", username: "", ID64: "7656119", vCode: 3, codeResp: 1, expectedError: &models.RequestError{
 			Response: "invalid steam account", Err: errors.New("invalid steam account")}, statusCode: http.StatusOK},
 		{name: "Test failed Get", username: "Onijuan", ID64: "7656119", vCode: 3,
@@ -90,15 +95,24 @@ func TestValve_ValidateValveAccount(t *testing.T) {
 			Err: errors.New("invalid steam account"), Response: "invalid steam account"}, statusCode: http.StatusOK},
 		{name: "Test invalid prefix", username: "Onijuan", ID64: "7656f96119", vCode: 3, codeResp: 1, expectedError: &models.RequestError{
 			Response: "invalid steam account", Err: errors.New("invalid steam account")}, statusCode: http.StatusOK},
-		{name: "Test private account", username: "Onijuan", ID64: "7656119", vCode: 0, codeResp: 1, expectedError: &models.RequestError{
-			Err: errors.New("private steam account"), Response: "private steam account"}, statusCode: http.StatusOK},
+		{name: "Test private account", username: "Onijuan", ID64: "7656119", vCode: 1, codeResp: 1, expectedState: models.ValvePrivate,
+			statusCode: http.StatusOK},
+		{name: "Test friends only account", username: "Onijuan", ID64: "7656119", vCode: 2, codeResp: 1,
+			expectedState: models.ValveFriendsOnly, statusCode: http.StatusOK},
+		{name: "Test games private account", username: "Onijuan", ID64: "7656119", vCode: 3, codeResp: 1, gamesPrivate: true,
+			expectedState: models.ValveGamesPrivate, statusCode: http.StatusOK},
+		{name: "Test profile URL", username: "https://steamcommunity.com/profiles/76561197960287930/", vCode: 3,
+			expectedError: nil, statusCode: http.StatusOK},
+		{name: "Test SteamID2", username: "STEAM_0:0:11101", vCode: 3, expectedError: nil, statusCode: http.StatusOK},
+		{name: "Test invalid SteamID3", username: "[G:1:11101]", vCode: 3, expectedError: &models.RequestError{
+			Err: errors.New("invalid steam id"), Response: "invalid steam id: not an individual account"}, statusCode: http.StatusOK},
 		// {name:"Test ",username:"Onijuan",ID64:"7656119",codeResp:1,vCode:0,
 		// expectedError:errors.New(""),respError:errors.New(""),statusCode:http.StatusOK},
 	}
 
 	// creating a mockGetter item to use the custom "Get" func
 	getter := &mockGetter{}
-	valve := New(getter, "123")
+	valve := New(getter, "123", false)
 
 	// run a test for each of the test items (array above)
 	for _, tc := range test {
@@ -107,6 +121,9 @@ func TestValve_ValidateValveAccount(t *testing.T) {
 			setup := &respSetup{err: tc.respError, statusCode: tc.statusCode, testRes: &testResp{}}
 			setup.testRes.Response.ID64 = tc.ID64
 			setup.testRes.Response.Code = tc.codeResp
+			if !tc.gamesPrivate {
+				setup.testRes.Response.GameCount = new(int)
+			}
 			var tmpPlayer = struct {
 				ID64           string `json:"steamid"`
 				VisibilityCode int    `json:"communityvisibilitystate"`
@@ -115,10 +132,17 @@ func TestValve_ValidateValveAccount(t *testing.T) {
 			getter.setup = *setup
 
 			// runs the actual function
-			_, err := valve.ValidateValveAccount(tc.username)
+			acc, err := valve.ValidateValveAccount(context.Background(), tc.username)
 
 			// if the error we got does not correspond with the expected error, fail test
 			assert.Equal(t, tc.expectedError, err)
+			if tc.expectedError == nil {
+				if tc.expectedState == "" {
+					tc.expectedState = models.ValvePublic
+				}
+				assert.Equal(t, tc.expectedState, acc.Status)
+				assert.Equal(t, tc.username, acc.Username)
+			}
 		})
 	}
 }
@@ -132,18 +156,22 @@ func TestValve_ValidateValveID(t *testing.T) {
 		expectedError error
 		statusCode    int
 	}{
-		{name: "Test OK", ID64: "7656119arstarst", vCode: 3, expectedError: nil, statusCode: http.StatusOK},
-		{name: "Test unauthorized", ID64: "7656119arstarst", vCode: 3, expectedError: &models.ExternalAPIError{
+		{name: "Test OK", ID64: "76561197960287930", vCode: 3, expectedError: nil, statusCode: http.StatusOK},
+		{name: "Test SteamID3", ID64: "[U:1:22202]", vCode: 3, expectedError: nil, statusCode: http.StatusOK},
+		{name: "Test unauthorized", ID64: "76561197960287930", vCode: 3, expectedError: &models.ExternalAPIError{
 			Err: errors.New("unautorized request to external API"), API: "Valve", Code: http.StatusForbidden}, statusCode: http.StatusForbidden},
-		{name: "Test http err", ID64: "7656119arstarst", vCode: 3, expectedError: errors.New("test error"),
+		{name: "Test http err", ID64: "76561197960287930", vCode: 3, expectedError: errors.New("test error"),
 			respError: errors.New("test error"), statusCode: http.StatusOK},
 		{name: "Test invalid ID", ID64: "765arstars6119arstarst", vCode: 3, expectedError: &models.RequestError{
 			Err: errors.New("invalid steam id"), Response: "invalid steam id"}, statusCode: http.StatusOK},
+		{name: "Test unknown account", ID64: "76561197960287930", vCode: -1, expectedError: &models.RequestError{
+			Err: fmt.Errorf("steam account 76561197960287930: %w", models.ErrNotFound), Response: "steam account not found"},
+			statusCode: http.StatusOK},
 	}
 
 	// creating a mockGetter item to use the custom "Get" func
 	getter := &mockGetter{}
-	valve := New(getter, "123")
+	valve := New(getter, "123", false)
 
 	// run a test for each of the test items (array above)
 	for _, tc := range test {
@@ -151,18 +179,25 @@ func TestValve_ValidateValveID(t *testing.T) {
 			// setting up the Get() resp according per test_case
 			setup := &respSetup{err: tc.respError, statusCode: tc.statusCode, testRes: &testResp{}}
 			setup.testRes.Response.ID64 = tc.ID64
+			setup.testRes.Response.GameCount = new(int)
 			var tmpPlayer = struct {
 				ID64           string `json:"steamid"`
 				VisibilityCode int    `json:"communityvisibilitystate"`
 			}{ID64: tc.ID64, VisibilityCode: tc.vCode}
-			setup.testRes.Response.Players = append(setup.testRes.Response.Players, tmpPlayer)
+			// no players are returned for unknown accounts
+			if tc.vCode >= 0 {
+				setup.testRes.Response.Players = append(setup.testRes.Response.Players, tmpPlayer)
+			}
 			getter.setup = *setup
 
 			// runs the actual function
-			err := valve.ValidateValveID(tc.ID64)
+			acc, err := valve.ValidateValveID(context.Background(), tc.ID64)
 
 			// if the error we got does not correspond with the expected error, fail test
 			assert.Equal(t, tc.expectedError, err)
+			if tc.expectedError == nil {
+				assert.Equal(t, &models.ValveAccount{ID: "76561197960287930", Status: models.ValvePublic}, acc)
+			}
 		})
 	}
 }
@@ -184,7 +219,7 @@ func TestValve_GetValvePlaytime(t *testing.T) {
 
 	// creating a mockGetter item to use the custom "Get" func
 	getter := &mockGetter{}
-	valve := New(getter, "123")
+	valve := New(getter, "123", false)
 
 	var games []models.ValveGames
 
@@ -205,10 +240,113 @@ func TestValve_GetValvePlaytime(t *testing.T) {
 			getter.setup = *setup
 
 			// runs the actual function
-			_, err = valve.GetValvePlaytime(tc.ID)
+			_, err = valve.GetValvePlaytime(context.Background(), tc.ID)
 
 			// if the error we got does not correspond with the expected error, fail test
 			assert.Equal(t, tc.expectedError, err)
 		})
 	}
 }
+
+// used to mock get requests with a different response for each endpoint, identified by a part of the url
+type mockURLGetter struct {
+	responses map[string]string
+	status    int // status code for the achievement endpoints
+}
+
+func (m *mockURLGetter) Get(ctx context.Context, url string) (*http.Response, error) {
+	for part, body := range m.responses {
+		if strings.Contains(url, part) {
+			status := http.StatusOK
+			if strings.Contains(url, "ISteamUserStats") {
+				status = m.status
+			}
+
+			return &http.Response{StatusCode: status, Body: ioutil.NopCloser(strings.NewReader(body))}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected url: %s", url)
+}
+
+func TestValve_GetValvePlaytimeDetails(t *testing.T) {
+	var test = []struct {
+		name                 string
+		achievements         bool
+		achievementStatus    int
+		expectedAchievements *models.Achievements
+	}{
+		{"Test without achievements", false, http.StatusOK, nil},
+		{"Test with achievements", true, http.StatusOK, &models.Achievements{Unlocked: 2, Total: 3}},
+		{"Test private achievements", true, http.StatusForbidden, nil},
+	}
+
+	owned := `{"response": {"game_count": 3, "games": [
+		{"appid": 400, "name": "Portal", "playtime_forever": 600, "playtime_2weeks": 30, "img_icon_url": "abc",
+			"rtime_last_played": 1570000000, "playtime_windows_forever": 500, "playtime_linux_forever": 70,
+			"playtime_deck_forever": 30},
+		{"appid": 620, "name": "Portal 2", "playtime_forever": 25},
+		{"appid": 70, "name": "Half-Life", "playtime_forever": 0}
+	]}}`
+	achievements := `{"playerstats": {"success": true, "achievements": [{"achieved": 1}, {"achieved": 0}, {"achieved": 1}]}}`
+	schema := `{"game": {"availableGameStats": {"achievements": [{"name": "a"}, {"name": "b"}, {"name": "c"}]}}}`
+
+	// tc - test cases
+	for _, tc := range test {
+		t.Run(tc.name, func(t *testing.T) {
+			getter := &mockURLGetter{status: tc.achievementStatus, responses: map[string]string{
+				"GetOwnedGames":         owned,
+				"GetPlayerAchievements": achievements,
+				"GetSchemaForGame":      schema,
+			}}
+			valve := New(getter, "123", tc.achievements)
+
+			games, err := valve.GetValvePlaytime(context.Background(), "76561197997974710")
+			require.Nil(t, err)
+
+			// games which have never been played are skipped, while games played less than an hour are kept
+			require.Len(t, games, 2)
+			assert.Equal(t, models.Game{
+				Name: "Portal", Time: 10, AppID: 400, LastPlayed: 1570000000, Minutes: 600, RecentMinutes: 30,
+				Icon:         "https://media.steampowered.com/steamcommunity/public/images/apps/400/abc.jpg",
+				Platforms:    &models.PlatformPlaytime{Windows: 500, Linux: 70, Deck: 30},
+				Achievements: tc.expectedAchievements,
+			}, games[0])
+			assert.Equal(t, 0, games[1].Time)
+			assert.Equal(t, 25, games[1].Minutes)
+			assert.Empty(t, games[1].Icon)
+		})
+	}
+}
+
+func TestValve_GetValvePlaytimePrivate(t *testing.T) {
+	var test = []struct {
+		name          string
+		summaries     string
+		expectedError error
+	}{
+		{"Test games private", `{"response": {"players": [{"communityvisibilitystate": 3}]}}`,
+			&models.PrivateProfileError{Status: models.ValveGamesPrivate}},
+		{"Test friends only", `{"response": {"players": [{"communityvisibilitystate": 2}]}}`,
+			&models.PrivateProfileError{Status: models.ValveFriendsOnly}},
+		{"Test private", `{"response": {"players": [{"communityvisibilitystate": 1}]}}`,
+			&models.PrivateProfileError{Status: models.ValvePrivate}},
+		{"Test no players", `{"response": {"players": []}}`, &models.RequestError{
+			Err: fmt.Errorf("steam account 76561197960287930: %w", models.ErrNotFound), Response: "steam account not found"}},
+	}
+
+	for _, tc := range test {
+		t.Run(tc.name, func(t *testing.T) {
+			// the games are left out of the response for private profiles
+			getter := &mockURLGetter{responses: map[string]string{
+				"GetOwnedGames":      `{"response": {}}`,
+				"GetPlayerSummaries": tc.summaries,
+			}}
+			valve := New(getter, "123", false)
+
+			games, err := valve.GetValvePlaytime(context.Background(), "76561197960287930")
+			assert.Nil(t, games)
+			assert.Equal(t, tc.expectedError, err)
+		})
+	}
+}
@@ -0,0 +1,458 @@
+// Command apigen generates code from the OpenAPI document in api/openapi.json:
+// the document served by the server (pkg/server/openapi_gen.go), and the typed Go client (pkg/client/client_gen.go).
+// It is run by "go generate ./..." from the root of the repository.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+)
+
+// spec contains the parts of the OpenAPI document used by the generator
+type spec struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas    map[string]*schema    `json:"schemas"`
+		Responses  map[string]*response  `json:"responses"`
+		Parameters map[string]*parameter `json:"parameters"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Parameters  []parameter          `json:"parameters"`
+	RequestBody *body                `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+	Skip        bool                 `json:"x-go-skip"` // operations which make no sense for a client, e.g. redirects
+}
+
+type parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type body struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type response struct {
+	Ref         string                 `json:"$ref"`
+	Description string                 `json:"description"`
+	Headers     map[string]interface{} `json:"headers"`
+	Content     map[string]*mediaType  `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref         string             `json:"$ref"`
+	Type        string             `json:"type"`
+	Format      string             `json:"format"`
+	Description string             `json:"description"`
+	Items       *schema            `json:"items"`
+	Properties  map[string]*schema `json:"properties"`
+	GoType      string             `json:"x-go-type"` // existing type the schema describes
+}
+
+func main() {
+	specPath := flag.String("spec", "api/openapi.json", "Path to the OpenAPI document")
+	serverPath := flag.String("server", "pkg/server/openapi_gen.go", "Path to write the document served by the server to")
+	clientPath := flag.String("client", "pkg/client/client_gen.go", "Path to write the client to")
+	flag.Parse()
+
+	raw, err := ioutil.ReadFile(*specPath)
+	if err != nil {
+		log.Fatalf("Unable to read OpenAPI document: %s", err)
+	}
+
+	var s spec
+	err = json.Unmarshal(raw, &s)
+	if err != nil {
+		log.Fatalf("Unable to parse OpenAPI document: %s", err)
+	}
+
+	server, err := generateServer(raw)
+	if err != nil {
+		log.Fatalf("Unable to generate server document: %s", err)
+	}
+
+	client, err := generateClient(&s)
+	if err != nil {
+		log.Fatalf("Unable to generate client: %s", err)
+	}
+
+	for path, src := range map[string][]byte{*serverPath: server, *clientPath: client} {
+		err = ioutil.WriteFile(path, src, 0644)
+		if err != nil {
+			log.Fatalf("Unable to write %s: %s", path, err)
+		}
+	}
+}
+
+const header = "// Code generated by apigen from api/openapi.json. DO NOT EDIT.\n\n"
+
+// generateServer returns the source of a file containing the document as a constant
+func generateServer(raw []byte) ([]byte, error) {
+	if bytes.ContainsRune(raw, '`') {
+		return nil, fmt.Errorf("the document can not contain backticks")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.WriteString("package server\n\n")
+	buf.WriteString("// openAPISpec is the OpenAPI document describing the API, served at /api/v1/openapi.json\n")
+	fmt.Fprintf(&buf, "const openAPISpec = `%s`\n", raw)
+
+	return format.Source(buf.Bytes())
+}
+
+// generateClient returns the source of the client, containing a type for each schema and a method for each operation
+func generateClient(s *spec) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// types
+	for _, name := range sortedKeys(s.Components.Schemas) {
+		sch := s.Components.Schemas[name]
+		writeComment(&buf, fmt.Sprintf("%s is the %s schema.", name, name), sch.Description)
+
+		if sch.GoType != "" {
+			fmt.Fprintf(&buf, "type %s = %s\n\n", name, sch.GoType)
+			continue
+		}
+
+		fmt.Fprintf(&buf, "type %s struct {\n", name)
+		for _, prop := range sortedKeys(sch.Properties) {
+			fmt.Fprintf(&buf, "%s %s `json:\"%s,omitempty\"`\n", exported(prop), goType(sch.Properties[prop]), prop)
+		}
+		buf.WriteString("}\n\n")
+	}
+
+	// operations, sorted by path and method to keep the output stable
+	for _, path := range sortedKeys(s.Paths) {
+		for _, method := range sortedKeys(s.Paths[path]) {
+			op := s.Paths[path][method]
+			if op.Skip {
+				continue
+			}
+
+			err := writeOperation(&buf, s, path, strings.ToUpper(method), op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+		}
+	}
+
+	// only importing the packages used by the generated code
+	var src bytes.Buffer
+	src.WriteString(header)
+	src.WriteString("package client\n\nimport (\n")
+	for _, pkg := range []string{"context", "ctp/pkg/models", "net/http", "net/url", "strconv"} {
+		if bytes.Contains(buf.Bytes(), []byte(pkg[strings.LastIndex(pkg, "/")+1:]+".")) {
+			fmt.Fprintf(&src, "%q\n", pkg)
+		}
+	}
+	src.WriteString(")\n\n")
+	src.Write(buf.Bytes())
+
+	return format.Source(src.Bytes())
+}
+
+// writeOperation writes the client method for a single operation
+func writeOperation(buf *bytes.Buffer, s *spec, path, method string, op *operation) error {
+	if op.OperationID == "" {
+		return fmt.Errorf("missing operationId")
+	}
+
+	params := []string{"ctx context.Context"}
+	pathExpr := fmt.Sprintf("%q", path)
+	var query, header []parameter
+
+	for _, p := range op.Parameters {
+		p, err := s.resolveParameter(p)
+		if err != nil {
+			return err
+		}
+
+		params = append(params, fmt.Sprintf("%s %s", paramName(p.Name), goType(p.Schema)))
+
+		switch p.In {
+		case "path":
+			pathExpr = strings.Replace(pathExpr, "{"+p.Name+"}", "\" + url.PathEscape("+p.Name+") + \"", 1)
+		case "query":
+			query = append(query, p)
+		case "header":
+			header = append(header, p)
+		default:
+			return fmt.Errorf("unsupported parameter location %q", p.In)
+		}
+	}
+	pathExpr = strings.TrimSuffix(strings.Replace(pathExpr, ` + ""`, "", -1), ` + ""`)
+
+	// the request body, if any
+	bodyExpr, contentType := "nil", ""
+	if op.RequestBody != nil {
+		for _, ct := range sortedKeys(op.RequestBody.Content) {
+			contentType = ct
+			params = append(params, "body "+pointer(goType(op.RequestBody.Content[ct].Schema)))
+			bodyExpr = "body"
+
+			break
+		}
+	}
+
+	// the successful response, if any, and whether it has an ETag
+	var result string
+	var etag bool
+	if resp := s.resolve(op.Responses["200"]); resp != nil {
+		if mt, ok := resp.Content["application/json"]; ok {
+			result = goType(mt.Schema)
+		}
+		_, etag = resp.Headers["ETag"]
+	}
+
+	results := []string{"error"}
+	if etag {
+		results = []string{"string", "error"}
+	}
+	if result != "" {
+		results = append([]string{pointer(result)}, results...)
+	}
+
+	writeComment(buf, fmt.Sprintf("%s sends %s %s.", exported(op.OperationID), method, path), op.Summary)
+	if etag {
+		buf.WriteString("// The ETag of the response is returned along with the result.\n")
+	}
+	fmt.Fprintf(buf, "func (c *Client) %s(%s) (%s) {\n", exported(op.OperationID), strings.Join(params, ", "), strings.Join(results, ", "))
+
+	queryExpr := "nil"
+	if len(query) > 0 {
+		queryExpr = "query"
+		buf.WriteString("query := url.Values{}\n")
+		for _, p := range query {
+			writeQueryParam(buf, p)
+		}
+	}
+
+	headerExpr := "nil"
+	if len(header) > 0 {
+		headerExpr = "header"
+		buf.WriteString("header := http.Header{}\n")
+		for _, p := range header {
+			fmt.Fprintf(buf, "if %s != \"\" {\nheader.Set(%q, %s)\n}\n", paramName(p.Name), p.Name, paramName(p.Name))
+		}
+	}
+
+	method = "http.Method" + exported(strings.ToLower(method))
+	resultExpr := "nil"
+	if result != "" {
+		resultExpr = "&result"
+		fmt.Fprintf(buf, "var result %s\n", result)
+	}
+
+	respHeader := "_"
+	if etag {
+		respHeader = "respHeader"
+	}
+	fmt.Fprintf(buf, "%s, err := c.do(ctx, %s, %s, %s, %s, %q, %s, %s)\n",
+		respHeader, method, pathExpr, queryExpr, headerExpr, contentType, bodyExpr, resultExpr)
+
+	// the values returned if the request succeeded, and if it failed
+	var ok, failed []string
+	switch {
+	case result == "":
+	case pointer(result) == result:
+		ok, failed = append(ok, "result"), append(failed, "result")
+	default:
+		ok, failed = append(ok, "&result"), append(failed, "nil")
+	}
+	if etag {
+		ok, failed = append(ok, `respHeader.Get("ETag")`), append(failed, `""`)
+	}
+
+	if len(ok) == 0 {
+		buf.WriteString("return err\n}\n\n")
+		return nil
+	}
+
+	fmt.Fprintf(buf, "if err != nil {\nreturn %s, err\n}\n\nreturn %s, nil\n}\n\n", strings.Join(failed, ", "), strings.Join(ok, ", "))
+
+	return nil
+}
+
+// writeQueryParam writes the code setting the query parameter. Optional parameters are only set if they are not the zero value.
+func writeQueryParam(buf *bytes.Buffer, p parameter) {
+	name := paramName(p.Name)
+	value, zero := name, `""`
+
+	switch goType(p.Schema) {
+	case "int":
+		value, zero = "strconv.Itoa("+name+")", "0"
+	case "int64":
+		value, zero = "strconv.FormatInt("+name+", 10)", "0"
+	case "bool":
+		value, zero = "strconv.FormatBool("+name+")", "false"
+	}
+
+	if p.Required {
+		fmt.Fprintf(buf, "query.Set(%q, %s)\n", p.Name, value)
+		return
+	}
+
+	cond := name + " != " + zero
+	if zero == "false" {
+		cond = name
+	}
+
+	fmt.Fprintf(buf, "if %s {\nquery.Set(%q, %s)\n}\n", cond, p.Name, value)
+}
+
+// resolve returns the response referenced by resp, or resp itself if it is not a reference
+func (s *spec) resolve(resp *response) *response {
+	if resp == nil || resp.Ref == "" {
+		return resp
+	}
+
+	return s.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+}
+
+// resolveParameter returns the parameter referenced by p, or p itself if it is not a reference
+func (s *spec) resolveParameter(p parameter) (parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+
+	resolved, ok := s.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+	if !ok {
+		return p, fmt.Errorf("unknown parameter %s", p.Ref)
+	}
+
+	return *resolved, nil
+}
+
+// goType returns the Go type for the schema
+func goType(sch *schema) string {
+	if sch == nil {
+		return "interface{}"
+	}
+
+	if sch.Ref != "" {
+		return strings.TrimPrefix(sch.Ref, "#/components/schemas/")
+	}
+
+	switch sch.Type {
+	case "string":
+		return "string"
+	case "integer":
+		if sch.Format == "int64" {
+			return "int64"
+		}
+
+		return "int"
+	case "number":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + goType(sch.Items)
+	case "object":
+		return "map[string]interface{}"
+	}
+
+	return "interface{}"
+}
+
+// pointer returns the type used by the client for request bodies and results.
+// Structs are passed as pointers, while slices, maps and basic types are passed as they are.
+func pointer(t string) string {
+	switch t {
+	case "string", "int", "int64", "float64", "bool", "interface{}":
+		return t
+	}
+
+	if strings.HasPrefix(t, "[]") || strings.HasPrefix(t, "map[") {
+		return t
+	}
+
+	return "*" + t
+}
+
+// exported returns the name with the first letter in upper case, and "Id" replaced by "ID"
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+
+	name = strings.ToUpper(name[:1]) + name[1:]
+	if strings.HasSuffix(name, "Id") {
+		name = strings.TrimSuffix(name, "Id") + "ID"
+	}
+
+	return name
+}
+
+// paramName returns the name of the Go parameter for the parameter, e.g. "ifMatch" for "If-Match"
+func paramName(name string) string {
+	parts := strings.Split(name, "-")
+	for i := range parts {
+		if i == 0 {
+			parts[i] = strings.ToLower(parts[i][:1]) + parts[i][1:]
+			continue
+		}
+
+		parts[i] = exported(parts[i])
+	}
+
+	return strings.Join(parts, "")
+}
+
+// writeComment writes a doc comment consisting of the first sentence, followed by the description from the document (if any)
+func writeComment(buf *bytes.Buffer, first, description string) {
+	if description == "" {
+		fmt.Fprintf(buf, "// %s\n", first)
+		return
+	}
+
+	fmt.Fprintf(buf, "// %s\n// %s\n", first, description)
+}
+
+// sortedKeys returns the keys of the map in sorted order
+func sortedKeys(m interface{}) []string {
+	var keys []string
+
+	switch v := m.(type) {
+	case map[string]*schema:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]map[string]*operation:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]*operation:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	case map[string]*mediaType:
+		for k := range v {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,59 @@
+// Command embedgen generates a Go file containing the content of a data file as a string constant,
+// such that data files (e.g. the default XP rates in pkg/jagex) are compiled into the binary.
+// It is run by "go generate ./..." from the root of the repository.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+)
+
+func main() {
+	in := flag.String("in", "", "Path to the data file")
+	out := flag.String("out", "", "Path to write the Go file to")
+	pkg := flag.String("pkg", "", "Package of the Go file")
+	name := flag.String("name", "", "Name of the constant")
+	doc := flag.String("doc", "", "Documentation of the constant, following its name")
+	flag.Parse()
+
+	if *in == "" || *out == "" || *pkg == "" || *name == "" {
+		log.Fatalf("-in, -out, -pkg and -name are required")
+	}
+
+	raw, err := ioutil.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("Unable to read data file: %s", err)
+	}
+
+	src, err := generate(raw, filepath.Base(*in), *pkg, *name, *doc)
+	if err != nil {
+		log.Fatalf("Unable to generate %s: %s", *out, err)
+	}
+
+	err = ioutil.WriteFile(*out, src, 0644)
+	if err != nil {
+		log.Fatalf("Unable to write %s: %s", *out, err)
+	}
+}
+
+// generate returns the source of a file containing the data as a constant
+func generate(raw []byte, file, pkg, name, doc string) ([]byte, error) {
+	if bytes.ContainsRune(raw, '`') {
+		return nil, fmt.Errorf("the data file can not contain backticks")
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by embedgen from %s. DO NOT EDIT.\n\n", file)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if doc != "" {
+		fmt.Fprintf(&buf, "// %s %s\n", name, doc)
+	}
+	fmt.Fprintf(&buf, "const %s = `%s`\n", name, raw)
+
+	return format.Source(buf.Bytes())
+}